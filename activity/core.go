@@ -104,7 +104,7 @@ func Warnf(ctx context.Context, msg string, a ...interface{}) {
 	journal.Warnf(msg, a...)
 }
 
-// SubmitMethodInvocation submits a method invocation event to Google Analytics.
+// SubmitMethodInvocation submits a method invocation event to analytics.
 // It then records the invocation to the journal identified by the ID at `activity.JournalKey`
 // in the provided context.
 //
@@ -132,8 +132,9 @@ func SubmitMethodInvocation(ctx context.Context, plugin string, entryType string
 			"Invocation",
 			"Method",
 			analytics.Params{
-				"Label":  method,
-				"Plugin": plugin,
+				"Label":      method,
+				"Plugin":     plugin,
+				"Entry Type": entryType,
 			},
 		)
 		if err != nil {
@@ -0,0 +1,212 @@
+package activity
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CacheStatus describes whether an event's result was served from Wash's cache.
+type CacheStatus string
+
+// The possible cache statuses. Events for uncached operations have an empty status.
+const (
+	CacheHit  CacheStatus = "hit"
+	CacheMiss CacheStatus = "miss"
+)
+
+// Event is a structured record of a plugin method invocation.
+type Event struct {
+	Method  string `json:"method,omitempty"`
+	EntryID string `json:"entry,omitempty"`
+	Plugin  string `json:"plugin,omitempty"`
+	// Duration is in nanoseconds when encoded as JSON.
+	Duration time.Duration `json:"duration,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Cache    CacheStatus   `json:"cache,omitempty"`
+}
+
+func (e Event) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%v %v", e.Method, e.EntryID)
+	if e.Cache != "" {
+		fmt.Fprintf(&sb, " (cache %v)", e.Cache)
+	}
+	fmt.Fprintf(&sb, " took %v", e.Duration)
+	if e.Error != "" {
+		fmt.Fprintf(&sb, ": %v", e.Error)
+	}
+	return sb.String()
+}
+
+func (e Event) fields() log.Fields {
+	fields := log.Fields{
+		"method":   e.Method,
+		"entry":    e.EntryID,
+		"plugin":   e.Plugin,
+		"duration": e.Duration,
+	}
+	if e.Error != "" {
+		fields["error"] = e.Error
+	}
+	if e.Cache != "" {
+		fields["cache"] = e.Cache
+	}
+	return fields
+}
+
+// Line is a line in a journal. Lines written by RecordEvent include the event's fields.
+type Line struct {
+	Time  time.Time `json:"time"`
+	Level string    `json:"level"`
+	Msg   string    `json:"msg"`
+	Event
+}
+
+// IsEvent returns whether the line records an event.
+func (l Line) IsEvent() bool {
+	return l.Method != ""
+}
+
+// ParseLine parses a line of a journal.
+func ParseLine(data []byte) (Line, error) {
+	var line Line
+	err := json.Unmarshal(data, &line)
+	return line, err
+}
+
+// PluginError is a plugin's errored event along with the index of its journal in history.
+type PluginError struct {
+	Index int
+	Line
+}
+
+// RecordEvent writes a structured event to the journal identified by the ID at
+// `activity.JournalKey` in the provided context. It follows the same rules as Record for
+// contexts without a journal, except that the event is written to the server logs at the
+// debug level.
+func RecordEvent(ctx context.Context, e Event) {
+	journal, ok := ctx.Value(JournalKey).(Journal)
+	if !ok {
+		log.Debug(e)
+		return
+	}
+
+	if journal.ID == "" {
+		journal = deadLetterOfficeJournal
+	} else {
+		journal.addToHistory()
+		if e.Error != "" && e.Plugin != "" {
+			journal.recordPluginError(Line{Time: time.Now(), Level: log.WarnLevel.String(), Msg: e.String(), Event: e})
+		}
+	}
+
+	journal.RecordEvent(e)
+}
+
+// RecordEvent writes a structured event to the journal. Events that errored are written at
+// WARN level.
+func (j Journal) RecordEvent(e Event) {
+	log.Debug(e)
+
+	if logger, err := j.getLogger(); err != nil {
+		log.Warnf("Error creating journal's logger %v: %v", j.ID, err)
+	} else if entry := logger.WithFields(e.fields()); e.Error != "" {
+		entry.Warn(e)
+	} else {
+		entry.Info(e)
+	}
+}
+
+// Events returns the events recorded in the journal. Lines that can't be parsed are skipped.
+func (j Journal) Events() ([]Line, error) {
+	f, err := os.Open(j.filepath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Nothing has been recorded yet.
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Line
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 4096), 100*1024*1024)
+	for scanner.Scan() {
+		if line, err := ParseLine(scanner.Bytes()); err == nil && line.IsEvent() {
+			events = append(events, line)
+		}
+	}
+	return events, scanner.Err()
+}
+
+// Filter selects journals and events from history. Zero-valued fields match everything.
+type Filter struct {
+	// Since and Until bound when a journal started or when an event was recorded.
+	Since, Until time.Time
+	Plugin       string
+	// PathPrefix matches events for entries whose ID starts with it.
+	PathPrefix string
+	ErrorsOnly bool
+}
+
+// FiltersEvents returns whether the filter has criteria that apply to events.
+func (f Filter) FiltersEvents() bool {
+	return f.Plugin != "" || f.PathPrefix != "" || f.ErrorsOnly
+}
+
+func (f Filter) inRange(t time.Time) bool {
+	return (f.Since.IsZero() || !t.Before(f.Since)) && (f.Until.IsZero() || !t.After(f.Until))
+}
+
+// MatchesEvent returns whether the event line matches the filter.
+func (f Filter) MatchesEvent(l Line) bool {
+	if !f.inRange(l.Time) {
+		return false
+	}
+	if f.Plugin != "" && l.Plugin != f.Plugin {
+		return false
+	}
+	if f.PathPrefix != "" && !hasPathPrefix(l.EntryID, f.PathPrefix) {
+		return false
+	}
+	return !f.ErrorsOnly || l.Error != ""
+}
+
+// MatchesJournal returns whether the journal matches the filter. If the filter has criteria
+// that apply to events, the journal must include a matching event.
+func (f Filter) MatchesJournal(j Journal) (bool, error) {
+	if !f.inRange(j.Start()) {
+		return false, nil
+	}
+	if !f.FiltersEvents() {
+		return true, nil
+	}
+
+	events, err := j.Events()
+	if err != nil {
+		return false, err
+	}
+	// The journal's already in range, so only match events on their other criteria.
+	eventFilter := f
+	eventFilter.Since, eventFilter.Until = time.Time{}, time.Time{}
+	for _, event := range events {
+		if eventFilter.MatchesEvent(event) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// hasPathPrefix returns whether id is prefix or one of its descendants.
+func hasPathPrefix(id, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || id == prefix || strings.HasPrefix(id, prefix+"/")
+}
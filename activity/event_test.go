@@ -0,0 +1,89 @@
+package activity
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordEvent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wash_activity")
+	if !assert.NoError(t, err) {
+		return
+	}
+	savedDir := Dir()
+	SetDir(dir)
+	history = initHistory()
+	defer func() {
+		CloseAll()
+		history = initHistory()
+		SetDir(savedDir)
+		assert.NoError(t, os.RemoveAll(dir))
+	}()
+
+	journal := Journal{ID: "events", start: time.Now()}
+	ctx := context.WithValue(context.Background(), JournalKey, journal)
+	Record(ctx, "not an event")
+	RecordEvent(ctx, Event{Method: "List", EntryID: "/docker/containers", Plugin: "docker", Duration: time.Millisecond, Cache: CacheMiss})
+	RecordEvent(ctx, Event{Method: "Exec", EntryID: "/aws/ec2/foo", Plugin: "aws", Duration: time.Second, Error: "failed"})
+	assert.Equal(t, []Journal{journal}, History())
+
+	events, err := journal.Events()
+	if assert.NoError(t, err) && assert.Len(t, events, 2) {
+		assert.Equal(t, "info", events[0].Level)
+		assert.Equal(t, "List /docker/containers (cache miss) took 1ms", events[0].Msg)
+		assert.Equal(t, Event{Method: "List", EntryID: "/docker/containers", Plugin: "docker", Duration: time.Millisecond, Cache: CacheMiss}, events[0].Event)
+		assert.Equal(t, "warning", events[1].Level)
+		assert.Equal(t, "failed", events[1].Error)
+		assert.Empty(t, events[1].Cache)
+	}
+
+	lastErrors := LastPluginErrors()
+	if assert.Len(t, lastErrors, 1) {
+		assert.Equal(t, 0, lastErrors["aws"].Index)
+		assert.Equal(t, "warning", lastErrors["aws"].Level)
+		assert.Equal(t, "Exec /aws/ec2/foo took 1s: failed", lastErrors["aws"].Msg)
+		assert.Equal(t, Event{Method: "Exec", EntryID: "/aws/ec2/foo", Plugin: "aws", Duration: time.Second, Error: "failed"}, lastErrors["aws"].Event)
+	}
+
+	events, err = Journal{ID: "missing"}.Events()
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestFilter(t *testing.T) {
+	now := time.Now()
+	line := Line{Time: now, Event: Event{Method: "List", EntryID: "/docker/containers/foo", Plugin: "docker"}}
+
+	assert.False(t, Filter{}.FiltersEvents())
+	assert.True(t, Filter{}.MatchesEvent(line))
+	assert.True(t, Filter{Since: now.Add(-time.Minute), Until: now}.MatchesEvent(line))
+	assert.False(t, Filter{Since: now.Add(time.Second)}.MatchesEvent(line))
+	assert.False(t, Filter{Until: now.Add(-time.Second)}.MatchesEvent(line))
+
+	assert.True(t, Filter{Plugin: "docker"}.MatchesEvent(line))
+	assert.False(t, Filter{Plugin: "aws"}.MatchesEvent(line))
+
+	assert.True(t, Filter{PathPrefix: "/docker/containers/"}.MatchesEvent(line))
+	assert.True(t, Filter{PathPrefix: "/docker/containers/foo"}.MatchesEvent(line))
+	assert.False(t, Filter{PathPrefix: "/docker/cont"}.MatchesEvent(line))
+
+	assert.False(t, Filter{ErrorsOnly: true}.MatchesEvent(line))
+	line.Error = "failed"
+	assert.True(t, Filter{ErrorsOnly: true}.MatchesEvent(line))
+
+	ok, err := Filter{Since: now.Add(time.Second)}.MatchesJournal(Journal{ID: "missing", start: now})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = Filter{Since: now.Add(-time.Second)}.MatchesJournal(Journal{ID: "missing", start: now})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	// Journals without matching events don't match event criteria.
+	ok, err = Filter{Plugin: "docker"}.MatchesJournal(Journal{ID: "missing", start: now})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
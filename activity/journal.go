@@ -35,14 +35,18 @@ type historyBlob struct {
 	mux    sync.RWMutex
 	list   []Journal
 	stored map[string]int
+	// lastErrors tracks each plugin's most recent errored event so that it can be
+	// found without re-reading every journal in history.
+	lastErrors map[string]PluginError
 }
 
 var history = initHistory()
 
 func initHistory() historyBlob {
 	return historyBlob{
-		list:   make([]Journal, 0),
-		stored: make(map[string]int),
+		list:       make([]Journal, 0),
+		stored:     make(map[string]int),
+		lastErrors: make(map[string]PluginError),
 	}
 }
 
@@ -70,6 +74,25 @@ func (j Journal) addToHistory() {
 	history.list = append(history.list, j)
 }
 
+// recordPluginError records line as its plugin's most recent error. The journal must
+// already be in history.
+func (j Journal) recordPluginError(line Line) {
+	history.mux.Lock()
+	defer history.mux.Unlock()
+	history.lastErrors[line.Plugin] = PluginError{Index: history.stored[j.ID], Line: line}
+}
+
+// LastPluginErrors returns the most recent errored event recorded in history for each plugin.
+func LastPluginErrors() map[string]PluginError {
+	history.mux.RLock()
+	defer history.mux.RUnlock()
+	lastErrors := make(map[string]PluginError, len(history.lastErrors))
+	for plugin, pluginErr := range history.lastErrors {
+		lastErrors[plugin] = pluginErr
+	}
+	return lastErrors
+}
+
 // Callers retrieving the recorder this way should not use
 // recorder.logger since it is not guaranteed that
 // recorder.logger != nil. Use getRecorder() instead.
@@ -106,7 +129,7 @@ func (j Journal) getRecorder() (recorder, error) {
 		l := &log.Logger{
 			Out:       f,
 			Level:     log.TraceLevel,
-			Formatter: &log.TextFormatter{TimestampFormat: time.RFC3339Nano},
+			Formatter: &log.JSONFormatter{TimestampFormat: time.RFC3339Nano},
 		}
 		recorder.logger = l
 		return recorder, nil
@@ -140,7 +163,8 @@ func (j Journal) Record(msg string, a ...interface{}) {
 	}
 }
 
-// Open returns a reader to read the journal.
+// Open returns a reader to read the journal. Each line of the journal is a JSON object that can
+// be parsed with ParseLine.
 func (j Journal) Open() (io.ReadCloser, error) {
 	return os.Open(j.filepath())
 }
@@ -22,7 +22,8 @@ import (
 // Params represents additional measurement protocol parameters to
 // pass into Client#Screenview or Client#Event. These will typically be
 // custom dimension values. The currently supported custom dimensions
-// are "Plugin" and "Entry Type". Note that Event hits can also specify
+// are "Plugin" and "Entry Type". "Entry Type" is only recorded by the
+// local client; it isn't sent to GA. Note that Event hits can also specify
 // the event's (optional) label and value via the "Label" and "Value"
 // keys in params.
 //
@@ -39,11 +40,22 @@ type Client interface {
 }
 
 // NewClient returns a new Google Analytics client for submitting Wash analytics.
+// If config.Local is set, then the returned client records the hits to a local
+// file instead.
 func NewClient(config Config) Client {
 	if config.Disabled {
 		log.Debugf("Analytics opt-out is set, analytics will be disabled")
 		return &noopClient{}
 	}
+	if config.Local {
+		path, err := config.HitsFile()
+		if err != nil {
+			log.Infof("Could not find the local analytics file, analytics will be disabled: %v", err)
+			return &noopClient{}
+		}
+		log.Debugf("Local analytics is set, analytics will be recorded to %v", path)
+		return &localClient{path: path}
+	}
 	client := &client{
 		userID: config.UserID,
 	}
@@ -166,8 +178,11 @@ func (c *client) baseParams() Params {
 	return params
 }
 
+// settableCustomDimensions maps each custom dimension to its GA index. Custom
+// dimensions without an index are only recorded locally.
 var settableCustomDimensions = map[string]string{
-	"Plugin": "cd2",
+	"Plugin":     "cd2",
+	"Entry Type": "",
 }
 
 func mungeCustomDimensions(customDimensions Params) (Params, error) {
@@ -186,6 +201,9 @@ func mungeCustomDimensions(customDimensions Params) (Params, error) {
 			)
 		}
 		delete(customDimensions, cd)
+		if index == "" {
+			continue
+		}
 		mungedCustomDimensions[index] = value
 	}
 	return mungedCustomDimensions, nil
@@ -140,6 +140,21 @@ func (s *ClientTestSuite) TestEvent_ValidInput_WithValue_EnqueuesHit() {
 	}
 }
 
+func (s *ClientTestSuite) TestEvent_LocalOnlyCustomDimension_IsNotSubmitted() {
+	err := s.c.Event("Invocation", "Method", Params{
+		"Plugin":     "aws",
+		"Entry Type": "aws::s3Bucket",
+	})
+	if s.NoError(err) {
+		s.assertHits(Params{
+			"t":   "event",
+			"ec":  "Invocation",
+			"ea":  "Method",
+			"cd2": "aws",
+		})
+	}
+}
+
 func (s *ClientTestSuite) TestFlush_NoQueuedHits() {
 	s.c.Flush()
 	s.mockHTTPClient.AssertNotCalled(s.T(), "post")
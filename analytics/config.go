@@ -15,10 +15,28 @@ import (
 type Config struct {
 	Disabled bool      `yaml:"disabled"`
 	UserID   uuid.UUID `yaml:"user-id"`
+	// Local records hits to File instead of submitting them to GA.
+	Local bool `yaml:"local,omitempty"`
+	// File is where hits are recorded if Local is set. It defaults to
+	// $HOME/.puppetlabs/wash/analytics.jsonl.
+	File string `yaml:"file,omitempty"`
+}
+
+// HitsFile returns the file that hits are recorded to if c.Local is set.
+func (c Config) HitsFile() (string, error) {
+	if c.File != "" {
+		return c.File, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".puppetlabs", "wash", "analytics.jsonl"), nil
 }
 
 // GetConfig returns Wash's analytics config, which is located at
-// $HOME/.puppetlabs/wash/analytics.yaml.
+// $HOME/.puppetlabs/wash/analytics.yaml. Setting the WASH_LOCAL_ANALYTICS
+// environment variable to true is the same as setting local.
 func GetConfig() (Config, error) {
 	config := Config{}
 
@@ -45,7 +63,15 @@ func GetConfig() (Config, error) {
 	if err != nil && !os.IsNotExist(err) {
 		return config, newConfigReadErr(analyticsConfigFile, err)
 	}
-	if config.Disabled || config.UserID != uuid.Nil {
+	if localStr, ok := os.LookupEnv("WASH_LOCAL_ANALYTICS"); ok {
+		localBool, err := strconv.ParseBool(localStr)
+		if err != nil {
+			return config, fmt.Errorf("WASH_LOCAL_ANALYTICS is set to %v. Valid values are 'true' or 'false'", localStr)
+		}
+		config.Local = localBool
+	}
+	if config.Disabled || config.Local || config.UserID != uuid.Nil {
+		// Local hits are not associated with a user ID, so there is no need to generate one.
 		return config, nil
 	}
 	// Analytics is not disabled, but no user ID is set. Thus, we'll need
@@ -1,5 +1,5 @@
 // Package analytics provides tools for sending over Wash events and screenviews
-// to Google Analytics
+// to Google Analytics, or for recording them locally
 package analytics
 
 import (
@@ -49,6 +49,14 @@ func (p Params) String() string {
 	return string(jsonBytes)
 }
 
+func (p Params) clone() Params {
+	clone := make(Params, len(p))
+	for param, value := range p {
+		clone[param] = value
+	}
+	return clone
+}
+
 func (p Params) delete(key string) string {
 	value := p[key]
 	delete(p, key)
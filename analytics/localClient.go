@@ -0,0 +1,187 @@
+package analytics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Hit represents a screenview or event hit that's recorded by the local
+// client. Hits are recorded as newline-delimited JSON objects.
+type Hit struct {
+	Time time.Time `json:"time"`
+	// Type is either "screenview" or "event".
+	Type string `json:"type"`
+	// Name is the screenview's screen name.
+	Name     string `json:"name,omitempty"`
+	Category string `json:"category,omitempty"`
+	Action   string `json:"action,omitempty"`
+	Label    string `json:"label,omitempty"`
+	Value    string `json:"value,omitempty"`
+	// Dimensions are the hit's custom dimensions, e.g. "Plugin".
+	Dimensions Params `json:"dimensions,omitempty"`
+}
+
+// localClient records hits to a local file so that the data stays on the
+// machine. Hits are written as they're received, so there's nothing to flush.
+type localClient struct {
+	path string
+	mux  sync.Mutex
+}
+
+func (c *localClient) Screenview(name string, params Params) error {
+	// newScreenview munges the params so pass it a copy.
+	s, err := newScreenview(name, params.clone())
+	if err != nil {
+		return err
+	}
+	log.Debugf("%v received", s)
+	c.record(Hit{
+		Type:       "screenview",
+		Name:       name,
+		Dimensions: params,
+	})
+	return nil
+}
+
+func (c *localClient) Event(category string, action string, params Params) error {
+	// newEvent munges the params so pass it a copy.
+	e, err := newEvent(category, action, params.clone())
+	if err != nil {
+		return err
+	}
+	log.Debugf("%v received", e)
+	params = params.clone()
+	c.record(Hit{
+		Type:       "event",
+		Category:   category,
+		Action:     action,
+		Label:      params.delete("Label"),
+		Value:      params.delete("Value"),
+		Dimensions: params,
+	})
+	return nil
+}
+
+func (c *localClient) Flush() {}
+
+func (c *localClient) record(hit Hit) {
+	hit.Time = time.Now()
+	if len(hit.Dimensions) == 0 {
+		hit.Dimensions = nil
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if err := c.write(hit); err != nil {
+		log.Infof("Failed to record analytics to %v: %v", c.path, err)
+	}
+}
+
+func (c *localClient) write(hit Hit) error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(c.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(hit); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadHits reads the hits that the local client recorded to the given file.
+func ReadHits(path string) ([]Hit, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var hits []Hit
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var hit Hit
+		if err := json.Unmarshal(scanner.Bytes(), &hit); err != nil {
+			return nil, fmt.Errorf("%v:%v: could not parse the hit: %v", path, lineNum, err)
+		}
+		hits = append(hits, hit)
+	}
+	return hits, scanner.Err()
+}
+
+// Count is the number of times that something was used.
+type Count struct {
+	Name  string
+	Count int
+}
+
+// Usage summarizes the recorded hits. Each list is sorted from most to least
+// used.
+type Usage struct {
+	// Commands counts the screenview hits, which are submitted for each
+	// invoked Wash command.
+	Commands []Count
+	// Plugins, EntryTypes and Methods count the method invocation events.
+	Plugins    []Count
+	EntryTypes []Count
+	Methods    []Count
+}
+
+// Summarize counts the hits that were recorded at or after since. A zero since
+// includes all of the hits.
+func Summarize(hits []Hit, since time.Time) Usage {
+	commands := make(map[string]int)
+	plugins := make(map[string]int)
+	entryTypes := make(map[string]int)
+	methods := make(map[string]int)
+	for _, hit := range hits {
+		if hit.Time.Before(since) {
+			continue
+		}
+		switch {
+		case hit.Type == "screenview":
+			commands[hit.Name]++
+		case hit.Type == "event" && hit.Category == "Invocation" && hit.Action == "Method":
+			methods[hit.Label]++
+			if plugin := hit.Dimensions["Plugin"]; plugin != "" {
+				plugins[plugin]++
+			}
+			if entryType := hit.Dimensions["Entry Type"]; entryType != "" {
+				entryTypes[entryType]++
+			}
+		}
+	}
+	return Usage{
+		Commands:   sortCounts(commands),
+		Plugins:    sortCounts(plugins),
+		EntryTypes: sortCounts(entryTypes),
+		Methods:    sortCounts(methods),
+	}
+}
+
+func sortCounts(counts map[string]int) []Count {
+	sorted := make([]Count, 0, len(counts))
+	for name, count := range counts {
+		sorted = append(sorted, Count{Name: name, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
@@ -0,0 +1,141 @@
+package analytics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type LocalClientTestSuite struct {
+	suite.Suite
+	dir string
+	c   *localClient
+}
+
+func (s *LocalClientTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "analytics")
+	if err != nil {
+		s.FailNow(err.Error())
+	}
+	s.dir = dir
+	config := Config{
+		Local: true,
+		File:  filepath.Join(dir, "wash", "analytics.jsonl"),
+	}
+	s.c = NewClient(config).(*localClient)
+}
+
+func (s *LocalClientTestSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *LocalClientTestSuite) TestNewClient_AnalyticsDisabled_ReturnsNoopClient() {
+	config := Config{
+		Disabled: true,
+		Local:    true,
+		UserID:   uuid.New(),
+	}
+	_, ok := NewClient(config).(*noopClient)
+	s.True(ok, "NewClient does not return a noopClient when analytics is disabled")
+}
+
+func (s *LocalClientTestSuite) TestScreenview_InvalidCustomDimension_ReturnsError() {
+	err := s.c.Screenview("foo", Params{"Invalid Custom Dimension": "Foo"})
+	s.Regexp("Invalid.*Dimension.*settable", err)
+	s.assertHits()
+}
+
+func (s *LocalClientTestSuite) TestEvent_EmptyCategory_ReturnsError() {
+	err := s.c.Event("", "", Params{})
+	s.Regexp("category.*required", err)
+	s.assertHits()
+}
+
+func (s *LocalClientTestSuite) TestRecordsHits() {
+	params := Params{
+		"Label":      "List",
+		"Plugin":     "aws",
+		"Entry Type": "aws::s3Bucket",
+	}
+	s.NoError(s.c.Screenview("ls", Params{}))
+	s.NoError(s.c.Event("Invocation", "Method", params))
+	// The caller's params shouldn't be modified.
+	s.Len(params, 3)
+
+	s.assertHits(
+		Hit{Type: "screenview", Name: "ls"},
+		Hit{
+			Type:       "event",
+			Category:   "Invocation",
+			Action:     "Method",
+			Label:      "List",
+			Dimensions: Params{"Plugin": "aws", "Entry Type": "aws::s3Bucket"},
+		},
+	)
+}
+
+func (s *LocalClientTestSuite) TestReadHits_InvalidHit_ReturnsError() {
+	path := filepath.Join(s.dir, "invalid.jsonl")
+	if err := ioutil.WriteFile(path, []byte("{}\nnot json\n"), 0640); err != nil {
+		s.FailNow(err.Error())
+	}
+	_, err := ReadHits(path)
+	s.Regexp("invalid.jsonl:2: could not parse the hit", err)
+}
+
+func (s *LocalClientTestSuite) TestSummarize() {
+	now := time.Now()
+	invocation := func(method string, plugin string, entryType string) Hit {
+		return Hit{
+			Time:       now,
+			Type:       "event",
+			Category:   "Invocation",
+			Action:     "Method",
+			Label:      method,
+			Dimensions: Params{"Plugin": plugin, "Entry Type": entryType},
+		}
+	}
+	hits := []Hit{
+		{Time: now.Add(-time.Hour), Type: "screenview", Name: "find"},
+		{Time: now, Type: "screenview", Name: "ls"},
+		{Time: now, Type: "screenview", Name: "ls"},
+		{Time: now, Type: "screenview", Name: "exec"},
+		invocation("List", "docker", "docker::containers"),
+		invocation("List", "aws", "aws::s3Bucket"),
+		invocation("Exec", "docker", "docker::container"),
+		{Time: now, Type: "event", Category: "Other", Action: "Method", Label: "Read"},
+	}
+
+	usage := Summarize(hits, now.Add(-time.Minute))
+	s.Equal([]Count{{"ls", 2}, {"exec", 1}}, usage.Commands)
+	s.Equal([]Count{{"docker", 2}, {"aws", 1}}, usage.Plugins)
+	s.Equal([]Count{{"aws::s3Bucket", 1}, {"docker::container", 1}, {"docker::containers", 1}}, usage.EntryTypes)
+	s.Equal([]Count{{"List", 2}, {"Exec", 1}}, usage.Methods)
+
+	usage = Summarize(hits, time.Time{})
+	s.Equal([]Count{{"ls", 2}, {"exec", 1}, {"find", 1}}, usage.Commands)
+}
+
+func (s *LocalClientTestSuite) assertHits(expected ...Hit) {
+	hits, err := ReadHits(s.c.path)
+	if len(expected) == 0 {
+		s.True(os.IsNotExist(err), "expected no hits to be recorded")
+		return
+	}
+	if s.NoError(err) && s.Len(hits, len(expected)) {
+		for i, hit := range hits {
+			s.WithinDuration(time.Now(), hit.Time, time.Minute)
+			hit.Time = time.Time{}
+			s.Equal(expected[i], hit)
+		}
+	}
+}
+
+func TestLocalClient(t *testing.T) {
+	suite.Run(t, new(LocalClientTestSuite))
+}
@@ -19,6 +19,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/Benchkram/errz"
 	"github.com/puppetlabs/wash/activity"
@@ -33,7 +34,11 @@ type Client interface {
 	Metadata(path string) (map[string]interface{}, error)
 	Stream(path string) (io.ReadCloser, error)
 	Exec(path string, command string, args []string, opts apitypes.ExecOptions) (<-chan apitypes.ExecPacket, error)
-	History(bool) (chan apitypes.Activity, error)
+	BatchExec(body apitypes.BatchExecBody) (<-chan apitypes.BatchExecPacket, error)
+	ExecSession(path string, body apitypes.ExecSessionBody) (*ExecSession, error)
+	Find(path string, query interface{}) ([]apitypes.Entry, error)
+	History(follow bool, filter apitypes.HistoryFilter) (chan apitypes.Activity, error)
+	HistoryEvents(index int, filter apitypes.HistoryFilter) ([]apitypes.ActivityEvent, error)
 	ActivityJournal(index int, follow bool) (io.ReadCloser, error)
 	Clear(path string) ([]string, error)
 	// A "nil" schema means that the schema's unknown.
@@ -41,6 +46,12 @@ type Client interface {
 	Screenview(name string, params analytics.Params) error
 	Delete(path string) (bool, error)
 	Signal(path string, signal string) error
+	Queries() ([]apitypes.SavedQuery, error)
+	SaveQuery(query apitypes.SavedQuery) error
+	DeleteQuery(name string) error
+	Plugins() ([]apitypes.PluginStatus, error)
+	Plugin(name string) (apitypes.PluginStatus, error)
+	ReloadPlugins(name string) ([]apitypes.PluginReload, error)
 }
 
 // A domainSocketClient is a wash API client.
@@ -214,12 +225,68 @@ func (c *domainSocketClient) Exec(path string, command string, args []string, op
 	return events, nil
 }
 
+// BatchExec invokes the given command + args on each of the resources in
+// body.Paths.
+//
+// The resulting channel contains events from all of the resources, ordered as
+// we receive them from the server. The channel will be closed when there are no
+// more events.
+func (c *domainSocketClient) BatchExec(body apitypes.BatchExecBody) (<-chan apitypes.BatchExecPacket, error) {
+	paths := make([]string, len(body.Paths))
+	for i, path := range body.Paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not calculate the absolute path of %v: %v", path, err)
+		}
+		paths[i] = absPath
+	}
+	body.Paths = paths
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := c.doRequest(http.MethodPost, "/fs/exec/batch", url.Values{}, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan apitypes.BatchExecPacket, 1)
+	go func() {
+		defer func() { errz.Log(respBody.Close()) }()
+		defer close(events)
+		decoder := json.NewDecoder(respBody)
+		for {
+			var pkt apitypes.BatchExecPacket
+			if err := decoder.Decode(&pkt); err != nil {
+				if err != io.EOF {
+					log.Println(err)
+				}
+				return
+			}
+			events <- pkt
+		}
+	}()
+	return events, nil
+}
+
+// Find returns the entries under "path" that satisfy the given RQL query.
+func (c *domainSocketClient) Find(path string, query interface{}) ([]apitypes.Entry, error) {
+	jsonBody, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	var entries []apitypes.Entry
+	err = c.doRequestAndParseJSONBody(http.MethodPost, "/fs/find", url.Values{"path": []string{path}}, bytes.NewReader(jsonBody), &entries)
+	return entries, err
+}
+
 // History returns a command history channel for the current wash server session.
 // If follow is false, it closes when all current activity has been delivered.
-func (c *domainSocketClient) History(follow bool) (chan apitypes.Activity, error) {
-	var params url.Values
+func (c *domainSocketClient) History(follow bool, filter apitypes.HistoryFilter) (chan apitypes.Activity, error) {
+	params := historyFilterParams(filter)
 	if follow {
-		params = url.Values{"follow": []string{"true"}}
+		params.Set("follow", "true")
 	}
 	respBody, err := c.doRequest(http.MethodGet, "/history", params, nil)
 	if err != nil {
@@ -245,6 +312,41 @@ func (c *domainSocketClient) History(follow bool) (chan apitypes.Activity, error
 	return acts, nil
 }
 
+// HistoryEvents returns the structured events recorded in the current wash server session's
+// history that match the filter. If index is non-negative, then only the events recorded by
+// the activity at that index are returned.
+func (c *domainSocketClient) HistoryEvents(index int, filter apitypes.HistoryFilter) ([]apitypes.ActivityEvent, error) {
+	params := historyFilterParams(filter)
+	if index >= 0 {
+		params.Set("index", strconv.Itoa(index))
+	}
+	var events []apitypes.ActivityEvent
+	if err := c.getRequest("/history/events", params, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func historyFilterParams(filter apitypes.HistoryFilter) url.Values {
+	params := url.Values{}
+	if !filter.Since.IsZero() {
+		params.Set("since", filter.Since.Format(time.RFC3339))
+	}
+	if !filter.Until.IsZero() {
+		params.Set("until", filter.Until.Format(time.RFC3339))
+	}
+	if filter.Plugin != "" {
+		params.Set("plugin", filter.Plugin)
+	}
+	if filter.PathPrefix != "" {
+		params.Set("path", filter.PathPrefix)
+	}
+	if filter.ErrorsOnly {
+		params.Set("errors", "true")
+	}
+	return params
+}
+
 // ActivityJournal returns a reader for the journal associated with a particular command in history.
 // If follow is true, it streams new updates instead of returning the whole journal.
 func (c *domainSocketClient) ActivityJournal(index int, follow bool) (io.ReadCloser, error) {
@@ -316,3 +418,57 @@ func (c *domainSocketClient) Signal(path string, signal string) error {
 	_, err = c.doRequest(http.MethodPost, "/fs/signal", url.Values{"path": []string{path}}, bytes.NewReader(jsonBody))
 	return err
 }
+
+// Queries returns the saved queries
+func (c *domainSocketClient) Queries() ([]apitypes.SavedQuery, error) {
+	var queries []apitypes.SavedQuery
+	if err := c.getRequest("/queries", url.Values{}, &queries); err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+// SaveQuery saves the given query, replacing any existing query with the same name
+func (c *domainSocketClient) SaveQuery(query apitypes.SavedQuery) error {
+	jsonBody, err := json.Marshal(query)
+	if err != nil {
+		return err
+	}
+	_, err = c.doRequest(http.MethodPost, "/queries", url.Values{}, bytes.NewReader(jsonBody))
+	return err
+}
+
+// DeleteQuery deletes the named query
+func (c *domainSocketClient) DeleteQuery(name string) error {
+	_, err := c.doRequest(http.MethodDelete, "/queries/"+url.PathEscape(name), url.Values{}, nil)
+	return err
+}
+
+// Plugins returns the status of each plugin loaded by the Wash daemon
+func (c *domainSocketClient) Plugins() ([]apitypes.PluginStatus, error) {
+	var statuses []apitypes.PluginStatus
+	if err := c.getRequest("/plugins", url.Values{}, &statuses); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// Plugin returns the named plugin's status
+func (c *domainSocketClient) Plugin(name string) (apitypes.PluginStatus, error) {
+	var status apitypes.PluginStatus
+	err := c.getRequest("/plugins/"+url.PathEscape(name), url.Values{}, &status)
+	return status, err
+}
+
+// ReloadPlugins reloads the named plugin, or every plugin that changed if name is empty
+func (c *domainSocketClient) ReloadPlugins(name string) ([]apitypes.PluginReload, error) {
+	params := url.Values{}
+	if name != "" {
+		params.Set("name", name)
+	}
+	var reloads []apitypes.PluginReload
+	if err := c.doRequestAndParseJSONBody(http.MethodPost, "/plugins/reload", params, nil, &reloads); err != nil {
+		return nil, err
+	}
+	return reloads, nil
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/puppetlabs/wash/activity"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// ExecSession represents an interactive exec session. Write sends input to the
+// command's stdin.
+type ExecSession struct {
+	conn   net.Conn
+	mux    sync.Mutex
+	enc    *json.Encoder
+	output chan apitypes.ExecSessionOutput
+}
+
+// Write writes p to the command's stdin.
+func (s *ExecSession) Write(p []byte) (int, error) {
+	if err := s.send(apitypes.ExecSessionInput{Stdin: p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Resize resizes the session's terminal.
+func (s *ExecSession) Resize(size plugin.TerminalSize) error {
+	return s.send(apitypes.ExecSessionInput{Resize: &size})
+}
+
+// CloseStdin closes the command's stdin.
+func (s *ExecSession) CloseStdin() error {
+	return s.send(apitypes.ExecSessionInput{CloseStdin: true})
+}
+
+// Output returns the session's output. The channel's last message contains the
+// command's exit code. The channel is closed when the session ends.
+func (s *ExecSession) Output() <-chan apitypes.ExecSessionOutput {
+	return s.output
+}
+
+// Close ends the session.
+func (s *ExecSession) Close() error {
+	return s.conn.Close()
+}
+
+func (s *ExecSession) send(input apitypes.ExecSessionInput) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.enc.Encode(input)
+}
+
+// ExecSession starts an interactive exec session that runs the given command +
+// args on the resource located at "path".
+func (c *domainSocketClient) ExecSession(path string, body apitypes.ExecSessionBody) (*ExecSession, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not calculate the absolute path of %v: %v", path, err)
+	}
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, domainSocketBaseURL, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	req.URL.Path = "/fs/exec/session"
+	req.URL.RawQuery = url.Values{"path": []string{absPath}}.Encode()
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", apitypes.ExecSessionUpgrade)
+	journal := activity.JournalForPID(os.Getpid())
+	req.Header.Set(apitypes.JournalIDHeader, journal.ID)
+	req.Header.Set(apitypes.JournalDescHeader, journal.Description)
+
+	// The connection's hijacked by the server, so we need to manage it ourselves
+	// instead of going through the HTTP client.
+	conn, err := c.Transport.(*http.Transport).DialContext(context.Background(), "unix", "")
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer conn.Close()
+		return nil, unmarshalErrorResp(resp)
+	}
+
+	s := &ExecSession{
+		conn:   conn,
+		enc:    json.NewEncoder(conn),
+		output: make(chan apitypes.ExecSessionOutput, 1),
+	}
+	go func() {
+		defer close(s.output)
+		decoder := json.NewDecoder(reader)
+		for {
+			var msg apitypes.ExecSessionOutput
+			if err := decoder.Decode(&msg); err != nil {
+				if err != io.EOF {
+					log.Println(err)
+				}
+				return
+			}
+			s.output <- msg
+		}
+	}()
+	return s, nil
+}
@@ -34,7 +34,7 @@ var deleteHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *er
 	}
 	deleted, err := plugin.DeleteWithAnalytics(ctx, entry.(plugin.Deletable))
 	if err != nil {
-		return erroredActionResponse(path, plugin.DeleteAction(), err.Error())
+		return actionErrorResponse(path, plugin.DeleteAction(), err)
 	}
 	activity.Record(ctx, "API: Delete %v %v", path, deleted)
 	jsonEncoder := json.NewEncoder(w)
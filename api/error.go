@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -165,6 +166,14 @@ func invalidIntParam(name, value string) *errorResponse {
 	)}
 }
 
+func invalidTimeParam(name, value string) *errorResponse {
+	return &errorResponse{http.StatusBadRequest, newErrorObj(
+		apitypes.InvalidTime,
+		fmt.Sprintf("Invalid RFC3339 time '%v' given for %v parameter", value, name),
+		apitypes.ErrorFields{"value": value},
+	)}
+}
+
 func invalidPathsResponse() *errorResponse {
 	return &errorResponse{http.StatusBadRequest, newErrorObj(
 		apitypes.InvalidPaths,
@@ -189,6 +198,31 @@ func erroredActionResponse(path string, a plugin.Action, reason string) *errorRe
 	return &errorResponse{statusCode, body}
 }
 
+// actionErrorResponse returns the response for an error returned by the action's
+// method. Timeouts are distinguished from other errors so that clients can retry them.
+func actionErrorResponse(path string, a plugin.Action, err error) *errorResponse {
+	if plugin.IsTimeoutErr(err) {
+		return timeoutResponse(path, err)
+	}
+	return erroredActionResponse(path, a, err.Error())
+}
+
+func timeoutResponse(path string, err error) *errorResponse {
+	var timeoutErr plugin.TimeoutErr
+	errors.As(err, &timeoutErr)
+	fields := apitypes.ErrorFields{
+		"path":    path,
+		"method":  timeoutErr.Method,
+		"timeout": timeoutErr.Timeout.String(),
+	}
+
+	return &errorResponse{http.StatusGatewayTimeout, newErrorObj(
+		apitypes.Timeout,
+		fmt.Sprintf("The %v method timed out on %v after %v", timeoutErr.Method, path, timeoutErr.Timeout),
+		fields,
+	)}
+}
+
 func duplicateCNameResponse(e plugin.DuplicateCNameErr) *errorResponse {
 	fields := apitypes.ErrorFields{
 		"parent_id":                   e.ParentID,
@@ -229,3 +263,24 @@ func nonWashPathResponse(path string) *errorResponse {
 		apitypes.ErrorFields{"path": path},
 	)}
 }
+
+func queryNotFoundResponse(name string) *errorResponse {
+	return &errorResponse{http.StatusNotFound, newErrorObj(
+		apitypes.QueryNotFound,
+		fmt.Sprintf("Query %v does not exist", name),
+		apitypes.ErrorFields{"name": name},
+	)}
+}
+
+func unsupportedExecOptionResponse(path string, e plugin.UnsupportedExecOptionErr) *errorResponse {
+	fields := apitypes.ErrorFields{
+		"path":   path,
+		"option": e.Option,
+	}
+
+	return &errorResponse{http.StatusBadRequest, newErrorObj(
+		apitypes.UnsupportedExecOption,
+		fmt.Sprintf("Cannot exec on %v: %v", path, e.Error()),
+		fields,
+	)}
+}
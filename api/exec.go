@@ -86,31 +86,79 @@ var execHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *erro
 		return unknownErrorResponse(fmt.Errorf("Cannot stream %v, response handler does not support flushing", path))
 	}
 
-	activity.Record(ctx, "API: Exec %v %+v", path, body)
-	opts := plugin.ExecOptions{}
-	if body.Opts.Input != "" {
-		opts.Stdin = strings.NewReader(body.Opts.Input)
+	opts, err := toPluginExecOptions(body.Opts)
+	if err != nil {
+		return badActionRequestResponse(path, plugin.ExecAction(), err.Error())
 	}
+
+	activity.Record(ctx, "API: Exec %v %+v", path, body)
 	cmd, err := plugin.ExecWithAnalytics(ctx, entry.(plugin.Execable), body.Cmd, body.Args, opts)
 	if err != nil {
-		return erroredActionResponse(path, plugin.ExecAction(), err.Error())
+		return execErrorResponse(path, err)
 	}
 
 	// Ensure every write is a flush, and do an initial flush to send the header.
 	w.WriteHeader(http.StatusOK)
 	fw.Flush()
 
-	// Stream the command's output
 	enc := json.NewEncoder(&streamableResponseWriter{fw})
+	streamExecOutput(cmd, func(packet *apitypes.ExecPacket) {
+		sendPacket(ctx, enc, packet)
+	})
+
+	return nil
+}}
+
+func toPluginExecOptions(opts apitypes.ExecOptions) (plugin.ExecOptions, error) {
+	pluginOpts := plugin.ExecOptions{
+		Env:        opts.Env,
+		WorkingDir: opts.WorkingDir,
+		User:       opts.User,
+	}
+	if opts.Input != "" {
+		pluginOpts.Stdin = strings.NewReader(opts.Input)
+	}
+	if opts.Timeout != "" {
+		timeout, err := time.ParseDuration(opts.Timeout)
+		if err != nil {
+			return plugin.ExecOptions{}, fmt.Errorf("could not parse the timeout: %v", err)
+		}
+		if timeout <= 0 {
+			return plugin.ExecOptions{}, fmt.Errorf("the timeout must be positive, got %v", opts.Timeout)
+		}
+		pluginOpts.Timeout = timeout
+	}
+	return pluginOpts, nil
+}
+
+// execErrorResponse returns the response for an error returned by plugin.Exec.
+func execErrorResponse(path string, err error) *errorResponse {
+	if optErr, ok := err.(plugin.UnsupportedExecOptionErr); ok {
+		return unsupportedExecOptionResponse(path, optErr)
+	}
+	if plugin.IsInvalidInputErr(err) {
+		return badActionRequestResponse(path, plugin.ExecAction(), err.Error())
+	}
+	return actionErrorResponse(path, plugin.ExecAction(), err)
+}
+
+// streamExecOutput sends the command's output followed by its exit code.
+// It returns false if the command failed, i.e. if it exited with a non-zero
+// exit code or if its output or exit code could not be retrieved.
+func streamExecOutput(cmd plugin.ExecCommand, send func(*apitypes.ExecPacket)) bool {
+	successful := true
+
+	// Stream the command's output
 	for chunk := range cmd.OutputCh() {
 		packet := apitypes.ExecPacket{TypeField: chunk.StreamID, Timestamp: chunk.Timestamp}
 		if err := chunk.Err; err != nil {
 			packet.Err = newStreamingErrorObj(chunk.StreamID, err.Error())
+			successful = false
 		} else {
 			packet.Data = chunk.Data
 		}
 
-		sendPacket(ctx, enc, &packet)
+		send(&packet)
 	}
 
 	// Now stream its exit code
@@ -118,10 +166,12 @@ var execHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *erro
 	exitCode, err := cmd.ExitCode()
 	if err != nil {
 		packet.Err = newUnknownErrorObj(fmt.Errorf("could not get the exit code: %v", err))
+		successful = false
 	} else {
 		packet.Data = exitCode
+		successful = successful && exitCode == 0
 	}
-	sendPacket(ctx, enc, &packet)
+	send(&packet)
 
-	return nil
-}}
+	return successful
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// DefaultBatchExecParallel is the default number of commands that a batch exec
+// runs at once.
+const DefaultBatchExecParallel = 10
+
+// swagger:parameters executeBatchCommand
+//nolint:deadcode,unused
+type batchExecBody struct {
+	// in: body
+	Body apitypes.BatchExecBody
+}
+
+// swagger:response
+//nolint:deadcode,unused
+type batchExecResponse struct {
+	// in: body
+	Packets []apitypes.BatchExecPacket
+}
+
+// swagger:route POST /fs/exec/batch exec executeBatchCommand
+//
+// Execute a command on several remote systems
+//
+// Executes a command on each of the remote systems described by the supplied
+// paths. The commands are run concurrently. Their output is streamed as it's
+// received, with each packet tagged by the path it came from.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Responses:
+//       200: batchExecResponse
+//       400: errorResp
+//       500: errorResp
+var batchExecHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
+	ctx := r.Context()
+	if r.Body == nil {
+		return badRequestResponse("Please send a JSON request body")
+	}
+
+	var body apitypes.BatchExecBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return badRequestResponse(err.Error())
+	}
+	if len(body.Paths) == 0 {
+		return badRequestResponse("Please specify at least one path")
+	}
+	for _, path := range body.Paths {
+		if !filepath.IsAbs(path) {
+			return relativePathResponse(path)
+		}
+	}
+	if _, err := toPluginExecOptions(body.Opts); err != nil {
+		return badRequestResponse(err.Error())
+	}
+	if body.Parallel < 0 {
+		return badRequestResponse("parallel cannot be negative")
+	} else if body.Parallel == 0 {
+		body.Parallel = DefaultBatchExecParallel
+	}
+
+	fw, ok := w.(flushableWriter)
+	if !ok {
+		return unknownErrorResponse(fmt.Errorf("Cannot stream the batch exec, response handler does not support flushing"))
+	}
+
+	activity.Record(ctx, "API: Batch exec %+v", body)
+
+	// Ensure every write is a flush, and do an initial flush to send the header.
+	w.WriteHeader(http.StatusOK)
+	fw.Flush()
+
+	b := &batchExec{
+		ctx:  ctx,
+		body: body,
+		enc:  json.NewEncoder(&streamableResponseWriter{fw}),
+	}
+	b.run()
+
+	return nil
+}}
+
+type batchExec struct {
+	ctx    context.Context
+	body   apitypes.BatchExecBody
+	enc    *json.Encoder
+	mux    sync.Mutex
+	failed bool
+}
+
+func (b *batchExec) run() {
+	sem := make(chan struct{}, b.body.Parallel)
+	var wg sync.WaitGroup
+	for _, path := range b.body.Paths {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(path string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if !b.execOn(path) {
+				b.mux.Lock()
+				b.failed = true
+				b.mux.Unlock()
+			}
+		}(path)
+	}
+	wg.Wait()
+}
+
+// execOn runs the command on the given path. It returns false if the command
+// failed.
+func (b *batchExec) execOn(path string) bool {
+	b.mux.Lock()
+	skip := b.body.FailFast && b.failed
+	b.mux.Unlock()
+	if skip {
+		b.sendExitError(path, erroredActionResponse(path, plugin.ExecAction(), "skipped because a previous command failed"))
+		return true
+	}
+
+	entry, _, errResp := getEntryFromPath(b.ctx, path)
+	if errResp != nil {
+		b.sendExitError(path, errResp)
+		return false
+	}
+	if !plugin.ExecAction().IsSupportedOn(entry) {
+		b.sendExitError(path, unsupportedActionResponse(path, plugin.ExecAction()))
+		return false
+	}
+
+	// The options were validated by the handler. They're converted for each
+	// entry so that each command gets its own stdin reader.
+	opts, _ := toPluginExecOptions(b.body.Opts)
+	cmd, err := plugin.ExecWithAnalytics(b.ctx, entry.(plugin.Execable), b.body.Cmd, b.body.Args, opts)
+	if err != nil {
+		b.sendExitError(path, execErrorResponse(path, err))
+		return false
+	}
+	return streamExecOutput(cmd, func(packet *apitypes.ExecPacket) {
+		b.send(path, packet)
+	})
+}
+
+func (b *batchExec) sendExitError(path string, errResp *errorResponse) {
+	b.send(path, &apitypes.ExecPacket{TypeField: apitypes.Exitcode, Timestamp: time.Now(), Err: errResp.body})
+}
+
+func (b *batchExec) send(path string, packet *apitypes.ExecPacket) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	select {
+	case <-b.ctx.Done():
+		// Don't send anything if the context's finished. Otherwise, the Encode
+		// will error w/ a broken pipe.
+	default:
+		if err := b.enc.Encode(apitypes.BatchExecPacket{Path: path, ExecPacket: *packet}); err != nil {
+			activity.Record(b.ctx, "Error encoding the packet from %v: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+type BatchExecHandlerTestSuite struct {
+	suite.Suite
+	router *mux.Router
+	ctx    context.Context
+}
+
+func (suite *BatchExecHandlerTestSuite) SetupTest() {
+	plugin.SetTestCache(datastore.NewMemCache())
+	suite.router = mux.NewRouter()
+	suite.router.Handle("/fs/exec/batch", batchExecHandler).Methods(http.MethodPost)
+
+	registry := plugin.NewRegistry()
+	suite.Require().NoError(registry.RegisterPlugin(&batchExecRoot{}, nil))
+	suite.ctx = context.WithValue(context.Background(), mountpointKey, "/")
+	suite.ctx = context.WithValue(suite.ctx, pluginRegistryKey, registry)
+	// Listing the registry sets the roots' IDs
+	_, err := plugin.List(suite.ctx, registry)
+	suite.Require().NoError(err)
+}
+
+func (suite *BatchExecHandlerTestSuite) TearDownTest() {
+	plugin.UnsetTestCache()
+}
+
+func (suite *BatchExecHandlerTestSuite) batchExec(body apitypes.BatchExecBody) (int, []apitypes.BatchExecPacket) {
+	jsonBody, err := json.Marshal(body)
+	suite.Require().NoError(err)
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/fs/exec/batch", bytes.NewReader(jsonBody)).WithContext(suite.ctx)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	var packets []apitypes.BatchExecPacket
+	if w.Code == http.StatusOK {
+		decoder := json.NewDecoder(w.Body)
+		for decoder.More() {
+			var pkt apitypes.BatchExecPacket
+			suite.Require().NoError(decoder.Decode(&pkt))
+			packets = append(packets, pkt)
+		}
+	}
+	return w.Code, packets
+}
+
+func exitPackets(packets []apitypes.BatchExecPacket) map[string]apitypes.ExecPacket {
+	exitPkts := make(map[string]apitypes.ExecPacket)
+	for _, pkt := range packets {
+		if pkt.TypeField == apitypes.Exitcode {
+			exitPkts[pkt.Path] = pkt.ExecPacket
+		}
+	}
+	return exitPkts
+}
+
+func (suite *BatchExecHandlerTestSuite) TestErrors() {
+	code, _ := suite.batchExec(apitypes.BatchExecBody{})
+	suite.Equal(http.StatusBadRequest, code)
+
+	code, _ = suite.batchExec(apitypes.BatchExecBody{Paths: []string{"mock/a"}})
+	suite.Equal(http.StatusBadRequest, code)
+
+	code, _ = suite.batchExec(apitypes.BatchExecBody{Paths: []string{"/mock/a"}, Parallel: -1})
+	suite.Equal(http.StatusBadRequest, code)
+
+	body := apitypes.BatchExecBody{
+		ExecBody: apitypes.ExecBody{Cmd: "echo", Opts: apitypes.ExecOptions{Timeout: "bad"}},
+		Paths:    []string{"/mock/ok"},
+	}
+	code, _ = suite.batchExec(body)
+	suite.Equal(http.StatusBadRequest, code)
+}
+
+func (suite *BatchExecHandlerTestSuite) TestBatchExec_UnsupportedOption() {
+	body := apitypes.BatchExecBody{
+		ExecBody: apitypes.ExecBody{Cmd: "echo", Opts: apitypes.ExecOptions{User: "root"}},
+		Paths:    []string{"/mock/ok"},
+	}
+	_, packets := suite.batchExec(body)
+
+	exitPkts := exitPackets(packets)
+	if suite.Len(exitPkts, 1) {
+		suite.Equal(apitypes.UnsupportedExecOption, exitPkts["/mock/ok"].Err.Kind)
+		suite.Equal("user", exitPkts["/mock/ok"].Err.Fields["option"])
+	}
+}
+
+func (suite *BatchExecHandlerTestSuite) TestBatchExec() {
+	body := apitypes.BatchExecBody{
+		ExecBody: apitypes.ExecBody{Cmd: "echo"},
+		Paths:    []string{"/mock/ok", "/mock/fail", "/mock/file", "/mock/missing"},
+	}
+	code, packets := suite.batchExec(body)
+	suite.Equal(http.StatusOK, code)
+
+	for _, pkt := range packets {
+		if pkt.TypeField == apitypes.Stdout {
+			suite.Equal("/mock/ok", pkt.Path)
+			suite.Equal("hello\n", pkt.Data)
+		}
+	}
+
+	exitPkts := exitPackets(packets)
+	if suite.Len(exitPkts, 4) {
+		suite.Equal(float64(0), exitPkts["/mock/ok"].Data)
+		suite.Equal(float64(1), exitPkts["/mock/fail"].Data)
+		suite.Equal(apitypes.UnsupportedAction, exitPkts["/mock/file"].Err.Kind)
+		suite.Equal(apitypes.EntryNotFound, exitPkts["/mock/missing"].Err.Kind)
+	}
+}
+
+func (suite *BatchExecHandlerTestSuite) TestBatchExec_FailFast() {
+	body := apitypes.BatchExecBody{
+		ExecBody: apitypes.ExecBody{Cmd: "echo"},
+		Paths:    []string{"/mock/fail", "/mock/ok"},
+		Parallel: 1,
+		FailFast: true,
+	}
+	_, packets := suite.batchExec(body)
+
+	exitPkts := exitPackets(packets)
+	if suite.Len(exitPkts, 2) {
+		suite.Equal(float64(1), exitPkts["/mock/fail"].Data)
+		suite.Regexp("skipped", exitPkts["/mock/ok"].Err.Msg)
+	}
+}
+
+func TestBatchExecHandler(t *testing.T) {
+	suite.Run(t, new(BatchExecHandlerTestSuite))
+}
+
+type batchExecRoot struct {
+	plugin.EntryBase
+}
+
+func (r *batchExecRoot) Init(map[string]interface{}) error {
+	r.EntryBase = plugin.NewEntry("mock")
+	return nil
+}
+
+func (r *batchExecRoot) Schema() *plugin.EntrySchema {
+	return nil
+}
+
+func (r *batchExecRoot) ChildSchemas() []*plugin.EntrySchema {
+	return nil
+}
+
+func (r *batchExecRoot) List(context.Context) ([]plugin.Entry, error) {
+	return []plugin.Entry{
+		&batchExecTarget{EntryBase: plugin.NewEntry("ok")},
+		&batchExecTarget{EntryBase: plugin.NewEntry("fail"), exitCode: 1},
+		&batchExecFile{plugin.NewEntry("file")},
+	}, nil
+}
+
+type batchExecTarget struct {
+	plugin.EntryBase
+	exitCode int
+}
+
+func (t *batchExecTarget) Schema() *plugin.EntrySchema {
+	return nil
+}
+
+func (t *batchExecTarget) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	if opts.User != "" {
+		return nil, plugin.UnsupportedExecOptionErr{Option: "user"}
+	}
+	execCmd := plugin.NewExecCommand(ctx)
+	go func() {
+		if t.exitCode == 0 {
+			_, _ = execCmd.Stdout().Write([]byte("hello\n"))
+		}
+		execCmd.CloseStreamsWithError(nil)
+		execCmd.SetExitCode(t.exitCode)
+	}()
+	return execCmd, nil
+}
+
+type batchExecFile struct {
+	plugin.EntryBase
+}
+
+func (f *batchExecFile) Schema() *plugin.EntrySchema {
+	return nil
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/puppetlabs/wash/activity"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/metrics"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// swagger:parameters startExecSession
+//nolint:deadcode,unused
+type execSessionBody struct {
+	params
+	// in: body
+	Body apitypes.ExecSessionBody
+}
+
+// swagger:route POST /fs/exec/session exec startExecSession
+//
+// Start an interactive exec session
+//
+// Starts an interactive exec session on the remote system described by the
+// supplied path. The command is run with a TTY. The request must set the
+// "Connection: Upgrade" and "Upgrade: wash-exec-session" headers. If the
+// command's started, the server responds with 101 Switching Protocols and
+// hijacks the connection. Afterwards, the client sends newline-delimited
+// ExecSessionInput messages and the server sends newline-delimited
+// ExecSessionOutput messages. The server closes the connection after sending
+// the command's exit code. Closing the connection from the client ends the
+// session.
+//
+//     Consumes:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Responses:
+//       101:
+//       400: errorResp
+//       404: errorResp
+//       500: errorResp
+var execSessionHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
+	ctx := r.Context()
+	entry, path, errResp := getEntryFromRequest(r)
+	if errResp != nil {
+		return errResp
+	}
+
+	if !plugin.ExecAction().IsSupportedOn(entry) {
+		return unsupportedActionResponse(path, plugin.ExecAction())
+	}
+
+	if !strings.EqualFold(r.Header.Get("Upgrade"), apitypes.ExecSessionUpgrade) {
+		return badActionRequestResponse(path, plugin.ExecAction(), fmt.Sprintf("Please set the Upgrade header to %v", apitypes.ExecSessionUpgrade))
+	}
+
+	if r.Body == nil {
+		return badActionRequestResponse(path, plugin.ExecAction(), "Please send a JSON request body")
+	}
+
+	var body apitypes.ExecSessionBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return badActionRequestResponse(path, plugin.ExecAction(), err.Error())
+	}
+
+	body.Opts.Input = ""
+	opts, err := toPluginExecOptions(body.Opts)
+	if err != nil {
+		return badActionRequestResponse(path, plugin.ExecAction(), err.Error())
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return unknownErrorResponse(fmt.Errorf("Cannot start an exec session on %v, response handler does not support hijacking", path))
+	}
+
+	// The session ends when the command exits or when the client closes the
+	// connection, whichever comes first.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	activity.Record(ctx, "API: Exec session %v %+v", path, body)
+	stdinR, stdinW := io.Pipe()
+	resize := make(chan plugin.TerminalSize, 1)
+	resize <- body.Size
+	opts.Stdin, opts.Tty, opts.Resize = stdinR, true, resize
+	cmd, err := plugin.ExecWithAnalytics(ctx, entry.(plugin.Execable), body.Cmd, body.Args, opts)
+	if err != nil {
+		close(resize)
+		return execErrorResponse(path, err)
+	}
+
+	conn, bufrw, err := hijacker.Hijack()
+	if err != nil {
+		close(resize)
+		stdinW.Close()
+		return unknownErrorResponse(fmt.Errorf("Could not hijack the connection for the exec session on %v: %v", path, err))
+	}
+	defer conn.Close()
+	metrics.ExecSessionStarted()
+	defer metrics.ExecSessionEnded()
+
+	_, err = bufrw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: " + apitypes.ExecSessionUpgrade + "\r\n\r\n")
+	if err == nil {
+		err = bufrw.Flush()
+	}
+	if err != nil {
+		close(resize)
+		stdinW.Close()
+		activity.Record(ctx, "API: Exec session %v: could not send the upgrade response: %v", path, err)
+		return nil
+	}
+
+	// Use the buffered reader in case it already read some of the client's input.
+	go readExecSessionInput(ctx, bufrw.Reader, stdinW, resize, cancel)
+
+	enc := json.NewEncoder(conn)
+	send := func(msg *apitypes.ExecSessionOutput) {
+		if err := enc.Encode(msg); err != nil {
+			activity.Record(ctx, "API: Exec session %v: error sending %v: %v", path, msg.TypeField, err)
+		}
+	}
+	for chunk := range cmd.OutputCh() {
+		msg := apitypes.ExecSessionOutput{TypeField: chunk.StreamID}
+		if err := chunk.Err; err != nil {
+			msg.Err = newStreamingErrorObj(chunk.StreamID, err.Error())
+		} else {
+			msg.Data = []byte(chunk.Data)
+		}
+		send(&msg)
+	}
+
+	msg := apitypes.ExecSessionOutput{TypeField: apitypes.Exitcode}
+	if exitCode, err := cmd.ExitCode(); err != nil {
+		msg.Err = newUnknownErrorObj(fmt.Errorf("could not get the exit code: %v", err))
+	} else {
+		msg.ExitCode = exitCode
+	}
+	send(&msg)
+
+	activity.Record(ctx, "API: Exec session %v complete", path)
+	return nil
+}}
+
+// readExecSessionInput forwards the client's input to the command until the
+// client closes the connection.
+func readExecSessionInput(ctx context.Context, r io.Reader, stdin *io.PipeWriter, resize chan plugin.TerminalSize, cancel func()) {
+	defer close(resize)
+	decoder := json.NewDecoder(r)
+	for {
+		var input apitypes.ExecSessionInput
+		if err := decoder.Decode(&input); err != nil {
+			if err != io.EOF {
+				activity.Record(ctx, "API: Exec session ended: %v", err)
+			}
+			stdin.Close()
+			cancel()
+			return
+		}
+
+		switch {
+		case input.Resize != nil:
+			// Drop the previous size if the executor hasn't consumed it yet so
+			// that executors which ignore resizes don't block the session.
+			select {
+			case <-resize:
+			default:
+			}
+			resize <- *input.Resize
+		case input.CloseStdin:
+			stdin.Close()
+		default:
+			// This errors if stdin was closed, in which case there's nothing
+			// left to do with the input.
+			_, _ = stdin.Write(input.Stdin)
+		}
+	}
+}
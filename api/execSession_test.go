@@ -0,0 +1,158 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExecSessionHandlerTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+	target *sessionTarget
+}
+
+func (suite *ExecSessionHandlerTestSuite) SetupTest() {
+	plugin.SetTestCache(datastore.NewMemCache())
+	suite.target = &sessionTarget{EntryBase: plugin.NewEntry("target"), sizes: make(chan plugin.TerminalSize, 10)}
+	registry := plugin.NewRegistry()
+	suite.Require().NoError(registry.RegisterPlugin(&sessionRoot{target: suite.target}, nil))
+	ctx := context.WithValue(context.Background(), mountpointKey, "/")
+	ctx = context.WithValue(ctx, pluginRegistryKey, registry)
+	_, err := plugin.List(ctx, registry)
+	suite.Require().NoError(err)
+
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		execSessionHandler.ServeHTTP(w, r.WithContext(ctx))
+	}))
+}
+
+func (suite *ExecSessionHandlerTestSuite) TearDownTest() {
+	suite.server.Close()
+	plugin.UnsetTestCache()
+}
+
+func (suite *ExecSessionHandlerTestSuite) startSession(path string, upgrade bool) (net.Conn, *bufio.Reader, *http.Response) {
+	body, err := json.Marshal(apitypes.ExecSessionBody{Cmd: "cat", Size: plugin.TerminalSize{Rows: 10, Cols: 20}})
+	suite.Require().NoError(err)
+	req, err := http.NewRequest(http.MethodPost, suite.server.URL+"/fs/exec/session?path="+path, bytes.NewReader(body))
+	suite.Require().NoError(err)
+	if upgrade {
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", apitypes.ExecSessionUpgrade)
+	}
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(suite.server.URL, "http://"))
+	suite.Require().NoError(err)
+	suite.Require().NoError(req.Write(conn))
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	suite.Require().NoError(err)
+	return conn, reader, resp
+}
+
+func (suite *ExecSessionHandlerTestSuite) TestErrors() {
+	conn, _, resp := suite.startSession("/mock/target", false)
+	defer conn.Close()
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	conn, _, resp = suite.startSession("/mock/missing", true)
+	defer conn.Close()
+	suite.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *ExecSessionHandlerTestSuite) TestSession() {
+	conn, reader, resp := suite.startSession("/mock/target", true)
+	defer conn.Close()
+	suite.Require().Equal(http.StatusSwitchingProtocols, resp.StatusCode)
+	suite.Equal(plugin.TerminalSize{Rows: 10, Cols: 20}, <-suite.target.sizes)
+
+	enc := json.NewEncoder(conn)
+	suite.NoError(enc.Encode(apitypes.ExecSessionInput{Resize: &plugin.TerminalSize{Rows: 30, Cols: 40}}))
+	suite.NoError(enc.Encode(apitypes.ExecSessionInput{Stdin: []byte("hello\x03")}))
+	suite.NoError(enc.Encode(apitypes.ExecSessionInput{CloseStdin: true}))
+
+	var output []byte
+	var exitMsg apitypes.ExecSessionOutput
+	dec := json.NewDecoder(reader)
+	for {
+		var msg apitypes.ExecSessionOutput
+		if err := dec.Decode(&msg); err != nil {
+			suite.Equal(io.EOF, err)
+			break
+		}
+		if msg.TypeField == apitypes.Exitcode {
+			exitMsg = msg
+		} else {
+			output = append(output, msg.Data...)
+		}
+	}
+	suite.Equal("hello\x03", string(output))
+	suite.Nil(exitMsg.Err)
+	suite.Equal(6, exitMsg.ExitCode)
+	suite.Equal(plugin.TerminalSize{Rows: 30, Cols: 40}, <-suite.target.sizes)
+}
+
+func TestExecSessionHandler(t *testing.T) {
+	suite.Run(t, new(ExecSessionHandlerTestSuite))
+}
+
+type sessionRoot struct {
+	plugin.EntryBase
+	target *sessionTarget
+}
+
+func (r *sessionRoot) Init(map[string]interface{}) error {
+	r.EntryBase = plugin.NewEntry("mock")
+	return nil
+}
+
+func (r *sessionRoot) Schema() *plugin.EntrySchema {
+	return nil
+}
+
+func (r *sessionRoot) ChildSchemas() []*plugin.EntrySchema {
+	return nil
+}
+
+func (r *sessionRoot) List(context.Context) ([]plugin.Entry, error) {
+	return []plugin.Entry{r.target}, nil
+}
+
+// sessionTarget echoes its stdin and exits with the number of bytes it read
+// from stdin. It records each terminal size it receives.
+type sessionTarget struct {
+	plugin.EntryBase
+	sizes chan plugin.TerminalSize
+}
+
+func (t *sessionTarget) Schema() *plugin.EntrySchema {
+	return nil
+}
+
+func (t *sessionTarget) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	go func() {
+		for size := range opts.Resize {
+			t.sizes <- size
+		}
+	}()
+	execCmd := plugin.NewExecCommand(ctx)
+	go func() {
+		n, err := io.Copy(execCmd.Stdout(), opts.Stdin)
+		execCmd.CloseStreamsWithError(err)
+		execCmd.SetExitCode(int(n))
+	}()
+	return execCmd, nil
+}
@@ -9,7 +9,9 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/puppetlabs/wash/activity"
 	apifs "github.com/puppetlabs/wash/api/fs"
 	apitypes "github.com/puppetlabs/wash/api/types"
 	"github.com/puppetlabs/wash/plugin"
@@ -22,6 +24,9 @@ func findEntry(ctx context.Context, root plugin.Entry, segments []string) (plugi
 		if cnameErr, ok := err.(plugin.DuplicateCNameErr); ok {
 			return nil, duplicateCNameResponse(cnameErr)
 		}
+		if plugin.IsTimeoutErr(err) {
+			return nil, timeoutResponse(path, err)
+		}
 
 		return nil, entryNotFoundResponse(path, err.Error())
 	}
@@ -68,8 +73,11 @@ func getEntryFromRequest(r *http.Request) (plugin.Entry, string, *errorResponse)
 	if errResp != nil {
 		return nil, "", errResp
 	}
+	return getEntryFromPath(r.Context(), path)
+}
 
-	ctx := r.Context()
+// getEntryFromPath returns the entry located at the given absolute path.
+func getEntryFromPath(ctx context.Context, path string) (plugin.Entry, string, *errorResponse) {
 	trimmedPath, errResp := toWashPath(ctx, path)
 	if errResp != nil {
 		if errResp.body.Kind != apitypes.NonWashPath {
@@ -121,6 +129,22 @@ func getEntryFromRequest(r *http.Request) (plugin.Entry, string, *errorResponse)
 	return entry, path, err
 }
 
+// newAPIEntry creates an API entry for entry at path. It also reads the target of links; a
+// link whose target can't be read is still included, but its target is left empty.
+func newAPIEntry(ctx context.Context, entry plugin.Entry, path string) apitypes.Entry {
+	apiEntry := apitypes.NewEntry(entry)
+	apiEntry.Path = path
+	if plugin.ReadlinkAction().IsSupportedOn(entry) {
+		target, err := plugin.Readlink(ctx, entry.(plugin.Linkable))
+		if err != nil {
+			activity.Warnf(ctx, "API: Readlink %v errored: %v", path, err)
+		} else {
+			apiEntry.LinkTarget = target
+		}
+	}
+	return apiEntry
+}
+
 func getBoolParam(u *url.URL, key string) (bool, *errorResponse) {
 	val := u.Query().Get(key)
 	if val != "" {
@@ -133,6 +157,20 @@ func getBoolParam(u *url.URL, key string) (bool, *errorResponse) {
 	return false, nil
 }
 
+// getTimeParam parses an RFC3339 time parameter. It returns the zero time if the parameter
+// isn't set.
+func getTimeParam(u *url.URL, key string) (time.Time, *errorResponse) {
+	val := u.Query().Get(key)
+	if val != "" {
+		t, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			return time.Time{}, invalidTimeParam(key, val)
+		}
+		return t, nil
+	}
+	return time.Time{}, nil
+}
+
 // return is (n, found, err)
 func getIntParam(u *url.URL, key string) (int, bool, *errorResponse) {
 	val := u.Query().Get(key)
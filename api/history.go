@@ -1,11 +1,11 @@
 package api
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
@@ -24,11 +24,47 @@ type historyParams struct {
 	Follow bool
 }
 
+// swagger:parameters retrieveHistory retrieveHistoryEvents
+//nolint:deadcode,unused
+type historyFilterParams struct {
+	// only include activities started, or events recorded, at or after this RFC3339 time
+	//
+	// in: query
+	Since string
+	// only include activities started, or events recorded, at or before this RFC3339 time
+	//
+	// in: query
+	Until string
+	// only include events for entries in this plugin
+	//
+	// in: query
+	Plugin string
+	// only include events for this path and its descendants
+	//
+	// in: query
+	Path string
+	// only include events that errored when true
+	//
+	// in: query
+	Errors bool
+}
+
+// swagger:parameters retrieveHistoryEvents
+//nolint:deadcode,unused
+type historyEventsParams struct {
+	// only include events recorded by the command at this index in history
+	//
+	// in: query
+	Index int
+}
+
 // swagger:route GET /history history retrieveHistory
 //
 // Get command history
 //
 // Get a list of commands that have been run via 'wash' and when they were run.
+// Filters on plugin, path or errors only include commands that recorded a matching
+// event.
 //
 //     Produces:
 //     - application/json
@@ -45,6 +81,10 @@ var historyHandler = handler{logOnly: true, fn: func(w http.ResponseWriter, r *h
 	if err != nil {
 		return err
 	}
+	filter, err := getHistoryFilter(r.URL)
+	if err != nil {
+		return err
+	}
 
 	var enc *json.Encoder
 	if follow {
@@ -59,7 +99,7 @@ var historyHandler = handler{logOnly: true, fn: func(w http.ResponseWriter, r *h
 	}
 
 	history := activity.History()
-	if err := writeHistory(r.Context(), enc, history); err != nil {
+	if err := writeHistory(enc, history, 0, filter); err != nil {
 		return err
 	}
 
@@ -76,7 +116,7 @@ var historyHandler = handler{logOnly: true, fn: func(w http.ResponseWriter, r *h
 
 			history = activity.History()
 			if len(history) > last {
-				if err := writeHistory(r.Context(), enc, history[last:]); err != nil {
+				if err := writeHistory(enc, history[last:], last, filter); err != nil {
 					return err
 				}
 				last = len(history)
@@ -86,9 +126,19 @@ var historyHandler = handler{logOnly: true, fn: func(w http.ResponseWriter, r *h
 	return nil
 }}
 
-func writeHistory(ctx context.Context, enc *json.Encoder, history []activity.Journal) *errorResponse {
+// writeHistory writes the journals in history that match filter. offset is the index of
+// history's first journal.
+func writeHistory(enc *json.Encoder, history []activity.Journal, offset int, filter activity.Filter) *errorResponse {
 	var act apitypes.Activity
-	for _, item := range history {
+	for i, item := range history {
+		matches, err := filter.MatchesJournal(item)
+		if err != nil {
+			return journalUnavailableResponse(item.String(), err.Error())
+		}
+		if !matches {
+			continue
+		}
+		act.Index = offset + i
 		act.Description = item.Description
 		act.Start = item.Start()
 		if err := enc.Encode(&act); err != nil {
@@ -98,6 +148,80 @@ func writeHistory(ctx context.Context, enc *json.Encoder, history []activity.Jou
 	return nil
 }
 
+// swagger:route GET /history/events history retrieveHistoryEvents
+//
+// Get events from command history
+//
+// Get the structured events recorded by commands that have been run via 'wash'. An
+// event records a plugin method invocation, including its duration, whether it
+// errored, and whether its result came from the cache. Requesting the events of a
+// single command by index only reads that command's journal.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Responses:
+//       200: HistoryEventsResponse
+//       400: errorResp
+//       500: errorResp
+var historyEventsHandler = handler{logOnly: true, fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
+	filter, errResp := getHistoryFilter(r.URL)
+	if errResp != nil {
+		return errResp
+	}
+
+	history := activity.History()
+	offset := 0
+	idx, hasIndex, errResp := getIntParam(r.URL, "index")
+	if errResp != nil {
+		return errResp
+	}
+	if hasIndex {
+		if idx < 0 || idx >= len(history) {
+			return outOfBoundsRequest(len(history), "index out of bounds")
+		}
+		history, offset = history[idx:idx+1], idx
+	}
+
+	events := []apitypes.ActivityEvent{}
+	for i, journal := range history {
+		lines, err := journal.Events()
+		if err != nil {
+			return journalUnavailableResponse(journal.String(), err.Error())
+		}
+		for _, line := range lines {
+			if filter.MatchesEvent(line) {
+				events = append(events, apitypes.ActivityEvent{Index: offset + i, Line: line})
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		return unknownErrorResponse(fmt.Errorf("Could not marshal events: %v", err))
+	}
+	return nil
+}}
+
+func getHistoryFilter(u *url.URL) (activity.Filter, *errorResponse) {
+	var filter activity.Filter
+	var errResp *errorResponse
+	if filter.Since, errResp = getTimeParam(u, "since"); errResp != nil {
+		return filter, errResp
+	}
+	if filter.Until, errResp = getTimeParam(u, "until"); errResp != nil {
+		return filter, errResp
+	}
+	if filter.ErrorsOnly, errResp = getBoolParam(u, "errors"); errResp != nil {
+		return filter, errResp
+	}
+	filter.Plugin = u.Query().Get("plugin")
+	filter.PathPrefix = u.Query().Get("path")
+	return filter, nil
+}
+
 // swagger:route GET /history/{id} journal getJournal
 //
 // Get logs for a particular entry in history
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/puppetlabs/wash/activity"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/stretchr/testify/suite"
+)
+
+type HistoryTestSuite struct {
+	suite.Suite
+	dir      string
+	savedDir string
+}
+
+func (suite *HistoryTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "wash_history")
+	suite.Require().NoError(err)
+	suite.dir = dir
+	suite.savedDir = activity.Dir()
+	activity.SetDir(dir)
+}
+
+func (suite *HistoryTestSuite) TearDownTest() {
+	activity.CloseAll()
+	activity.SetDir(suite.savedDir)
+	suite.NoError(os.RemoveAll(suite.dir))
+}
+
+func (suite *HistoryTestSuite) getEvents(query string) ([]apitypes.ActivityEvent, *errorResponse) {
+	req := httptest.NewRequest(http.MethodGet, "/history/events?"+query, nil)
+	w := httptest.NewRecorder()
+	if errResp := historyEventsHandler.fn(w, req); errResp != nil {
+		return nil, errResp
+	}
+	var events []apitypes.ActivityEvent
+	suite.NoError(json.NewDecoder(w.Body).Decode(&events))
+	return events, nil
+}
+
+func (suite *HistoryTestSuite) TestHistoryEvents_Index() {
+	for _, journal := range []activity.Journal{activity.NewJournal("history-first", "ls"), activity.NewJournal("history-second", "cat")} {
+		ctx := context.WithValue(context.Background(), activity.JournalKey, journal)
+		activity.RecordEvent(ctx, activity.Event{Method: journal.Description, EntryID: "/docker"})
+	}
+
+	idx := len(activity.History()) - 1
+	events, errResp := suite.getEvents("index=" + strconv.Itoa(idx))
+	suite.Nil(errResp)
+	if suite.Len(events, 1) {
+		suite.Equal(idx, events[0].Index)
+		suite.Equal("cat", events[0].Method)
+	}
+
+	events, errResp = suite.getEvents("")
+	suite.Nil(errResp)
+	suite.True(len(events) >= 2)
+
+	_, errResp = suite.getEvents("index=" + strconv.Itoa(idx+1))
+	if suite.NotNil(errResp) {
+		suite.Equal(http.StatusBadRequest, errResp.statusCode)
+		suite.Equal(apitypes.OutOfBounds, errResp.body.Kind)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	suite.Run(t, new(HistoryTestSuite))
+}
@@ -53,14 +53,12 @@ var listHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *erro
 			return duplicateCNameResponse(cnameErr)
 		}
 
-		return erroredActionResponse(path, plugin.ListAction(), err.Error())
+		return actionErrorResponse(path, plugin.ListAction(), err)
 	}
 
 	result := make([]apitypes.Entry, 0, entries.Len())
 	entries.Range(func(_ string, entry plugin.Entry) bool {
-		apiEntry := apitypes.NewEntry(entry)
-		apiEntry.Path = path + "/" + apiEntry.CName
-		result = append(result, apiEntry)
+		result = append(result, newAPIEntry(ctx, entry, path+"/"+plugin.CName(entry)))
 		return true
 	})
 	// Sort entries so they have a deterministic order.
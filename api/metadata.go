@@ -40,6 +40,9 @@ var metadataHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *
 	metadata, err := plugin.Metadata(ctx, entry)
 
 	if err != nil {
+		if plugin.IsTimeoutErr(err) {
+			return timeoutResponse(path, err)
+		}
 		return unknownErrorResponse(err)
 	}
 	activity.Record(ctx, "API: Metadata %v %+v", path, metadata)
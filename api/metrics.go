@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/puppetlabs/wash/metrics"
+)
+
+// swagger:route GET /metrics metrics getMetrics
+//
+// Get the server's metrics
+//
+// Returns the server's metrics in the Prometheus text format. They include
+// plugin method invocations, cache hits, misses and evictions, open streams,
+// exec sessions, external plugin processes, FUSE operations and API requests.
+//
+//     Produces:
+//     - text/plain
+//
+//     Schemes: http
+//
+//     Responses:
+//       200: octetResponse
+var metricsHandler = handler{logOnly: true, fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
+	metrics.Handler().ServeHTTP(w, r)
+	return nil
+}}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	"github.com/puppetlabs/wash/activity"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/plugin/external"
+	log "github.com/sirupsen/logrus"
+)
+
+// swagger:route GET /plugins plugins listPlugins
+//
+// Lists the plugins
+//
+// Returns the status of each plugin loaded by the Wash daemon, including whether its
+// initialization succeeded and the last error recorded for its entries.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Responses:
+//       200: PluginsResponse
+//       500: errorResp
+var listPluginsHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
+	registry := r.Context().Value(pluginRegistryKey).(*plugin.Registry)
+	lastErrors := lastPluginErrors()
+
+	statuses := []apitypes.PluginStatus{}
+	for name, root := range registry.Plugins() {
+		if status, ok := newPluginStatus(registry, name, root, lastErrors); ok {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	if err := json.NewEncoder(w).Encode(statuses); err != nil {
+		return unknownErrorResponse(fmt.Errorf("Could not marshal plugin statuses: %v", err))
+	}
+	return nil
+}}
+
+// swagger:route GET /plugins/{name} plugins getPlugin
+//
+// Gets a plugin's status
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Responses:
+//       200: PluginResponse
+//       404: errorResp
+//       500: errorResp
+var getPluginHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
+	registry := r.Context().Value(pluginRegistryKey).(*plugin.Registry)
+	name := mux.Vars(r)["name"]
+
+	root, ok := registry.Plugins()[name]
+	if !ok {
+		return pluginDoesNotExistResponse(name)
+	}
+	status, ok := newPluginStatus(registry, name, root, lastPluginErrors())
+	if !ok {
+		return pluginDoesNotExistResponse(name)
+	}
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		return unknownErrorResponse(fmt.Errorf("Could not marshal %v's status: %v", name, err))
+	}
+	return nil
+}}
+
+// newPluginStatus returns the status of the named plugin, whose registered root is root.
+// It returns false if the plugin was unregistered.
+func newPluginStatus(
+	registry *plugin.Registry,
+	name string,
+	root plugin.Root,
+	lastErrors map[string]*apitypes.ActivityEvent,
+) (apitypes.PluginStatus, bool) {
+	status, ok := registry.PluginStatus(name)
+	if !ok {
+		return apitypes.PluginStatus{}, false
+	}
+
+	apiStatus := apitypes.PluginStatus{
+		Name:         name,
+		Kind:         apitypes.CorePlugin,
+		Status:       apitypes.PluginLoaded,
+		InitStart:    status.InitStart,
+		InitDuration: status.InitDuration,
+		Config:       status.Config,
+		LastError:    lastErrors[name],
+	}
+	if status.IsExternal() {
+		apiStatus.Kind = apitypes.ExternalPlugin
+		apiStatus.Script, _ = external.ScriptPath(status.Root)
+		if manifest := external.ManifestOf(status.Root); manifest != nil {
+			apiStatus.Version = manifest.Version
+		}
+	}
+	if breaker, ok := plugin.BreakerStatusOf(name); ok {
+		apiStatus.Breaker = &apitypes.BreakerStatus{
+			State:    breaker.State,
+			Failures: breaker.Failures,
+			OpenedAt: breaker.OpenedAt,
+		}
+	}
+	if status.InitErr != nil {
+		apiStatus.Status = apitypes.PluginFailed
+		apiStatus.InitError = status.InitErr.Error()
+	}
+
+	// Failed plugins are registered as a stub whose schema only includes their docs.
+	schema, err := plugin.Schema(root)
+	if err != nil {
+		log.Infof("API: Could not get %v's schema: %v", name, err)
+	} else if schema != nil {
+		apiStatus.Docs = apitypes.NewEntrySchema(schema).Description()
+		apiStatus.HasSchema = status.InitErr == nil
+	}
+	return apiStatus, true
+}
+
+// lastPluginErrors returns the most recent errored event in history for each plugin.
+func lastPluginErrors() map[string]*apitypes.ActivityEvent {
+	lastErrors := make(map[string]*apitypes.ActivityEvent)
+	for plugin, pluginErr := range activity.LastPluginErrors() {
+		lastErrors[plugin] = &apitypes.ActivityEvent{Index: pluginErr.Index, Line: pluginErr.Line}
+	}
+	return lastErrors
+}
+
+// PluginReloader reloads plugins from Wash's config.
+type PluginReloader interface {
+	// ReloadPlugins reloads the named plugin. If name is empty, it reloads every plugin
+	// that was added, removed, changed or previously failed to initialize.
+	ReloadPlugins(name string) ([]apitypes.PluginReload, error)
+}
+
+// swagger:parameters reloadPlugins
+//nolint:deadcode,unused
+type reloadPluginsParams struct {
+	// name of the plugin to reload
+	//
+	// in: query
+	Name string
+}
+
+// swagger:route POST /plugins/reload plugins reloadPlugins
+//
+// Reload plugins
+//
+// Re-reads Wash's config and reloads the named plugin, or every plugin that was added,
+// removed, changed or previously failed to initialize if no name is given. Reloaded
+// plugins have their cache cleared.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Responses:
+//       200: PluginReloadResponse
+//       404: errorResp
+//       500: errorResp
+var reloadPluginsHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
+	reloader, ok := r.Context().Value(pluginReloaderKey).(PluginReloader)
+	if !ok || reloader == nil {
+		return unknownErrorResponse(fmt.Errorf("this server does not support reloading plugins"))
+	}
+
+	name := r.URL.Query().Get("name")
+	reloads, err := reloader.ReloadPlugins(name)
+	if err != nil {
+		return unknownErrorResponse(err)
+	}
+	if name != "" && len(reloads) == 0 {
+		return pluginDoesNotExistResponse(name)
+	}
+	activity.Record(r.Context(), "API: Reloaded plugins %+v", reloads)
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(reloads); err != nil {
+		return unknownErrorResponse(fmt.Errorf("Could not marshal plugin reloads: %v", err))
+	}
+	return nil
+}}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+type failingRoot struct {
+	mockRoot
+}
+
+func (m *failingRoot) Init(map[string]interface{}) error {
+	return errors.New("expired token")
+}
+
+type PluginsTestSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func (suite *PluginsTestSuite) SetupTest() {
+	reg := plugin.NewRegistry()
+	suite.NoError(reg.RegisterPlugin(&mockRoot{EntryBase: plugin.NewEntry("mine")}, map[string]interface{}{"key": "value"}))
+	suite.EqualError(reg.RegisterPlugin(&failingRoot{mockRoot{EntryBase: plugin.NewEntry("broken")}}, nil), "expired token")
+	suite.ctx = context.WithValue(context.Background(), pluginRegistryKey, reg)
+}
+
+func (suite *PluginsTestSuite) TestListPlugins() {
+	req := httptest.NewRequest(http.MethodGet, "/plugins", nil).WithContext(suite.ctx)
+	w := httptest.NewRecorder()
+	suite.Nil(listPluginsHandler.fn(w, req))
+
+	var statuses []apitypes.PluginStatus
+	suite.NoError(json.NewDecoder(w.Body).Decode(&statuses))
+	if suite.Len(statuses, 2) {
+		suite.Equal("broken", statuses[0].Name)
+		suite.Equal(apitypes.CorePlugin, statuses[0].Kind)
+		suite.Equal(apitypes.PluginFailed, statuses[0].Status)
+		suite.Equal("expired token", statuses[0].InitError)
+		suite.False(statuses[0].HasSchema)
+
+		suite.Equal("mine", statuses[1].Name)
+		suite.Equal(apitypes.PluginLoaded, statuses[1].Status)
+		suite.Empty(statuses[1].InitError)
+		suite.Equal(map[string]interface{}{"key": "value"}, statuses[1].Config)
+		suite.False(statuses[1].InitStart.IsZero())
+	}
+}
+
+func (suite *PluginsTestSuite) TestGetPlugin() {
+	req := httptest.NewRequest(http.MethodGet, "/plugins/broken", nil).WithContext(suite.ctx)
+	req = mux.SetURLVars(req, map[string]string{"name": "broken"})
+	w := httptest.NewRecorder()
+	suite.Nil(getPluginHandler.fn(w, req))
+
+	var status apitypes.PluginStatus
+	suite.NoError(json.NewDecoder(w.Body).Decode(&status))
+	suite.Equal("broken", status.Name)
+	suite.Equal(apitypes.PluginFailed, status.Status)
+
+	req = mux.SetURLVars(req, map[string]string{"name": "missing"})
+	errResp := getPluginHandler.fn(httptest.NewRecorder(), req)
+	if suite.NotNil(errResp) {
+		suite.Equal(http.StatusNotFound, errResp.statusCode)
+		suite.Equal(apitypes.PluginDoesNotExist, errResp.body.Kind)
+	}
+}
+
+func TestPlugins(t *testing.T) {
+	suite.Run(t, new(PluginsTestSuite))
+}
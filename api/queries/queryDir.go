@@ -0,0 +1,115 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/api/rql"
+	"github.com/puppetlabs/wash/api/rql/ast"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+type queryDir struct {
+	plugin.EntryBase
+	registry *plugin.Registry
+	def      apitypes.SavedQuery
+	query    rql.Query
+	opts     rql.Options
+}
+
+func newQueryDir(registry *plugin.Registry, def apitypes.SavedQuery) (*queryDir, error) {
+	if !strings.HasPrefix(def.Path, "/") {
+		return nil, fmt.Errorf("the path %q must be an absolute path rooted at Wash's root (e.g. /docker/containers)", def.Path)
+	}
+	// Searching Wash's root or the queries plugin would make the query
+	// evaluate itself.
+	switch pluginName := strings.SplitN(strings.Trim(def.Path, "/"), "/", 2)[0]; pluginName {
+	case "":
+		return nil, fmt.Errorf("the path must start with a plugin (e.g. /docker)")
+	case PluginName:
+		return nil, fmt.Errorf("queries cannot search the %v plugin", PluginName)
+	}
+
+	query := ast.Query()
+	if def.Query == nil {
+		def.Query = true
+	}
+	if err := query.Unmarshal(def.Query); err != nil {
+		return nil, fmt.Errorf("could not decode the RQL query: %v", err)
+	}
+
+	opts := rql.NewOptions()
+	opts.Mindepth = def.Mindepth
+	opts.Fullmeta = def.Fullmeta
+	if def.Maxdepth > 0 {
+		opts.Maxdepth = def.Maxdepth
+	} else if def.Maxdepth < 0 {
+		return nil, fmt.Errorf("maxdepth cannot be negative")
+	}
+
+	ttl := DefaultTTL
+	if def.TTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(def.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the TTL: %v", err)
+		}
+		if ttl < 0 {
+			return nil, fmt.Errorf("the TTL cannot be negative")
+		}
+	}
+
+	q := &queryDir{
+		EntryBase: plugin.NewEntry(def.Name),
+		registry:  registry,
+		def:       def,
+		query:     query,
+		opts:      opts,
+	}
+	q.SetTTLOf(plugin.ListOp, ttl)
+	q.DisableCachingFor(plugin.MetadataOp)
+	q.PreserveChildIDs()
+	q.SetPartialMetadata(def)
+	return q, nil
+}
+
+func (q *queryDir) Schema() *plugin.EntrySchema {
+	return nil
+}
+
+func (q *queryDir) ChildSchemas() []*plugin.EntrySchema {
+	return nil
+}
+
+// List evaluates the query. The matching entries keep their original IDs, so
+// operations on them behave exactly as they would at their original paths.
+func (q *queryDir) List(ctx context.Context) ([]plugin.Entry, error) {
+	segments := strings.Split(strings.Trim(q.def.Path, "/"), "/")
+	start, err := plugin.FindEntry(ctx, q.registry, segments)
+	if err != nil {
+		return nil, fmt.Errorf("could not find the query's start path %v: %v", q.def.Path, err)
+	}
+
+	matches, err := rql.Find(ctx, start, q.query, q.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]plugin.Entry, 0, len(matches))
+	seen := make(map[string]string)
+	for _, match := range matches {
+		entry := match.PluginEntry()
+		cname := plugin.CName(entry)
+		if other, ok := seen[cname]; ok {
+			activity.Warnf(ctx, "query %v: omitting %v because it has the same cname as %v", q.def.Name, plugin.ID(entry), other)
+			continue
+		}
+		seen[cname] = plugin.ID(entry)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,169 @@
+// Package queries presents saved RQL queries as directories. Listing a
+// query's directory evaluates the query and returns the matching entries.
+//
+// Queries are configured via the "queries" key in wash.yaml, or created
+// via the API's /queries endpoint.
+package queries
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// PluginName is the name of the queries plugin's root.
+const PluginName = "queries"
+
+// Root of the queries plugin
+type Root struct {
+	plugin.EntryBase
+	registry *plugin.Registry
+	mux      sync.RWMutex
+	queries  map[string]*queryDir
+}
+
+// NewRoot creates a new queries plugin root. Queries are evaluated
+// against the given registry.
+func NewRoot(registry *plugin.Registry) *Root {
+	return &Root{
+		registry: registry,
+		queries:  make(map[string]*queryDir),
+	}
+}
+
+// Init for root. Each key in cfg is the query's name while its value is the
+// query's definition. See apitypes.SavedQuery for the available fields.
+func (r *Root) Init(cfg map[string]interface{}) error {
+	r.EntryBase = plugin.NewEntry(PluginName)
+	// Disable caching so that queries created via the API show up immediately.
+	r.DisableDefaultCaching()
+
+	for name, defI := range cfg {
+		q, err := decodeQuery(name, defI)
+		if err != nil {
+			return err
+		}
+		if err := r.Save(q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeQuery(name string, defI interface{}) (apitypes.SavedQuery, error) {
+	var q apitypes.SavedQuery
+	if _, ok := defI.(map[string]interface{}); !ok {
+		return q, fmt.Errorf("queries.%v config must be an object, not %T", name, defI)
+	}
+	// Round-trip the definition through JSON so that the query is decoded the
+	// same way as it would be if it were submitted via the API.
+	rawDef, err := json.Marshal(defI)
+	if err != nil {
+		return q, fmt.Errorf("could not decode queries.%v: %v", name, err)
+	}
+	if err := json.Unmarshal(rawDef, &q); err != nil {
+		return q, fmt.Errorf("could not decode queries.%v: %v", name, err)
+	}
+	q.Name = name
+	return q, nil
+}
+
+var queryNameRegex = regexp.MustCompile("^[0-9a-zA-Z_.-]+$")
+
+// Save validates the given query then adds it to r. It replaces any existing
+// query with the same name. Query names are case-insensitive, so they're
+// downcased like the names of queries in Wash's config file.
+func (r *Root) Save(q apitypes.SavedQuery) error {
+	q.Name = strings.ToLower(q.Name)
+	if !queryNameRegex.MatchString(q.Name) {
+		return fmt.Errorf("invalid query name %q. The name must consist of alphanumeric characters, a '.', '_', or a hyphen", q.Name)
+	}
+	dir, err := newQueryDir(r.registry, q)
+	if err != nil {
+		return fmt.Errorf("invalid query %v: %v", q.Name, err)
+	}
+
+	r.mux.Lock()
+	r.queries[q.Name] = dir
+	r.mux.Unlock()
+
+	r.clearCacheFor(q.Name)
+	return nil
+}
+
+// Remove removes the named query. It returns false if the query does not
+// exist.
+func (r *Root) Remove(name string) bool {
+	name = strings.ToLower(name)
+	r.mux.Lock()
+	_, ok := r.queries[name]
+	delete(r.queries, name)
+	r.mux.Unlock()
+
+	if ok {
+		r.clearCacheFor(name)
+	}
+	return ok
+}
+
+// Queries returns all of the saved queries, sorted by name.
+func (r *Root) Queries() []apitypes.SavedQuery {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	queries := make([]apitypes.SavedQuery, 0, len(r.queries))
+	for _, dir := range r.queries {
+		queries = append(queries, dir.def)
+	}
+	sort.Slice(queries, func(i, j int) bool {
+		return queries[i].Name < queries[j].Name
+	})
+	return queries
+}
+
+func (r *Root) clearCacheFor(name string) {
+	// The ID is only set once the registry's been listed. If it isn't set,
+	// then there's nothing to clear.
+	if r.ID() == "" {
+		return
+	}
+	plugin.ClearCacheFor(r.ID()+"/"+name, false)
+}
+
+// Schema returns the root's schema. It's unknown because a query's results
+// could include any kind of entry.
+func (r *Root) Schema() *plugin.EntrySchema {
+	return nil
+}
+
+// ChildSchemas returns the root's child schemas. They're unknown for the
+// same reason as the root's schema.
+func (r *Root) ChildSchemas() []*plugin.EntrySchema {
+	return nil
+}
+
+// List lists the saved queries.
+func (r *Root) List(ctx context.Context) ([]plugin.Entry, error) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	entries := make([]plugin.Entry, 0, len(r.queries))
+	for _, dir := range r.queries {
+		entries = append(entries, dir)
+	}
+	return entries, nil
+}
+
+var _ = plugin.Root(&Root{})
+
+// DefaultTTL is how long a query's results are cached if its TTL isn't
+// specified.
+const DefaultTTL = 1 * time.Minute
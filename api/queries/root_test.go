@@ -0,0 +1,157 @@
+package queries
+
+import (
+	"context"
+	"testing"
+
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+type RootTestSuite struct {
+	suite.Suite
+	ctx      context.Context
+	registry *plugin.Registry
+	root     *Root
+}
+
+func (s *RootTestSuite) SetupTest() {
+	s.ctx = plugin.SetTestCache(datastore.NewMemCache())
+	s.registry = plugin.NewRegistry()
+	s.Require().NoError(s.registry.RegisterPlugin(&mockRoot{}, nil))
+	s.root = NewRoot(s.registry)
+	s.Require().NoError(s.registry.RegisterPlugin(s.root, nil))
+	// Listing the registry sets the roots' IDs
+	_, err := plugin.List(s.ctx, s.registry)
+	s.Require().NoError(err)
+}
+
+func (s *RootTestSuite) TearDownTest() {
+	plugin.UnsetTestCache()
+}
+
+func (s *RootTestSuite) TestInit_DecodesQueries() {
+	root := NewRoot(s.registry)
+	err := root.Init(map[string]interface{}{
+		"files": map[string]interface{}{
+			"path":  "/mock",
+			"query": []interface{}{"name", []interface{}{"glob", "file*"}},
+			"ttl":   "5m",
+		},
+	})
+	if s.NoError(err) {
+		queries := root.Queries()
+		if s.Len(queries, 1) {
+			s.Equal("files", queries[0].Name)
+			s.Equal("/mock", queries[0].Path)
+			s.Equal("5m", queries[0].TTL)
+		}
+	}
+}
+
+func (s *RootTestSuite) TestInit_InvalidConfig() {
+	root := NewRoot(s.registry)
+	err := root.Init(map[string]interface{}{"files": "foo"})
+	s.Regexp("queries.files config must be an object", err)
+}
+
+func (s *RootTestSuite) TestSave_Errors() {
+	s.Regexp("invalid query name", s.root.Save(apitypes.SavedQuery{Name: "foo/bar", Path: "/mock"}))
+	s.Regexp("must be an absolute path", s.root.Save(apitypes.SavedQuery{Name: "foo", Path: "mock"}))
+	s.Regexp("must start with a plugin", s.root.Save(apitypes.SavedQuery{Name: "foo", Path: "/"}))
+	s.Regexp("cannot search the queries plugin", s.root.Save(apitypes.SavedQuery{Name: "foo", Path: "/queries/bar"}))
+	s.Regexp("could not decode the RQL query", s.root.Save(apitypes.SavedQuery{Name: "foo", Path: "/mock", Query: "bad"}))
+	s.Regexp("could not parse the TTL", s.root.Save(apitypes.SavedQuery{Name: "foo", Path: "/mock", TTL: "bad"}))
+}
+
+func (s *RootTestSuite) TestSave_DowncasesName() {
+	s.Require().NoError(s.root.Save(apitypes.SavedQuery{Name: "MyFiles", Path: "/mock"}))
+	queries := s.root.Queries()
+	if s.Len(queries, 1) {
+		s.Equal("myfiles", queries[0].Name)
+	}
+	s.True(s.root.Remove("MYFILES"))
+	s.Empty(s.root.Queries())
+}
+
+func (s *RootTestSuite) TestListQuery() {
+	err := s.root.Save(apitypes.SavedQuery{
+		Name:  "files",
+		Path:  "/mock",
+		Query: []interface{}{"name", []interface{}{"glob", "file*"}},
+	})
+	s.Require().NoError(err)
+
+	queryDirs, err := plugin.List(s.ctx, s.root)
+	s.Require().NoError(err)
+	dir, ok := queryDirs.Load("files")
+	s.Require().True(ok)
+
+	matches, err := plugin.List(s.ctx, dir.(plugin.Parent))
+	if s.NoError(err) {
+		s.Equal(2, matches.Len())
+		// The matches should keep their original IDs
+		file1, _ := matches.Load("file1")
+		s.Equal("/mock/dir/file1", plugin.ID(file1))
+		file2, _ := matches.Load("file2")
+		s.Equal("/mock/file2", plugin.ID(file2))
+	}
+
+	s.True(s.root.Remove("files"))
+	s.False(s.root.Remove("files"))
+	s.Empty(s.root.Queries())
+}
+
+func TestRoot(t *testing.T) {
+	suite.Run(t, new(RootTestSuite))
+}
+
+type mockRoot struct {
+	plugin.EntryBase
+}
+
+func (r *mockRoot) Init(map[string]interface{}) error {
+	r.EntryBase = plugin.NewEntry("mock")
+	return nil
+}
+
+func (r *mockRoot) Schema() *plugin.EntrySchema {
+	return nil
+}
+
+func (r *mockRoot) ChildSchemas() []*plugin.EntrySchema {
+	return nil
+}
+
+func (r *mockRoot) List(context.Context) ([]plugin.Entry, error) {
+	return []plugin.Entry{
+		&mockDir{plugin.NewEntry("dir")},
+		&mockFile{plugin.NewEntry("file2")},
+	}, nil
+}
+
+type mockDir struct {
+	plugin.EntryBase
+}
+
+func (d *mockDir) Schema() *plugin.EntrySchema {
+	return nil
+}
+
+func (d *mockDir) ChildSchemas() []*plugin.EntrySchema {
+	return nil
+}
+
+func (d *mockDir) List(context.Context) ([]plugin.Entry, error) {
+	return []plugin.Entry{&mockFile{plugin.NewEntry("file1")}}, nil
+}
+
+type mockFile struct {
+	plugin.EntryBase
+}
+
+func (f *mockFile) Schema() *plugin.EntrySchema {
+	return nil
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/api/queries"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+func getQueriesRoot(r *http.Request) (*queries.Root, *errorResponse) {
+	registry := r.Context().Value(pluginRegistryKey).(*plugin.Registry)
+	root, ok := registry.Plugins()[queries.PluginName].(*queries.Root)
+	if !ok {
+		return nil, pluginDoesNotExistResponse(queries.PluginName)
+	}
+	return root, nil
+}
+
+// swagger:response
+//nolint:deadcode,unused
+type savedQueryList struct {
+	// in: body
+	Queries []apitypes.SavedQuery
+}
+
+// swagger:route GET /queries queries listQueries
+//
+// Lists the saved queries
+//
+// Returns a list of SavedQuery objects. Each saved query is exposed as a
+// directory under the queries plugin.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Responses:
+//       200: savedQueryList
+//       404: errorResp
+//       500: errorResp
+var listQueriesHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
+	root, errResp := getQueriesRoot(r)
+	if errResp != nil {
+		return errResp
+	}
+
+	jsonEncoder := json.NewEncoder(w)
+	if err := jsonEncoder.Encode(root.Queries()); err != nil {
+		return unknownErrorResponse(fmt.Errorf("Could not marshal the saved queries: %v", err))
+	}
+	return nil
+}}
+
+// swagger:parameters saveQuery
+//nolint:deadcode,unused
+type saveQueryBody struct {
+	// in: body
+	Body apitypes.SavedQuery
+}
+
+// swagger:route POST /queries queries saveQuery
+//
+// Saves a query
+//
+// Saves the given query, replacing any existing query with the same name.
+//
+//     Consumes:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Responses:
+//       200:
+//       400: errorResp
+//       404: errorResp
+var saveQueryHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
+	root, errResp := getQueriesRoot(r)
+	if errResp != nil {
+		return errResp
+	}
+
+	if r.Body == nil {
+		return badRequestResponse("Please send a JSON request body")
+	}
+	var body apitypes.SavedQuery
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return badRequestResponse(err.Error())
+	}
+
+	if err := root.Save(body); err != nil {
+		return badRequestResponse(err.Error())
+	}
+
+	activity.Record(r.Context(), "API: Saved query %v: %+v", body.Name, body)
+	return nil
+}}
+
+// swagger:route DELETE /queries/{name} queries deleteQuery
+//
+// Deletes a saved query
+//
+//     Schemes: http
+//
+//     Responses:
+//       200:
+//       404: errorResp
+var deleteQueryHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
+	root, errResp := getQueriesRoot(r)
+	if errResp != nil {
+		return errResp
+	}
+
+	name := mux.Vars(r)["name"]
+	if !root.Remove(name) {
+		return queryNotFoundResponse(name)
+	}
+
+	activity.Record(r.Context(), "API: Deleted query %v", name)
+	return nil
+}}
@@ -53,3 +53,8 @@ func newEntry(parent *Entry, pluginEntry plugin.Entry) Entry {
 func (e Entry) SchemaKnown() bool {
 	return e.Schema != nil
 }
+
+// PluginEntry returns the plugin entry that e represents.
+func (e Entry) PluginEntry() plugin.Entry {
+	return e.pluginEntry
+}
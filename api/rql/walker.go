@@ -66,7 +66,8 @@ func (w *walkerImpl) walk(ctx context.Context, e *Entry, depth int) ([]Entry, er
 	}
 
 	childDepth := depth + 1
-	if int(childDepth) <= w.opts.Maxdepth && e.Supports(plugin.ListAction()) {
+	// Links aren't followed so that walks can't loop.
+	if int(childDepth) <= w.opts.Maxdepth && e.Supports(plugin.ListAction()) && !e.Supports(plugin.ReadlinkAction()) {
 		childrenMap, err := plugin.List(ctx, e.pluginEntry.(plugin.Parent))
 		if err != nil {
 			return nil, fmt.Errorf("could not get children of %v: %w\n", e.Path, err)
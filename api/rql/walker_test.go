@@ -159,6 +159,29 @@ func (s *WalkerTestSuite) TestWalk_MaxdepthAndMindepthSet() {
 	)
 }
 
+func (s *WalkerTestSuite) TestWalk_DoesNotFollowLinks() {
+	tree := s.setupDefaultMocksForWalk()
+	// A link to an ancestor would loop forever if it were followed.
+	link := s.toPluginEntry("./foo/bar/link", true, "")
+	link.isLink = true
+	s.mockList(tree["./foo/bar"], true, []plugin.Entry{tree["./foo/bar/1"], tree["./foo/bar/2"], link}, nil)
+
+	entries := s.mustWalk(context.Background(), tree["."])
+	s.assertEntries(
+		[]string{
+			"foo",
+			"foo/bar",
+			"foo/bar/1",
+			"foo/bar/2",
+			"foo/bar/link",
+			"foo/baz",
+		},
+		entries,
+		nil,
+	)
+	link.AssertNotCalled(s.T(), "List", mock.Anything)
+}
+
 func (s *WalkerTestSuite) TestWalk_ListErrors() {
 	tree := s.setupDefaultMocksForWalk()
 	expectedErr := fmt.Errorf("failed to list")
@@ -360,6 +383,7 @@ type mockPluginEntry struct {
 	mock.Mock
 	rawTypeID   string
 	isNotParent bool
+	isLink      bool
 }
 
 func newMockPluginEntry(name string) *mockPluginEntry {
@@ -401,6 +425,9 @@ func (m *mockPluginEntry) MethodSignature(method string) plugin.MethodSignature
 	if method == plugin.ListAction().Name && m.isNotParent {
 		return plugin.UnsupportedSignature
 	}
+	if method == plugin.ReadlinkAction().Name && !m.isLink {
+		return plugin.UnsupportedSignature
+	}
 	return plugin.DefaultSignature
 }
 
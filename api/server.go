@@ -8,12 +8,15 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/analytics"
 	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/metrics"
 	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/tracing"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -23,6 +26,7 @@ type key int
 const (
 	pluginRegistryKey key = iota
 	mountpointKey
+	pluginReloaderKey
 )
 
 // swagger:parameters cacheDelete listEntries entryInfo getMetadata readContent streamUpdates deleteEntry signalEntry entrySchema
@@ -55,7 +59,24 @@ func (handle handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	record("API: %v %v", r.Method, r.URL)
 
+	// Requests that don't return an error are counted as 200s.
+	route := routeName(r)
+	start, code := time.Now(), http.StatusOK
+	defer func() { metrics.ObserveAPIRequest(route, r.Method, code, time.Since(start)) }()
+
+	ctx, span := tracing.Start(
+		r.Context(),
+		"API "+r.Method+" "+route,
+		tracing.String("http.method", r.Method),
+		tracing.String("http.route", route),
+		tracing.String("wash.path", r.URL.Query().Get("path")),
+	)
+	var spanErr error
+	defer func() { tracing.End(ctx, span, spanErr) }()
+	r = r.WithContext(ctx)
+
 	if err := handle.fn(w, r); err != nil {
+		code, spanErr = err.statusCode, err
 		record("API: %v %v: %v", r.Method, r.URL, err)
 		w.WriteHeader(err.statusCode)
 
@@ -73,6 +94,16 @@ func (handle handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// routeName returns the path template of the request's route, e.g. /plugins/{name}.
+func routeName(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return r.URL.Path
+}
+
 // StartAPI starts the api. It returns three values:
 //   1. A channel to initiate the shutdown (stopCh). stopCh accepts a Context object
 //      that is used to cancel a stalled shutdown.
@@ -80,11 +111,15 @@ func (handle handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 //   2. A read-only channel that signals whether the server was shutdown.
 //
 //   3. An error object
+//
+// reloader is used to reload plugins. It can be nil if the server doesn't support reloading
+// plugins.
 func StartAPI(
 	registry *plugin.Registry,
 	mountpoint string,
 	socketPath string,
 	analyticsClient analytics.Client,
+	reloader PluginReloader,
 ) (chan<- context.Context, <-chan struct{}, error) {
 	log.Infof("API: Listening at %s", socketPath)
 
@@ -110,6 +145,7 @@ func StartAPI(
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			newctx := context.WithValue(r.Context(), pluginRegistryKey, registry)
 			newctx = context.WithValue(newctx, mountpointKey, mountpoint)
+			newctx = context.WithValue(newctx, pluginReloaderKey, reloader)
 			journal := activity.NewJournal(
 				r.Header.Get(apitypes.JournalIDHeader),
 				r.Header.Get(apitypes.JournalDescHeader),
@@ -131,12 +167,22 @@ func StartAPI(
 	r.Handle("/fs/metadata", metadataHandler).Methods(http.MethodGet)
 	r.Handle("/fs/stream", streamHandler).Methods(http.MethodGet)
 	r.Handle("/fs/exec", execHandler).Methods(http.MethodPost)
+	r.Handle("/fs/exec/batch", batchExecHandler).Methods(http.MethodPost)
+	r.Handle("/fs/exec/session", execSessionHandler).Methods(http.MethodPost)
 	r.Handle("/fs/schema", schemaHandler).Methods(http.MethodGet)
 	r.Handle("/fs/delete", deleteHandler).Methods(http.MethodDelete)
 	r.Handle("/fs/signal", signalHandler).Methods(http.MethodPost)
 	r.Handle("/cache", cacheHandler).Methods(http.MethodDelete)
 	r.Handle("/history", historyHandler).Methods(http.MethodGet)
+	r.Handle("/history/events", historyEventsHandler).Methods(http.MethodGet)
 	r.Handle("/history/{index:[0-9]+}", historyEntryHandler).Methods(http.MethodGet)
+	r.Handle("/plugins", listPluginsHandler).Methods(http.MethodGet)
+	r.Handle("/plugins/reload", reloadPluginsHandler).Methods(http.MethodPost)
+	r.Handle("/plugins/{name}", getPluginHandler).Methods(http.MethodGet)
+	r.Handle("/queries", listQueriesHandler).Methods(http.MethodGet)
+	r.Handle("/queries", saveQueryHandler).Methods(http.MethodPost)
+	r.Handle("/queries/{name}", deleteQueryHandler).Methods(http.MethodDelete)
+	r.Handle("/metrics", metricsHandler).Methods(http.MethodGet)
 
 	r.Use(prepareContextMiddleWare)
 
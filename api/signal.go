@@ -50,7 +50,7 @@ var signalHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *er
 		if plugin.IsInvalidInputErr(err) {
 			return badActionRequestResponse(path, plugin.SignalAction(), err.Error())
 		}
-		return erroredActionResponse(path, plugin.SignalAction(), err.Error())
+		return actionErrorResponse(path, plugin.SignalAction(), err)
 	}
 
 	activity.Record(ctx, "API: Signal %v %v", path, body.Signal)
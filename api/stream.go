@@ -44,7 +44,7 @@ var streamHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *er
 	rdr, err := plugin.StreamWithAnalytics(ctx, entry.(plugin.Streamable))
 
 	if err != nil {
-		return erroredActionResponse(path, plugin.StreamAction(), err.Error())
+		return actionErrorResponse(path, plugin.StreamAction(), err)
 	}
 	activity.Record(ctx, "API: Streaming %v", path)
 
@@ -13,6 +13,8 @@ type Entry struct {
 	CName      string                 `json:"cname"`
 	Attributes plugin.EntryAttributes `json:"attributes"`
 	Metadata   plugin.JSONObject      `json:"metadata"`
+	// LinkTarget is the target of a link. It's only set for Linkable entries.
+	LinkTarget string `json:"link_target,omitempty"`
 }
 
 func NewEntry(e plugin.Entry) Entry {
@@ -27,20 +27,24 @@ func (e *ErrorObj) Error() string {
 
 // Define error kinds returned by the API
 const (
-	UnsupportedAction  = "puppetlabs.wash/unsupported-action"
-	UnknownError       = "puppetlabs.wash/unknown-error"
-	StreamingError     = "puppetlabs.wash/streaming-error"
-	EntryNotFound      = "puppetlabs.wash/entry-not-found"
-	PluginDoesNotExist = "puppetlabs.wash/plugin-does-not-exist"
-	BadRequest         = "puppetlabs.wash/bad-request"
-	BadActionRequest   = "puppetlabs.wash/bad-action-request"
-	JournalUnavailable = "puppetlabs.wash/journal-unavailable"
-	ErroredAction      = "puppetlabs.wash/errored-action"
-	DuplicateCName     = "puppetlabs.wash/duplicate-cname"
-	RelativePath       = "puppetlabs.wash/relative-path"
-	InvalidPaths       = "puppetlabs.wash/invalid-paths"
-	OutOfBounds        = "puppetlabs.wash/out-of-bounds"
-	NonWashPath        = "puppetlabs.wash/non-wash-path"
-	InvalidBool        = "puppetlabs.wash/invalid-bool"
-	InvalidInt         = "puppetlabs.wash/invalid-int"
+	UnsupportedAction     = "puppetlabs.wash/unsupported-action"
+	UnknownError          = "puppetlabs.wash/unknown-error"
+	StreamingError        = "puppetlabs.wash/streaming-error"
+	EntryNotFound         = "puppetlabs.wash/entry-not-found"
+	PluginDoesNotExist    = "puppetlabs.wash/plugin-does-not-exist"
+	BadRequest            = "puppetlabs.wash/bad-request"
+	BadActionRequest      = "puppetlabs.wash/bad-action-request"
+	JournalUnavailable    = "puppetlabs.wash/journal-unavailable"
+	ErroredAction         = "puppetlabs.wash/errored-action"
+	DuplicateCName        = "puppetlabs.wash/duplicate-cname"
+	RelativePath          = "puppetlabs.wash/relative-path"
+	InvalidPaths          = "puppetlabs.wash/invalid-paths"
+	OutOfBounds           = "puppetlabs.wash/out-of-bounds"
+	NonWashPath           = "puppetlabs.wash/non-wash-path"
+	InvalidBool           = "puppetlabs.wash/invalid-bool"
+	InvalidInt            = "puppetlabs.wash/invalid-int"
+	InvalidTime           = "puppetlabs.wash/invalid-time"
+	QueryNotFound         = "puppetlabs.wash/query-not-found"
+	UnsupportedExecOption = "puppetlabs.wash/unsupported-exec-option"
+	Timeout               = "puppetlabs.wash/timeout"
 )
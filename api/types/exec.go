@@ -12,6 +12,15 @@ import (
 type ExecOptions struct {
 	// Input to pass on stdin when executing the command
 	Input string `json:"input"`
+	// Additional environment variables to set for the command
+	Env map[string]string `json:"env,omitempty"`
+	// Directory to run the command in
+	WorkingDir string `json:"working_dir,omitempty"`
+	// User to run the command as
+	User string `json:"user,omitempty"`
+	// Maximum amount of time that the command can run for, as a duration
+	// string (e.g. "30s"). Unset means no timeout.
+	Timeout string `json:"timeout,omitempty"`
 }
 
 // ExecBody encapsulates the payload for a call to a plugin's Exec function
@@ -42,3 +51,63 @@ type ExecPacket struct {
 	Data      interface{}           `json:"data"`
 	Err       *ErrorObj             `json:"error"`
 }
+
+// BatchExecBody encapsulates the payload for a batch exec, which runs the
+// same command on several entries
+type BatchExecBody struct {
+	ExecBody
+	// Absolute paths of the entries to run the command on
+	Paths []string `json:"paths"`
+	// Maximum number of commands to run at once. Defaults to 10.
+	Parallel int `json:"parallel"`
+	// If set, don't start any more commands once a command fails
+	FailFast bool `json:"failFast"`
+}
+
+// BatchExecPacket is a single packet of results from a batch exec. Path is the
+// path of the entry that the packet came from. Each path's final packet is an
+// Exitcode packet. If the command could not be started on the entry (or it was
+// skipped because FailFast was set), then that packet's Err field is set.
+type BatchExecPacket struct {
+	Path string `json:"path"`
+	ExecPacket
+}
+
+// ExecSessionUpgrade is the value of the Upgrade header that's used to start an
+// interactive exec session.
+const ExecSessionUpgrade = "wash-exec-session"
+
+// ExecSessionBody encapsulates the payload that starts an interactive exec session
+type ExecSessionBody struct {
+	// Name of the executable to invoke
+	Cmd string `json:"cmd"`
+	// Array of arguments to the executable
+	Args []string `json:"args"`
+	// Initial size of the terminal
+	Size plugin.TerminalSize `json:"size"`
+	// Additional execution options. Input is ignored because the session's
+	// input is streamed.
+	Opts ExecOptions `json:"opts"`
+}
+
+// ExecSessionInput is a message sent by the client during an interactive exec
+// session. Only one of its fields should be set.
+type ExecSessionInput struct {
+	// Input to write to the command's stdin
+	Stdin []byte `json:"stdin,omitempty"`
+	// New size of the terminal
+	Resize *plugin.TerminalSize `json:"resize,omitempty"`
+	// Closes the command's stdin
+	CloseStdin bool `json:"closeStdin,omitempty"`
+}
+
+// ExecSessionOutput is a message sent by the server during an interactive exec
+// session. If TypeField is Stdout or Stderr, Data contains the output. The
+// session's final message is an Exitcode message, after which the server closes
+// the connection.
+type ExecSessionOutput struct {
+	TypeField plugin.ExecPacketType `json:"type"`
+	Data      []byte                `json:"data,omitempty"`
+	ExitCode  int                   `json:"exitCode"`
+	Err       *ErrorObj             `json:"error,omitempty"`
+}
@@ -1,6 +1,10 @@
 package apitypes
 
-import "time"
+import (
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+)
 
 // JournalIDHeader is the name of the HTTP Header used to provide a journal ID to assocate multiple actions.
 const JournalIDHeader = "JournalID"
@@ -11,10 +15,23 @@ const JournalDescHeader = "JournalDesc"
 
 // Activity describes an activity from wash's `activity.History`.
 type Activity struct {
+	// Index identifies the activity in history. Use it to request the activity's journal.
+	Index       int       `json:"index"`
 	Description string    `json:"description"`
 	Start       time.Time `json:"start"`
 }
 
+// ActivityEvent describes a structured event recorded in an activity's journal.
+type ActivityEvent struct {
+	// Index identifies the activity whose journal recorded the event.
+	Index int `json:"index"`
+	activity.Line
+}
+
+// HistoryFilter selects activities and events from history. Zero-valued fields match
+// everything.
+type HistoryFilter = activity.Filter
+
 // HistoryResponse describes the result returned by the `/history` endpoint.
 //
 // swagger:response
@@ -22,3 +39,11 @@ type HistoryResponse struct {
 	// in: body
 	Activities []Activity
 }
+
+// HistoryEventsResponse describes the result returned by the `/history/events` endpoint.
+//
+// swagger:response
+type HistoryEventsResponse struct {
+	// in: body
+	Events []ActivityEvent
+}
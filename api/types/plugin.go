@@ -0,0 +1,93 @@
+package apitypes
+
+import "time"
+
+// The kinds of plugins.
+const (
+	CorePlugin     = "core"
+	ExternalPlugin = "external"
+)
+
+// The statuses of a plugin's initialization.
+const (
+	PluginLoaded = "loaded"
+	PluginFailed = "failed"
+)
+
+// PluginStatus describes a plugin loaded by the Wash daemon.
+type PluginStatus struct {
+	Name string `json:"name"`
+	// Kind is one of "core" or "external".
+	Kind string `json:"kind"`
+	// Script is the path to an external plugin's script.
+	Script string `json:"script,omitempty"`
+	// Version is an external plugin's version, as declared in its manifest.
+	Version string `json:"version,omitempty"`
+	// Status is one of "loaded" or "failed". Plugins that fail to initialize are replaced
+	// by a stub that only provides their docs.
+	Status       string                 `json:"status"`
+	InitError    string                 `json:"init_error,omitempty"`
+	InitStart    time.Time              `json:"init_start"`
+	InitDuration time.Duration          `json:"init_duration"`
+	Config       map[string]interface{} `json:"config,omitempty"`
+	// HasSchema is true if the plugin provides a schema.
+	HasSchema bool `json:"has_schema"`
+	// Docs is the plugin's documentation, which usually includes how to set it up.
+	Docs string `json:"docs,omitempty"`
+	// LastError is the most recent errored event recorded for the plugin's entries.
+	LastError *ActivityEvent `json:"last_error,omitempty"`
+	// Breaker is the state of the plugin's circuit breaker. It's omitted if the plugin's
+	// limits don't configure one.
+	Breaker *BreakerStatus `json:"breaker,omitempty"`
+}
+
+// BreakerStatus describes the state of a plugin's circuit breaker.
+type BreakerStatus struct {
+	// State is one of "closed", "open" or "half-open". Calls fail fast while it's open.
+	State string `json:"state"`
+	// Failures is the number of consecutive failed calls.
+	Failures int `json:"failures"`
+	// OpenedAt is when the breaker last opened.
+	OpenedAt time.Time `json:"opened_at,omitempty"`
+}
+
+// PluginsResponse describes the result returned by the `/plugins` endpoint.
+//
+// swagger:response
+type PluginsResponse struct {
+	// in: body
+	Plugins []PluginStatus
+}
+
+// PluginResponse describes the result returned by the `/plugins/{name}` endpoint.
+//
+// swagger:response
+type PluginResponse struct {
+	// in: body
+	Plugin PluginStatus
+}
+
+// The changes that reloading plugins can make to a plugin.
+const (
+	PluginAdded    = "added"
+	PluginReloaded = "reloaded"
+	PluginRemoved  = "removed"
+)
+
+// PluginReload describes a change made to a plugin when reloading plugins.
+type PluginReload struct {
+	Name string `json:"name"`
+	// Action is one of "added", "reloaded" or "removed".
+	Action string `json:"action"`
+	// Error is the error returned by the plugin's Init, if it failed. Plugins that
+	// fail to initialize are replaced by a stub that only provides their docs.
+	Error string `json:"error,omitempty"`
+}
+
+// PluginReloadResponse describes the result returned by the `/plugins/reload` endpoint.
+//
+// swagger:response
+type PluginReloadResponse struct {
+	// in: body
+	Reloads []PluginReload
+}
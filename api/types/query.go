@@ -0,0 +1,24 @@
+package apitypes
+
+// SavedQuery describes a named RQL query that's exposed as a directory
+// under the queries plugin. Listing that directory evaluates the query.
+//
+// swagger:response
+type SavedQuery struct {
+	// Name of the query. It's also the name of the query's directory. Names are case-insensitive
+	// and are downcased when the query is saved.
+	Name string `json:"name"`
+	// Path of the entry that the query starts from, relative to Wash's root
+	// (e.g. /aws/default/resources/ec2).
+	Path string `json:"path"`
+	// The RQL query. See the RQL docs for its syntax.
+	Query interface{} `json:"query"`
+	// Mindepth, Maxdepth and Fullmeta are the RQL options. A Maxdepth of 0 means
+	// that the search is unbounded.
+	Mindepth int  `json:"mindepth,omitempty"`
+	Maxdepth int  `json:"maxdepth,omitempty"`
+	Fullmeta bool `json:"fullmeta,omitempty"`
+	// TTL is how long the query's results are cached for (e.g. "5m"). Defaults to
+	// one minute.
+	TTL string `json:"ttl,omitempty"`
+}
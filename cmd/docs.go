@@ -227,6 +227,11 @@ func stringifySupportedActions(path string, entry apitypes.Entry) string {
 				fmt.Sprintf("- signal <signal> %s", path),
 				fmt.Sprintf("    e.g. signal start %s", path),
 			}
+		case plugin.ReadlinkAction().Name:
+			actionDescriptionLines = []string{
+				fmt.Sprintf("- ls -l %s", path),
+				fmt.Sprintf("    Shows the link's target"),
+			}
 		}
 		for _, line := range actionDescriptionLines {
 			supportedActions.WriteString(fmt.Sprintf("    %v\n", line))
@@ -62,6 +62,7 @@ func (suite *DocsTestSuite) TestStringifySupportedActions() {
 			"exec",
 			"delete",
 			"signal",
+			"readlink",
 		},
 	}
 
@@ -74,6 +75,7 @@ func (suite *DocsTestSuite) TestStringifySupportedActions() {
 	suite.Regexp(`exec.*\n.*wexec foo <command> <args\.\.\.>.*\n.*wexec foo uname`, supportedActions)
 	suite.Regexp("delete.*\n.*delete foo", supportedActions)
 	suite.Regexp("signal.*\n.*signal <signal> foo.*\n.*signal start foo", supportedActions)
+	suite.Regexp("readlink.*\n.*ls -l foo", supportedActions)
 
 	// Test non-file-like entry
 	entry.Actions = []string{"read", "write"}
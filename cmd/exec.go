@@ -1,27 +1,71 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/puppetlabs/wash/api"
 	apitypes "github.com/puppetlabs/wash/api/types"
 	cmdutil "github.com/puppetlabs/wash/cmd/util"
+	"github.com/puppetlabs/wash/plugin"
 	"github.com/spf13/cobra"
 )
 
 func execCommand() *cobra.Command {
 	use, aliases := generateShellAlias("exec")
 	execCmd := &cobra.Command{
-		Use:     use + " <path> <command> [<arg>...]",
+		Use:     use + " <path> <command> [<arg>...] | " + use + " <path>... -- <command> [<arg>...]",
 		Aliases: aliases,
-		Short:   "Executes the given command on the indicated target",
+		Short:   "Executes the given command on the indicated target(s)",
 		Long: `For a Wash resource (specified by <path>) that implements the ability to execute a command, run the
 specified command and arguments. The results will be forwarded from the target on stdout, stderr,
-and exit code.`,
+and exit code.
+
+Multiple targets can be specified by separating them from the command with "--". The first "--"
+always ends the targets, so to pass "--" to a single target's command, separate the target from the
+command with "--" too (e.g. "exec <path> -- git log -- file"). Every target must be an execable
+entry; otherwise, no commands are run. When --query is set, the targets are all the execable entries
+under the given paths (default ".") that satisfy the RQL query. If there is more than one target, the command is run on all of them
+concurrently. Each line of output is prefixed with its target's path and the targets' exit codes
+are printed once all the commands finish. In that case, wash exec exits with 0 if all the commands
+succeeded, and 1 otherwise.
+
+Use --env, --workdir and --user to set the command's environment variables, working directory and
+user. Not all executors support these options; if one doesn't, then the command fails with an
+error instead of running without them. Use --timeout to stop the command if it runs for too long.
+
+Use -it to start an interactive session on a single target (e.g. to run a shell). The command runs
+in a TTY and the local terminal is put in raw mode until the command exits. Interactive sessions are
+supported by the Docker, Kubernetes and SSH-based (e.g. AWS and GCP) executors.`,
 		Example: `exec docker/containers/example_1 printenv USER
-  print the USER environment variable from a Docker container instance`,
-		Args: cobra.MinimumNArgs(2),
+  print the USER environment variable from a Docker container instance
+
+exec docker/containers/* -- uname -a
+  print the kernel information of all Docker containers
+
+exec --fail-fast --query '["action", ["has", "exec"]]' aws -- uptime
+  print the uptime of all execable entries in the aws plugin, stopping at the first failure
+
+exec -it docker/containers/example_1 sh
+  start an interactive shell in a Docker container instance
+
+exec --env FOO=bar --workdir /tmp --timeout 30s docker/containers/example_1 sh -c 'echo $FOO $PWD'
+  print the FOO environment variable and working directory, stopping the command after 30 seconds`,
+		Args: cobra.MinimumNArgs(1),
 		RunE: toRunE(execMain),
 	}
+	execCmd.Flags().StringP("query", "q", "", "Run the command on the execable entries under the paths that satisfy the RQL query")
+	execCmd.Flags().IntP("parallel", "p", api.DefaultBatchExecParallel, "Run at most this many commands at once")
+	execCmd.Flags().Bool("fail-fast", false, "Don't start any more commands once a command fails")
+	execCmd.Flags().BoolP("interactive", "i", false, "Keep stdin open for an interactive session (use with -t)")
+	execCmd.Flags().BoolP("tty", "t", false, "Allocate a TTY for an interactive session (use with -i)")
+	execCmd.Flags().StringArrayP("env", "e", nil, "Set an environment variable for the command (KEY=VALUE). Can be repeated.")
+	execCmd.Flags().StringP("workdir", "w", "", "Run the command in this directory")
+	execCmd.Flags().StringP("user", "u", "", "Run the command as this user")
+	execCmd.Flags().Duration("timeout", 0, "Stop the command if it runs for longer than this duration (e.g. 30s)")
 
 	// Don't interpret any flags after the first positional argument. Those should
 	// instead get interpreted by this command as normal args, not flags.
@@ -69,27 +113,274 @@ func printPackets(pkts <-chan apitypes.ExecPacket) (int, error) {
 	return exit, nil
 }
 
+// parseExecOptions parses the exec options from cmd's flags.
+func parseExecOptions(cmd *cobra.Command) (apitypes.ExecOptions, error) {
+	var opts apitypes.ExecOptions
+	env, err := cmd.Flags().GetStringArray("env")
+	if err != nil {
+		panic(err.Error())
+	}
+	for _, kv := range env {
+		segments := strings.SplitN(kv, "=", 2)
+		if len(segments) != 2 || segments[0] == "" {
+			return opts, fmt.Errorf("invalid --env %v: must be of the form KEY=VALUE", kv)
+		}
+		if opts.Env == nil {
+			opts.Env = make(map[string]string)
+		}
+		opts.Env[segments[0]] = segments[1]
+	}
+	if opts.WorkingDir, err = cmd.Flags().GetString("workdir"); err != nil {
+		panic(err.Error())
+	}
+	if opts.User, err = cmd.Flags().GetString("user"); err != nil {
+		panic(err.Error())
+	}
+	timeout, err := cmd.Flags().GetDuration("timeout")
+	if err != nil {
+		panic(err.Error())
+	}
+	if timeout < 0 {
+		return opts, fmt.Errorf("--timeout cannot be negative")
+	} else if timeout > 0 {
+		opts.Timeout = timeout.String()
+	}
+	return opts, nil
+}
+
+// splitExecArgs splits args into the target paths and the command + its args.
+// The first "--" always ends the paths. Without one, the first arg is the path.
+func splitExecArgs(cmd *cobra.Command, args []string) ([]string, []string) {
+	// Cobra only consumes "--" when it precedes all the positional args.
+	// Otherwise, it's passed along as a regular arg.
+	if n := cmd.ArgsLenAtDash(); n >= 0 {
+		return args[:n], args[n:]
+	}
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+	return args[:1], args[1:]
+}
+
 func execMain(cmd *cobra.Command, args []string) exitCode {
-	var path string
-	var command string
-	var commandArgs []string
+	query, err := cmd.Flags().GetString("query")
+	if err != nil {
+		panic(err.Error())
+	}
+	parallel, err := cmd.Flags().GetInt("parallel")
+	if err != nil {
+		panic(err.Error())
+	}
+	failFast, err := cmd.Flags().GetBool("fail-fast")
+	if err != nil {
+		panic(err.Error())
+	}
+	interactive, err := cmd.Flags().GetBool("interactive")
+	if err != nil {
+		panic(err.Error())
+	}
+	tty, err := cmd.Flags().GetBool("tty")
+	if err != nil {
+		panic(err.Error())
+	}
+	if interactive != tty {
+		cmdutil.ErrPrintf("-i and -t must be used together\n")
+		return exitCode{1}
+	}
+	if parallel <= 0 {
+		cmdutil.ErrPrintf("--parallel must be greater than 0\n")
+		return exitCode{1}
+	}
+	opts, err := parseExecOptions(cmd)
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
 
-	path = args[0]
-	command = args[1]
-	commandArgs = args[2:]
+	paths, commandArgs := splitExecArgs(cmd, args)
+	if len(commandArgs) == 0 {
+		cmdutil.ErrPrintf("please specify a command\n")
+		return exitCode{1}
+	}
+	command := commandArgs[0]
+	commandArgs = commandArgs[1:]
 
 	conn := cmdutil.NewClient()
 
-	ch, err := conn.Exec(path, command, commandArgs, apitypes.ExecOptions{})
+	if interactive {
+		if query != "" || len(paths) != 1 {
+			cmdutil.ErrPrintf("an interactive session requires exactly one path\n")
+			return exitCode{1}
+		}
+		return execInteractive(conn, paths[0], command, commandArgs, opts)
+	}
+
+	// displayPaths maps each target's absolute path to the path that's displayed
+	// in the output.
+	displayPaths := make(map[string]string)
+	var targets []string
+	if query != "" {
+		if len(paths) == 0 {
+			paths = []string{"."}
+		}
+		var rawQuery interface{}
+		if err := json.Unmarshal([]byte(query), &rawQuery); err != nil {
+			cmdutil.ErrPrintf("could not parse the RQL query: %v\n", err)
+			return exitCode{1}
+		}
+		for _, path := range paths {
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				cmdutil.ErrPrintf("could not calculate the absolute path of %v: %v\n", path, err)
+				return exitCode{1}
+			}
+			entries, err := conn.Find(absPath, rawQuery)
+			if err != nil {
+				cmdutil.ErrPrintf("%v: %v\n", path, err)
+				return exitCode{1}
+			}
+			for _, entry := range entries {
+				if !entry.Supports(plugin.ExecAction()) {
+					continue
+				}
+				targets = append(targets, entry.Path)
+				displayPaths[entry.Path] = filepath.Join(path, strings.TrimPrefix(entry.Path, absPath))
+			}
+		}
+		if len(targets) == 0 {
+			cmdutil.ErrPrintf("no execable entries satisfied the query\n")
+			return exitCode{1}
+		}
+	} else {
+		if len(paths) == 0 {
+			cmdutil.ErrPrintf("please specify at least one path\n")
+			return exitCode{1}
+		}
+		if len(paths) == 1 {
+			ch, err := conn.Exec(paths[0], command, commandArgs, opts)
+			if err != nil {
+				cmdutil.ErrPrintf("%v\n", err)
+				return exitCode{1}
+			}
+
+			code, err := printPackets(ch)
+			if err != nil {
+				return exitCode{1}
+			}
+
+			return exitCode{code}
+		}
+		for _, path := range paths {
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				cmdutil.ErrPrintf("could not calculate the absolute path of %v: %v\n", path, err)
+				return exitCode{1}
+			}
+			// Check every path before running anything so that a typo doesn't
+			// leave the command running on only some of the targets.
+			entry, err := conn.Info(absPath)
+			if err != nil {
+				cmdutil.ErrPrintf("%v: %v\n", path, err)
+				return exitCode{1}
+			}
+			if !entry.Supports(plugin.ExecAction()) {
+				cmdutil.ErrPrintf("%v: entry does not support exec\n", path)
+				return exitCode{1}
+			}
+			targets = append(targets, absPath)
+			displayPaths[absPath] = path
+		}
+	}
+
+	body := apitypes.BatchExecBody{
+		ExecBody: apitypes.ExecBody{Cmd: command, Args: commandArgs, Opts: opts},
+		Paths:    targets,
+		Parallel: parallel,
+		FailFast: failFast,
+	}
+	ch, err := conn.BatchExec(body)
 	if err != nil {
 		cmdutil.ErrPrintf("%v\n", err)
 		return exitCode{1}
 	}
+	return exitCode{printBatchPackets(ch, targets, displayPaths)}
+}
 
-	code, err := printPackets(ch)
-	if err != nil {
-		return exitCode{1}
+// printBatchPackets prints each line of output prefixed by its target's path.
+// Once all the packets are received, it prints each target's exit code. It
+// returns 0 if all the commands succeeded, and 1 otherwise.
+func printBatchPackets(pkts <-chan apitypes.BatchExecPacket, targets []string, displayPaths map[string]string) int {
+	displayPath := func(path string) string {
+		if p, ok := displayPaths[path]; ok {
+			return p
+		}
+		return path
+	}
+
+	// Output isn't line-buffered, so save any partial lines until the rest of
+	// the line (or the exit code) arrives.
+	partialLines := make(map[string]map[plugin.ExecPacketType]string)
+	printLines := func(path string, stream plugin.ExecPacketType, data string, flush bool) {
+		if partialLines[path] == nil {
+			partialLines[path] = make(map[plugin.ExecPacketType]string)
+		}
+		lines := strings.Split(partialLines[path][stream]+data, "\n")
+		partialLines[path][stream] = lines[len(lines)-1]
+		lines = lines[:len(lines)-1]
+		if flush && partialLines[path][stream] != "" {
+			lines = append(lines, partialLines[path][stream])
+			partialLines[path][stream] = ""
+		}
+		for _, line := range lines {
+			if stream == apitypes.Stderr {
+				fmt.Fprintf(cmdutil.Stderr, "%v: %v\n", displayPath(path), line)
+			} else {
+				cmdutil.Printf("%v: %v\n", displayPath(path), line)
+			}
+		}
 	}
 
-	return exitCode{code}
+	results := make(map[string]string)
+	ec := 0
+	for pkt := range pkts {
+		switch pkt.TypeField {
+		case apitypes.Stdout, apitypes.Stderr:
+			if pkt.Err != nil {
+				printLines(pkt.Path, apitypes.Stderr, pkt.Err.Msg+"\n", false)
+				continue
+			}
+			printLines(pkt.Path, pkt.TypeField, pkt.Data.(string), false)
+		case apitypes.Exitcode:
+			printLines(pkt.Path, apitypes.Stdout, "", true)
+			printLines(pkt.Path, apitypes.Stderr, "", true)
+			if pkt.Err != nil {
+				results[pkt.Path] = pkt.Err.Msg
+				ec = 1
+				continue
+			}
+			code := int(pkt.Data.(float64))
+			if code != 0 {
+				ec = 1
+			}
+			results[pkt.Path] = strconv.Itoa(code)
+		}
+	}
+
+	headers := []cmdutil.ColumnHeader{
+		{ShortName: "target", FullName: "TARGET"},
+		{ShortName: "exit", FullName: "EXIT CODE"},
+	}
+	rows := make([][]string, len(targets))
+	for i, target := range targets {
+		result, ok := results[target]
+		if !ok {
+			result = "unknown: the exec endpoint did not send an exit code"
+			ec = 1
+		}
+		rows[i] = []string{displayPath(target), result}
+	}
+	fmt.Fprintf(cmdutil.Stderr, "\n%v", cmdutil.NewTableWithHeaders(headers, rows).Format())
+	return ec
 }
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/puppetlabs/wash/api/client"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	cmdutil "github.com/puppetlabs/wash/cmd/util"
+	"github.com/puppetlabs/wash/plugin"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+func terminalSize(fd int) plugin.TerminalSize {
+	cols, rows, err := terminal.GetSize(fd)
+	if err != nil {
+		return plugin.InitialTerminalSize(nil)
+	}
+	return plugin.TerminalSize{Rows: uint16(rows), Cols: uint16(cols)}
+}
+
+// execInteractive runs the command in an interactive exec session. The local
+// terminal is put in raw mode for the duration of the session so that all
+// input (including control characters like Ctrl-C) is sent to the command.
+func execInteractive(conn client.Client, path string, command string, args []string, opts apitypes.ExecOptions) exitCode {
+	stdinFd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(stdinFd) {
+		cmdutil.ErrPrintf("an interactive session requires stdin to be a terminal\n")
+		return exitCode{1}
+	}
+	stdoutFd := int(os.Stdout.Fd())
+
+	body := apitypes.ExecSessionBody{Cmd: command, Args: args, Size: terminalSize(stdoutFd), Opts: opts}
+	session, err := conn.ExecSession(path, body)
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+	defer session.Close()
+
+	oldState, err := terminal.MakeRaw(stdinFd)
+	if err != nil {
+		cmdutil.ErrPrintf("could not put the terminal in raw mode: %v\n", err)
+		return exitCode{1}
+	}
+	defer func() {
+		_ = terminal.Restore(stdinFd, oldState)
+	}()
+
+	// Forward terminal resizes to the session
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGWINCH)
+	defer func() {
+		signal.Stop(sigCh)
+		close(sigCh)
+	}()
+	go func() {
+		for range sigCh {
+			_ = session.Resize(terminalSize(stdoutFd))
+		}
+	}()
+
+	go func() {
+		// The session's closed once the command exits, so there's no need to
+		// handle the error.
+		if _, err := io.Copy(session, os.Stdin); err == nil {
+			_ = session.CloseStdin()
+		}
+	}()
+
+	code := 1
+	for msg := range session.Output() {
+		if msg.Err != nil {
+			// The terminal's in raw mode, so include the carriage return.
+			fmt.Fprintf(cmdutil.Stderr, "%v\r\n", msg.Err)
+			continue
+		}
+		switch msg.TypeField {
+		case apitypes.Stdout:
+			_, _ = cmdutil.Stdout.Write(msg.Data)
+		case apitypes.Stderr:
+			_, _ = cmdutil.Stderr.Write(msg.Data)
+		case apitypes.Exitcode:
+			code = msg.ExitCode
+		}
+	}
+	return exitCode{code}
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/cmd/internal/cmdtest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExecTestSuite struct {
+	*cmdtest.Suite
+}
+
+func (s *ExecTestSuite) splitArgs(args ...string) ([]string, []string) {
+	cmd := execCommand()
+	s.Require().NoError(cmd.ParseFlags(args))
+	return splitExecArgs(cmd, cmd.Flags().Args())
+}
+
+func (s *ExecTestSuite) TestSplitExecArgs() {
+	paths, command := s.splitArgs("foo", "echo", "hello")
+	s.Equal([]string{"foo"}, paths)
+	s.Equal([]string{"echo", "hello"}, command)
+
+	// The first "--" always ends the paths.
+	paths, command = s.splitArgs("foo", "git", "log", "--", "file")
+	s.Equal([]string{"foo", "git", "log"}, paths)
+	s.Equal([]string{"file"}, command)
+
+	paths, command = s.splitArgs("foo", "--", "git", "log", "--", "file")
+	s.Equal([]string{"foo"}, paths)
+	s.Equal([]string{"git", "log", "--", "file"}, command)
+
+	paths, command = s.splitArgs("--query", "[]", "foo", "bar", "--", "echo", "--", "hello")
+	s.Equal([]string{"foo", "bar"}, paths)
+	s.Equal([]string{"echo", "--", "hello"}, command)
+
+	paths, command = s.splitArgs("--parallel", "2", "--", "echo")
+	s.Empty(paths)
+	s.Equal([]string{"echo"}, command)
+}
+
+func (s *ExecTestSuite) TestExecMain_BadPath() {
+	foo, err := filepath.Abs("foo")
+	s.Require().NoError(err)
+	bar, err := filepath.Abs("bar")
+	s.Require().NoError(err)
+	s.Client.On("Info", foo).Return(apitypes.Entry{Actions: []string{"exec"}}, nil)
+	s.Client.On("Info", bar).Return(apitypes.Entry{}, fmt.Errorf("not found"))
+
+	cmd := execCommand()
+	s.Require().NoError(cmd.ParseFlags([]string{"foo", "bar", "--", "echo"}))
+	s.Equal(exitCode{1}, execMain(cmd, cmd.Flags().Args()))
+	s.Equal("bar: not found\n", s.Stderr())
+	s.Client.AssertNotCalled(s.T(), "BatchExec", mock.Anything)
+}
+
+func (s *ExecTestSuite) parseOptions(args ...string) (apitypes.ExecOptions, error) {
+	cmd := execCommand()
+	s.Require().NoError(cmd.ParseFlags(args))
+	return parseExecOptions(cmd)
+}
+
+func (s *ExecTestSuite) TestParseExecOptions() {
+	opts, err := s.parseOptions("foo", "echo")
+	if s.NoError(err) {
+		s.Equal(apitypes.ExecOptions{}, opts)
+	}
+
+	opts, err = s.parseOptions("-e", "A=b=c", "--env", "D=", "-w", "/tmp", "-u", "root", "--timeout", "90s", "foo", "echo")
+	if s.NoError(err) {
+		expected := apitypes.ExecOptions{
+			Env:        map[string]string{"A": "b=c", "D": ""},
+			WorkingDir: "/tmp",
+			User:       "root",
+			Timeout:    "1m30s",
+		}
+		s.Equal(expected, opts)
+	}
+
+	_, err = s.parseOptions("-e", "A", "foo", "echo")
+	s.Regexp("KEY=VALUE", err)
+
+	_, err = s.parseOptions("--timeout", "-1s", "foo", "echo")
+	s.Regexp("cannot be negative", err)
+}
+
+func (s *ExecTestSuite) TestPrintBatchPackets() {
+	ch := make(chan apitypes.BatchExecPacket, 10)
+	send := func(path string, typ apitypes.ExecPacket) {
+		ch <- apitypes.BatchExecPacket{Path: path, ExecPacket: typ}
+	}
+	send("/a", apitypes.ExecPacket{TypeField: apitypes.Stdout, Data: "hello "})
+	send("/b", apitypes.ExecPacket{TypeField: apitypes.Stderr, Data: "oops\n"})
+	send("/a", apitypes.ExecPacket{TypeField: apitypes.Stdout, Data: "world\npartial"})
+	send("/a", apitypes.ExecPacket{TypeField: apitypes.Exitcode, Data: float64(0)})
+	send("/b", apitypes.ExecPacket{TypeField: apitypes.Exitcode, Data: float64(2)})
+	send("/c", apitypes.ExecPacket{TypeField: apitypes.Exitcode, Err: &apitypes.ErrorObj{Msg: "not execable"}})
+	close(ch)
+
+	ec := printBatchPackets(ch, []string{"/a", "/b", "/c"}, map[string]string{"/a": "a", "/b": "b"})
+	s.Equal(1, ec)
+	s.Equal("a: hello world\na: partial\n", s.Stdout())
+	s.Contains(s.Stderr(), "b: oops\n")
+	s.Regexp(`a\s+0`, s.Stderr())
+	s.Regexp(`b\s+2`, s.Stderr())
+	s.Regexp(`/c\s+not execable`, s.Stderr())
+}
+
+func (s *ExecTestSuite) TestPrintBatchPackets_AllSucceeded() {
+	ch := make(chan apitypes.BatchExecPacket, 2)
+	ch <- apitypes.BatchExecPacket{Path: "/a", ExecPacket: apitypes.ExecPacket{TypeField: apitypes.Exitcode, Data: float64(0)}}
+	ch <- apitypes.BatchExecPacket{Path: "/b", ExecPacket: apitypes.ExecPacket{TypeField: apitypes.Exitcode, Data: float64(0)}}
+	close(ch)
+
+	s.Equal(0, printBatchPackets(ch, []string{"/a", "/b"}, map[string]string{}))
+}
+
+func (s *ExecTestSuite) TestPrintBatchPackets_MissingExitCode() {
+	ch := make(chan apitypes.BatchExecPacket)
+	close(ch)
+
+	s.Equal(1, printBatchPackets(ch, []string{"/a"}, map[string]string{}))
+	s.Contains(s.Stderr(), "did not send an exit code")
+}
+
+func TestExec(t *testing.T) {
+	suite.Run(t, &ExecTestSuite{Suite: new(cmdtest.Suite)})
+}
@@ -8,7 +8,8 @@ import (
 	"time"
 
 	"github.com/Benchkram/errz"
-	"github.com/kr/logfmt"
+	"github.com/puppetlabs/wash/activity"
+	apitypes "github.com/puppetlabs/wash/api/types"
 	cmdutil "github.com/puppetlabs/wash/cmd/util"
 	"github.com/spf13/cobra"
 )
@@ -16,31 +17,36 @@ import (
 func historyCommand() *cobra.Command {
 	use, aliases := generateShellAlias("history")
 	historyCmd := &cobra.Command{
-		Use:     use + " [-f] [<id>]",
+		Use:     use + " [-f] [--events] [<id>]",
 		Aliases: aliases,
 		Short:   "Prints the wash command history, or journal of a particular item",
 		Long: `Wash maintains a history of commands executed through it. Print that command history, or specify an
-<id> to print a log of activity related to a particular command.`,
+<id> to print a log of activity related to a particular command.
+
+Wash also records an event for each plugin method it invokes. Use --events to print a table of those
+events, optionally limited to a particular command by specifying its <id>. The --since, --until,
+--plugin, --path and --errors flags filter the printed commands or events.`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: toRunE(historyMain),
 	}
 	historyCmd.Flags().BoolP("follow", "f", false, "Follow new updates")
+	historyCmd.Flags().Bool("events", false, "Print a table of plugin method events")
+	historyCmd.Flags().String("since", "", "Only include activity at or after this time, either RFC3339 or a duration ago like 1h")
+	historyCmd.Flags().String("until", "", "Only include activity at or before this time, either RFC3339 or a duration ago like 1h")
+	historyCmd.Flags().String("plugin", "", "Only include events for entries in this plugin")
+	historyCmd.Flags().String("path", "", "Only include events for this entry ID, like /docker/containers, and its descendants")
+	historyCmd.Flags().Bool("errors", false, "Only include events that errored")
 	return historyCmd
 }
 
-type logFmtLine struct {
-	Time, Level, Msg string
-}
-
 func printJournalEntry(index string, follow bool) error {
-	idx, err := strconv.Atoi(index)
+	idx, err := parseHistoryID(index)
 	if err != nil {
 		return err
 	}
 
 	conn := cmdutil.NewClient()
-	// Translate from 1-indexing for history entries
-	rdr, err := conn.ActivityJournal(idx-1, follow)
+	rdr, err := conn.ActivityJournal(idx, follow)
 	if err != nil {
 		return err
 	}
@@ -55,25 +61,19 @@ func printJournalEntry(index string, follow bool) error {
 	scanner := bufio.NewScanner(rdr)
 	scanner.Buffer(make([]byte, 4096), 100*1024*1024)
 	for scanner.Scan() {
-		var line, empty logFmtLine
-		if err := logfmt.Unmarshal(scanner.Bytes(), &line); err != nil {
-			cmdutil.ErrPrintf("Error parsing %v: %v\n", line, err)
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
 			continue
 		}
 
-		if line == empty {
-			// Parser ignored incomplete line rather than erroring. Skip it.
+		line, err := activity.ParseLine(scanner.Bytes())
+		if err != nil {
+			cmdutil.ErrPrintf("Error parsing %v: %v\n", scanner.Text(), err)
 			continue
 		}
 
 		// TODO: add option to print the original longer time format.
-		t, err := time.Parse(time.RFC3339Nano, line.Time)
-		if err != nil {
-			panic(fmt.Sprintf("Unexpected time format %s", line.Time))
-		}
-
 		lines := strings.Split(line.Msg, "\n")
-		timeStr := t.Format(time.StampMilli)
+		timeStr := line.Time.Format(time.StampMilli)
 		fmt.Println(timeStr, lines[0])
 		if len(lines) > 1 {
 			prefix := strings.Repeat(" ", len(timeStr))
@@ -85,34 +85,174 @@ func printJournalEntry(index string, follow bool) error {
 	return scanner.Err()
 }
 
-func printHistory(follow bool) error {
+func printHistory(follow bool, filter apitypes.HistoryFilter) error {
 	conn := cmdutil.NewClient()
-	history, err := conn.History(follow)
+	history, err := conn.History(follow, filter)
 	if err != nil {
 		return err
 	}
 
+	if follow {
+		// The largest index isn't known while following, so the index column isn't aligned.
+		for item := range history {
+			cmdutil.Print(formatHistory([]apitypes.Activity{item}))
+		}
+		return nil
+	}
+
+	var items []apitypes.Activity
+	for item := range history {
+		items = append(items, item)
+	}
+	cmdutil.Print(formatHistory(items))
+	return nil
+}
+
+// formatHistory formats items with a fixed-width index column.
+func formatHistory(items []apitypes.Activity) string {
+	maxIndex := 0
+	for _, item := range items {
+		if item.Index > maxIndex {
+			maxIndex = item.Index
+		}
+	}
+
 	// Use 1-indexing for history entries
-	indexColumnLength := len(strconv.Itoa(len(history)))
+	indexColumnLength := len(strconv.Itoa(maxIndex + 1))
 	formatStr := "%" + strconv.Itoa(indexColumnLength) + "d  %s  %s\n"
-	i := 0
-	for item := range history {
-		cmdutil.Printf(formatStr, i+1, item.Start.Format("2006-01-02 15:04"), item.Description)
-		i++
+	var sb strings.Builder
+	for _, item := range items {
+		fmt.Fprintf(&sb, formatStr, item.Index+1, item.Start.Format("2006-01-02 15:04"), item.Description)
+	}
+	return sb.String()
+}
+
+func printEvents(index string, filter apitypes.HistoryFilter) error {
+	idx := -1
+	if index != "" {
+		var err error
+		if idx, err = parseHistoryID(index); err != nil {
+			return err
+		}
+	}
+
+	conn := cmdutil.NewClient()
+	events, err := conn.HistoryEvents(idx, filter)
+	if err != nil {
+		return err
 	}
+	cmdutil.Print(formatEvents(events))
 	return nil
 }
 
+// formatEvents formats events as a table.
+func formatEvents(events []apitypes.ActivityEvent) string {
+	headers := []cmdutil.ColumnHeader{
+		{ShortName: "id", FullName: "ID"},
+		{ShortName: "time", FullName: "TIME"},
+		{ShortName: "method", FullName: "METHOD"},
+		{ShortName: "entry", FullName: "ENTRY"},
+		{ShortName: "duration", FullName: "DURATION"},
+		{ShortName: "cache", FullName: "CACHE"},
+		{ShortName: "error", FullName: "ERROR"},
+	}
+	var table [][]string
+	for _, event := range events {
+		table = append(table, []string{
+			// Use 1-indexing for history entries
+			strconv.Itoa(event.Index + 1),
+			event.Time.Format(time.StampMilli),
+			event.Method,
+			event.EntryID,
+			cmdutil.FormatDuration(event.Duration),
+			string(event.Cache),
+			// Keep each event on a single row.
+			strings.Replace(event.Error, "\n", " ", -1),
+		})
+	}
+	return cmdutil.NewTableWithHeaders(headers, table).Format()
+}
+
+// parseHistoryID translates a 1-indexed history ID to an index.
+func parseHistoryID(id string) (int, error) {
+	idx, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, err
+	}
+	return idx - 1, nil
+}
+
+// parseHistoryTime parses either an RFC3339 time or a duration relative to now.
+func parseHistoryTime(str string, now time.Time) (time.Time, error) {
+	if str == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, str); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%v must be an RFC3339 time or a duration like 1h", str)
+	}
+	return now.Add(-d), nil
+}
+
+func getHistoryFilter(cmd *cobra.Command) (apitypes.HistoryFilter, error) {
+	var filter apitypes.HistoryFilter
+	now := time.Now()
+	for flag, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		str, err := cmd.Flags().GetString(flag)
+		if err != nil {
+			panic(err.Error())
+		}
+		if *t, err = parseHistoryTime(str, now); err != nil {
+			return filter, fmt.Errorf("invalid --%v: %v", flag, err)
+		}
+	}
+
+	var err error
+	if filter.Plugin, err = cmd.Flags().GetString("plugin"); err != nil {
+		panic(err.Error())
+	}
+	if filter.PathPrefix, err = cmd.Flags().GetString("path"); err != nil {
+		panic(err.Error())
+	}
+	if filter.ErrorsOnly, err = cmd.Flags().GetBool("errors"); err != nil {
+		panic(err.Error())
+	}
+	return filter, nil
+}
+
 func historyMain(cmd *cobra.Command, args []string) exitCode {
 	follow, err := cmd.Flags().GetBool("follow")
 	if err != nil {
 		panic(err.Error())
 	}
+	events, err := cmd.Flags().GetBool("events")
+	if err != nil {
+		panic(err.Error())
+	}
+
+	var filter apitypes.HistoryFilter
+	if filter, err = getHistoryFilter(cmd); err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
 
+	var id string
 	if len(args) > 0 {
-		err = printJournalEntry(args[0], follow)
-	} else {
-		err = printHistory(follow)
+		id = args[0]
+	}
+
+	switch {
+	case events && follow:
+		err = fmt.Errorf("--follow cannot be used with --events")
+	case events:
+		err = printEvents(id, filter)
+	case id != "":
+		err = printJournalEntry(id, follow)
+	default:
+		err = printHistory(follow, filter)
 	}
 
 	if err != nil {
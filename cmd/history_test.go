@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHistoryTime(t *testing.T) {
+	now := time.Now()
+
+	tm, err := parseHistoryTime("", now)
+	assert.NoError(t, err)
+	assert.True(t, tm.IsZero())
+
+	tm, err = parseHistoryTime("1h30m", now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(-90*time.Minute), tm)
+
+	tm, err = parseHistoryTime("2019-06-13T15:44:04Z", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2019, 6, 13, 15, 44, 4, 0, time.UTC), tm)
+
+	_, err = parseHistoryTime("yesterday", now)
+	assert.EqualError(t, err, "yesterday must be an RFC3339 time or a duration like 1h")
+}
+
+func TestFormatEvents(t *testing.T) {
+	tm := time.Date(2019, 6, 13, 15, 44, 4, 0, time.UTC)
+	events := []apitypes.ActivityEvent{
+		{Index: 0, Line: activity.Line{Time: tm, Event: activity.Event{Method: "List", EntryID: "/docker", Duration: time.Second, Cache: activity.CacheMiss}}},
+		{Index: 1, Line: activity.Line{Time: tm, Event: activity.Event{Method: "Exec", EntryID: "/docker/containers/foo", Error: "exec\nfailed"}}},
+	}
+
+	lines := strings.Split(strings.TrimSpace(formatEvents(events)), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.Equal(t, []string{"ID", "TIME", "METHOD", "ENTRY", "DURATION", "CACHE", "ERROR"}, strings.Fields(lines[0]))
+		assert.Equal(t, []string{"1", "Jun", "13", "15:44:04.000", "List", "/docker", "00:01.00", "miss"}, strings.Fields(lines[1]))
+		assert.Equal(t, []string{"2", "Jun", "13", "15:44:04.000", "Exec", "/docker/containers/foo", "00:00.00", "exec", "failed"}, strings.Fields(lines[2]))
+	}
+}
+
+func TestFormatHistory(t *testing.T) {
+	tm := time.Date(2019, 6, 13, 15, 44, 4, 0, time.UTC)
+	items := []apitypes.Activity{
+		{Index: 8, Description: "ls", Start: tm},
+		{Index: 9, Description: "exec", Start: tm},
+	}
+	assert.Equal(t, " 9  2019-06-13 15:44  ls\n10  2019-06-13 15:44  exec\n", formatHistory(items))
+	assert.Equal(t, "9  2019-06-13 15:44  ls\n", formatHistory(items[:1]))
+	assert.Empty(t, formatHistory(nil))
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"github.com/puppetlabs/wash/analytics"
+	"github.com/puppetlabs/wash/api/client"
 	apitypes "github.com/puppetlabs/wash/api/types"
 )
 
@@ -44,12 +45,36 @@ func (c *MockClient) Exec(path string, command string, args []string, opts apity
 	return margs.Get(0).(<-chan apitypes.ExecPacket), margs.Error(1)
 }
 
+// BatchExec mocks Client#BatchExec
+func (c *MockClient) BatchExec(body apitypes.BatchExecBody) (<-chan apitypes.BatchExecPacket, error) {
+	args := c.Called(body)
+	return args.Get(0).(<-chan apitypes.BatchExecPacket), args.Error(1)
+}
+
+// ExecSession mocks Client#ExecSession
+func (c *MockClient) ExecSession(path string, body apitypes.ExecSessionBody) (*client.ExecSession, error) {
+	args := c.Called(path, body)
+	return args.Get(0).(*client.ExecSession), args.Error(1)
+}
+
+// Find mocks Client#Find
+func (c *MockClient) Find(path string, query interface{}) ([]apitypes.Entry, error) {
+	args := c.Called(path, query)
+	return args.Get(0).([]apitypes.Entry), args.Error(1)
+}
+
 // History mocks Client#History
-func (c *MockClient) History(follow bool) (chan apitypes.Activity, error) {
-	args := c.Called(follow)
+func (c *MockClient) History(follow bool, filter apitypes.HistoryFilter) (chan apitypes.Activity, error) {
+	args := c.Called(follow, filter)
 	return args.Get(0).(chan apitypes.Activity), args.Error(1)
 }
 
+// HistoryEvents mocks Client#HistoryEvents
+func (c *MockClient) HistoryEvents(index int, filter apitypes.HistoryFilter) ([]apitypes.ActivityEvent, error) {
+	args := c.Called(index, filter)
+	return args.Get(0).([]apitypes.ActivityEvent), args.Error(1)
+}
+
 // ActivityJournal mocks Client#ActivityJournal
 func (c *MockClient) ActivityJournal(index int, follow bool) (io.ReadCloser, error) {
 	args := c.Called(index, follow)
@@ -85,3 +110,39 @@ func (c *MockClient) Signal(path string, signal string) error {
 	args := c.Called(path, signal)
 	return args.Error(0)
 }
+
+// Queries mocks Client#Queries
+func (c *MockClient) Queries() ([]apitypes.SavedQuery, error) {
+	args := c.Called()
+	return args.Get(0).([]apitypes.SavedQuery), args.Error(1)
+}
+
+// SaveQuery mocks Client#SaveQuery
+func (c *MockClient) SaveQuery(query apitypes.SavedQuery) error {
+	args := c.Called(query)
+	return args.Error(0)
+}
+
+// DeleteQuery mocks Client#DeleteQuery
+func (c *MockClient) DeleteQuery(name string) error {
+	args := c.Called(name)
+	return args.Error(0)
+}
+
+// Plugins mocks Client#Plugins
+func (c *MockClient) Plugins() ([]apitypes.PluginStatus, error) {
+	args := c.Called()
+	return args.Get(0).([]apitypes.PluginStatus), args.Error(1)
+}
+
+// Plugin mocks Client#Plugin
+func (c *MockClient) Plugin(name string) (apitypes.PluginStatus, error) {
+	args := c.Called(name)
+	return args.Get(0).(apitypes.PluginStatus), args.Error(1)
+}
+
+// ReloadPlugins mocks Client#ReloadPlugins
+func (c *MockClient) ReloadPlugins(name string) ([]apitypes.PluginReload, error) {
+	args := c.Called(name)
+	return args.Get(0).([]apitypes.PluginReload), args.Error(1)
+}
@@ -38,6 +38,7 @@ func Init() error {
 		return err
 	}
 	defaultFileAbs = filepath.Join(homeDir, defaultFileSuffix)
+	defaultPluginsDir = filepath.Join(homeDir, defaultPluginsDirSuffix)
 
 	// Tell viper that the config. can be read from WASH_<entry>
 	// environment variables
@@ -73,6 +74,18 @@ func DefaultFileAbsPath() string {
 	return defaultFileAbs
 }
 
+var defaultPluginsDirSuffix = filepath.Join(".puppetlabs", "wash", "plugins")
+var defaultPluginsDir string
+
+// DefaultPluginsDir returns the absolute path of the directory
+// that installed external plugins are placed in
+func DefaultPluginsDir() string {
+	if defaultPluginsDir == "" {
+		panic("config.DefaultPluginsDir: plugins directory not set. Please call config.Init()")
+	}
+	return defaultPluginsDir
+}
+
 // ReadFrom reads the config from the specified file.
 // If file == DefaultFile(), then ReadFrom wil not return
 // an error if file does not exist.
@@ -11,6 +11,7 @@ import (
 	"github.com/puppetlabs/wash/cmd/internal/find/primary"
 	"github.com/puppetlabs/wash/cmd/internal/find/types"
 	cmdutil "github.com/puppetlabs/wash/cmd/util"
+	"github.com/puppetlabs/wash/plugin"
 )
 
 // Main is `wash find`'s main function.
@@ -27,6 +28,10 @@ func Main(args []string) int {
 		cmdutil.ErrPrintf("find: %v\n", err)
 		return 1
 	}
+	if primary.IsSet(primary.Delete) && !opts.Force && !plugin.IsInteractive() {
+		cmdutil.ErrPrintf("find: -delete requires -force when find can't prompt for confirmation\n")
+		return 1
+	}
 	if opts.Daystart {
 		// Set the ReferenceTime to the start of the current day
 		year, month, day := params.ReferenceTime.Date()
@@ -45,12 +50,16 @@ func Main(args []string) int {
 	// Do the walk
 	conn := cmdutil.NewClient()
 	walker := newWalker(result, conn)
+	primary.Actions.Start(conn, int(opts.Parallel), opts.Force)
 	exitCode := 0
 	for _, path := range result.Paths {
 		if !walker.Walk(path) {
 			exitCode = 1
 		}
 	}
+	if !primary.Actions.Finish() {
+		exitCode = 1
+	}
 	return exitCode
 }
 
@@ -33,6 +33,7 @@ func (s *MainTestSuite) SetupTest() {
 func (s *MainTestSuite) TearDownTest() {
 	s.Suite.TearDownTest()
 	newWalker = s.oldNewWalker
+	primary.Parser.SetPrimaries = make(map[*primary.Primary]bool)
 	s.walker = nil
 	s.oldNewWalker = nil
 }
@@ -47,6 +48,17 @@ func (s *MainTestSuite) TestMain_ParseError() {
 	s.Regexp("find:.*", s.Stderr())
 }
 
+func (s *MainTestSuite) TestMain_DeleteRequiresForce() {
+	// Tests aren't interactive, so find can't prompt for confirmation.
+	s.Equal(1, Main([]string{"-delete"}))
+	s.Regexp("-delete requires -force", s.Stderr())
+	s.walker.AssertNotCalled(s.T(), "Walk", mock.Anything)
+
+	s.walker.On("Walk", ".").Return(true)
+	s.Equal(0, Main([]string{"-force", "-delete"}))
+	s.walker.AssertCalled(s.T(), "Walk", ".")
+}
+
 func (s *MainTestSuite) TestMain_ReferenceTime_NoDaystart() {
 	s.walker.On("Walk", mock.Anything).Return(true)
 	Main([]string{})
@@ -2,6 +2,8 @@ package parser
 
 import (
 	"flag"
+	"fmt"
+
 	"github.com/puppetlabs/wash/cmd/internal/find/types"
 )
 
@@ -75,6 +77,9 @@ func parseOptions(args []string) (types.Options, []string, error) {
 			o.Maxdepth = types.DefaultMaxdepth
 		}
 	})
+	if o.Parallel == 0 {
+		return o, nil, fmt.Errorf("-%v must be greater than 0", types.ParallelFlag)
+	}
 
 	// Calculate the remaining args
 	if endIx == len(args) {
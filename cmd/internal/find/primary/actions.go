@@ -0,0 +1,398 @@
+package primary
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/puppetlabs/wash/api/client"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/cmd/internal/find/types"
+	cmdutil "github.com/puppetlabs/wash/cmd/util"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// This file contains the action primaries. Action primaries are primaries with
+// side-effects. If any of them are set, then find will not print the entries
+// that satisfy the expression.
+//
+// The print primaries run synchronously so that their output is ordered. All
+// other actions are submitted to Actions, which runs them in parallel. Since
+// these actions run asynchronously, their primaries always return true (unlike
+// POSIX find, where e.g. -exec returns true only if the command succeeded).
+
+// ActionFunc performs an action on the given entry.
+type ActionFunc = func(conn client.Client, e types.Entry) error
+
+type actionResult struct {
+	path   string
+	action string
+	err    error
+}
+
+// ActionRunner runs the asynchronous actions with bounded parallelism.
+type ActionRunner struct {
+	conn          client.Client
+	pool          cmdutil.Pool
+	mux           sync.Mutex
+	results       []actionResult
+	deletedPaths  []string
+	force         bool
+	submittedWork bool
+}
+
+// Actions is `wash find`'s action runner. It should be started before the walk
+// and finished after the walk.
+var Actions = &ActionRunner{}
+
+// Start starts the action runner. Actions are run via the given client, with at
+// most parallel actions running at once. If force is set, entries are deleted
+// without prompting for confirmation.
+func (r *ActionRunner) Start(conn client.Client, parallel int, force bool) {
+	r.conn = conn
+	r.pool = cmdutil.NewPool(parallel)
+	r.results = nil
+	r.deletedPaths = nil
+	r.force = force
+	r.submittedWork = false
+}
+
+// Submit submits the named action on the given entry.
+func (r *ActionRunner) Submit(action string, e types.Entry, fn ActionFunc) {
+	r.mux.Lock()
+	r.submittedWork = true
+	r.mux.Unlock()
+	r.pool.Submit(func() {
+		defer r.pool.Done()
+		err := fn(r.conn, e)
+		r.mux.Lock()
+		r.results = append(r.results, actionResult{path: e.NormalizedPath, action: action, err: err})
+		r.mux.Unlock()
+	})
+}
+
+// Finish waits for all the submitted actions to complete, then prints a summary
+// of each action's result on stderr. It returns false if any of the actions
+// failed.
+func (r *ActionRunner) Finish() bool {
+	r.pool.Finish()
+	if !r.submittedWork {
+		return true
+	}
+
+	headers := []cmdutil.ColumnHeader{
+		{ShortName: "path", FullName: "PATH"},
+		{ShortName: "action", FullName: "ACTION"},
+		{ShortName: "result", FullName: "RESULT"},
+	}
+	var rows [][]string
+	failures := 0
+	for _, result := range r.results {
+		status := "succeeded"
+		if result.err != nil {
+			status = "failed: " + strings.Replace(result.err.Error(), "\n", " ", -1)
+			failures++
+		}
+		rows = append(rows, []string{result.path, result.action, status})
+	}
+	fmt.Fprintf(cmdutil.Stderr, "\n%v", cmdutil.NewTableWithHeaders(headers, rows).Format())
+	fmt.Fprintf(cmdutil.Stderr, "%v succeeded, %v failed\n", len(r.results)-failures, failures)
+	return failures == 0
+}
+
+// markedForDeletion returns true if path or one of its ancestors was already
+// marked for deletion. Deleting an entry also deletes its children, so there's
+// no need to delete them separately.
+func (r *ActionRunner) markedForDeletion(path string) bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	for _, deletedPath := range r.deletedPaths {
+		if path == deletedPath || strings.HasPrefix(path, deletedPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// markForDeletion marks path for deletion.
+func (r *ActionRunner) markForDeletion(path string) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.deletedPaths = append(r.deletedPaths, path)
+}
+
+var actionPrimaries = make(map[*Primary]bool)
+
+func addAction(p *Primary) *Primary {
+	actionPrimaries[p] = true
+	return Parser.add(p)
+}
+
+// ActionIsSet returns true if an action primary was set. Call this after
+// `wash find` finishes parsing its arguments.
+func ActionIsSet() bool {
+	for p := range actionPrimaries {
+		if IsSet(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// supportsActionP returns a predicate that's true if the entry supports the given
+// action.
+func supportsActionP(action plugin.Action, f func(e types.Entry)) types.EntryPredicate {
+	p := types.ToEntryP(func(e types.Entry) bool {
+		if !e.Supports(action) {
+			return false
+		}
+		f(e)
+		return true
+	})
+	p.SetSchemaP(types.ToEntrySchemaP(func(s *types.EntrySchema) bool {
+		for _, a := range s.Actions() {
+			if action.Name == a {
+				return true
+			}
+		}
+		return false
+	}))
+	return p
+}
+
+// Print is the print primary
+//
+// printPrimary => -print
+//nolint
+var Print = addAction(&Primary{
+	Description: "Prints the entry's path followed by a newline. Always returns true",
+	name:        "print",
+	parseFunc: func(tokens []string) (types.EntryPredicate, []string, error) {
+		return types.ToEntryP(func(e types.Entry) bool {
+			cmdutil.SafePrintf("%v\n", e.NormalizedPath)
+			return true
+		}), tokens, nil
+	},
+})
+
+// Print0 is the print0 primary
+//
+// print0Primary => -print0
+//nolint
+var Print0 = addAction(&Primary{
+	Description: "Prints the entry's path followed by a NUL character. Always returns true",
+	name:        "print0",
+	parseFunc: func(tokens []string) (types.EntryPredicate, []string, error) {
+		return types.ToEntryP(func(e types.Entry) bool {
+			cmdutil.SafePrintf("%v\x00", e.NormalizedPath)
+			return true
+		}), tokens, nil
+	},
+})
+
+// printfData is the data that's passed into the printf primary's template
+type printfData struct {
+	Path       string
+	Name       string
+	CName      string
+	TypeID     string
+	Actions    []string
+	Attributes map[string]interface{}
+	Metadata   map[string]interface{}
+}
+
+var printfEscapes = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\t`, "\t", `\0`, "\x00")
+
+// Printf is the printf primary
+//
+// printfPrimary => -printf format
+//nolint
+var Printf = addAction(&Primary{
+	Description:         "Prints the entry using the Go template format. Always returns true",
+	DetailedDescription: printfDetailedDescription,
+	name:                "printf",
+	args:                "format",
+	parseFunc: func(tokens []string) (types.EntryPredicate, []string, error) {
+		if len(tokens) == 0 {
+			return nil, nil, fmt.Errorf("requires additional arguments")
+		}
+		tmpl, err := template.New("printf").Option("missingkey=zero").Parse(printfEscapes.Replace(tokens[0]))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid format: %v", err)
+		}
+		return types.ToEntryP(func(e types.Entry) bool {
+			data := printfData{
+				Path:       e.NormalizedPath,
+				Name:       e.Name,
+				CName:      e.CName,
+				TypeID:     e.TypeID,
+				Actions:    e.Actions,
+				Attributes: e.Attributes.ToMap(),
+				Metadata:   e.Metadata,
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, data); err != nil {
+				cmdutil.SafeErrPrintf("%v: could not format the entry: %v\n", e.NormalizedPath, err)
+				return true
+			}
+			cmdutil.SafePrint(buf.String())
+			return true
+		}), tokens[1:], nil
+	},
+})
+
+// Exec is the exec primary
+//
+// execPrimary => -exec command [arg ...] ;
+//nolint
+var Exec = addAction(&Primary{
+	Description: "Execs command on the entry. Any {} args are replaced with the entry's path. Returns false if the entry is not execable",
+	name:        "exec",
+	args:        "command [arg ...] ;",
+	parseFunc: func(tokens []string) (types.EntryPredicate, []string, error) {
+		endIx := -1
+		for i, token := range tokens {
+			if token == ";" {
+				endIx = i
+				break
+			}
+		}
+		if endIx < 0 {
+			return nil, nil, fmt.Errorf("missing terminating ;")
+		}
+		if endIx == 0 {
+			return nil, nil, fmt.Errorf("requires a command")
+		}
+		command, args := tokens[0], tokens[1:endIx]
+		p := supportsActionP(plugin.ExecAction(), func(e types.Entry) {
+			Actions.Submit("exec", e, func(conn client.Client, e types.Entry) error {
+				return execOn(conn, e, command, args)
+			})
+		})
+		return p, tokens[endIx+1:], nil
+	},
+})
+
+func execOn(conn client.Client, e types.Entry, command string, args []string) error {
+	expandedArgs := make([]string, len(args))
+	for i, arg := range args {
+		expandedArgs[i] = strings.Replace(arg, "{}", e.NormalizedPath, -1)
+	}
+	ch, err := conn.Exec(e.Path, command, expandedArgs, apitypes.ExecOptions{})
+	if err != nil {
+		return err
+	}
+
+	// Buffer the output so that the output of concurrent execs isn't interleaved.
+	var stdout, stderr strings.Builder
+	exitCode := 0
+	for pkt := range ch {
+		if pkt.Err != nil {
+			return pkt.Err
+		}
+		switch pkt.TypeField {
+		case apitypes.Stdout:
+			stdout.WriteString(pkt.Data.(string))
+		case apitypes.Stderr:
+			stderr.WriteString(pkt.Data.(string))
+		case apitypes.Exitcode:
+			exitCode = int(pkt.Data.(float64))
+		}
+	}
+	if stdout.Len() > 0 {
+		cmdutil.SafePrint(stdout.String())
+	}
+	if stderr.Len() > 0 {
+		cmdutil.SafeErrPrintf("%v", stderr.String())
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("exited with %v", exitCode)
+	}
+	return nil
+}
+
+// Signal is the signal primary
+//
+// signalPrimary => -signal signal
+//nolint
+var Signal = addAction(&Primary{
+	Description: "Sends signal to the entry. Returns false if the entry is not signalable",
+	name:        "signal",
+	args:        "signal",
+	parseFunc: func(tokens []string) (types.EntryPredicate, []string, error) {
+		if len(tokens) == 0 {
+			return nil, nil, fmt.Errorf("requires additional arguments")
+		}
+		signal := tokens[0]
+		p := supportsActionP(plugin.SignalAction(), func(e types.Entry) {
+			Actions.Submit("signal "+signal, e, func(conn client.Client, e types.Entry) error {
+				return conn.Signal(e.Path, signal)
+			})
+		})
+		return p, tokens[1:], nil
+	},
+})
+
+// Delete is the delete primary
+//
+// deletePrimary => -delete
+//nolint
+var Delete = addAction(&Primary{
+	Description: "Deletes the entry after prompting for confirmation (see -force). Returns false if the entry is not deletable",
+	name:        "delete",
+	parseFunc: func(tokens []string) (types.EntryPredicate, []string, error) {
+		p := supportsActionP(plugin.DeleteAction(), func(e types.Entry) {
+			if Actions.markedForDeletion(e.NormalizedPath) {
+				return
+			}
+			if !Actions.force {
+				// find refuses to start a non-interactive -delete without -force, so
+				// this is only a safeguard.
+				if !plugin.IsInteractive() {
+					return
+				}
+				input, err := cmdutil.Prompt(fmt.Sprintf("remove %v?", e.NormalizedPath), cmdutil.YesOrNoP)
+				if err != nil {
+					cmdutil.SafeErrPrintf("failed to get confirmation: %v\n", err)
+					return
+				}
+				if !input.(bool) {
+					return
+				}
+			}
+			Actions.markForDeletion(e.NormalizedPath)
+			Actions.Submit("delete", e, func(conn client.Client, e types.Entry) error {
+				_, err := conn.Delete(e.Path)
+				return err
+			})
+		})
+		return p, tokens, nil
+	},
+})
+
+const printfDetailedDescription = `
+-printf format
+
+Prints the entry using the given Go template (see https://golang.org/pkg/text/template).
+Unlike -print, a newline is not appended so include "\n" in the format if you need one.
+The escapes "\n", "\t", "\0" and "\\" are supported. Always returns true.
+
+The following fields are available to the template:
+    .Path        The entry's path
+    .Name        The entry's name
+    .CName       The entry's cname
+    .TypeID      The entry's type ID
+    .Actions     The entry's supported actions
+    .Attributes  The entry's attributes (e.g. .Attributes.size, .Attributes.mtime)
+    .Metadata    The entry's metadata. This is the partial metadata unless the
+                 fullmeta option is set.
+
+Examples:
+    -printf '{{.Path}} {{.Attributes.size}}\n'
+    Prints the entry's path and size
+
+    -printf '{{.CName}}: {{.Metadata.State.Name}}\n'
+    Prints an EC2 instance's cname and state
+`
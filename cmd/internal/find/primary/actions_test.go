@@ -0,0 +1,137 @@
+package primary
+
+import (
+	"fmt"
+	"testing"
+
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/cmd/internal/cmdtest"
+	"github.com/puppetlabs/wash/cmd/internal/find/types"
+	"github.com/stretchr/testify/suite"
+)
+
+type ActionsPrimaryTestSuite struct {
+	*cmdtest.Suite
+}
+
+func (s *ActionsPrimaryTestSuite) SetupTest() {
+	s.Suite.SetupTest()
+	Actions.Start(s.Client, 2, true)
+}
+
+func (s *ActionsPrimaryTestSuite) newEntry(path string, actions ...string) types.Entry {
+	e := types.Entry{NormalizedPath: path}
+	e.Path = "/abs/" + path
+	e.Name = path
+	e.CName = path
+	e.Actions = actions
+	return e
+}
+
+func (s *ActionsPrimaryTestSuite) parse(primary *Primary, tokens ...string) (types.EntryPredicate, []string) {
+	p, tokens, err := primary.Parse(tokens)
+	s.Require().NoError(err)
+	return p.(types.EntryPredicate), tokens
+}
+
+func execOutput(stdout string, exitCode int) <-chan apitypes.ExecPacket {
+	ch := make(chan apitypes.ExecPacket, 2)
+	ch <- apitypes.ExecPacket{TypeField: apitypes.Stdout, Data: stdout}
+	ch <- apitypes.ExecPacket{TypeField: apitypes.Exitcode, Data: float64(exitCode)}
+	close(ch)
+	return ch
+}
+
+func (s *ActionsPrimaryTestSuite) TestPrint() {
+	p, _ := s.parse(Print)
+	s.True(p.P(s.newEntry("foo")))
+	s.Equal("foo\n", s.Stdout())
+}
+
+func (s *ActionsPrimaryTestSuite) TestPrint0() {
+	p, _ := s.parse(Print0)
+	s.True(p.P(s.newEntry("foo")))
+	s.Equal("foo\x00", s.Stdout())
+}
+
+func (s *ActionsPrimaryTestSuite) TestPrintf() {
+	_, _, err := Printf.Parse([]string{})
+	s.Regexp("requires additional arguments", err)
+	_, _, err = Printf.Parse([]string{"{{.Path"})
+	s.Regexp("invalid format", err)
+
+	p, tokens := s.parse(Printf, `{{.Path}}\t{{.CName}}\n`, "-true")
+	s.Equal([]string{"-true"}, tokens)
+	s.True(p.P(s.newEntry("foo")))
+	s.Equal("foo\tfoo\n", s.Stdout())
+}
+
+func (s *ActionsPrimaryTestSuite) TestExec_Errors() {
+	_, _, err := Exec.Parse([]string{"echo", "{}"})
+	s.Regexp("missing terminating ;", err)
+	_, _, err = Exec.Parse([]string{";"})
+	s.Regexp("requires a command", err)
+}
+
+func (s *ActionsPrimaryTestSuite) TestExec() {
+	p, tokens := s.parse(Exec, "echo", "{}", ";", "-true")
+	s.Equal([]string{"-true"}, tokens)
+
+	s.False(p.P(s.newEntry("notExecable", "list")))
+
+	s.Client.On("Exec", "/abs/foo", "echo", []string{"foo"}, apitypes.ExecOptions{}).Return(execOutput("foo\n", 0), nil)
+	s.Client.On("Exec", "/abs/bar", "echo", []string{"bar"}, apitypes.ExecOptions{}).Return(execOutput("bar\n", 1), nil)
+	s.True(p.P(s.newEntry("foo", "exec")))
+	s.True(p.P(s.newEntry("bar", "exec")))
+
+	s.False(Actions.Finish())
+	s.Contains(s.Stdout(), "foo\n")
+	s.Contains(s.Stdout(), "bar\n")
+	s.Regexp(`foo\s+exec\s+succeeded`, s.Stderr())
+	s.Regexp(`bar\s+exec\s+failed: exited with 1`, s.Stderr())
+	s.Contains(s.Stderr(), "1 succeeded, 1 failed")
+}
+
+func (s *ActionsPrimaryTestSuite) TestSignal() {
+	p, tokens := s.parse(Signal, "stop")
+	s.Empty(tokens)
+
+	s.False(p.P(s.newEntry("foo", "list")))
+
+	s.Client.On("Signal", "/abs/foo", "stop").Return(nil)
+	s.True(p.P(s.newEntry("foo", "signal")))
+	s.True(Actions.Finish())
+	s.Client.AssertExpectations(s.T())
+}
+
+func (s *ActionsPrimaryTestSuite) TestDelete_SkipsDescendantsOfDeletedEntries() {
+	p, _ := s.parse(Delete)
+
+	s.Client.On("Delete", "/abs/foo").Return(true, nil)
+	s.Client.On("Delete", "/abs/foobar").Return(false, fmt.Errorf("failed"))
+	s.True(p.P(s.newEntry("foo", "delete")))
+	s.True(p.P(s.newEntry("foo/bar", "delete")))
+	s.True(p.P(s.newEntry("foobar", "delete")))
+
+	s.False(Actions.Finish())
+	s.Client.AssertNotCalled(s.T(), "Delete", "/abs/foo/bar")
+	s.Contains(s.Stderr(), "1 succeeded, 1 failed")
+}
+
+func (s *ActionsPrimaryTestSuite) TestDelete_RequiresForceWhenNotInteractive() {
+	Actions.Start(s.Client, 2, false)
+	p, _ := s.parse(Delete)
+
+	s.True(p.P(s.newEntry("foo", "delete")))
+	s.True(Actions.Finish())
+	s.Client.AssertNotCalled(s.T(), "Delete", "/abs/foo")
+}
+
+func (s *ActionsPrimaryTestSuite) TestFinish_NoActions() {
+	s.True(Actions.Finish())
+	s.Empty(s.Stderr())
+}
+
+func TestActionsPrimary(t *testing.T) {
+	suite.Run(t, &ActionsPrimaryTestSuite{Suite: new(cmdtest.Suite)})
+}
@@ -26,6 +26,12 @@ func (s *ParserTestSuite) TestRegisteredPrimaries() {
 		Atime,
 		Crtime,
 		Kind,
+		Print,
+		Print0,
+		Printf,
+		Exec,
+		Signal,
+		Delete,
 	}
 	expectedMp := map[string]*Primary{
 		"-action": Action,
@@ -42,6 +48,12 @@ func (s *ParserTestSuite) TestRegisteredPrimaries() {
 		"-crtime": Crtime,
 		"-kind":   Kind,
 		"-k":      Kind,
+		"-print":  Print,
+		"-print0": Print0,
+		"-printf": Printf,
+		"-exec":   Exec,
+		"-signal": Signal,
+		"-delete": Delete,
 	}
 
 	s.ElementsMatch(expectedList, Parser.primaries)
@@ -14,6 +14,8 @@ type Options struct {
 	Mindepth uint
 	Daystart bool
 	Fullmeta bool
+	Parallel uint
+	Force    bool
 	Help     HelpOption
 	setFlags map[string]struct{}
 }
@@ -22,6 +24,9 @@ type Options struct {
 // It is set to the max value of a 32-bit integer.
 const DefaultMaxdepth = 1<<31 - 1
 
+// DefaultParallel is the default value of the parallel option.
+const DefaultParallel = 10
+
 // NewOptions creates a new Options object
 func NewOptions() Options {
 	return Options{
@@ -32,6 +37,8 @@ func NewOptions() Options {
 		Maxdepth: DefaultMaxdepth,
 		Daystart: false,
 		Fullmeta: false,
+		Parallel: DefaultParallel,
+		Force:    false,
 		setFlags: make(map[string]struct{}),
 	}
 }
@@ -47,6 +54,10 @@ const (
 	DaystartFlag = "daystart"
 	// FullmetaFlag is the name of the fullmeta option's flag
 	FullmetaFlag = "fullmeta"
+	// ParallelFlag is the name of the parallel option's flag
+	ParallelFlag = "parallel"
+	// ForceFlag is the name of the force option's flag
+	ForceFlag = "force"
 )
 
 // IsSet returns true if the flag was set, false otherwise.
@@ -71,6 +82,8 @@ func (opts *Options) FlagSet() *flag.FlagSet {
 	fs.IntVar(&opts.Maxdepth, MaxdepthFlag, opts.Maxdepth, "")
 	fs.BoolVar(&opts.Daystart, DaystartFlag, opts.Daystart, "")
 	fs.BoolVar(&opts.Fullmeta, FullmetaFlag, opts.Fullmeta, "")
+	fs.UintVar(&opts.Parallel, ParallelFlag, opts.Parallel, "")
+	fs.BoolVar(&opts.Force, ForceFlag, opts.Force, "")
 	return fs
 }
 
@@ -84,6 +97,8 @@ func OptionsTable() *cmdutil.Table {
 		[]string{"      -maxdepth depth",  "Do not print entries at levels greater than depth (default infinity)"},
 		[]string{"      -daystart",        "Set the reference time to the start of the current day (default false)"},
 		[]string{"      -fullmeta",        "Use the entry's full metadata in meta primary predicates (default false)"},
+		[]string{"      -parallel n",      "Run at most n -exec, -signal or -delete actions at once (default 10)"},
+		[]string{"      -force",           "Delete entries without prompting for confirmation (default false)"},
 		[]string{"  -h, -help",            "Print this usage"},
 		[]string{"  -h, -help <primary>",  "Print a detailed description of the specified primary (e.g. \"-help meta\")"},
 		[]string{"  -h, -help syntax",     "Print a detailed description of find's expression syntax"},
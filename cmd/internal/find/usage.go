@@ -46,6 +46,13 @@ func Usage() string {
 	u += "the specified attribute. For example, the -mtime primary will always return false\n"
 	u += "if the entry does not have an mtime attribute.\n"
 	u += "\n"
+	u += "NOTE: If an action primary (-print, -print0, -printf, -exec, -signal or -delete)\n"
+	u += "is specified, find does not print the entries that satisfy the expression. The\n"
+	u += "-exec, -signal and -delete actions run in parallel (see -parallel) and always return\n"
+	u += "true once queued. A summary of their results is printed when find finishes.\n"
+	u += "-delete prompts for confirmation unless -force is set, and requires -force when\n"
+	u += "find isn't run interactively.\n"
+	u += "\n"
 	u += "NOTE: find exits with status 0 if all entries are processed successfully, greater\n"
 	u += "than 0 if errors occur. This is deliberately a very broad description, but if the\n"
 	u += "return value is non-zero, you should not rely on the correctness of find.\n"
@@ -65,7 +65,8 @@ func (w *walkerImpl) walk(e types.Entry, depth uint) bool {
 		check(w.visit(e, depth))
 	}
 	childDepth := depth + 1
-	if int(childDepth) <= w.opts.Maxdepth && e.Supports(plugin.ListAction()) {
+	// Links aren't followed so that walks can't loop.
+	if int(childDepth) <= w.opts.Maxdepth && e.Supports(plugin.ListAction()) && !e.Supports(plugin.ReadlinkAction()) {
 		if e.SchemaKnown {
 			if e.Schema == nil || len(e.Schema.Children()) == 0 {
 				// We've reached the end of our traversal
@@ -123,7 +124,8 @@ func (w *walkerImpl) visit(e types.Entry, depth uint) bool {
 			e.Metadata = meta
 		}
 	}
-	if w.p.P(e) {
+	// If an action primary was set, then it's responsible for the output.
+	if w.p.P(e) && !primary.ActionIsSet() {
 		cmdutil.Printf("%v\n", e.NormalizedPath)
 	}
 	return true
@@ -3,8 +3,12 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"reflect"
 	"runtime/pprof"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -13,12 +17,18 @@ import (
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/analytics"
 	"github.com/puppetlabs/wash/api"
+	"github.com/puppetlabs/wash/api/queries"
+	apitypes "github.com/puppetlabs/wash/api/types"
 	"github.com/puppetlabs/wash/fuse"
+	"github.com/puppetlabs/wash/metrics"
 	"github.com/puppetlabs/wash/plugin"
 	"github.com/puppetlabs/wash/plugin/aws"
 	"github.com/puppetlabs/wash/plugin/docker"
+	"github.com/puppetlabs/wash/plugin/external"
 	"github.com/puppetlabs/wash/plugin/gcp"
 	"github.com/puppetlabs/wash/plugin/kubernetes"
+	"github.com/puppetlabs/wash/plugin/ssh"
+	"github.com/puppetlabs/wash/tracing"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -29,6 +39,7 @@ var InternalPlugins = map[string]plugin.Root{
 	"docker":     &docker.Root{},
 	"gcp":        &gcp.Root{},
 	"kubernetes": &kubernetes.Root{},
+	"ssh":        &ssh.Root{},
 }
 
 // Opts exposes additional configuration for server operation.
@@ -36,10 +47,21 @@ type Opts struct {
 	CPUProfilePath string
 	LogFile        string
 	// LogLevel can be "warn", "info", "debug", or "trace".
-	LogLevel     string
+	LogLevel string
+	// MetricsAddr is the TCP address that metrics are served on. The API's /metrics
+	// endpoint serves them regardless.
+	MetricsAddr  string
 	PluginConfig map[string]map[string]interface{}
+	// PluginLoader re-reads Wash's config when reloading plugins. If it's nil, reloads
+	// use the plugins and PluginConfig that the server was created with.
+	PluginLoader PluginLoader
+	// Tracing configures where the server's traces are exported to.
+	Tracing tracing.Config
 }
 
+// PluginLoader returns the plugins to load and their config.
+type PluginLoader func() (map[string]plugin.Root, map[string]map[string]interface{}, error)
+
 // SetupLogging configures log level and output file according to configured options.
 // If an output file was configured, returns a handle for you to close later.
 func (o Opts) SetupLogging() (*os.File, error) {
@@ -74,9 +96,14 @@ type Server struct {
 	logFH            *os.File
 	api              controlChannels
 	fuse             controlChannels
+	metricsServer    *http.Server
+	stopTracing      func()
 	plugins          map[string]plugin.Root
 	analyticsClient  analytics.Client
 	forVerifyInstall bool
+	registry         *plugin.Registry
+	reloadMux        sync.Mutex
+	loaded           map[string]loadedPlugin
 }
 
 // New creates a new Server. Accepts a list of plugins to load.
@@ -111,6 +138,7 @@ func (s *Server) Start() (bool, error) {
 	}
 
 	registry := plugin.NewRegistry()
+	s.registry = registry
 
 	successfullyLoadedPlugins := true
 	if !s.forVerifyInstall {
@@ -118,6 +146,7 @@ func (s *Server) Start() (bool, error) {
 		if len(registry.Plugins()) == 0 {
 			return successfullyLoadedPlugins, fmt.Errorf("no plugins loaded. If you're planning on using Wash just for its external plugins, then go to https://puppetlabs.github.io/wash/docs/external-plugins")
 		}
+		s.loadQueries(registry)
 
 		plugin.InitCache()
 
@@ -128,13 +157,33 @@ func (s *Server) Start() (bool, error) {
 		s.analyticsClient = analytics.NewClient(analyticsConfig)
 	}
 
+	if !s.forVerifyInstall {
+		if s.stopTracing, err = tracing.Init(s.opts.Tracing); err != nil {
+			return successfullyLoadedPlugins, err
+		}
+	}
+
+	if !s.forVerifyInstall && s.opts.MetricsAddr != "" {
+		if err := s.serveMetrics(); err != nil {
+			s.stopTracingSpans()
+			return successfullyLoadedPlugins, err
+		}
+	}
+
+	var reloader api.PluginReloader
+	if !s.forVerifyInstall {
+		reloader = s
+	}
 	apiServerStopCh, apiServerStoppedCh, err := api.StartAPI(
 		registry,
 		s.mountpoint,
 		s.socket,
 		s.analyticsClient,
+		reloader,
 	)
 	if err != nil {
+		s.stopMetricsServer()
+		s.stopTracingSpans()
 		return successfullyLoadedPlugins, err
 	}
 	s.api = controlChannels{stopCh: apiServerStopCh, stoppedCh: apiServerStoppedCh}
@@ -146,6 +195,8 @@ func (s *Server) Start() (bool, error) {
 	)
 	if err != nil {
 		s.stopAPIServer()
+		s.stopMetricsServer()
+		s.stopTracingSpans()
 		return successfullyLoadedPlugins, err
 	}
 	s.fuse = controlChannels{stopCh: fuseServerStopCh, stoppedCh: fuseServerStoppedCh}
@@ -170,6 +221,42 @@ func (s *Server) Start() (bool, error) {
 	return successfullyLoadedPlugins, nil
 }
 
+// serveMetrics serves the metrics at /metrics on s.opts.MetricsAddr so that Prometheus
+// can scrape them. The API only listens on a Unix socket, which it can't.
+func (s *Server) serveMetrics() error {
+	listener, err := net.Listen("tcp", s.opts.MetricsAddr)
+	if err != nil {
+		return fmt.Errorf("could not serve metrics on %v: %v", s.opts.MetricsAddr, err)
+	}
+	log.Infof("Metrics: Listening at %v", listener.Addr())
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	s.metricsServer = &http.Server{Handler: mux}
+	go func() {
+		if err := s.metricsServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Warnf("Metrics: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (s *Server) stopMetricsServer() {
+	if s.metricsServer == nil {
+		return
+	}
+	if err := s.metricsServer.Close(); err != nil {
+		log.Warnf("Metrics: Shutdown failed: %v", err)
+	}
+}
+
+// stopTracingSpans flushes any spans that haven't been exported yet.
+func (s *Server) stopTracingSpans() {
+	if s.stopTracing != nil {
+		s.stopTracing()
+	}
+}
+
 func (s *Server) stopAPIServer() {
 	// Shutdown the API server; wait for the shutdown to finish
 	apiShutdownDeadline := time.Now().Add(3 * time.Second)
@@ -195,6 +282,9 @@ func (s *Server) shutdown() {
 		pprof.StopCPUProfile()
 	}
 
+	s.stopMetricsServer()
+	s.stopTracingSpans()
+
 	// Close any open journals on shutdown to ensure remaining entries are flushed to disk.
 	activity.CloseAll()
 
@@ -251,6 +341,11 @@ func (s *Server) loadPlugins(registry *plugin.Registry) bool {
 	var mux sync.Mutex
 	var failedPlugins []string
 
+	s.loaded = make(map[string]loadedPlugin)
+	for name, root := range s.plugins {
+		s.loaded[name] = newLoadedPlugin(root, s.opts.PluginConfig[name])
+	}
+
 	for name, root := range s.plugins {
 		log.Infof("Loading %v", name)
 		wg.Add(1)
@@ -271,7 +366,7 @@ func (s *Server) loadPlugins(registry *plugin.Registry) bool {
 	wg.Wait()
 	if len(failedPlugins) > 0 {
 		log.Warnf(
-			"You can use 'docs <plugin>' (e.g. 'docs %v') to view set-up instructions for %v. However, you'll need to exit then restart the shell in order to properly reload them.\n",
+			"You can use 'docs <plugin>' (e.g. 'docs %v') to view set-up instructions for %v. Once they're set up, use 'plugin reload' to load them without restarting the shell.\n",
 			failedPlugins[0],
 			strings.Join(failedPlugins, ", "),
 		)
@@ -279,3 +374,138 @@ func (s *Server) loadPlugins(registry *plugin.Registry) bool {
 	log.Debug("Finished loading plugins")
 	return len(failedPlugins) <= 0
 }
+
+// loadQueries registers the queries plugin, which exposes the saved queries. It's
+// loaded separately from the other plugins because it needs the registry.
+func (s *Server) loadQueries(registry *plugin.Registry) {
+	if _, ok := registry.Plugins()[queries.PluginName]; ok {
+		log.Warnf("Skipping saved queries: the %v plugin's already been loaded", queries.PluginName)
+		return
+	}
+	root := queries.NewRoot(registry)
+	s.loaded[queries.PluginName] = newLoadedPlugin(root, s.opts.PluginConfig[queries.PluginName])
+	if err := registry.RegisterPlugin(root, s.opts.PluginConfig[queries.PluginName]); err != nil {
+		log.Warnf("%v failed to load: %+v", queries.PluginName, err)
+	}
+}
+
+// loadedPlugin records what a plugin was loaded from so that reloads can tell whether it
+// changed.
+type loadedPlugin struct {
+	typ    reflect.Type
+	config map[string]interface{}
+	// script and modTime are only set for external plugins.
+	script  string
+	modTime time.Time
+}
+
+func newLoadedPlugin(root plugin.Root, config map[string]interface{}) loadedPlugin {
+	loaded := loadedPlugin{typ: reflect.TypeOf(root), config: config}
+	if script, ok := external.ScriptPath(root); ok {
+		loaded.script = script
+		if fi, err := os.Stat(script); err == nil {
+			loaded.modTime = fi.ModTime()
+		}
+	}
+	return loaded
+}
+
+func (l loadedPlugin) changed(other loadedPlugin) bool {
+	return l.typ != other.typ ||
+		l.script != other.script ||
+		!l.modTime.Equal(other.modTime) ||
+		!reflect.DeepEqual(l.config, other.config)
+}
+
+// newRoot returns a root that can be initialized without affecting root, which may still
+// be in use. External plugin roots are re-loaded from their script, and the queries root
+// is recreated with the server's registry.
+func (s *Server) newRoot(root plugin.Root) (plugin.Root, error) {
+	if script, ok := external.ScriptPath(root); ok {
+		return external.PluginSpec{Script: script}.Load()
+	}
+	if _, ok := root.(*queries.Root); ok {
+		return queries.NewRoot(s.registry), nil
+	}
+	return reflect.New(reflect.TypeOf(root).Elem()).Interface().(plugin.Root), nil
+}
+
+// ReloadPlugins re-reads Wash's config, then reloads the named plugin. If name is empty,
+// it adds new plugins, removes plugins that are no longer configured, and reloads plugins
+// that changed or previously failed to initialize. A plugin changes when its config
+// changes or, for external plugins, when its script is modified. The queries plugin is
+// reloaded like the other plugins unless another plugin has the same name.
+func (s *Server) ReloadPlugins(name string) ([]apitypes.PluginReload, error) {
+	s.reloadMux.Lock()
+	defer s.reloadMux.Unlock()
+
+	plugins, pluginConfig := s.plugins, s.opts.PluginConfig
+	if s.opts.PluginLoader != nil {
+		var err error
+		if plugins, pluginConfig, err = s.opts.PluginLoader(); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := plugins[queries.PluginName]; !ok {
+		// Copy plugins so that the queries root isn't added to the loader's map.
+		withQueries := map[string]plugin.Root{queries.PluginName: queries.NewRoot(s.registry)}
+		for pluginName, root := range plugins {
+			withQueries[pluginName] = root
+		}
+		plugins = withQueries
+	}
+
+	var reloads []apitypes.PluginReload
+	for pluginName := range s.loaded {
+		if _, ok := plugins[pluginName]; ok || (name != "" && name != pluginName) {
+			continue
+		}
+		log.Infof("Removing %v", pluginName)
+		s.registry.UnregisterPlugin(pluginName)
+		delete(s.loaded, pluginName)
+		reloads = append(reloads, apitypes.PluginReload{Name: pluginName, Action: apitypes.PluginRemoved})
+	}
+
+	var wg sync.WaitGroup
+	var mux sync.Mutex
+	for pluginName, root := range plugins {
+		if name != "" && name != pluginName {
+			continue
+		}
+
+		loaded := newLoadedPlugin(root, pluginConfig[pluginName])
+		action := apitypes.PluginReloaded
+		if previous, ok := s.loaded[pluginName]; !ok {
+			action = apitypes.PluginAdded
+		} else if name == "" && !previous.changed(loaded) && s.registry.InitErr(pluginName) == nil {
+			continue
+		}
+		s.loaded[pluginName] = loaded
+
+		log.Infof("Reloading %v", pluginName)
+		wg.Add(1)
+		go func(name string, root plugin.Root, action string) {
+			defer wg.Done()
+			reload := apitypes.PluginReload{Name: name, Action: action}
+			newRoot, err := s.newRoot(root)
+			if err == nil {
+				err = s.registry.ReplacePlugin(newRoot, pluginConfig[name])
+			}
+			if err != nil {
+				// %+v is a convention used by some errors to print additional context such as a stack trace
+				log.Warnf("%v failed to load: %+v", name, err)
+				reload.Error = err.Error()
+			}
+			mux.Lock()
+			reloads = append(reloads, reload)
+			mux.Unlock()
+		}(pluginName, root, action)
+	}
+	wg.Wait()
+
+	s.plugins, s.opts.PluginConfig = plugins, pluginConfig
+	sort.Slice(reloads, func(i, j int) bool {
+		return reloads[i].Name < reloads[j].Name
+	})
+	return reloads, nil
+}
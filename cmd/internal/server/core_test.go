@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/puppetlabs/wash/api/queries"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/plugin/external"
+	"github.com/stretchr/testify/suite"
+)
+
+// testRootInitErr is returned by testRoot's Init. It's global because reloads
+// initialize new roots.
+var testRootInitErr error
+
+type testRoot struct {
+	plugin.EntryBase
+}
+
+func (r *testRoot) Init(map[string]interface{}) error {
+	r.EntryBase = plugin.NewEntry("mine")
+	return testRootInitErr
+}
+
+func (r *testRoot) Schema() *plugin.EntrySchema {
+	return nil
+}
+
+func (r *testRoot) ChildSchemas() []*plugin.EntrySchema {
+	return nil
+}
+
+func (r *testRoot) List(context.Context) ([]plugin.Entry, error) {
+	return nil, nil
+}
+
+type ServerTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ServerTestSuite) SetupTest() {
+	plugin.SetTestCache(datastore.NewMemCache())
+}
+
+func (suite *ServerTestSuite) TearDownTest() {
+	plugin.UnsetTestCache()
+	testRootInitErr = nil
+}
+
+func (suite *ServerTestSuite) TestReloadPlugins() {
+	root := &testRoot{}
+	plugins := map[string]plugin.Root{"mine": root}
+	config := map[string]map[string]interface{}{"mine": {"key": "value"}}
+	s := New("", "", plugins, Opts{
+		PluginConfig: config,
+		PluginLoader: func() (map[string]plugin.Root, map[string]map[string]interface{}, error) {
+			return plugins, config, nil
+		},
+	})
+	s.registry = plugin.NewRegistry()
+
+	testRootInitErr = errors.New("expired token")
+	s.loadPlugins(s.registry)
+	s.loadQueries(s.registry)
+	suite.EqualError(s.registry.InitErr("mine"), "expired token")
+
+	// Plugins that previously failed are reloaded.
+	testRootInitErr = nil
+	reloads, err := s.ReloadPlugins("")
+	suite.NoError(err)
+	suite.Equal([]apitypes.PluginReload{{Name: "mine", Action: apitypes.PluginReloaded}}, reloads)
+	suite.NoError(s.registry.InitErr("mine"))
+	suite.True(root != s.registry.Plugins()["mine"], "expected a new root to be registered")
+
+	// Unchanged plugins aren't reloaded unless they're named.
+	reloads, err = s.ReloadPlugins("")
+	suite.NoError(err)
+	suite.Empty(reloads)
+	reloads, err = s.ReloadPlugins("mine")
+	suite.NoError(err)
+	suite.Equal([]apitypes.PluginReload{{Name: "mine", Action: apitypes.PluginReloaded}}, reloads)
+	reloads, err = s.ReloadPlugins("other")
+	suite.NoError(err)
+	suite.Empty(reloads)
+
+	// Plugins whose config changed are reloaded.
+	config = map[string]map[string]interface{}{"mine": {"key": "other value"}}
+	testRootInitErr = errors.New("bad config")
+	reloads, err = s.ReloadPlugins("")
+	suite.NoError(err)
+	suite.Equal([]apitypes.PluginReload{{Name: "mine", Action: apitypes.PluginReloaded, Error: "bad config"}}, reloads)
+
+	// Plugins that are no longer configured are removed, and new plugins are added.
+	plugins = map[string]plugin.Root{}
+	reloads, err = s.ReloadPlugins("")
+	suite.NoError(err)
+	suite.Equal([]apitypes.PluginReload{{Name: "mine", Action: apitypes.PluginRemoved}}, reloads)
+	suite.NotContains(s.registry.Plugins(), "mine")
+
+	testRootInitErr = nil
+	plugins = map[string]plugin.Root{"mine": root}
+	reloads, err = s.ReloadPlugins("")
+	suite.NoError(err)
+	suite.Equal([]apitypes.PluginReload{{Name: "mine", Action: apitypes.PluginAdded}}, reloads)
+	suite.Contains(s.registry.Plugins(), "mine")
+}
+
+func (suite *ServerTestSuite) TestReloadPlugins_Queries() {
+	config := map[string]map[string]interface{}{}
+	s := New("", "", map[string]plugin.Root{}, Opts{
+		PluginConfig: config,
+		PluginLoader: func() (map[string]plugin.Root, map[string]map[string]interface{}, error) {
+			return map[string]plugin.Root{}, config, nil
+		},
+	})
+	s.registry = plugin.NewRegistry()
+	s.loadPlugins(s.registry)
+	s.loadQueries(s.registry)
+	root := s.registry.Plugins()[queries.PluginName]
+
+	reloads, err := s.ReloadPlugins(queries.PluginName)
+	suite.NoError(err)
+	suite.Equal([]apitypes.PluginReload{{Name: queries.PluginName, Action: apitypes.PluginReloaded}}, reloads)
+	suite.True(root != s.registry.Plugins()[queries.PluginName], "expected a new root to be registered")
+
+	// Saved queries are reloaded when their config changes.
+	config = map[string]map[string]interface{}{queries.PluginName: {"running": map[string]interface{}{"path": "/docker"}}}
+	reloads, err = s.ReloadPlugins("")
+	suite.NoError(err)
+	suite.Equal([]apitypes.PluginReload{{Name: queries.PluginName, Action: apitypes.PluginReloaded}}, reloads)
+	if newRoot, ok := s.registry.Plugins()[queries.PluginName].(*queries.Root); suite.True(ok) {
+		suite.Len(newRoot.Queries(), 1)
+	}
+}
+
+func (suite *ServerTestSuite) TestReloadPlugins_External() {
+	dir, err := ioutil.TempDir("", "wash_server")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "ext.sh")
+	suite.Require().NoError(ioutil.WriteFile(script, []byte("#!/bin/sh\necho '{}'\n"), 0755))
+	root, err := external.PluginSpec{Script: script}.Load()
+	suite.Require().NoError(err)
+
+	// Without a PluginLoader, the external root is re-loaded from its script rather
+	// than re-initialized.
+	s := New("", "", map[string]plugin.Root{"ext": root}, Opts{})
+	s.registry = plugin.NewRegistry()
+	s.loadPlugins(s.registry)
+	suite.NoError(s.registry.InitErr("ext"))
+
+	reloads, err := s.ReloadPlugins("ext")
+	suite.NoError(err)
+	suite.Equal([]apitypes.PluginReload{{Name: "ext", Action: apitypes.PluginReloaded}}, reloads)
+	suite.True(root != s.registry.Plugins()["ext"], "expected a new root to be registered")
+	suite.NoError(s.registry.InitErr("ext"))
+}
+
+func TestServer(t *testing.T) {
+	suite.Run(t, new(ServerTestSuite))
+}
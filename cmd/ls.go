@@ -66,8 +66,12 @@ func formatItem(item lsItem, longFormat bool) [][]string {
 			} else {
 				mtimeStr = "<mtime unknown>"
 			}
+			name := cname(entry)
+			if entry.LinkTarget != "" {
+				name += " -> " + entry.LinkTarget
+			}
 			verbs := strings.Join(entry.Actions, ", ")
-			row = []string{cname(entry), mtimeStr, verbs}
+			row = []string{name, mtimeStr, verbs}
 		}
 
 		rows = append(rows, row)
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/cmd/internal/config"
+	cmdutil "github.com/puppetlabs/wash/cmd/util"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/plugin/external"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+func pluginCommand() *cobra.Command {
+	pluginCmd := &cobra.Command{
+		Use:   "plugin <subcommand>",
+		Short: "Manages the plugins loaded by the Wash daemon",
+		Args:  cobra.NoArgs,
+		RunE:  toRunE(pluginMain),
+	}
+	addCommand(pluginCmd, pluginLsCommand())
+	addCommand(pluginCmd, pluginStatusCommand())
+	addCommand(pluginCmd, pluginDocsCommand())
+	addCommand(pluginCmd, pluginReloadCommand())
+	addCommand(pluginCmd, pluginInstallCommand())
+	return pluginCmd
+}
+
+func pluginMain(cmd *cobra.Command, args []string) exitCode {
+	if err := cmd.Help(); err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+	return exitCode{0}
+}
+
+func pluginLsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ls",
+		Short: "Lists the plugins and whether they loaded",
+		Args:  cobra.NoArgs,
+		RunE:  toRunE(pluginLsMain),
+	}
+}
+
+func pluginLsMain(cmd *cobra.Command, args []string) exitCode {
+	conn := cmdutil.NewClient()
+	statuses, err := conn.Plugins()
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+	cmdutil.Print(formatPluginStatuses(statuses))
+	return exitCode{0}
+}
+
+func formatPluginStatuses(statuses []apitypes.PluginStatus) string {
+	headers := []cmdutil.ColumnHeader{
+		{ShortName: "name", FullName: "NAME"},
+		{ShortName: "kind", FullName: "KIND"},
+		{ShortName: "status", FullName: "STATUS"},
+		{ShortName: "init", FullName: "INIT"},
+		{ShortName: "schema", FullName: "SCHEMA"},
+		{ShortName: "error", FullName: "LAST ERROR"},
+	}
+	var table [][]string
+	for _, status := range statuses {
+		lastError := status.InitError
+		if lastError == "" && status.LastError != nil {
+			lastError = formatPluginError(status.LastError)
+		}
+		table = append(table, []string{
+			status.Name,
+			status.Kind,
+			status.Status,
+			cmdutil.FormatDuration(status.InitDuration),
+			formatYesOrNo(status.HasSchema),
+			lastError,
+		})
+	}
+	return cmdutil.NewTableWithHeaders(headers, table).Format()
+}
+
+func pluginStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status <name>",
+		Short: "Prints a plugin's status",
+		Long: `Prints the named plugin's status, including whether it loaded, how long its initialization
+took, the config it received, and the last error recorded for its entries.`,
+		Args: cobra.ExactArgs(1),
+		RunE: toRunE(pluginStatusMain),
+	}
+}
+
+func pluginStatusMain(cmd *cobra.Command, args []string) exitCode {
+	conn := cmdutil.NewClient()
+	status, err := conn.Plugin(args[0])
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+	cmdutil.Print(formatPluginStatus(status))
+	return exitCode{0}
+}
+
+func formatPluginStatus(status apitypes.PluginStatus) string {
+	var sb strings.Builder
+	field := func(name string, value interface{}) {
+		fmt.Fprintf(&sb, "%-12v %v\n", name+":", value)
+	}
+
+	field("Name", status.Name)
+	field("Kind", status.Kind)
+	if status.Script != "" {
+		field("Script", status.Script)
+	}
+	if status.Version != "" {
+		field("Version", status.Version)
+	}
+	field("Status", status.Status)
+	if status.InitError != "" {
+		field("Init error", status.InitError)
+	}
+	field("Init start", status.InitStart.Format(time.RFC3339))
+	field("Init time", cmdutil.FormatDuration(status.InitDuration))
+	field("Schema", formatYesOrNo(status.HasSchema))
+	if breaker := status.Breaker; breaker != nil {
+		state := breaker.State
+		if breaker.State != plugin.BreakerClosed {
+			state = fmt.Sprintf("%v since %v", state, breaker.OpenedAt.Format(time.RFC3339))
+		}
+		if breaker.Failures > 0 {
+			state = fmt.Sprintf("%v (%v consecutive failures)", state, breaker.Failures)
+		}
+		field("Breaker", state)
+	}
+	if status.LastError != nil {
+		// Use 1-indexing for history entries
+		field("Last error", fmt.Sprintf(
+			"%v (history %v) %v",
+			status.LastError.Time.Format(time.StampMilli),
+			status.LastError.Index+1,
+			formatPluginError(status.LastError),
+		))
+	}
+	if len(status.Config) > 0 {
+		if config, err := yaml.Marshal(status.Config); err == nil {
+			sb.WriteString("Config:\n")
+			for _, line := range strings.Split(strings.TrimRight(string(config), "\n"), "\n") {
+				sb.WriteString("  " + line + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+func formatPluginError(event *apitypes.ActivityEvent) string {
+	// Keep the error on a single line.
+	return fmt.Sprintf("%v %v: %v", event.Method, event.EntryID, strings.Replace(event.Error, "\n", " ", -1))
+}
+
+func formatYesOrNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func pluginDocsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "docs <name>",
+		Short: "Displays a plugin's documentation",
+		Long: `Displays the named plugin's documentation, which usually describes how to set it up. This works
+even if the plugin failed to load.`,
+		Args: cobra.ExactArgs(1),
+		RunE: toRunE(pluginDocsMain),
+	}
+}
+
+func pluginDocsMain(cmd *cobra.Command, args []string) exitCode {
+	conn := cmdutil.NewClient()
+	status, err := conn.Plugin(args[0])
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+
+	if docs := strings.Trim(status.Docs, "\n"); docs != "" {
+		cmdutil.Println(docs)
+	} else {
+		cmdutil.Printf("The %v plugin does not provide any documentation\n", status.Name)
+	}
+	if status.InitError != "" {
+		cmdutil.Printf("\nThe %v plugin failed to load: %v\n", status.Name, status.InitError)
+		cmdutil.Printf("Use 'plugin reload %v' to reload it once it's set up.\n", status.Name)
+	}
+	return exitCode{0}
+}
+
+func pluginReloadCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reload [<name>]",
+		Short: "Reloads plugins without restarting the Wash daemon",
+		Long: `Re-reads Wash's config file, then reloads the named plugin. If no name is given, new plugins are
+added, plugins that are no longer configured are removed, and plugins whose config or script changed
+or that previously failed to load are reloaded. Reloading a plugin clears its cache.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: toRunE(pluginReloadMain),
+	}
+}
+
+func pluginReloadMain(cmd *cobra.Command, args []string) exitCode {
+	var name string
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	conn := cmdutil.NewClient()
+	reloads, err := conn.ReloadPlugins(name)
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+
+	if len(reloads) == 0 {
+		cmdutil.Println("All plugins are up-to-date")
+		return exitCode{0}
+	}
+
+	ec := 0
+	for _, reload := range reloads {
+		if reload.Error != "" {
+			ec = 1
+			cmdutil.ErrPrintf("%v failed to load: %v\n", reload.Name, reload.Error)
+			continue
+		}
+		switch reload.Action {
+		case apitypes.PluginAdded:
+			cmdutil.Println("Added", reload.Name)
+		case apitypes.PluginRemoved:
+			cmdutil.Println("Removed", reload.Name)
+		default:
+			cmdutil.Println("Reloaded", reload.Name)
+		}
+	}
+	return exitCode{ec}
+}
+
+func pluginInstallCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "install <dir|tarball>",
+		Short: "Installs an external plugin",
+		Long: fmt.Sprintf(`Installs the external plugin packaged in the given directory or tarball. The package must contain
+a %v manifest that specifies the plugin's name and script. The plugin is installed into
+%v, where Wash discovers it on startup. Installing a plugin
+replaces any previously installed version.`, external.ManifestFile, filepath.Join("~", ".puppetlabs", "wash", "plugins", "<name>")),
+		Args: cobra.ExactArgs(1),
+		RunE: toRunE(pluginInstallMain),
+	}
+}
+
+func pluginInstallMain(cmd *cobra.Command, args []string) exitCode {
+	manifest, err := external.Install(args[0], config.DefaultPluginsDir())
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+
+	installed := manifest.Name
+	if manifest.Version != "" {
+		installed += " " + manifest.Version
+	}
+	cmdutil.Printf("Installed %v to %v\n", installed, filepath.Dir(manifest.ScriptPath()))
+	cmdutil.Printf("Use 'plugin reload %v' to load it into a running Wash daemon.\n", manifest.Name)
+	return exitCode{0}
+}
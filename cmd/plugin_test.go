@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/cmd/internal/cmdtest"
+	"github.com/stretchr/testify/suite"
+)
+
+type PluginTestSuite struct {
+	*cmdtest.Suite
+}
+
+func (s *PluginTestSuite) statuses() []apitypes.PluginStatus {
+	return []apitypes.PluginStatus{
+		{
+			Name:         "aws",
+			Kind:         apitypes.CorePlugin,
+			Status:       apitypes.PluginFailed,
+			InitError:    "expired token",
+			InitDuration: time.Second,
+		},
+		{
+			Name:      "puppetwash",
+			Kind:      apitypes.ExternalPlugin,
+			Script:    "/plugins/puppetwash.rb",
+			Version:   "1.2.0",
+			Status:    apitypes.PluginLoaded,
+			HasSchema: true,
+			Config:    map[string]interface{}{"url": "https://puppet"},
+			LastError: &apitypes.ActivityEvent{
+				Index: 2,
+				Line: activity.Line{
+					Time:  time.Date(2019, 6, 13, 15, 44, 4, 0, time.UTC),
+					Event: activity.Event{Method: "List", EntryID: "/puppetwash/nodes", Error: "connection\nrefused"},
+				},
+			},
+			Breaker: &apitypes.BreakerStatus{
+				State:    "open",
+				Failures: 5,
+				OpenedAt: time.Date(2019, 6, 13, 15, 44, 4, 0, time.UTC),
+			},
+		},
+	}
+}
+
+func (s *PluginTestSuite) TestPluginLs() {
+	s.Client.On("Plugins").Return(s.statuses(), nil)
+	s.Equal(exitCode{0}, pluginLsMain(pluginLsCommand(), nil))
+
+	lines := strings.Split(strings.TrimSpace(s.Stdout()), "\n")
+	if s.Len(lines, 3) {
+		s.Equal([]string{"NAME", "KIND", "STATUS", "INIT", "SCHEMA", "LAST", "ERROR"}, strings.Fields(lines[0]))
+		s.Equal([]string{"aws", "core", "failed", "00:01.00", "no", "expired", "token"}, strings.Fields(lines[1]))
+		s.Equal([]string{"puppetwash", "external", "loaded", "00:00.00", "yes", "List", "/puppetwash/nodes:", "connection", "refused"}, strings.Fields(lines[2]))
+	}
+}
+
+func (s *PluginTestSuite) TestPluginStatus() {
+	s.Client.On("Plugin", "puppetwash").Return(s.statuses()[1], nil)
+	s.Equal(exitCode{0}, pluginStatusMain(pluginStatusCommand(), []string{"puppetwash"}))
+	s.Contains(s.Stdout(), "Script:      /plugins/puppetwash.rb\nVersion:     1.2.0\n")
+	s.Contains(s.Stdout(), "Last error:  Jun 13 15:44:04.000 (history 3) List /puppetwash/nodes: connection refused\n")
+	s.Contains(s.Stdout(), "Config:\n  url: https://puppet\n")
+	s.Contains(s.Stdout(), "Breaker:     open since 2019-06-13T15:44:04Z (5 consecutive failures)\n")
+	s.NotContains(s.Stdout(), "Init error")
+
+	s.Client.On("Plugin", "missing").Return(apitypes.PluginStatus{}, fmt.Errorf("Plugin missing does not exist"))
+	s.Equal(exitCode{1}, pluginStatusMain(pluginStatusCommand(), []string{"missing"}))
+	s.Equal("Plugin missing does not exist\n", s.Stderr())
+}
+
+func (s *PluginTestSuite) TestPluginDocs() {
+	status := s.statuses()[0]
+	status.Docs = "\nSet up your AWS credentials.\n"
+	s.Client.On("Plugin", "aws").Return(status, nil)
+	s.Equal(exitCode{0}, pluginDocsMain(pluginDocsCommand(), []string{"aws"}))
+	s.Equal("Set up your AWS credentials.\n\nThe aws plugin failed to load: expired token\nUse 'plugin reload aws' to reload it once it's set up.\n", s.Stdout())
+}
+
+func (s *PluginTestSuite) TestPluginReload() {
+	s.Client.On("ReloadPlugins", "").Return([]apitypes.PluginReload{
+		{Name: "aws", Action: apitypes.PluginReloaded},
+		{Name: "gcp", Action: apitypes.PluginAdded, Error: "no credentials"},
+		{Name: "old", Action: apitypes.PluginRemoved},
+	}, nil)
+	s.Equal(exitCode{1}, pluginReloadMain(pluginReloadCommand(), nil))
+	s.Equal("Reloaded aws\nRemoved old\n", s.Stdout())
+	s.Equal("gcp failed to load: no credentials\n", s.Stderr())
+}
+
+func TestPlugin(t *testing.T) {
+	suite.Run(t, &PluginTestSuite{new(cmdtest.Suite)})
+}
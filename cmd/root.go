@@ -161,11 +161,13 @@ then starts your system shell with shortcuts configured for wash subcommands.`,
 	addCommand(rootCmd, clearCommand())
 	addCommand(rootCmd, tailCommand())
 	addCommand(rootCmd, historyCommand())
+	addCommand(rootCmd, statsCommand())
 	addCommand(rootCmd, infoCommand())
 	addCommand(rootCmd, streeCommand())
 	addCommand(rootCmd, docsCommand())
 	addCommand(rootCmd, deleteCommand())
 	addCommand(rootCmd, signalCommand())
+	addCommand(rootCmd, pluginCommand())
 
 	return rootCmd
 }
@@ -11,11 +11,13 @@ import (
 
 	"github.com/Benchkram/errz"
 	apifs "github.com/puppetlabs/wash/api/fs"
+	"github.com/puppetlabs/wash/api/queries"
 	"github.com/puppetlabs/wash/cmd/internal/config"
 	"github.com/puppetlabs/wash/cmd/internal/server"
 	cmdutil "github.com/puppetlabs/wash/cmd/util"
 	"github.com/puppetlabs/wash/plugin"
 	"github.com/puppetlabs/wash/plugin/external"
+	"github.com/puppetlabs/wash/tracing"
 	"gopkg.in/yaml.v2"
 
 	log "github.com/sirupsen/logrus"
@@ -75,6 +77,7 @@ func addServerArgs(cmd *cobra.Command, defaultLogLevel string) {
 	cmd.Flags().String("loglevel", defaultLogLevel, "Set the logging level")
 	cmd.Flags().String("logfile", "", "Set the log file's location. Defaults to stdout")
 	cmd.Flags().String("cpuprofile", "", "Write cpu profile to file")
+	cmd.Flags().String("metrics-addr", "", "Serve Prometheus metrics at /metrics on this address (e.g. localhost:9464)")
 	cmd.Flags().String("config-file", config.DefaultFile(), "Set the config file's location")
 }
 
@@ -83,18 +86,48 @@ func bindServerArgs(cmd *cobra.Command, args []string) {
 	errz.Fatal(viper.BindPFlag("loglevel", cmd.Flags().Lookup("loglevel")))
 	errz.Fatal(viper.BindPFlag("logfile", cmd.Flags().Lookup("logfile")))
 	errz.Fatal(viper.BindPFlag("cpuprofile", cmd.Flags().Lookup("cpuprofile")))
+	errz.Fatal(viper.BindPFlag("metrics-addr", cmd.Flags().Lookup("metrics-addr")))
 }
 
 // serverOptsFor returns map of plugins and server.Opts for the given command.
 func serverOptsFor(cmd *cobra.Command) (map[string]plugin.Root, server.Opts, error) {
-	// Read the config
 	configFile, err := cmd.Flags().GetString("config-file")
 	if err != nil {
 		panic(err.Error())
 	}
-	if err := config.ReadFrom(configFile); err != nil {
+	plugins, pluginConfig, err := loadPlugins(configFile, true)
+	if err != nil {
 		return nil, server.Opts{}, err
 	}
+	var tracingConfig tracing.Config
+	if err := viper.UnmarshalKey("tracing", &tracingConfig); err != nil {
+		return nil, server.Opts{}, fmt.Errorf("invalid tracing config: %v", err)
+	}
+
+	// Return the options
+	return plugins, server.Opts{
+		CPUProfilePath: viper.GetString("cpuprofile"),
+		LogFile:        viper.GetString("logfile"),
+		LogLevel:       viper.GetString("loglevel"),
+		MetricsAddr:    viper.GetString("metrics-addr"),
+		PluginConfig:   pluginConfig,
+		Tracing:        tracingConfig,
+		PluginLoader: func() (map[string]plugin.Root, map[string]map[string]interface{}, error) {
+			// Don't prompt on reloads because the shell's using the terminal.
+			return loadPlugins(configFile, false)
+		},
+	}, nil
+}
+
+// loadPlugins reads the config from configFile, then returns the plugins it enables and
+// their config. If prompt is true and the config doesn't specify any plugins, then it
+// prompts the user for the core plugins that they'd like to enable.
+func loadPlugins(configFile string, prompt bool) (map[string]plugin.Root, map[string]map[string]interface{}, error) {
+	// Read the config
+	err := config.ReadFrom(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	plugins := make(map[string]plugin.Root)
 
@@ -107,7 +140,7 @@ func serverOptsFor(cmd *cobra.Command) (map[string]plugin.Root, server.Opts, err
 				log.Warnf("Requested unknown plugin %s", name)
 			}
 		}
-	} else if !plugin.IsInteractive() {
+	} else if !prompt || !plugin.IsInteractive() {
 		// This is an edge-case for a user but a common case for
 		// CI. Thus, load all the plugins so that we don't break
 		// the latter. Note that we copy server.InternalPlugins
@@ -122,7 +155,7 @@ func serverOptsFor(cmd *cobra.Command) (map[string]plugin.Root, server.Opts, err
 		// enabled plugins back to their specified config file.
 		plugins, err = promptEnabledPlugins()
 		if err != nil {
-			return nil, server.Opts{}, err
+			return nil, nil, err
 		}
 		enabledPlugins := []string{}
 		for plugin := range plugins {
@@ -142,16 +175,22 @@ func serverOptsFor(cmd *cobra.Command) (map[string]plugin.Root, server.Opts, err
 			if len(enabledPlugins) <= 0 {
 				action = "enable"
 			}
-			cmdutil.Printf("You can %v them by modifying the 'plugins' key in your config\nfile (%v), and then restarting the shell\n\n", action, configFile)
+			cmdutil.Printf("You can %v them by modifying the 'plugins' key in your config\nfile (%v), and then running 'plugin reload'\n\n", action, configFile)
 		}
 	}
 
 	// Check the external plugins. First unmarshal their spec, ensure that
-	// they're valid scripts, then convert them to plugin.Root types.
-	var externalPlugins []external.PluginSpec
-	if err := viper.UnmarshalKey("external-plugins", &externalPlugins); err != nil {
-		return nil, server.Opts{}, fmt.Errorf("failed to unmarshal the external-plugins key: %v", err)
+	// they're valid scripts, then convert them to plugin.Root types. Installed
+	// plugins come first so that the external-plugins key overrides them.
+	externalPlugins, err := external.InstalledPlugins(config.DefaultPluginsDir())
+	if err != nil {
+		log.Warnf("Failed to discover the plugins installed in %v: %v", config.DefaultPluginsDir(), err)
 	}
+	var configuredPlugins []external.PluginSpec
+	if err := viper.UnmarshalKey("external-plugins", &configuredPlugins); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal the external-plugins key: %v", err)
+	}
+	externalPlugins = append(externalPlugins, configuredPlugins...)
 	for _, spec := range externalPlugins {
 		intPlugin, err := spec.Load()
 		if err != nil {
@@ -170,6 +209,9 @@ func serverOptsFor(cmd *cobra.Command) (map[string]plugin.Root, server.Opts, err
 	for name := range plugins {
 		pluginConfig[name] = viper.GetStringMap(name)
 	}
+	if _, ok := plugins[queries.PluginName]; !ok {
+		pluginConfig[queries.PluginName] = viper.GetStringMap(queries.PluginName)
+	}
 
 	// Developer flag to enable a local filesystem for testing core functionality.
 	if localfsPath := os.Getenv("WASH_LOCALFS"); localfsPath != "" {
@@ -177,13 +219,7 @@ func serverOptsFor(cmd *cobra.Command) (map[string]plugin.Root, server.Opts, err
 		pluginConfig["local"] = map[string]interface{}{"basepath": localfsPath}
 	}
 
-	// Return the options
-	return plugins, server.Opts{
-		CPUProfilePath: viper.GetString("cpuprofile"),
-		LogFile:        viper.GetString("logfile"),
-		LogLevel:       viper.GetString("loglevel"),
-		PluginConfig:   pluginConfig,
-	}, nil
+	return plugins, pluginConfig, nil
 }
 
 func promptEnabledPlugins() (map[string]plugin.Root, error) {
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/puppetlabs/wash/analytics"
+	cmdutil "github.com/puppetlabs/wash/cmd/util"
+	"github.com/spf13/cobra"
+)
+
+func statsCommand() *cobra.Command {
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Summarizes the locally recorded analytics",
+		Long: `Prints the commands, plugins, entry types and plugin methods that were used most, as recorded by local
+analytics. Local analytics records Wash's analytics to a file instead of submitting them to Google Analytics.
+Enable it by setting 'local: true' in ~/.puppetlabs/wash/analytics.yaml or WASH_LOCAL_ANALYTICS=true before
+starting the Wash daemon.`,
+		Args: cobra.NoArgs,
+		RunE: toRunE(statsMain),
+	}
+	statsCmd.Flags().String("since", "", "Only include usage at or after this time, either RFC3339 or a duration ago like 24h")
+	statsCmd.Flags().IntP("limit", "n", 10, "The number of rows to print for each summary; 0 prints all of them")
+	return statsCmd
+}
+
+// formatUsage formats the usage as a table for each summary, including at most limit
+// rows in each table. A limit of 0 includes all the rows.
+func formatUsage(usage analytics.Usage, limit int) string {
+	summaries := []struct {
+		name   string
+		counts []analytics.Count
+	}{
+		{"COMMAND", usage.Commands},
+		{"PLUGIN", usage.Plugins},
+		{"ENTRY TYPE", usage.EntryTypes},
+		{"METHOD", usage.Methods},
+	}
+	var tables []string
+	for _, summary := range summaries {
+		counts := summary.counts
+		if limit > 0 && len(counts) > limit {
+			counts = counts[:limit]
+		}
+		headers := []cmdutil.ColumnHeader{
+			{ShortName: "name", FullName: summary.name},
+			{ShortName: "count", FullName: "COUNT"},
+		}
+		var table [][]string
+		for _, count := range counts {
+			table = append(table, []string{count.Name, strconv.Itoa(count.Count)})
+		}
+		tables = append(tables, strings.TrimRight(cmdutil.NewTableWithHeaders(headers, table).Format(), "\n"))
+	}
+	return strings.Join(tables, "\n\n") + "\n"
+}
+
+func statsMain(cmd *cobra.Command, args []string) exitCode {
+	sinceStr, err := cmd.Flags().GetString("since")
+	if err != nil {
+		panic(err.Error())
+	}
+	limit, err := cmd.Flags().GetInt("limit")
+	if err != nil {
+		panic(err.Error())
+	}
+	since, err := parseHistoryTime(sinceStr, time.Now())
+	if err != nil {
+		cmdutil.ErrPrintf("invalid --since: %v\n", err)
+		return exitCode{1}
+	}
+
+	config, err := analytics.GetConfig()
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+	path, err := config.HitsFile()
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+	hits, err := analytics.ReadHits(path)
+	if os.IsNotExist(err) {
+		msg := "No analytics have been recorded to %v."
+		if !config.Local {
+			msg += " Set 'local: true' in ~/.puppetlabs/wash/analytics.yaml to record them."
+		}
+		cmdutil.ErrPrintf(msg+"\n", path)
+		return exitCode{1}
+	} else if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+
+	cmdutil.Print(formatUsage(analytics.Summarize(hits, since), limit))
+	if !config.Local {
+		cmdutil.ErrPrintf("Note that local analytics is disabled, so %v is no longer updated.\n", path)
+	}
+	return exitCode{0}
+}
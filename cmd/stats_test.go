@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/puppetlabs/wash/analytics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatUsage(t *testing.T) {
+	usage := analytics.Usage{
+		Commands:   []analytics.Count{{Name: "ls", Count: 3}, {Name: "exec", Count: 2}, {Name: "find", Count: 1}},
+		Plugins:    []analytics.Count{{Name: "docker", Count: 4}},
+		EntryTypes: []analytics.Count{{Name: "docker::container", Count: 4}},
+	}
+
+	tables := strings.Split(strings.TrimSpace(formatUsage(usage, 2)), "\n\n")
+	if assert.Len(t, tables, 4) {
+		lines := strings.Split(tables[0], "\n")
+		if assert.Len(t, lines, 3) {
+			assert.Equal(t, []string{"COMMAND", "COUNT"}, strings.Fields(lines[0]))
+			assert.Equal(t, []string{"ls", "3"}, strings.Fields(lines[1]))
+			assert.Equal(t, []string{"exec", "2"}, strings.Fields(lines[2]))
+		}
+		assert.Equal(t, []string{"PLUGIN", "COUNT", "docker", "4"}, strings.Fields(tables[1]))
+		assert.Equal(t, []string{"ENTRY", "TYPE", "COUNT", "docker::container", "4"}, strings.Fields(tables[2]))
+		assert.Equal(t, []string{"METHOD", "COUNT"}, strings.Fields(tables[3]))
+	}
+
+	tables = strings.Split(strings.TrimSpace(formatUsage(usage, 0)), "\n\n")
+	if assert.Len(t, tables, 4) {
+		assert.Len(t, strings.Split(tables[0], "\n"), 4)
+	}
+}
@@ -2,16 +2,20 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"math/rand"
 	"os"
 	"os/signal"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/emirpasic/gods/maps/linkedhashmap"
 	"github.com/jedib0t/go-pretty/progress"
 	cmdutil "github.com/puppetlabs/wash/cmd/util"
 	"github.com/puppetlabs/wash/plugin"
@@ -36,13 +40,26 @@ when needed.
 
 Each line represents validation of an entry type. The 'lrsx' fields represent support for 'list',
 'read', 'stream', and 'execute' methods respectively, with '-' representing lack of support for a
-method.`,
+method.
+
+Each entry is also checked against its schema: its supported actions, partial metadata, and
+signals must match what the schema declares, its children's types must be declared children, and
+singleton types can only appear once. Metadata is fetched and validated against the schema's
+metadata schema. Signal and delete are never invoked since they can change the entries. Instead,
+the signal check verifies that the schema declares the signal action and each signal once, and the
+delete check verifies that the schema declares the delete action. They're reported as skipped if
+the plugin doesn't have a schema.
+
+Use --json or --junit to write a report of each check, including how long it took, so that
+validation can gate plugin changes in CI.`,
 		Args:   cobra.ExactArgs(1),
 		PreRun: bindServerArgs,
 		RunE:   toRunE(validateMain),
 	}
 	validateCmd.Flags().IntP("parallel", "p", 10, "Number of entries to validate in parallel")
 	validateCmd.Flags().BoolP("all", "a", false, "Validate all entries rather than an example at each level of hierarchy")
+	validateCmd.Flags().String("json", "", "Write a JSON report of the checks to the given file")
+	validateCmd.Flags().String("junit", "", "Write a JUnit XML report of the checks to the given file")
 	addServerArgs(validateCmd, "warn")
 	return validateCmd
 }
@@ -74,6 +91,18 @@ func validateMain(cmd *cobra.Command, args []string) exitCode {
 		return exitCode{1}
 	}
 
+	jsonPath, err := cmd.Flags().GetString("json")
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+
+	junitPath, err := cmd.Flags().GetString("junit")
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+
 	plug := args[0]
 	root, ok := plugins[plug]
 	if !ok {
@@ -152,15 +181,31 @@ func validateMain(cmd *cobra.Command, args []string) exitCode {
 		panic("List on registry should not fail")
 	}
 
+	// The schema graph is used to check entries' children and metadata.
+	graph, err := plugin.SchemaGraph(root)
+	if err != nil {
+		errs <- formatErr("Error loading the plugin's schema", "schema", err)
+	}
+
 	// We use a worker pool to limit work-in-progress. Put the plugin on the worker pool.
-	wp := cmdutil.NewPool(parallel)
+	start := time.Now()
+	v := &validator{
+		ctx:    ctx,
+		pw:     pw,
+		wp:     cmdutil.NewPool(parallel),
+		all:    all,
+		errs:   errs,
+		graph:  graph,
+		report: validateReport{Plugin: plug},
+	}
 	entries.Range(func(_ string, e plugin.Entry) bool {
-		wp.Submit(func() { processEntry(ctx, pw, wp, e, all, errs) })
+		v.wp.Submit(func() { v.processEntry(e) })
 		return true
 	})
 
 	// Wait for work to complete.
-	wp.Finish()
+	v.wp.Finish()
+	v.report.Duration = time.Since(start)
 
 	// Leave time for progress to finish rendering.
 	time.Sleep(100 * time.Millisecond)
@@ -170,6 +215,21 @@ func validateMain(cmd *cobra.Command, args []string) exitCode {
 	// routine to complete.
 	close(errs)
 	wg.Wait()
+
+	v.report.finish()
+	if jsonPath != "" {
+		if err := writeReport(jsonPath, v.report.json); err != nil {
+			cmdutil.ErrPrintf("Unable to write the JSON report: %v\n", err)
+			return exitCode{1}
+		}
+	}
+	if junitPath != "" {
+		if err := writeReport(junitPath, v.report.junit); err != nil {
+			cmdutil.ErrPrintf("Unable to write the JUnit report: %v\n", err)
+			return exitCode{1}
+		}
+	}
+
 	if erred > 0 {
 		cmdutil.ErrPrintf("Found %v errors.\n", erred)
 		return exitCode{1}
@@ -256,133 +316,378 @@ func withTimeout(ctx context.Context, method, name string,
 	return obj, cancelFunc, nil
 }
 
-func processEntry(ctx context.Context, pw progress.Writer, wp cmdutil.Pool, e plugin.Entry, all bool, errs chan<- error) {
-	defer wp.Done()
+// validator holds the state shared by the entries being validated.
+type validator struct {
+	ctx   context.Context
+	pw    progress.Writer
+	wp    cmdutil.Pool
+	all   bool
+	errs  chan<- error
+	graph *linkedhashmap.Map
+
+	mux    sync.Mutex
+	report validateReport
+}
+
+// schemaOf returns the schema for the type ID from the plugin's schema graph, or nil if
+// the plugin doesn't have a schema.
+func (v *validator) schemaOf(typeID string) *plugin.EntrySchema {
+	if v.graph == nil {
+		return nil
+	}
+	if schema, ok := v.graph.Get(typeID); ok {
+		s := schema.(plugin.EntrySchema)
+		return &s
+	}
+	return nil
+}
+
+// check invokes fn with a timeout, recording how long it took and its error, if any,
+// as the named check on the entry. It returns false if the check failed.
+func (v *validator) check(result *validateEntryResult, method string, fn func(context.Context) error) bool {
+	start := time.Now()
+	_, cancelFunc, err := withTimeout(v.ctx, method, result.ID, func(ctx context.Context) (interface{}, error) {
+		return nil, fn(ctx)
+	})
+	if err != nil {
+		v.fail(result, method, time.Since(start), err)
+		return false
+	}
+	cancelFunc()
+	result.Checks = append(result.Checks, validateCheckResult{Name: method, Duration: time.Since(start)})
+	return true
+}
+
+// fail records a failed check on the entry and reports the error.
+func (v *validator) fail(result *validateEntryResult, name string, duration time.Duration, err error) {
+	result.Checks = append(result.Checks, validateCheckResult{Name: name, Duration: duration, Error: err.Error()})
+	v.errs <- err
+}
+
+// conform records the schema violations, if any, as the named check on the entry.
+func (v *validator) conform(result *validateEntryResult, name string, violations []string) {
+	if len(violations) == 0 {
+		result.Checks = append(result.Checks, validateCheckResult{Name: name})
+		return
+	}
+	err := fmt.Errorf("%v does not conform to its schema: %v", result.ID, strings.Join(violations, "; "))
+	v.fail(result, name, 0, err)
+}
+
+func (v *validator) processEntry(e plugin.Entry) {
+	defer v.wp.Done()
 	name := plugin.ID(e)
 	crit := newCriteria(e)
+	result := &validateEntryResult{ID: name, TypeID: crit.typeID}
+	defer func() {
+		v.mux.Lock()
+		v.report.Entries = append(v.report.Entries, *result)
+		v.mux.Unlock()
+	}()
+
 	schema, err := plugin.Schema(e)
 	if err != nil {
-		errs <- err
+		v.fail(result, "schema", 0, err)
 		return
 	}
 	if schema != nil {
 		crit.label = schema.Label
 		crit.singleton = schema.Singleton
 	}
-	tracker := progress.Tracker{Message: fmt.Sprintf("Testing %s %s", crit, name), Total: 4}
-	pw.AppendTracker(&tracker)
+	tracker := progress.Tracker{Message: fmt.Sprintf("Testing %s %s", crit, name), Total: 7}
+	v.pw.AppendTracker(&tracker)
+
+	// Core entries' schemas don't include their children or metadata schemas, so use
+	// the ones from the schema graph.
+	fullSchema := v.schemaOf(crit.typeID)
+	if fullSchema != nil {
+		v.conform(result, "schema", plugin.CheckEntry(e, fullSchema))
+	}
 
 	if plugin.ListAction().IsSupportedOn(e) {
-		obj, cancelFunc, err := withTimeout(ctx, "list", name, func(ctx context.Context) (interface{}, error) {
-			return plugin.List(ctx, e.(plugin.Parent))
-		})
-		if err != nil {
-			errs <- err
+		var entries *plugin.EntryMap
+		ok := v.check(result, "list", func(ctx context.Context) (err error) {
+			entries, err = plugin.List(ctx, e.(plugin.Parent))
 			return
-		}
-		cancelFunc()
-		entries := obj.(*plugin.EntryMap)
-
-		if all {
-			entries.Range(func(_ string, entry plugin.Entry) bool {
-				wp.Submit(func() { processEntry(ctx, pw, wp, entry, all, errs) })
-				return true
-			})
-		} else {
-			// Group children by ones that look "similar", and select one from each group to test.
-			groups := make(map[criteria][]plugin.Entry)
-			entries.Range(func(_ string, entry plugin.Entry) bool {
-				// Skip files that we shouldn't read. This includes character devices, devices, and
-				// those we don't have permission to read.
-				attr := plugin.Attributes(entry)
-				if mode := attr.Mode(); attr.HasMode() &&
-					(mode&os.ModeCharDevice == os.ModeCharDevice ||
-						mode&os.ModeNamedPipe == os.ModeNamedPipe ||
-						mode&os.ModeDevice == os.ModeDevice || mode&0400 == 0) {
+		})
+		if ok {
+			if fullSchema != nil {
+				var children []plugin.Entry
+				entries.Range(func(_ string, entry plugin.Entry) bool {
+					children = append(children, entry)
 					return true
-				}
-
-				ccrit := newCriteria(entry)
-				// If we have a TypeID, only explore children if they are different from the parent.
-				// This prevents simple recursion like volume directories containing more dirs.
-				if ccrit.typeID == "" || ccrit.typeID != crit.typeID {
-					groups[ccrit] = append(groups[ccrit], entry)
-				}
-
-				return true
-			})
-
-			for _, items := range groups {
-				entry := items[rand.Intn(len(items))]
-				wp.Submit(func() { processEntry(ctx, pw, wp, entry, all, errs) })
+				})
+				v.conform(result, "children", plugin.CheckChildren(fullSchema, children, v.schemaOf))
 			}
+			v.submitChildren(crit, entries)
 		}
 	}
 	tracker.Increment(1)
 
 	if plugin.ReadAction().IsSupportedOn(e) {
-		_, cancelFunc, err := withTimeout(ctx, "read", name, func(ctx context.Context) (interface{}, error) {
-			data, err := plugin.Read(ctx, e, 0, 1)
+		v.check(result, "read", func(ctx context.Context) error {
+			_, err := plugin.Read(ctx, e, 0, 1)
 			if err == io.EOF {
 				err = nil
 			}
-			return data, err
+			return err
 		})
-		if err != nil {
-			errs <- err
-			return
-		}
-		cancelFunc()
 	}
 	tracker.Increment(1)
 
 	if plugin.StreamAction().IsSupportedOn(e) {
-		obj, cancelFunc, err := withTimeout(ctx, "stream", name, func(ctx context.Context) (interface{}, error) {
-			return plugin.Stream(ctx, e.(plugin.Streamable))
+		v.check(result, "stream", func(ctx context.Context) error {
+			rdr, err := plugin.Stream(ctx, e.(plugin.Streamable))
+			if err != nil {
+				return err
+			}
+			rdr.Close()
+			return nil
 		})
-		if err != nil {
-			errs <- err
-			return
-		}
-		obj.(io.Closer).Close()
-		cancelFunc()
 	}
 	tracker.Increment(1)
 
 	if plugin.ExecAction().IsSupportedOn(e) {
-		const testMessage = "hello"
-		obj, cancelFunc, err := withTimeout(ctx, "exec", name, func(ctx context.Context) (interface{}, error) {
-			return plugin.Exec(ctx, e.(plugin.Execable), "echo", []string{testMessage}, plugin.ExecOptions{})
+		v.check(result, "exec", func(ctx context.Context) error {
+			return validateExec(ctx, e.(plugin.Execable))
 		})
+	}
+	tracker.Increment(1)
+
+	v.check(result, "metadata", func(ctx context.Context) error {
+		meta, err := plugin.Metadata(ctx, e)
 		if err != nil {
-			errs <- err
-			return
+			return err
 		}
-		cmd := obj.(plugin.ExecCommand)
-
-		var output string
-		for chunk := range cmd.OutputCh() {
-			if err := chunk.Err; err != nil {
-				errs <- err
-			} else if chunk.StreamID == plugin.Stdout {
-				output += chunk.Data
-			} else if chunk.StreamID == plugin.Stderr {
-				errs <- fmt.Errorf("Unexpected error output on Exec: %v", chunk.Data)
-			}
+		if fullSchema == nil {
+			return nil
+		}
+		if err := plugin.ValidateJSON(fullSchema.MetadataSchema, meta); err != nil {
+			return fmt.Errorf("the metadata does not match the schema: %v", err)
+		}
+		return nil
+	})
+	tracker.Increment(1)
+
+	// Signal and delete can change the entries, so they're never invoked. Instead, check
+	// that the schema describes them.
+	v.conformAction(result, e, fullSchema, plugin.SignalAction(), plugin.CheckSignals)
+	tracker.Increment(1)
+
+	v.conformAction(result, e, fullSchema, plugin.DeleteAction(), plugin.CheckDelete)
+	tracker.MarkAsDone()
+}
+
+// conformAction records the action's schema violations, if any, as a check on the entry.
+// The check is recorded if the entry supports the action or its schema has violations.
+// Entries without a schema can't be checked, so the check is skipped.
+func (v *validator) conformAction(result *validateEntryResult, e plugin.Entry, fullSchema *plugin.EntrySchema,
+	action plugin.Action, check func(plugin.Entry, *plugin.EntrySchema) []string) {
+	if fullSchema == nil {
+		if action.IsSupportedOn(e) {
+			result.Checks = append(result.Checks, validateCheckResult{Name: action.Name, Skipped: "no schema"})
 		}
+		return
+	}
+	if violations := check(e, fullSchema); action.IsSupportedOn(e) || len(violations) > 0 {
+		v.conform(result, action.Name, violations)
+	}
+}
 
-		if msg := strings.Trim(output, "\n"); msg != testMessage {
-			errs <- fmt.Errorf("Unexpected output on Exec: %v", msg)
+// submitChildren submits the children to validate. Unless validating all entries, only
+// one child of each kind is validated.
+func (v *validator) submitChildren(crit criteria, entries *plugin.EntryMap) {
+	if v.all {
+		entries.Range(func(_ string, entry plugin.Entry) bool {
+			v.wp.Submit(func() { v.processEntry(entry) })
+			return true
+		})
+		return
+	}
+
+	// Group children by ones that look "similar", and select one from each group to test.
+	groups := make(map[criteria][]plugin.Entry)
+	entries.Range(func(_ string, entry plugin.Entry) bool {
+		// Skip files that we shouldn't read. This includes character devices, devices, and
+		// those we don't have permission to read.
+		attr := plugin.Attributes(entry)
+		if mode := attr.Mode(); attr.HasMode() &&
+			(mode&os.ModeCharDevice == os.ModeCharDevice ||
+				mode&os.ModeNamedPipe == os.ModeNamedPipe ||
+				mode&os.ModeDevice == os.ModeDevice || mode&0400 == 0) {
+			return true
 		}
 
-		if exitCode, err := cmd.ExitCode(); err != nil {
-			errs <- fmt.Errorf("Error getting exit code for 'echo': %v", err)
-		} else if exitCode != 0 {
-			errs <- fmt.Errorf("Non-zero exit code for 'echo': %v", exitCode)
+		ccrit := newCriteria(entry)
+		// If we have a TypeID, only explore children if they are different from the parent.
+		// This prevents simple recursion like volume directories containing more dirs.
+		if ccrit.typeID == "" || ccrit.typeID != crit.typeID {
+			groups[ccrit] = append(groups[ccrit], entry)
 		}
-		cancelFunc()
+
+		return true
+	})
+
+	for _, items := range groups {
+		entry := items[rand.Intn(len(items))]
+		v.wp.Submit(func() { v.processEntry(entry) })
 	}
-	tracker.MarkAsDone()
+}
+
+// validateExec runs 'echo' on the entry and checks its output.
+func validateExec(ctx context.Context, e plugin.Execable) error {
+	const testMessage = "hello"
+	cmd, err := plugin.Exec(ctx, e, "echo", []string{testMessage}, plugin.ExecOptions{})
+	if err != nil {
+		return err
+	}
+
+	var output string
+	var errs []string
+	for chunk := range cmd.OutputCh() {
+		if err := chunk.Err; err != nil {
+			errs = append(errs, err.Error())
+		} else if chunk.StreamID == plugin.Stdout {
+			output += chunk.Data
+		} else if chunk.StreamID == plugin.Stderr {
+			errs = append(errs, fmt.Sprintf("Unexpected error output on Exec: %v", chunk.Data))
+		}
+	}
+
+	if msg := strings.Trim(output, "\n"); msg != testMessage {
+		errs = append(errs, fmt.Sprintf("Unexpected output on Exec: %v", msg))
+	}
+
+	if exitCode, err := cmd.ExitCode(); err != nil {
+		errs = append(errs, fmt.Sprintf("Error getting exit code for 'echo': %v", err))
+	} else if exitCode != 0 {
+		errs = append(errs, fmt.Sprintf("Non-zero exit code for 'echo': %v", exitCode))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// validateReport is the machine-readable result of validating a plugin.
+type validateReport struct {
+	Plugin   string                `json:"plugin"`
+	Passed   bool                  `json:"passed"`
+	Duration time.Duration         `json:"duration"`
+	Entries  []validateEntryResult `json:"entries"`
+}
+
+// validateEntryResult records the checks run on an entry.
+type validateEntryResult struct {
+	ID     string                `json:"id"`
+	TypeID string                `json:"type_id,omitempty"`
+	Checks []validateCheckResult `json:"checks"`
+}
+
+// validateCheckResult records the result of a single check. Error is set if the check
+// failed. Skipped is set to the reason the check wasn't run.
+type validateCheckResult struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+	Skipped  string        `json:"skipped,omitempty"`
+}
+
+// finish sorts the report's entries so that it's stable across runs, and determines
+// whether validation passed.
+func (r *validateReport) finish() {
+	sort.Slice(r.Entries, func(i, j int) bool {
+		return r.Entries[i].ID < r.Entries[j].ID
+	})
+	r.Passed = true
+	for _, entry := range r.Entries {
+		for _, check := range entry.Checks {
+			if check.Error != "" {
+				r.Passed = false
+			}
+		}
+	}
+}
+
+func (r *validateReport) json(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Classname string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// junit writes the report as JUnit XML. The plugin is the test suite, and each check
+// is a test case whose class is the entry's ID.
+func (r *validateReport) junit(w io.Writer) error {
+	suite := junitTestSuite{Name: r.Plugin, Time: junitTime(r.Duration)}
+	for _, entry := range r.Entries {
+		for _, check := range entry.Checks {
+			tc := junitTestCase{Classname: entry.ID, Name: check.Name, Time: junitTime(check.Duration)}
+			if check.Error != "" {
+				tc.Failure = &junitMessage{Message: check.Error}
+				suite.Failures++
+			} else if check.Skipped != "" {
+				tc.Skipped = &junitMessage{Message: check.Skipped}
+				suite.Skipped++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+	}
+	suite.Tests = len(suite.Cases)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// writeReport writes a report to the file at path.
+func writeReport(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
 
 func formatErr(msg, method string, err error) error {
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestValidateReport() validateReport {
+	report := validateReport{
+		Plugin:   "greeter",
+		Duration: 1500 * time.Millisecond,
+		Entries: []validateEntryResult{
+			{
+				ID:     "/greeter/hello",
+				TypeID: "greeter::greeting",
+				Checks: []validateCheckResult{
+					{Name: "read", Duration: 20 * time.Millisecond},
+					{Name: "metadata", Duration: time.Millisecond, Error: "the metadata does not match the schema"},
+					{Name: "delete", Skipped: "no schema"},
+				},
+			},
+			{
+				ID:     "/greeter",
+				TypeID: "greeter::root",
+				Checks: []validateCheckResult{{Name: "list", Duration: time.Second}},
+			},
+		},
+	}
+	report.finish()
+	return report
+}
+
+func TestValidateReportFinish(t *testing.T) {
+	report := newTestValidateReport()
+	assert.False(t, report.Passed)
+	if assert.Len(t, report.Entries, 2) {
+		assert.Equal(t, "/greeter", report.Entries[0].ID)
+		assert.Equal(t, "/greeter/hello", report.Entries[1].ID)
+	}
+
+	report.Entries = report.Entries[:1]
+	report.finish()
+	assert.True(t, report.Passed)
+}
+
+func TestValidateReportJSON(t *testing.T) {
+	report := newTestValidateReport()
+	var buf bytes.Buffer
+	if assert.NoError(t, report.json(&buf)) {
+		var decoded validateReport
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, report, decoded)
+	}
+}
+
+func TestValidateReportJUnit(t *testing.T) {
+	report := newTestValidateReport()
+	var buf bytes.Buffer
+	if assert.NoError(t, report.junit(&buf)) {
+		expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="greeter" tests="4" failures="1" skipped="1" time="1.500">
+    <testcase classname="/greeter" name="list" time="1.000"></testcase>
+    <testcase classname="/greeter/hello" name="read" time="0.020"></testcase>
+    <testcase classname="/greeter/hello" name="metadata" time="0.001">
+      <failure message="the metadata does not match the schema"></failure>
+    </testcase>
+    <testcase classname="/greeter/hello" name="delete" time="0.000">
+      <skipped message="no schema"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+		assert.Equal(t, expected, buf.String())
+	}
+}
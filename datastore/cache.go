@@ -11,6 +11,7 @@ import (
 	// is merged, go back to importing the main go-cache repo.
 	cache "github.com/ekinanp/go-cache"
 	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/puppetlabs/wash/metrics"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -34,6 +35,7 @@ type MemCache struct {
 	locks       sync.Map
 	hasEviction bool
 	limit       int
+	metricsName string
 }
 
 var _ = Cache(&MemCache{})
@@ -74,6 +76,31 @@ func (cache *MemCache) Limit(n int) *MemCache {
 	return cache
 }
 
+// WithMetrics reports the cache's hits, misses and evictions as metrics labelled with
+// the given name.
+func (cache *MemCache) WithMetrics(name string) *MemCache {
+	cache.metricsName = name
+	return cache
+}
+
+func (cache *MemCache) recordHit() {
+	if cache.metricsName != "" {
+		metrics.CacheHit(cache.metricsName)
+	}
+}
+
+func (cache *MemCache) recordMiss() {
+	if cache.metricsName != "" {
+		metrics.CacheMiss(cache.metricsName)
+	}
+}
+
+func (cache *MemCache) recordEvictions(reason string, n int) {
+	if cache.metricsName != "" && n > 0 {
+		metrics.CacheEvictions(cache.metricsName, reason, n)
+	}
+}
+
 func formKey(category, key string) string {
 	return category + "::" + key
 }
@@ -113,6 +140,7 @@ func (cache *MemCache) GetOrUpdate(category, key string, ttl time.Duration, rese
 	value, found := cache.instance.Get(key)
 	if found {
 		log.Tracef("Cache hit on %v", key)
+		cache.recordHit()
 		if resetTTLOnHit {
 			// Update last-access time
 			cache.instance.Set(key, value, ttl)
@@ -125,6 +153,7 @@ func (cache *MemCache) GetOrUpdate(category, key string, ttl time.Duration, rese
 
 	// Cache misses should be rarer, so print them as debug messages.
 	log.Debugf("Cache miss on %v", key)
+	cache.recordMiss()
 
 	if cache.limit > 0 && cache.instance.ItemCount() >= cache.limit {
 		// Retain write lock when deleting items to avoid concurrent map read/write.
@@ -162,6 +191,7 @@ func (cache *MemCache) deleteClosestToExpiration() {
 		panic("should have found a candidate")
 	}
 	cache.instance.Delete(candidate)
+	cache.recordEvictions("limit", 1)
 }
 
 // Flush deletes all items from the cache. Also resets cache capacity.
@@ -170,6 +200,7 @@ func (cache *MemCache) Flush() {
 	cache.mux.Lock()
 	defer cache.mux.Unlock()
 
+	cache.recordEvictions("flushed", cache.instance.ItemCount())
 	if cache.hasEviction {
 		// Flush doesn't trigger the eviction callback. If we've registered one, ensure it's
 		// triggered for all keys being removed. First delete all valid entries, then delete
@@ -200,5 +231,6 @@ func (cache *MemCache) Delete(matcher *regexp.Regexp) []string {
 			log.Debugf("Skipping %v", k)
 		}
 	}
+	cache.recordEvictions("deleted", len(deleted))
 	return deleted
 }
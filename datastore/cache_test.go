@@ -2,10 +2,12 @@ package datastore
 
 import (
 	"errors"
+	"net/http/httptest"
 	"regexp"
 	"testing"
 	"time"
 
+	"github.com/puppetlabs/wash/metrics"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
@@ -143,6 +145,21 @@ func (suite *MemCacheTestSuite) TestDelete() {
 	suite.NotNil(suite.mem.instance.Get("another entry"))
 }
 
+func (suite *MemCacheTestSuite) TestWithMetrics() {
+	mem := NewMemCache().WithMetrics("memcache_test")
+	suite.thing.On("update").Return(anything, nil).Once()
+	suite.validate(mem.GetOrUpdate("category", "key", time.Minute, false, suite.update))
+	suite.validate(mem.GetOrUpdate("category", "key", time.Minute, false, suite.update))
+	mem.Delete(regexp.MustCompile("key"))
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	suite.Contains(body, `wash_cache_hits_total{cache="memcache_test"} 1`)
+	suite.Contains(body, `wash_cache_misses_total{cache="memcache_test"} 1`)
+	suite.Contains(body, `wash_cache_evictions_total{cache="memcache_test",reason="deleted"} 1`)
+}
+
 func TestMemCache(t *testing.T) {
 	suite.Run(t, new(MemCacheTestSuite))
 }
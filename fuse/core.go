@@ -5,14 +5,18 @@ import (
 	"context"
 	"os"
 	"os/user"
+	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/analytics"
+	"github.com/puppetlabs/wash/metrics"
 	"github.com/puppetlabs/wash/plugin"
 	log "github.com/sirupsen/logrus"
 )
@@ -30,7 +34,9 @@ func newRoot(registry *plugin.Registry) Root {
 
 // Root presents the root of the filesystem.
 func (r *Root) Root() (fs.Node, error) {
-	return newDir(nil, r.registry), nil
+	root := newDir(nil, r.registry)
+	kernel.track(plugin.ID(r.registry), root)
+	return root, nil
 }
 
 func getIDs() (uint32, uint32) {
@@ -68,14 +74,32 @@ func newFuseNode(ftype string, parent *dir, entry plugin.Entry) fuseNode {
 	}
 }
 
+// fuseErr returns the error that's reported to the kernel for an error returned by a
+// plugin call. Timeouts are reported as ETIMEDOUT; other errors are reported as EIO.
+func fuseErr(err error) error {
+	if plugin.IsTimeoutErr(err) {
+		return fuse.Errno(syscall.ETIMEDOUT)
+	}
+	return err
+}
+
 func (f *fuseNode) String() string {
 	return plugin.ID(f.entry)
 }
 
-// Applies attributes where non-default, and sets defaults otherwise.
-func applyAttr(a *fuse.Attr, attr plugin.EntryAttributes, defaultMode os.FileMode) {
-	// Setting a.Valid to 1 second avoids frequent Attr calls.
-	a.Valid = 1 * time.Second
+// attrTTL returns how long the kernel should cache the node's attributes. Attributes come from
+// listing the parent, so they're valid for as long as that list is cached.
+func (f *fuseNode) attrTTL() time.Duration {
+	if f.parent == nil {
+		return cacheTTL(f.entry)
+	}
+	return cacheTTL(f.parent.entry)
+}
+
+// Applies attributes where non-default, and sets defaults otherwise. The attributes are valid
+// for the specified duration, which avoids frequent Attr calls.
+func applyAttr(a *fuse.Attr, attr plugin.EntryAttributes, defaultMode os.FileMode, valid time.Duration) {
+	a.Valid = valid
 
 	// TODO: tie this to actual hard links in plugins
 	a.Nlink = 1
@@ -141,6 +165,18 @@ func (f *fuseNode) refind(ctx context.Context) (plugin.Entry, error) {
 	return plugin.FindEntry(ctx, parent, segments)
 }
 
+// fuseOpName returns the name of the request's operation, e.g. Lookup for a
+// *fuse.LookupRequest.
+func fuseOpName(req fuse.Request) string {
+	t := reflect.TypeOf(req)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return strings.TrimSuffix(t.Name(), "Request")
+}
+
+var registerCacheClearedHook sync.Once
+
 // ServeFuseFS starts serving a fuse filesystem that lists the registered plugins.
 // It returns three values:
 //   1. A channel to initiate the shutdown (stopCh).
@@ -170,6 +206,8 @@ func ServeFuseFS(
 	go func() {
 		serverConfig := &fs.Config{
 			WithContext: func(ctx context.Context, req fuse.Request) context.Context {
+				// WithContext is called for every request, so it's where they're counted.
+				metrics.FUSEOperation(fuseOpName(req))
 				pid := int(req.Hdr().Pid)
 				newctx := context.WithValue(ctx, activity.JournalKey, activity.JournalForPID(pid))
 				newctx = context.WithValue(newctx, analytics.ClientKey, analyticsClient)
@@ -177,6 +215,14 @@ func ServeFuseFS(
 			},
 		}
 		server := fs.New(fuseConn, serverConfig)
+		kernel.setInvalidator(server)
+		registerCacheClearedHook.Do(func() {
+			plugin.OnCacheCleared(func(path string, parentChanged bool) {
+				// Invalidate asynchronously because the cache is often cleared while handling a FUSE
+				// request, and the kernel may wait on that request before processing the notification.
+				go kernel.invalidate(path, parentChanged)
+			})
+		})
 		root := newRoot(filesys)
 		if err := server.Serve(&root); err != nil {
 			log.Warnf("FUSE: fs.Serve errored with: %v", err)
@@ -188,6 +234,7 @@ func ServeFuseFS(
 			log.Warnf("FUSE: Mount process errored with: %v", err)
 		}
 		log.Infof("FUSE: Serve complete")
+		kernel.setInvalidator(nil)
 
 		// Signal that Serve exited so the clean-up goroutine can close the stopped channel
 		// if it hasn't already done so.
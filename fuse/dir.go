@@ -50,6 +50,9 @@ func (d *dir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.Lo
 	entries, err := d.children(ctx)
 	if err != nil {
 		activity.Warnf(ctx, "FUSE: Find %v in %v errored: %v", req.Name, d, err)
+		if plugin.IsTimeoutErr(err) {
+			return nil, fuseErr(err)
+		}
 		return nil, fuse.ENOENT
 	}
 
@@ -60,14 +63,34 @@ func (d *dir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.Lo
 		return nil, fuse.ENOENT
 	}
 
+	// The kernel can reuse this result for as long as the listing it came from is cached.
+	resp.EntryValid = cacheTTL(d.entry)
+
+	if plugin.ReadlinkAction().IsSupportedOn(entry) {
+		childlink := newSymlink(d, entry.(plugin.Linkable))
+		log.Debugf("FUSE: Found symlink %v", childlink)
+		kernel.track(plugin.ID(entry), childlink)
+		return childlink, nil
+	}
+
 	if plugin.ListAction().IsSupportedOn(entry) {
 		childdir := newDir(d, entry.(plugin.Parent))
 		log.Debugf("FUSE: Found directory %v", childdir)
+		kernel.track(plugin.ID(entry), childdir)
 		return childdir, nil
 	}
 
 	log.Debugf("FUSE: Found file %v/%v", d, cname)
-	return newFile(d, entry), nil
+	childfile := newFile(d, entry)
+	kernel.track(plugin.ID(entry), childfile)
+	return childfile, nil
+}
+
+var _ = fs.NodeForgetter(&dir{})
+
+// Forget stops tracking the directory when the kernel forgets it.
+func (d *dir) Forget() {
+	kernel.forget(d)
 }
 
 // ReadDirAll lists all children of the directory.
@@ -77,14 +100,16 @@ func (d *dir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
 	entries, err := d.children(ctx)
 	if err != nil {
 		activity.Warnf(ctx, "FUSE: List %v errored: %v", d, err)
-		return nil, err
+		return nil, fuseErr(err)
 	}
 
 	res := make([]fuse.Dirent, 0, entries.Len())
 	entries.Range(func(cname string, entry plugin.Entry) bool {
 		var de fuse.Dirent
 		de.Name = cname
-		if plugin.ListAction().IsSupportedOn(entry) {
+		if plugin.ReadlinkAction().IsSupportedOn(entry) {
+			de.Type = fuse.DT_Link
+		} else if plugin.ListAction().IsSupportedOn(entry) {
 			de.Type = fuse.DT_Dir
 		} else {
 			de.Type = fuse.DT_File
@@ -103,14 +128,14 @@ func (d *dir) Attr(ctx context.Context, a *fuse.Attr) error {
 	entry, err := d.refind(ctx)
 	if err != nil {
 		activity.Warnf(ctx, "FUSE: Attr errored %v, %v", d, err)
-		return err
+		return fuseErr(err)
 	}
 	// NOTE: We could set f.entry to entry, but doing so would require
 	// a separate mutex which may hinder performance. Since updating f.entry
 	// is not strictly necessary for the other FUSE operations, we choose to
 	// leave it alone.
 
-	applyAttr(a, plugin.Attributes(entry), os.ModeDir|0550)
+	applyAttr(a, plugin.Attributes(entry), os.ModeDir|0550, d.attrTTL())
 	// Attr is not a particularly interesting call and happens a lot. Log it to debug like other
 	// activity, but leave it out of activity because it introduces history entries for lots of
 	// miscellaneous shell activity.
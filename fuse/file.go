@@ -8,7 +8,6 @@ import (
 
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
-	"bazil.org/fuse/fuseutil"
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 )
@@ -20,27 +19,33 @@ import (
 // - a *file-like* entry declares a `Size` in its `Attributes`
 //   - reads and writes are symmetric; the kernel page cache will be used, and the file size
 //     represents the current local content size of the file
-//   - when writing, `data` represents the local content of the file; changes to its size will
-//     usually be reflected in `data` and reads will be served from it
-//   - the file's size will be `readSize`; `data` will be resized as-needed to delay loading data
-//     from `plugin.Read` until it's needed (either a `Flush` or non-contiguous `Write`) and
-//     differences between `readSize` and `len(data)` will be resolved when `Flush` is called to
-//     avoid unnecessary calls to `plugin.Read` when overwriting a file
+//   - when writing, `buf` represents the local content of the file; changes to its size will
+//     usually be reflected in `buf` and reads will be served from it
+//   - the file's size will be `readSize`; `buf` will be resized as-needed to delay loading data
+//     from `plugin.Read` until it's needed (either a commit or non-contiguous `Write`) and
+//     differences between `readSize` and `buf.Len()` will be resolved when committing to avoid
+//     unnecessary calls to `plugin.Read` when overwriting a file
 //   - when not writing, data is read directly from `plugin.Read`
 // - a *non-file-like* entry has `Size` unset
 //   - read always pulls from `plugin.Read` and writes are buffered independently
-//   - `data` stores only the data to be written, and is not initialized from `plugin.Read`
+//   - `buf` stores only the data to be written, and is not initialized from `plugin.Read`
 //   - the file's size will be reported as its readable size; it will not reflect calls to `write`
 //
-// Note that writes only result in calling `plugin.Write` when a file handle is closed by the OS
-// (triggering a call to `Flush` as noted in https://libfuse.github.io/doxygen/structfuse__operations.html#ad4ec9c309072a92dd82ddb20efa4ab14)
-// Writing with multiple handles will be protected by `mux`, but all writes will operate on the
-// same `data` and the first handle close will trigger `plugin.Write`.
+// `buf` is a writeBuffer, which spools writes to a temporary file under the cache directory so
+// that writing large files doesn't hold them in memory. BlockWritable entries are passed the
+// spooled content as a stream when it's committed.
+//
+// Writes are committed - resulting in a call to `plugin.WriteFrom` - on `Flush`, which happens
+// when a file handle is closed by the OS (as noted in https://libfuse.github.io/doxygen/structfuse__operations.html#ad4ec9c309072a92dd82ddb20efa4ab14),
+// on `Fsync`, and on `Release` if there are uncommitted writes. Writing with multiple handles
+// will be protected by `mux`, but all writes will operate on the same `buf` and the first
+// handle to commit will write all of them. Content is only committed if it changed since the
+// last commit.
 //
 // `readSize` will always be initialized from either the `Size` attribute, or if unset then the
 // length of data available to read.
 //
-// `writers` are used to track in-progress writes so we know when to `plugin.Write` on `Flush`
+// `writers` are used to track in-progress writes so we know when to commit them.
 type file struct {
 	fuseNode
 
@@ -48,11 +53,17 @@ type file struct {
 	// Handles with in-progress writes
 	writers map[fuse.HandleID]struct{}
 	// Only valid if len(writers) > 0
-	data []byte
+	buf *writeBuffer
+	// Whether buf has changed since it was last committed
+	dirty bool
 	// Size of readable content, necessary for *non-file-like* entries
 	readSize uint64
 }
 
+// loadChunkSize is the most that's read from an entry at once when filling in a write
+// buffer, so that large files aren't loaded into memory.
+const loadChunkSize = 1 << 20
+
 func newFile(p *dir, e plugin.Entry) *file {
 	return &file{fuseNode: newFuseNode("f", p, e), writers: make(map[fuse.HandleID]struct{})}
 }
@@ -80,7 +91,7 @@ func (f *file) Attr(ctx context.Context, a *fuse.Attr) error {
 		entry, err := f.refind(ctx)
 		if err != nil {
 			activity.Warnf(ctx, "FUSE: Attr errored %v, %v", f, err)
-			return err
+			return fuseErr(err)
 		}
 		f.entry = entry
 	}
@@ -92,7 +103,7 @@ func (f *file) Attr(ctx context.Context, a *fuse.Attr) error {
 
 func (f *file) fillAttr(a *fuse.Attr) {
 	attr := plugin.Attributes(f.entry)
-	applyAttr(a, attr, defaultMode(f.entry))
+	applyAttr(a, attr, defaultMode(f.entry), f.attrTTL())
 
 	if f.useLocalContent() || !f.isFileLikeEntry() {
 		// Use whatever size we know locally. Retrieving content can be expensive so we settle for
@@ -113,6 +124,13 @@ func defaultMode(entry plugin.Entry) os.FileMode {
 	return mode
 }
 
+var _ = fs.NodeForgetter(&file{})
+
+// Forget stops tracking the file when the kernel forgets it.
+func (f *file) Forget() {
+	kernel.forget(f)
+}
+
 var _ = fs.NodeOpener(&file{})
 
 // Open an entry for reading or writing. Several patterns exist for how to interact with entries.
@@ -132,7 +150,7 @@ func (f *file) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenR
 		entry, err := f.refind(ctx)
 		if err != nil {
 			activity.Warnf(ctx, "FUSE: Open errored %v, %v", f, err)
-			return nil, err
+			return nil, fuseErr(err)
 		}
 		f.entry = entry
 	}
@@ -167,7 +185,7 @@ func (f *file) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenR
 		// Get the entry's readable size if we expect to do any reads or keep a local representation.
 		size, err := plugin.Size(ctx, f.entry)
 		if err != nil {
-			return nil, err
+			return nil, fuseErr(err)
 		}
 		f.readSize = size
 	}
@@ -175,15 +193,32 @@ func (f *file) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenR
 	return f, nil
 }
 
-func (f *file) releaseWriter(handle fuse.HandleID) {
+// addWriter ensures handle is in the list of writers, and creates the write buffer if needed.
+func (f *file) addWriter(handle fuse.HandleID) error {
+	if f.buf == nil {
+		buf, err := newWriteBuffer()
+		if err != nil {
+			return err
+		}
+		f.buf = buf
+	}
+	f.writers[handle] = struct{}{}
+	return nil
+}
+
+func (f *file) releaseWriter(ctx context.Context, handle fuse.HandleID) {
 	if _, ok := f.writers[handle]; ok {
 		delete(f.writers, handle)
 
 		if len(f.writers) == 0 {
-			// If we just released the last writer, release the data buffer to conserve memory and
-			// invalidate cache on the entry and its parent so we get updated content and size on the
-			// next request. Leave size for entries that don't set it.
-			f.data = nil
+			// If we just released the last writer, remove the write buffer and invalidate cache on
+			// the entry and its parent so we get updated content and size on the next request.
+			// Leave size for entries that don't set it.
+			if err := f.buf.Close(); err != nil {
+				activity.Warnf(ctx, "FUSE: Error removing write buffer for %v: %v", f, err)
+			}
+			f.buf = nil
+			f.dirty = false
 			plugin.ClearCacheFor(plugin.ID(f.entry), true)
 		}
 	}
@@ -192,24 +227,23 @@ func (f *file) releaseWriter(handle fuse.HandleID) {
 var _ = fs.HandleReleaser(&file{})
 
 func (f *file) Release(ctx context.Context, req *fuse.ReleaseRequest) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
 	if req.ReleaseFlags&fuse.ReleaseFlush != 0 {
 		activity.Record(ctx, "FUSE: Invoking Flush for Release on %v", f)
-		err := f.Flush(ctx, &fuse.FlushRequest{
-			Header:    req.Header,
-			Handle:    req.Handle,
-			LockOwner: uint64(req.LockOwner),
-		})
-		if err != nil {
-			return err
-		}
 	}
+	// Commit any writes that weren't flushed. Flush usually precedes Release, in which case
+	// there's nothing left to commit.
+	err := f.commit(ctx, req.Handle)
 
-	// Release writer and cleanup if all writers are released. Note that this is usually a noop for
-	// non-file-like entries, they will have released the writers immediately after `plugin.Write`.
-	f.releaseWriter(req.Handle)
+	// Release writer and cleanup if all writers are released, even if the commit failed because
+	// the handle can't be used again. Note that this is usually a noop for non-file-like entries,
+	// they will have released the writers immediately after committing.
+	f.releaseWriter(ctx, req.Handle)
 
 	activity.Record(ctx, "FUSE: Release %v: %+v", f, *req)
-	return nil
+	return err
 }
 
 var _ = fs.HandleReader(&file{})
@@ -219,13 +253,18 @@ func (f *file) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadR
 	defer f.mux.Unlock()
 
 	if f.useLocalContent() {
-		fuseutil.HandleRead(req, resp, f.data)
+		resp.Data = make([]byte, req.Size)
+		n, err := f.buf.ReadAt(resp.Data, req.Offset)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		resp.Data = resp.Data[:n]
 	} else {
 		data, err := plugin.ReadWithAnalytics(ctx, f.entry, int64(req.Size), req.Offset)
 		if err != nil && err != io.EOF {
 			// If we don't ignore EOF, then cat will display an input/output error message
 			// for entries with unknown content size.
-			return err
+			return fuseErr(err)
 		}
 		resp.Data = data
 	}
@@ -241,95 +280,125 @@ func (f *file) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.Wri
 	defer f.mux.Unlock()
 
 	// Ensure handle is in list of writers.
-	f.writers[req.Handle] = struct{}{}
+	if err := f.addWriter(req.Handle); err != nil {
+		activity.Warnf(ctx, "FUSE: Error creating write buffer for %v: %v", f, err)
+		return err
+	}
 
 	if f.isFileLikeEntry() {
 		// If starting write beyond the current length, read to fill it in.
-		if start := int64(len(f.data)); req.Offset > start {
-			data, err := f.load(ctx, start, req.Offset)
-			if err != nil {
-				return err
+		if start := f.buf.Len(); req.Offset > start {
+			if err := f.load(ctx, start, req.Offset); err != nil {
+				return fuseErr(err)
 			}
-			f.data = append(f.data, data...)
 		}
 	}
 
-	// Expand the buffer if necessary to store the write data.
-	newLen := req.Offset + int64(len(req.Data))
-	if newLen := int(newLen); newLen > len(f.data) {
-		f.data = append(f.data, make([]byte, newLen-len(f.data))...)
+	// The buffer expands as necessary to store the write data. Any gap is filled with zeros.
+	n, err := f.buf.WriteAt(req.Data, req.Offset)
+	if err != nil {
+		activity.Warnf(ctx, "FUSE: Error buffering write to %v: %v", f, err)
+		return err
 	}
+	f.dirty = true
 
 	// If file-like, then update readable size to reflect the expanded buffer.
+	newLen := req.Offset + int64(n)
 	if f.isFileLikeEntry() && f.readSize < uint64(newLen) {
 		f.readSize = uint64(newLen)
 	}
 
-	resp.Size = copy(f.data[req.Offset:], req.Data)
+	resp.Size = n
 	activity.Record(ctx, "FUSE: Write %v/%v bytes starting at %v from %v", resp.Size, len(req.Data), req.Offset, f)
 	return nil
 }
 
-func (f *file) load(ctx context.Context, start, end int64) ([]byte, error) {
+// load fills the write buffer from start to end with the entry's content, reading it in
+// chunks. Content past the end of the entry's data is left for the caller to fill in.
+func (f *file) load(ctx context.Context, start, end int64) error {
 	if !f.isFileLikeEntry() {
 		panic("load called on non-file-like entry")
 	}
 
 	if !plugin.ReadAction().IsSupportedOn(f.entry) {
 		activity.Warnf(ctx, "FUSE: Non-contiguous writes (at %v) unsupported on %v", start, f)
-		return nil, fuse.ENOTSUP
+		return fuse.ENOTSUP
 	}
 
-	return plugin.Read(ctx, f.entry, end-start, start)
+	for offset := start; offset < end; {
+		size := end - offset
+		if size > loadChunkSize {
+			size = loadChunkSize
+		}
+		data, err := plugin.Read(ctx, f.entry, size, offset)
+		if err != nil && err != io.EOF {
+			return fuseErr(err)
+		}
+		if _, writeErr := f.buf.WriteAt(data, offset); writeErr != nil {
+			return writeErr
+		}
+		offset += int64(len(data))
+		if err == io.EOF || len(data) == 0 {
+			break
+		}
+	}
+	return nil
 }
 
 var _ = fs.HandleFlusher(&file{})
 
-// Note that this implementation of Flush only calls plugin.Write if there were previous calls to
-// Write or Setattr. It doesn't check whether the data that's there matches what we're writing.
+// Note that this implementation of Flush only commits if there were previous calls to Write or
+// Setattr. It doesn't check whether the data that's there matches what we're writing.
 func (f *file) Flush(ctx context.Context, req *fuse.FlushRequest) error {
 	f.mux.Lock()
 	defer f.mux.Unlock()
 	activity.Record(ctx, "FUSE: Flush %v: %+v", f, *req)
 
-	if _, ok := f.writers[req.Handle]; !ok {
+	return f.commit(ctx, req.Handle)
+}
+
+// commit writes the buffered content to the entry if handle has written to it and there are
+// uncommitted changes.
+func (f *file) commit(ctx context.Context, handle fuse.HandleID) error {
+	if _, ok := f.writers[handle]; !ok || !f.dirty {
 		return nil
 	}
 
 	// If this handle had an open writer, write current data.
-	dataLen := int64(len(f.data))
 	if f.isFileLikeEntry() {
-		// Only file-like entries keep data and readSize in sync.
+		// Only file-like entries keep buf and readSize in sync.
+		dataLen := f.buf.Len()
 		if uint64(dataLen) > f.readSize {
 			panic("Size was not kept up-to-date with changes to data.")
 		}
 
 		if uint64(dataLen) < f.readSize {
 			// Missing some data, load the remainder before writing.
-			data, err := f.load(ctx, dataLen, int64(f.readSize))
-			if err != nil && err != io.EOF {
+			if err := f.load(ctx, dataLen, int64(f.readSize)); err != nil {
 				return err
 			}
-			f.data = append(f.data, data...)
 
 			// If a call to `Setattr` was used to increase the file's size, then `load` will have
-			// returned EOF and the loaded data would not be enough to increase the local content buffer
-			// to `readSize`. Fill the rest with null characters.
-			if sz := uint64(len(f.data)); sz < f.readSize {
-				f.data = append(f.data, make([]byte, f.readSize-sz)...)
+			// stopped at the end of the entry's content and the buffer would not reach `readSize`.
+			// Fill the rest with null characters.
+			if f.buf.Len() < int64(f.readSize) {
+				if err := f.buf.Truncate(int64(f.readSize)); err != nil {
+					return err
+				}
 			}
 		}
 	}
 
-	if err := plugin.WriteWithAnalytics(ctx, f.entry.(plugin.Writable), f.data); err != nil {
+	if err := plugin.WriteFromWithAnalytics(ctx, f.entry, f.buf.Len(), f.buf.Reader()); err != nil {
 		activity.Warnf(ctx, "FUSE: Error writing %v: %v", f, err)
-		return err
+		return fuseErr(err)
 	}
+	f.dirty = false
 
 	// Non-file-like entries start from scratch on each Write operation, and have their cache
 	// invalidated whenever we write to them because we can't accurately model their readable data.
 	if !f.isFileLikeEntry() {
-		f.releaseWriter(req.Handle)
+		f.releaseWriter(ctx, handle)
 	}
 	return nil
 }
@@ -350,19 +419,25 @@ func (f *file) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse
 
 		// Ensure handle is in list of writers because we need to operate on a local copy of the data,
 		// and changing the file size is similar to initiating a write.
-		f.writers[req.Handle] = struct{}{}
+		if err := f.addWriter(req.Handle); err != nil {
+			activity.Warnf(ctx, "FUSE: Error creating write buffer for %v: %v", f, err)
+			return err
+		}
+		f.dirty = true
 
 		if f.isFileLikeEntry() {
-			// Update known size.
+			// Update known size. Discard buffered content past the new size; if the file grew, the
+			// gap is filled in when the content is committed.
 			f.readSize = req.Size
-		} else {
-			// Non-file-like entries use `data` as a write buffer. There's nothing to fill in from, so
-			// just resize as necessary.
-			if curLen := uint64(len(f.data)); req.Size > curLen {
-				f.data = append(f.data, make([]byte, req.Size-uint64(len(f.data)))...)
-			} else if req.Size < curLen {
-				f.data = f.data[:req.Size]
+			if uint64(f.buf.Len()) > req.Size {
+				if err := f.buf.Truncate(int64(req.Size)); err != nil {
+					return err
+				}
 			}
+		} else if err := f.buf.Truncate(int64(req.Size)); err != nil {
+			// Non-file-like entries use `buf` as a write buffer. There's nothing to fill in from, so
+			// just resize it.
+			return err
 		}
 	}
 
@@ -373,11 +448,13 @@ func (f *file) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse
 // Needs to be defined or vim gets an fuse.EIO error on Fsync.
 var _ = fs.NodeFsyncer(&file{})
 
+// Fsync commits the handle's pending writes. On a handle opened for reading, we could potentially
+// invalidate the Wash cache and re-request data from the plugin, but in most cases that doesn't
+// seem to be necessary.
 func (f *file) Fsync(ctx context.Context, req *fuse.FsyncRequest) error {
-	// As noted in the docs for fs.NodeFsyncer, this should be implemented on a Handle. Write Fsync
-	// should be unnecessary because Flush handles complete serialization out. On a handle opened
-	// for reading, we could potentially invalidate the Wash cache and re-request data from the
-	// plugin, but in most cases that doesn't seem to be necessary.
+	f.mux.Lock()
+	defer f.mux.Unlock()
 	activity.Record(ctx, "FUSE: Fsync %v: %+v", f, *req)
-	return fuse.ENOSYS
+
+	return f.commit(ctx, req.Handle)
 }
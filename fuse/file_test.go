@@ -2,6 +2,8 @@ package fuse
 
 import (
 	"context"
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"bazil.org/fuse"
@@ -15,15 +17,29 @@ import (
 
 type fileTestSuite struct {
 	suite.Suite
-	ctx context.Context
+	ctx            context.Context
+	savedBufferDir string
 }
 
 func (suite *fileTestSuite) SetupTest() {
 	plugin.SetTestCache(datastore.NewMemCache())
+	dir, err := ioutil.TempDir("", "wash_writes")
+	suite.Require().NoError(err)
+	suite.savedBufferDir = writeBufferDir
+	writeBufferDir = dir
 }
 
 func (suite *fileTestSuite) TearDownTest() {
 	plugin.UnsetTestCache()
+	suite.NoError(os.RemoveAll(writeBufferDir))
+	writeBufferDir = suite.savedBufferDir
+}
+
+// assertNoWriteBuffers asserts that all write buffers have been removed.
+func (suite *fileTestSuite) assertNoWriteBuffers() {
+	files, err := ioutil.ReadDir(writeBufferDir)
+	suite.NoError(err)
+	suite.Empty(files)
 }
 
 func (suite *fileTestSuite) TestOpen_FileLikeEntry_OpenFlags() {
@@ -201,7 +217,7 @@ func (suite *fileTestSuite) TestOpenAndFlush() {
 		err = handle.(fs.HandleFlusher).Flush(suite.ctx, &fuse.FlushRequest{})
 		suite.NoError(err)
 		suite.Empty(f.writers)
-		suite.Nil(f.data)
+		suite.Nil(f.buf)
 	}
 }
 
@@ -220,7 +236,7 @@ func (suite *fileTestSuite) TestOpenAndFlushRelease() {
 		err = handle.(fs.HandleReleaser).Release(suite.ctx, &relReq)
 		suite.NoError(err)
 		suite.Empty(f.writers)
-		suite.Nil(f.data)
+		suite.Nil(f.buf)
 	}
 }
 
@@ -362,8 +378,8 @@ func (suite *fileTestSuite) TestWrite_NonFileLikeEntry() {
 func (suite *fileTestSuite) TestWrite_FileLikeEntry() {
 	m := plugintest.NewMockWrite()
 	m.Attributes().SetSize(5)
-	// Called on both Flush and Release+Flush only.
-	m.On("Write", suite.ctx, []byte("hello")).Return(nil).Twice()
+	// Called on Flush only; there's nothing left to commit on Release+Flush.
+	m.On("Write", suite.ctx, []byte("hello")).Return(nil).Once()
 
 	f := newFile(nil, m)
 	var resp fuse.OpenResponse
@@ -388,6 +404,102 @@ func (suite *fileTestSuite) TestWrite_FileLikeEntry() {
 	m.AssertExpectations(suite.T())
 }
 
+func (suite *fileTestSuite) TestWrite_BlockWritableNonFileLikeEntry() {
+	m := plugintest.NewMockBlockWrite()
+	m.On("Write", suite.ctx, int64(5), []byte("hello")).Return(nil).Once()
+
+	f := newFile(nil, m)
+	var resp fuse.OpenResponse
+	handle, err := f.Open(suite.ctx, &fuse.OpenRequest{Flags: fuse.OpenWriteOnly}, &resp)
+	if !suite.NoError(err) || !suite.assertFileHandle(handle) {
+		suite.FailNow("Unusable handle")
+	}
+
+	// Write out of order
+	for _, req := range []fuse.WriteRequest{
+		{Offset: 3, Data: []byte("lo"), Handle: 1},
+		{Offset: 0, Data: []byte("hel"), Handle: 1},
+	} {
+		var writeResp fuse.WriteResponse
+		err = handle.(fs.HandleWriter).Write(suite.ctx, &req, &writeResp)
+		suite.NoError(err)
+		suite.Equal(len(req.Data), writeResp.Size)
+	}
+
+	err = handle.(fs.HandleReleaser).Release(suite.ctx, &fuse.ReleaseRequest{Handle: 1})
+	suite.NoError(err)
+	suite.Nil(f.buf)
+	suite.assertNoWriteBuffers()
+
+	m.AssertExpectations(suite.T())
+}
+
+func (suite *fileTestSuite) TestFsync_FileLikeEntry() {
+	m := plugintest.NewMockWrite()
+	m.Attributes().SetSize(5)
+	m.On("Write", suite.ctx, []byte("hello")).Return(nil).Once()
+	m.On("Write", suite.ctx, []byte("jello")).Return(nil).Once()
+
+	f := newFile(nil, m)
+	var resp fuse.OpenResponse
+	handle, err := f.Open(suite.ctx, &fuse.OpenRequest{Flags: fuse.OpenWriteOnly}, &resp)
+	if !suite.NoError(err) || !suite.assertFileHandle(handle) {
+		suite.FailNow("Unusable handle")
+	}
+
+	writeReq := fuse.WriteRequest{Offset: 0, Data: []byte("hello"), Handle: 1}
+	var writeResp fuse.WriteResponse
+	err = handle.(fs.HandleWriter).Write(suite.ctx, &writeReq, &writeResp)
+	suite.NoError(err)
+
+	err = f.Fsync(suite.ctx, &fuse.FsyncRequest{Handle: 1})
+	suite.NoError(err)
+	// Fsync keeps the handle open for more writes.
+	suite.NotNil(f.buf)
+
+	writeReq = fuse.WriteRequest{Offset: 0, Data: []byte("j"), Handle: 1}
+	err = handle.(fs.HandleWriter).Write(suite.ctx, &writeReq, &writeResp)
+	suite.NoError(err)
+
+	err = handle.(fs.HandleReleaser).Release(suite.ctx, &fuse.ReleaseRequest{Handle: 1})
+	suite.NoError(err)
+	suite.assertNoWriteBuffers()
+
+	m.AssertExpectations(suite.T())
+}
+
+func (suite *fileTestSuite) TestWriteAndTruncate_FileLikeEntry() {
+	m := plugintest.NewMockWrite()
+	m.Attributes().SetSize(0)
+	m.On("Write", suite.ctx, []byte("hel")).Return(nil).Once()
+
+	f := newFile(nil, m)
+	var resp fuse.OpenResponse
+	handle, err := f.Open(suite.ctx, &fuse.OpenRequest{Flags: fuse.OpenWriteOnly}, &resp)
+	if !suite.NoError(err) || !suite.assertFileHandle(handle) {
+		suite.FailNow("Unusable handle")
+	}
+
+	writeReq := fuse.WriteRequest{Offset: 0, Data: []byte("hello"), Handle: 1}
+	var writeResp fuse.WriteResponse
+	err = handle.(fs.HandleWriter).Write(suite.ctx, &writeReq, &writeResp)
+	suite.NoError(err)
+
+	setReq := fuse.SetattrRequest{Valid: fuse.SetattrHandle | fuse.SetattrSize, Handle: 1, Size: 3}
+	var setResp fuse.SetattrResponse
+	err = f.Setattr(suite.ctx, &setReq, &setResp)
+	suite.NoError(err)
+	suite.Equal(uint64(3), setResp.Attr.Size)
+
+	err = handle.(fs.HandleFlusher).Flush(suite.ctx, &fuse.FlushRequest{Handle: 1})
+	suite.NoError(err)
+
+	err = handle.(fs.HandleReleaser).Release(suite.ctx, &fuse.ReleaseRequest{Handle: 1})
+	suite.NoError(err)
+
+	m.AssertExpectations(suite.T())
+}
+
 func (suite *fileTestSuite) TestTruncateAndWrite_FileLikeEntry() {
 	m := plugintest.NewMockReadWrite()
 	m.Attributes().SetSize(4)
@@ -0,0 +1,143 @@
+package fuse
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	"github.com/puppetlabs/wash/plugin"
+	log "github.com/sirupsen/logrus"
+)
+
+// The kernel caches the nodes and attributes we return for as long as we tell it they're valid.
+// Those timeouts are derived from the TTL of the List that produced them so that the kernel
+// cache expires along with Wash's cache. When Wash's cache is cleared early - by `wash clear`,
+// or because an entry was written to, signaled, or deleted - we also notify the kernel so that
+// it doesn't keep serving stale nodes.
+
+// cacheTTL returns how long the kernel should cache results derived from listing parent.
+func cacheTTL(parent plugin.Entry) time.Duration {
+	ttl := plugin.TTLOf(parent, plugin.ListOp)
+	if ttl < 0 {
+		// Caching is disabled.
+		return 0
+	}
+	return ttl
+}
+
+// kernelInvalidator is implemented by fs.Server.
+type kernelInvalidator interface {
+	InvalidateNodeData(node fs.Node) error
+	InvalidateEntry(parent fs.Node, name string) error
+}
+
+// kernelCache tracks the nodes the kernel knows about by their entry's ID so that they can be
+// invalidated when Wash's cache is cleared.
+type kernelCache struct {
+	mux         sync.Mutex
+	invalidator kernelInvalidator
+	nodes       map[string]fs.Node
+	ids         map[fs.Node]string
+}
+
+func newKernelCache() *kernelCache {
+	return &kernelCache{nodes: make(map[string]fs.Node), ids: make(map[fs.Node]string)}
+}
+
+var kernel = newKernelCache()
+
+// setInvalidator sets where invalidations are sent. A nil invalidator stops tracking nodes.
+func (k *kernelCache) setInvalidator(invalidator kernelInvalidator) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	k.invalidator = invalidator
+	k.nodes = make(map[string]fs.Node)
+	k.ids = make(map[fs.Node]string)
+}
+
+// track records that node was returned to the kernel for the entry with the given ID.
+func (k *kernelCache) track(id string, node fs.Node) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	if k.invalidator == nil {
+		return
+	}
+	if old, ok := k.nodes[id]; ok {
+		delete(k.ids, old)
+	}
+	k.nodes[id] = node
+	k.ids[node] = id
+}
+
+// forget stops tracking node. It's called when the kernel forgets it.
+func (k *kernelCache) forget(node fs.Node) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	if id, ok := k.ids[node]; ok {
+		delete(k.ids, node)
+		delete(k.nodes, id)
+	}
+}
+
+// invalidate notifies the kernel that path and its descendants may have changed, and if
+// parentChanged then that path's parent's children may have changed.
+func (k *kernelCache) invalidate(path string, parentChanged bool) {
+	k.mux.Lock()
+	invalidator := k.invalidator
+	type dentry struct {
+		parent fs.Node
+		name   string
+	}
+	var nodes []fs.Node
+	var dentries []dentry
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	for id, node := range k.nodes {
+		if id == path {
+			nodes = append(nodes, node)
+		} else if strings.HasPrefix(id, prefix) {
+			// Descendants may no longer exist, so the kernel needs to look them up again.
+			parentID, cname := splitID(id)
+			if parent, ok := k.nodes[parentID]; ok {
+				dentries = append(dentries, dentry{parent, cname})
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	if parentChanged {
+		parentID, cname := splitID(path)
+		if parent, ok := k.nodes[parentID]; ok {
+			dentries = append(dentries, dentry{parent, cname})
+			nodes = append(nodes, parent)
+		}
+	}
+	k.mux.Unlock()
+
+	if invalidator == nil {
+		return
+	}
+	for _, d := range dentries {
+		logInvalidateErr(invalidator.InvalidateEntry(d.parent, d.name), "entry %v in %v", d.name, d.parent)
+	}
+	for _, node := range nodes {
+		logInvalidateErr(invalidator.InvalidateNodeData(node), "%v", node)
+	}
+}
+
+func logInvalidateErr(err error, format string, args ...interface{}) {
+	// ErrNotCached means there was nothing to invalidate.
+	if err != nil && err != fuse.ErrNotCached {
+		log.Debugf("FUSE: Invalidating "+format+" errored: %v", append(args, err)...)
+	}
+}
+
+// splitID returns the ID of the entry's parent and the entry's cname.
+func splitID(id string) (string, string) {
+	i := strings.LastIndex(id, "/")
+	parentID := id[:i]
+	if parentID == "" {
+		parentID = "/"
+	}
+	return parentID, id[i+1:]
+}
@@ -0,0 +1,122 @@
+package fuse
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	"github.com/puppetlabs/wash/plugin"
+	plugintest "github.com/puppetlabs/wash/plugin/test"
+	"github.com/stretchr/testify/suite"
+)
+
+// mockInvalidator records invalidations.
+type mockInvalidator struct {
+	invalidated []string
+}
+
+func (m *mockInvalidator) InvalidateNodeData(node fs.Node) error {
+	m.invalidated = append(m.invalidated, fmt.Sprintf("node %v", node))
+	return nil
+}
+
+func (m *mockInvalidator) InvalidateEntry(parent fs.Node, name string) error {
+	m.invalidated = append(m.invalidated, fmt.Sprintf("entry %v in %v", name, parent))
+	return fuse.ErrNotCached
+}
+
+type kernelCacheTestSuite struct {
+	suite.Suite
+	kernel      *kernelCache
+	invalidator *mockInvalidator
+}
+
+func (suite *kernelCacheTestSuite) SetupTest() {
+	suite.kernel = newKernelCache()
+	suite.invalidator = &mockInvalidator{}
+	suite.kernel.setInvalidator(suite.invalidator)
+}
+
+// trackDir tracks a directory node for an entry with the given ID.
+func (suite *kernelCacheTestSuite) trackDir(parent *dir, id string) *dir {
+	m := plugintest.NewMockBase()
+	m.SetTestID(id)
+	d := &dir{newFuseNode("d", parent, m)}
+	suite.kernel.track(id, d)
+	return d
+}
+
+func (suite *kernelCacheTestSuite) invalidated() []string {
+	sort.Strings(suite.invalidator.invalidated)
+	return suite.invalidator.invalidated
+}
+
+func (suite *kernelCacheTestSuite) TestInvalidate() {
+	root := suite.trackDir(nil, "/")
+	docker := suite.trackDir(root, "/docker")
+	containers := suite.trackDir(docker, "/docker/containers")
+	suite.trackDir(containers, "/docker/containers/redis")
+	suite.trackDir(root, "/aws")
+
+	suite.kernel.invalidate("/docker/containers", false)
+	suite.Equal([]string{
+		"entry redis in /docker/containers",
+		"node /docker/containers",
+		"node /docker/containers/redis",
+	}, suite.invalidated())
+}
+
+func (suite *kernelCacheTestSuite) TestInvalidateParentChanged() {
+	root := suite.trackDir(nil, "/")
+	suite.trackDir(root, "/docker")
+
+	suite.kernel.invalidate("/docker", true)
+	suite.Equal([]string{
+		"entry docker in /",
+		"node /",
+		"node /docker",
+	}, suite.invalidated())
+
+	// Entries the kernel hasn't looked up are still invalidated in their parent.
+	suite.invalidator.invalidated = nil
+	suite.kernel.invalidate("/aws", true)
+	suite.Equal([]string{"entry aws in /", "node /"}, suite.invalidated())
+}
+
+func (suite *kernelCacheTestSuite) TestForget() {
+	root := suite.trackDir(nil, "/")
+	docker := suite.trackDir(root, "/docker")
+	suite.kernel.forget(docker)
+
+	suite.kernel.invalidate("/docker", false)
+	suite.Empty(suite.invalidated())
+
+	// Forgetting a node that's been replaced keeps the replacement.
+	old := suite.trackDir(root, "/docker")
+	suite.trackDir(root, "/docker")
+	suite.kernel.forget(old)
+	suite.kernel.invalidate("/docker", false)
+	suite.Equal([]string{"node /docker"}, suite.invalidated())
+}
+
+func (suite *kernelCacheTestSuite) TestNoInvalidator() {
+	suite.kernel.setInvalidator(nil)
+	suite.trackDir(nil, "/")
+	suite.Empty(suite.kernel.nodes)
+}
+
+func (suite *kernelCacheTestSuite) TestCacheTTL() {
+	m := plugintest.NewMockBase()
+	m.SetTTLOf(plugin.ListOp, 30*time.Second)
+	suite.Equal(30*time.Second, cacheTTL(m))
+
+	m.DisableCachingFor(plugin.ListOp)
+	suite.Equal(time.Duration(0), cacheTTL(m))
+}
+
+func TestKernelCache(t *testing.T) {
+	suite.Run(t, new(kernelCacheTestSuite))
+}
@@ -0,0 +1,72 @@
+package fuse
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	log "github.com/sirupsen/logrus"
+)
+
+// ==== FUSE Symlink Interface ====
+
+// symlink represents a Linkable entry.
+type symlink struct {
+	fuseNode
+}
+
+var _ fs.Node = (*symlink)(nil)
+var _ = fs.NodeReadlinker(&symlink{})
+var _ = fs.NodeForgetter(&symlink{})
+
+func newSymlink(p *dir, e plugin.Linkable) *symlink {
+	return &symlink{newFuseNode("l", p, e)}
+}
+
+func (l *symlink) Attr(ctx context.Context, a *fuse.Attr) error {
+	entry, err := l.refind(ctx)
+	if err != nil {
+		activity.Warnf(ctx, "FUSE: Attr errored %v, %v", l, err)
+		return fuseErr(err)
+	}
+
+	applyAttr(a, plugin.Attributes(entry), os.ModeSymlink|0777, l.attrTTL())
+	// Links are always links, regardless of what mode the plugin reports.
+	a.Mode = os.ModeSymlink | a.Mode.Perm()
+	log.Debugf("FUSE: Attr %v: %+v", l, *a)
+	return nil
+}
+
+// Readlink returns the link's target. Absolute Wash paths are converted to paths relative to
+// the link so that they resolve within the mountpoint.
+func (l *symlink) Readlink(ctx context.Context, req *fuse.ReadlinkRequest) (string, error) {
+	entry, err := l.refind(ctx)
+	if err != nil {
+		activity.Warnf(ctx, "FUSE: Readlink errored %v, %v", l, err)
+		return "", fuseErr(err)
+	}
+
+	target, err := plugin.ReadlinkWithAnalytics(ctx, entry.(plugin.Linkable))
+	if err != nil {
+		activity.Warnf(ctx, "FUSE: Readlink %v errored: %v", l, err)
+		return "", fuseErr(err)
+	}
+	if path.IsAbs(target) {
+		if target, err = filepath.Rel(path.Dir(plugin.ID(entry)), target); err != nil {
+			activity.Warnf(ctx, "FUSE: Readlink %v errored: %v", l, err)
+			return "", err
+		}
+	}
+	activity.Record(ctx, "FUSE: Readlink %v: %v", l, target)
+	return target, nil
+}
+
+// Forget stops tracking the link when the kernel forgets it.
+func (l *symlink) Forget() {
+	kernel.forget(l)
+}
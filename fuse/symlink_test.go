@@ -0,0 +1,62 @@
+package fuse
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"bazil.org/fuse"
+	"github.com/puppetlabs/wash/plugin"
+	plugintest "github.com/puppetlabs/wash/plugin/test"
+	"github.com/stretchr/testify/suite"
+)
+
+type symlinkTestSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func (suite *symlinkTestSuite) newLink(target string, err error) *symlink {
+	m := plugintest.NewMockLink()
+	m.SetTestID("/docker/volumes/link")
+	m.On("Readlink", suite.ctx).Return(target, err)
+	return newSymlink(nil, m)
+}
+
+func (suite *symlinkTestSuite) TestAttr() {
+	l := suite.newLink("data", nil)
+	l.entry.(*plugintest.MockLink).Attributes().SetMode(0644)
+
+	var attr fuse.Attr
+	suite.NoError(l.Attr(suite.ctx, &attr))
+	suite.Equal(os.ModeSymlink|0644, attr.Mode)
+}
+
+func (suite *symlinkTestSuite) TestReadlink() {
+	for target, expected := range map[string]string{
+		"data":                     "data",
+		"../data":                  "../data",
+		"/docker/volumes/data":     "data",
+		"/docker/containers/redis": "../containers/redis",
+	} {
+		actual, err := suite.newLink(target, nil).Readlink(suite.ctx, &fuse.ReadlinkRequest{})
+		if suite.NoError(err) {
+			suite.Equal(expected, actual, target)
+		}
+	}
+
+	_, err := suite.newLink("", errors.New("failed")).Readlink(suite.ctx, &fuse.ReadlinkRequest{})
+	suite.EqualError(err, "failed")
+
+	_, err = suite.newLink("", plugin.NewTimeoutErr("readlink", time.Second, context.DeadlineExceeded)).Readlink(suite.ctx, &fuse.ReadlinkRequest{})
+	suite.Equal(fuse.Errno(syscall.ETIMEDOUT), err)
+}
+
+func TestSymlink(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	suite.Run(t, &symlinkTestSuite{ctx: ctx})
+	cancel()
+}
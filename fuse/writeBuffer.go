@@ -0,0 +1,90 @@
+package fuse
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// writeBufferDir is where pending writes are spooled. It's a variable so that the tests can
+// override it.
+var writeBufferDir = func() string {
+	cdir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "wash", "writes")
+	}
+	return filepath.Join(cdir, "wash", "writes")
+}()
+
+// writeBuffer holds a file's pending writes. It spools them to a temporary file so that
+// writing large files doesn't hold their content in memory, and so that writes can happen
+// at any offset. Its length is tracked separately from the temporary file's size so that
+// it only includes content that's been written or loaded.
+type writeBuffer struct {
+	file *os.File
+	size int64
+}
+
+func newWriteBuffer() (*writeBuffer, error) {
+	if err := os.MkdirAll(writeBufferDir, 0750); err != nil {
+		return nil, err
+	}
+	file, err := ioutil.TempFile(writeBufferDir, "write")
+	if err != nil {
+		return nil, err
+	}
+	return &writeBuffer{file: file}, nil
+}
+
+// Len returns the length of the buffered content.
+func (b *writeBuffer) Len() int64 {
+	return b.size
+}
+
+// WriteAt writes p at off, extending the buffer if needed. Gaps are filled with zeros.
+func (b *writeBuffer) WriteAt(p []byte, off int64) (int, error) {
+	n, err := b.file.WriteAt(p, off)
+	if end := off + int64(n); end > b.size {
+		b.size = end
+	}
+	return n, err
+}
+
+// ReadAt reads from the buffered content. It returns io.EOF if it reads past the end.
+func (b *writeBuffer) ReadAt(p []byte, off int64) (int, error) {
+	if off >= b.size {
+		return 0, io.EOF
+	}
+	if remaining := b.size - off; int64(len(p)) > remaining {
+		n, err := b.file.ReadAt(p[:remaining], off)
+		if err == nil {
+			err = io.EOF
+		}
+		return n, err
+	}
+	return b.file.ReadAt(p, off)
+}
+
+// Truncate changes the buffer's length. Growing it fills the new space with zeros.
+func (b *writeBuffer) Truncate(size int64) error {
+	if err := b.file.Truncate(size); err != nil {
+		return err
+	}
+	b.size = size
+	return nil
+}
+
+// Reader returns a reader for the buffered content.
+func (b *writeBuffer) Reader() io.Reader {
+	return io.NewSectionReader(b.file, 0, b.size)
+}
+
+// Close removes the temporary file.
+func (b *writeBuffer) Close() error {
+	err := b.file.Close()
+	if rmErr := os.Remove(b.file.Name()); err == nil {
+		err = rmErr
+	}
+	return err
+}
@@ -0,0 +1,166 @@
+package fuse
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"syscall"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	log "github.com/sirupsen/logrus"
+)
+
+// Extended attributes expose an entry's Wash properties to tools that only understand the
+// filesystem, such as shell scripts and file managers.
+// - user.wash.id is the entry's ID
+// - user.wash.kind is the entry's type ID
+// - user.wash.actions is a comma-separated list of the entry's supported actions
+// - user.wash.meta.<key> is the <key> field of the entry's partial metadata. Strings are
+//   returned as-is, and other values are JSON-encoded.
+// - user.wash.signal can only be set; setting it sends the value as a signal to the entry
+const (
+	xattrPrefix     = "user.wash."
+	xattrID         = xattrPrefix + "id"
+	xattrKind       = xattrPrefix + "kind"
+	xattrActions    = xattrPrefix + "actions"
+	xattrMetaPrefix = xattrPrefix + "meta."
+	xattrSignal     = xattrPrefix + "signal"
+)
+
+var _ = fs.NodeGetxattrer(&fuseNode{})
+var _ = fs.NodeListxattrer(&fuseNode{})
+var _ = fs.NodeSetxattrer(&fuseNode{})
+
+// Getxattr gets one of the entry's extended attributes.
+func (f *fuseNode) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) error {
+	// Extended attributes are requested frequently by tools like ls. Log them to debug like
+	// Lookup, but leave them out of activity.
+	log.Debugf("FUSE: Getxattr %v on %v", req.Name, f)
+
+	entry, err := f.refind(ctx)
+	if err != nil {
+		activity.Warnf(ctx, "FUSE: Getxattr errored %v, %v", f, err)
+		return fuseErr(err)
+	}
+
+	value, ok, err := xattr(entry, req.Name)
+	if err != nil {
+		activity.Warnf(ctx, "FUSE: Getxattr %v on %v errored: %v", req.Name, f, err)
+		return err
+	}
+	if !ok {
+		return fuse.ErrNoXattr
+	}
+	resp.Xattr = value
+	return nil
+}
+
+// Listxattr lists the entry's extended attributes.
+func (f *fuseNode) Listxattr(ctx context.Context, req *fuse.ListxattrRequest, resp *fuse.ListxattrResponse) error {
+	log.Debugf("FUSE: Listxattr %v", f)
+
+	entry, err := f.refind(ctx)
+	if err != nil {
+		activity.Warnf(ctx, "FUSE: Listxattr errored %v, %v", f, err)
+		return fuseErr(err)
+	}
+
+	resp.Append(xattrNames(entry)...)
+	return nil
+}
+
+// Setxattr only supports user.wash.signal, which signals the entry.
+func (f *fuseNode) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) error {
+	if req.Name != xattrSignal {
+		log.Debugf("FUSE: Setxattr %v unsupported on %v", req.Name, f)
+		return fuse.ENOTSUP
+	}
+
+	entry, err := f.refind(ctx)
+	if err != nil {
+		activity.Warnf(ctx, "FUSE: Setxattr errored %v, %v", f, err)
+		return fuseErr(err)
+	}
+	if !plugin.SignalAction().IsSupportedOn(entry) {
+		activity.Warnf(ctx, "FUSE: Signal unsupported on %v", f)
+		return fuse.ENOTSUP
+	}
+
+	signal := strings.TrimSpace(string(req.Xattr))
+	activity.Record(ctx, "FUSE: Signal %v: %v", f, signal)
+	if err := plugin.SignalWithAnalytics(ctx, entry.(plugin.Signalable), signal); err != nil {
+		activity.Warnf(ctx, "FUSE: Signal %v on %v errored: %v", signal, f, err)
+		if plugin.IsInvalidInputErr(err) {
+			return fuse.Errno(syscall.EINVAL)
+		}
+		return fuseErr(err)
+	}
+	return nil
+}
+
+// xattrNames returns the names of the readable extended attributes for entry.
+func xattrNames(entry plugin.Entry) []string {
+	names := []string{xattrID, xattrKind, xattrActions}
+	meta := plugin.PartialMetadata(entry)
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, xattrMetaPrefix+key)
+	}
+	sort.Strings(keys)
+	return append(names, keys...)
+}
+
+// xattr returns the value of entry's extended attribute name. It returns false if the
+// attribute doesn't exist.
+func xattr(entry plugin.Entry, name string) ([]byte, bool, error) {
+	switch name {
+	case xattrID:
+		return []byte(plugin.ID(entry)), true, nil
+	case xattrKind:
+		return []byte(plugin.TypeID(entry)), true, nil
+	case xattrActions:
+		actions := plugin.SupportedActionsOf(entry)
+		sort.Strings(actions)
+		return []byte(strings.Join(actions, ",")), true, nil
+	}
+
+	if !strings.HasPrefix(name, xattrMetaPrefix) {
+		return nil, false, nil
+	}
+	value, ok := plugin.PartialMetadata(entry)[strings.TrimPrefix(name, xattrMetaPrefix)]
+	if !ok {
+		return nil, false, nil
+	}
+	if str, ok := value.(string); ok {
+		return []byte(str), true, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// file's extended attribute methods hold its lock because its entry's updated by other calls.
+
+func (f *file) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.fuseNode.Getxattr(ctx, req, resp)
+}
+
+func (f *file) Listxattr(ctx context.Context, req *fuse.ListxattrRequest, resp *fuse.ListxattrResponse) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.fuseNode.Listxattr(ctx, req, resp)
+}
+
+func (f *file) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.fuseNode.Setxattr(ctx, req)
+}
@@ -0,0 +1,116 @@
+package fuse
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+
+	"bazil.org/fuse"
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	plugintest "github.com/puppetlabs/wash/plugin/test"
+	"github.com/stretchr/testify/suite"
+)
+
+type mockSignal struct {
+	plugintest.MockBase
+}
+
+func newMockSignal() *mockSignal {
+	m := &mockSignal{plugintest.MockBase{EntryBase: plugin.NewEntry("mocks")}}
+	m.SetTestID("/mocks")
+	return m
+}
+
+func (m *mockSignal) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(m, "mocks").AddSignal("start", "Starts the entry")
+}
+
+func (m *mockSignal) Signal(ctx context.Context, signal string) error {
+	return m.Called(ctx, signal).Error(0)
+}
+
+type xattrTestSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func (suite *xattrTestSuite) SetupTest() {
+	plugin.SetTestCache(datastore.NewMemCache())
+}
+
+func (suite *xattrTestSuite) TearDownTest() {
+	plugin.UnsetTestCache()
+}
+
+func (suite *xattrTestSuite) getxattr(f *file, name string) (string, error) {
+	var resp fuse.GetxattrResponse
+	err := f.Getxattr(suite.ctx, &fuse.GetxattrRequest{Name: name}, &resp)
+	return string(resp.Xattr), err
+}
+
+func (suite *xattrTestSuite) TestListxattr() {
+	m := plugintest.NewMockRead()
+	m.SetPartialMetadata(map[string]interface{}{"state": "running", "cpus": 2})
+
+	var resp fuse.ListxattrResponse
+	suite.NoError(newFile(nil, m).Listxattr(suite.ctx, &fuse.ListxattrRequest{}, &resp))
+	suite.Equal("user.wash.id\x00user.wash.kind\x00user.wash.actions\x00user.wash.meta.cpus\x00user.wash.meta.state\x00", string(resp.Xattr))
+}
+
+func (suite *xattrTestSuite) TestGetxattr() {
+	m := plugintest.NewMockRead()
+	m.SetPartialMetadata(map[string]interface{}{"state": "running", "tags": map[string]interface{}{"env": "prod"}})
+	f := newFile(nil, m)
+
+	value, err := suite.getxattr(f, "user.wash.id")
+	suite.NoError(err)
+	suite.Equal("/mockr", value)
+
+	value, err = suite.getxattr(f, "user.wash.kind")
+	suite.NoError(err)
+	suite.Equal(plugin.TypeID(m), value)
+
+	value, err = suite.getxattr(f, "user.wash.actions")
+	suite.NoError(err)
+	suite.Equal("read", value)
+
+	value, err = suite.getxattr(f, "user.wash.meta.state")
+	suite.NoError(err)
+	suite.Equal("running", value)
+
+	value, err = suite.getxattr(f, "user.wash.meta.tags")
+	suite.NoError(err)
+	suite.Equal(`{"env":"prod"}`, value)
+
+	for _, name := range []string{"user.wash.meta.missing", "user.wash.signal", "user.other"} {
+		_, err = suite.getxattr(f, name)
+		suite.Equal(fuse.ErrNoXattr, err, name)
+	}
+}
+
+func (suite *xattrTestSuite) TestSetxattrSignal() {
+	m := newMockSignal()
+	m.On("Signal", suite.ctx, "start").Return(nil).Once()
+	f := newFile(nil, m)
+
+	suite.NoError(f.Setxattr(suite.ctx, &fuse.SetxattrRequest{Name: "user.wash.signal", Xattr: []byte("START\n")}))
+	suite.Equal(fuse.Errno(syscall.EINVAL), f.Setxattr(suite.ctx, &fuse.SetxattrRequest{Name: "user.wash.signal", Xattr: []byte("stop")}))
+	suite.Equal(fuse.ENOTSUP, f.Setxattr(suite.ctx, &fuse.SetxattrRequest{Name: "user.wash.id", Xattr: []byte("/other")}))
+	m.AssertExpectations(suite.T())
+
+	m.On("Signal", suite.ctx, "start").Return(errors.New("failed")).Once()
+	suite.EqualError(f.Setxattr(suite.ctx, &fuse.SetxattrRequest{Name: "user.wash.signal", Xattr: []byte("start")}), "failed")
+}
+
+func (suite *xattrTestSuite) TestSetxattrSignalUnsupported() {
+	f := newFile(nil, plugintest.NewMockRead())
+	suite.Equal(fuse.ENOTSUP, f.Setxattr(suite.ctx, &fuse.SetxattrRequest{Name: "user.wash.signal", Xattr: []byte("start")}))
+}
+
+func TestXattr(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	suite.Run(t, &xattrTestSuite{ctx: ctx})
+	cancel()
+}
@@ -0,0 +1,208 @@
+// Package metrics collects Prometheus metrics about a running Wash server. They're
+// exposed by the API's /metrics endpoint.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "wash"
+
+var (
+	pluginCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "plugin",
+		Name:      "calls_total",
+		Help:      "Plugin method invocations that weren't answered by the cache.",
+	}, []string{"plugin", "method"})
+	pluginErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "plugin",
+		Name:      "errors_total",
+		Help:      "Plugin method invocations that errored.",
+	}, []string{"plugin", "method"})
+	pluginDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "plugin",
+		Name:      "call_duration_seconds",
+		Help:      "How long plugin method invocations that weren't answered by the cache took.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 9),
+	}, []string{"plugin", "method"})
+
+	cacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "hits_total",
+		Help:      "Cache lookups that found a value.",
+	}, []string{"cache"})
+	cacheMisses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "misses_total",
+		Help:      "Cache lookups that had to generate a value.",
+	}, []string{"cache"})
+	cacheEvictions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "evictions_total",
+		Help:      "Values removed from the cache before they expired, by reason (limit, deleted or flushed).",
+	}, []string{"cache", "reason"})
+
+	openStreams = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "open_streams",
+		Help:      "Streams that are open.",
+	})
+	execSessions = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "exec_sessions",
+		Help:      "Interactive exec sessions that are in progress.",
+	})
+	externalProcesses = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "external_plugin",
+		Name:      "processes",
+		Help:      "External plugin script invocations that are running.",
+	})
+	externalProcessesStarted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "external_plugin",
+		Name:      "processes_started_total",
+		Help:      "External plugin script invocations that were started.",
+	})
+
+	fuseOps = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "fuse",
+		Name:      "operations_total",
+		Help:      "Requests received from the kernel, by operation.",
+	}, []string{"op"})
+
+	apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "requests_total",
+		Help:      "API requests, by route, HTTP method and status code.",
+	}, []string{"route", "method", "code"})
+	apiDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "request_duration_seconds",
+		Help:      "How long API requests took, by route and HTTP method.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 9),
+	}, []string{"route", "method"})
+)
+
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		pluginCalls,
+		pluginErrors,
+		pluginDuration,
+		cacheHits,
+		cacheMisses,
+		cacheEvictions,
+		openStreams,
+		execSessions,
+		externalProcesses,
+		externalProcessesStarted,
+		fuseOps,
+		apiRequests,
+		apiDuration,
+	)
+}
+
+// Handler returns an HTTP handler that serves the metrics in the Prometheus
+// text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObservePluginCall records an invocation of the plugin's method that wasn't
+// answered by the cache.
+func ObservePluginCall(plugin string, method string, duration time.Duration, err error) {
+	pluginCalls.WithLabelValues(plugin, method).Inc()
+	pluginDuration.WithLabelValues(plugin, method).Observe(duration.Seconds())
+	if err != nil {
+		pluginErrors.WithLabelValues(plugin, method).Inc()
+	}
+}
+
+// ObservePluginCacheHit records an invocation of the plugin's method that was
+// answered by the cache. Only its errors are counted since it didn't call the
+// plugin.
+func ObservePluginCacheHit(plugin string, method string, err error) {
+	if err != nil {
+		pluginErrors.WithLabelValues(plugin, method).Inc()
+	}
+}
+
+// CacheHit records a lookup that found a value in the named cache.
+func CacheHit(cache string) {
+	cacheHits.WithLabelValues(cache).Inc()
+}
+
+// CacheMiss records a lookup that didn't find a value in the named cache.
+func CacheMiss(cache string) {
+	cacheMisses.WithLabelValues(cache).Inc()
+}
+
+// CacheEvictions records n values that were removed from the named cache for
+// the given reason.
+func CacheEvictions(cache string, reason string, n int) {
+	cacheEvictions.WithLabelValues(cache, reason).Add(float64(n))
+}
+
+// StreamOpened records that a stream was opened. StreamClosed should be called
+// when it's closed.
+func StreamOpened() {
+	openStreams.Inc()
+}
+
+// StreamClosed records that a stream was closed.
+func StreamClosed() {
+	openStreams.Dec()
+}
+
+// ExecSessionStarted records that an interactive exec session started.
+// ExecSessionEnded should be called when it ends.
+func ExecSessionStarted() {
+	execSessions.Inc()
+}
+
+// ExecSessionEnded records that an interactive exec session ended.
+func ExecSessionEnded() {
+	execSessions.Dec()
+}
+
+// ExternalProcessStarted records that an external plugin's script was started.
+// ExternalProcessExited should be called once it's exited.
+func ExternalProcessStarted() {
+	externalProcesses.Inc()
+	externalProcessesStarted.Inc()
+}
+
+// ExternalProcessExited records that an external plugin's script exited.
+func ExternalProcessExited() {
+	externalProcesses.Dec()
+}
+
+// FUSEOperation records a request from the kernel.
+func FUSEOperation(op string) {
+	fuseOps.WithLabelValues(op).Inc()
+}
+
+// ObserveAPIRequest records a request to the route, which is its path template
+// (e.g. /plugins/{name}).
+func ObserveAPIRequest(route string, method string, code int, duration time.Duration) {
+	apiRequests.WithLabelValues(route, method, strconv.Itoa(code)).Inc()
+	apiDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObservePluginCall(t *testing.T) {
+	ObservePluginCall("mine", "List", time.Millisecond, nil)
+	ObservePluginCall("mine", "List", time.Millisecond, errors.New("failed"))
+	ObservePluginCacheHit("mine", "List", nil)
+	ObservePluginCacheHit("mine", "List", errors.New("failed"))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(pluginCalls.WithLabelValues("mine", "List")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(pluginErrors.WithLabelValues("mine", "List")))
+}
+
+func TestGauges(t *testing.T) {
+	StreamOpened()
+	StreamOpened()
+	StreamClosed()
+	assert.Equal(t, 1.0, testutil.ToFloat64(openStreams))
+
+	ExternalProcessStarted()
+	ExternalProcessExited()
+	ExternalProcessStarted()
+	assert.Equal(t, 1.0, testutil.ToFloat64(externalProcesses))
+	assert.Equal(t, 2.0, testutil.ToFloat64(externalProcessesStarted))
+}
+
+func TestHandler(t *testing.T) {
+	CacheHit("test")
+	CacheEvictions("test", "deleted", 3)
+	FUSEOperation("Lookup")
+	ObserveAPIRequest("/fs/list", "GET", 200, time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := ioutil.ReadAll(rec.Body)
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(body), `wash_cache_hits_total{cache="test"} 1`)
+		assert.Contains(t, string(body), `wash_cache_evictions_total{cache="test",reason="deleted"} 3`)
+		assert.Contains(t, string(body), `wash_fuse_operations_total{op="Lookup"} 1`)
+		assert.Contains(t, string(body), `wash_api_requests_total{code="200",method="GET",route="/fs/list"} 1`)
+		assert.Contains(t, string(body), "go_goroutines")
+	}
+}
@@ -8,6 +8,7 @@ const (
 	UnsupportedSignature MethodSignature = iota
 	DefaultSignature
 	BlockReadableSignature
+	BlockWritableSignature
 )
 
 // Action represents a Wash action.
@@ -72,6 +73,9 @@ var writeAction = newAction("write", "Writable", func(e Entry) MethodSignature {
 	if _, ok := e.(Writable); ok {
 		return DefaultSignature
 	}
+	if _, ok := e.(BlockWritable); ok {
+		return BlockWritableSignature
+	}
 	return UnsupportedSignature
 })
 
@@ -96,6 +100,13 @@ var signalAction = newAction("signal", "Signalable", func(e Entry) MethodSignatu
 	return UnsupportedSignature
 })
 
+var readlinkAction = newAction("readlink", "Linkable", func(e Entry) MethodSignature {
+	if _, ok := e.(Linkable); ok {
+		return DefaultSignature
+	}
+	return UnsupportedSignature
+})
+
 // ListAction represents the list action
 func ListAction() Action {
 	return listAction
@@ -131,6 +142,11 @@ func SignalAction() Action {
 	return signalAction
 }
 
+// ReadlinkAction represents the readlink action
+func ReadlinkAction() Action {
+	return readlinkAction
+}
+
 // Actions returns all of the available Wash actions as a map
 // of <action_name> => <action_object>.
 func Actions() map[string]Action {
@@ -35,6 +35,13 @@ func WriteWithAnalytics(ctx context.Context, w Writable, b []byte) error {
 	return Write(ctx, w, b)
 }
 
+// WriteFromWithAnalytics is a wrapper to plugin.WriteFrom. Use it when you need to report a
+// 'Write' invocation to analytics. Otherwise, use plugin.WriteFrom.
+func WriteFromWithAnalytics(ctx context.Context, e Entry, size int64, r io.Reader) error {
+	submitMethodInvocation(ctx, e, "Write")
+	return WriteFrom(ctx, e, size, r)
+}
+
 // ExecWithAnalytics is a wrapper to e#Exec. Use it when you need to report an 'Exec'
 // invocation to analytics. Otherwise, use e#Exec.
 func ExecWithAnalytics(ctx context.Context, e Execable, cmd string, args []string, opts ExecOptions) (ExecCommand, error) {
@@ -49,6 +56,13 @@ func SignalWithAnalytics(ctx context.Context, s Signalable, signal string) error
 	return Signal(ctx, s, signal)
 }
 
+// ReadlinkWithAnalytics is a wrapper to plugin.Readlink. Use it when you need to report a
+// 'Readlink' invocation to analytics. Otherwise, use plugin.Readlink.
+func ReadlinkWithAnalytics(ctx context.Context, l Linkable) (string, error) {
+	submitMethodInvocation(ctx, l, "Readlink")
+	return Readlink(ctx, l)
+}
+
 // DeleteWithAnalytics is a wrapper to plugin.Delete. Use it when you need to report a
 // 'Delete' invocation to analytics. Otherwise, use plugin.Delete.
 func DeleteWithAnalytics(ctx context.Context, d Deletable) (bool, error) {
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
 
 	"github.com/aws/aws-sdk-go/aws/session"
 	ec2Client "github.com/aws/aws-sdk-go/service/ec2"
@@ -14,15 +15,17 @@ type ec2Dir struct {
 	plugin.EntryBase
 	session *session.Session
 	client  *ec2Client.EC2
+	winrm   *transport.WinRMConfig
 }
 
-func newEC2Dir(session *session.Session) *ec2Dir {
+func newEC2Dir(session *session.Session, winrm *transport.WinRMConfig) *ec2Dir {
 	ec2Dir := &ec2Dir{
 		EntryBase: plugin.NewEntry("ec2"),
 	}
 	ec2Dir.DisableDefaultCaching()
 	ec2Dir.session = session
 	ec2Dir.client = ec2Client.New(session)
+	ec2Dir.winrm = winrm
 	return ec2Dir
 }
 
@@ -37,5 +40,5 @@ func (e *ec2Dir) ChildSchemas() []*plugin.EntrySchema {
 }
 
 func (e *ec2Dir) List(ctx context.Context) ([]plugin.Entry, error) {
-	return []plugin.Entry{newEC2InstancesDir(ctx, e.session, e.client)}, nil
+	return []plugin.Entry{newEC2InstancesDir(ctx, e.session, e.client, e.winrm)}, nil
 }
@@ -2,8 +2,11 @@ package aws
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/base64"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -18,7 +21,7 @@ import (
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 	"github.com/puppetlabs/wash/transport"
-	"github.com/puppetlabs/wash/volume"
+	"golang.org/x/crypto/ssh"
 )
 
 // ec2Instance represents an EC2 instance
@@ -27,6 +30,8 @@ type ec2Instance struct {
 	id                      string
 	session                 *session.Session
 	client                  *ec2Client.EC2
+	winrm                   *transport.WinRMConfig
+	isWindows               bool
 	latestConsoleOutputOnce sync.Once
 	hasLatestConsoleOutput  bool
 }
@@ -43,7 +48,7 @@ const (
 	EC2InstanceStopped           = 80
 )
 
-func newEC2Instance(ctx context.Context, inst *ec2Client.Instance, session *session.Session, client *ec2Client.EC2) *ec2Instance {
+func newEC2Instance(ctx context.Context, inst *ec2Client.Instance, session *session.Session, client *ec2Client.EC2, winrm *transport.WinRMConfig) *ec2Instance {
 	id := awsSDK.StringValue(inst.InstanceId)
 	name := id
 	// AWS has a practice of using a tag with the key 'Name' as the display name in the console, so
@@ -62,6 +67,8 @@ func newEC2Instance(ctx context.Context, inst *ec2Client.Instance, session *sess
 	ec2Instance.id = id
 	ec2Instance.session = session
 	ec2Instance.client = client
+	ec2Instance.winrm = winrm
+	ec2Instance.isWindows = strings.EqualFold(awsSDK.StringValue(inst.Platform), "windows")
 
 	attributes, metadata := getAttributesAndMetadata(inst)
 	ec2Instance.
@@ -180,7 +187,7 @@ func (inst *ec2Instance) ChildSchemas() []*plugin.EntrySchema {
 	return []*plugin.EntrySchema{
 		(&ec2InstanceConsoleOutput{}).Schema(),
 		(&plugin.MetadataJSONFile{}).Schema(),
-		(&volume.FS{}).Schema(),
+		(&transport.SFTP{}).Schema(),
 	}
 }
 
@@ -215,9 +222,9 @@ func (inst *ec2Instance) List(ctx context.Context) ([]plugin.Entry, error) {
 		entries = append(entries, latestConsoleOutput)
 	}
 
-	// Include a view of the remote filesystem using volume.FS. Use a small maxdepth because
-	// VMs can have lots of files and SSH is fast.
-	entries = append(entries, volume.NewFS(ctx, "fs", inst, 3))
+	// Include a view of the remote filesystem using SFTP, which falls back to exec if needed.
+	// Use a small maxdepth because VMs can have lots of files and SSH is fast.
+	entries = append(entries, transport.NewFS(ctx, "fs", inst, 3))
 
 	return entries, nil
 }
@@ -273,24 +280,34 @@ func (inst *ec2Instance) Delete(ctx context.Context) (bool, error) {
 }
 
 func (inst *ec2Instance) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
-	// TBD: how to get WinRM connection info. Only work with Kerberos? Require a mini-inventory from wash.yaml?
+	if inst.winrm.UsesWinRM(inst.id, inst.isWindows) {
+		identity, err := inst.WinRMIdentity(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return transport.ExecWinRM(ctx, identity, append([]string{cmd}, args...), opts)
+	}
 
-	meta, err := inst.Metadata(ctx)
+	identity, err := inst.SSHIdentity(ctx)
 	if err != nil {
 		return nil, err
 	}
-	var hostname string
+	return transport.ExecSSH(ctx, identity, append([]string{cmd}, args...), opts)
+}
+
+func (inst *ec2Instance) hostname(ctx context.Context, meta plugin.JSONObject) (string, error) {
 	if name, ok := meta["PublicDnsName"]; ok && name != nil {
-		hostname = name.(string)
+		return name.(string), nil
 	} else if ipaddr, ok := meta["PublicIpAddress"]; ok && ipaddr != nil {
-		hostname = ipaddr.(string)
+		return ipaddr.(string), nil
 	} else if ipaddr, ok := meta["PrivateIpAddress"]; ok && ipaddr != nil {
-		hostname = ipaddr.(string)
-		activity.Record(ctx, "No public address was found for %v, trying private IP address %v", inst, hostname)
-	} else {
-		return nil, fmt.Errorf("No available interface found for %v", inst)
+		activity.Record(ctx, "No public address was found for %v, trying private IP address %v", inst, ipaddr)
+		return ipaddr.(string), nil
 	}
+	return "", fmt.Errorf("No available interface found for %v", inst)
+}
 
+func (inst *ec2Instance) keyFile(ctx context.Context, meta plugin.JSONObject) string {
 	var identityfile string
 	if keyname, ok := meta["KeyName"]; ok && keyname != nil {
 		if homedir, err := os.UserHomeDir(); err != nil {
@@ -299,6 +316,84 @@ func (inst *ec2Instance) Exec(ctx context.Context, cmd string, args []string, op
 			identityfile = (filepath.Join(homedir, ".ssh", (keyname.(string) + ".pem")))
 		}
 	}
+	return identityfile
+}
+
+// WinRMIdentity returns the identity that's used to connect to a Windows instance. The
+// user defaults to Administrator. If a password isn't configured, the instance's
+// Administrator password is retrieved from EC2 and decrypted with its key pair.
+func (inst *ec2Instance) WinRMIdentity(ctx context.Context) (transport.WinRMIdentity, error) {
+	meta, err := inst.Metadata(ctx)
+	if err != nil {
+		return transport.WinRMIdentity{}, err
+	}
+	hostname, err := inst.hostname(ctx, meta)
+	if err != nil {
+		return transport.WinRMIdentity{}, err
+	}
+
+	identity := inst.winrm.Identity(hostname)
+	if identity.User == "" {
+		identity.User = "Administrator"
+	}
+	if identity.Password == "" {
+		keyfile := inst.keyFile(ctx, meta)
+		if keyfile == "" {
+			return transport.WinRMIdentity{}, fmt.Errorf("%v has no key pair to decrypt its password, so aws.winrm.password must be configured", inst)
+		}
+		if identity.Password, err = inst.windowsPassword(ctx, keyfile); err != nil {
+			return transport.WinRMIdentity{}, err
+		}
+	}
+	return identity, nil
+}
+
+func (inst *ec2Instance) windowsPassword(ctx context.Context, keyfile string) (string, error) {
+	resp, err := inst.client.GetPasswordDataWithContext(ctx, &ec2Client.GetPasswordDataInput{
+		InstanceId: awsSDK.String(inst.id),
+	})
+	if err != nil {
+		return "", err
+	}
+	passwordData := strings.TrimSpace(awsSDK.StringValue(resp.PasswordData))
+	if passwordData == "" {
+		return "", fmt.Errorf("the password for %v is not available yet", inst)
+	}
+	encrypted, err := base64.StdEncoding.DecodeString(passwordData)
+	if err != nil {
+		return "", fmt.Errorf("could not decode the password for %v: %v", inst, err)
+	}
+
+	keyPEM, err := ioutil.ReadFile(keyfile)
+	if err != nil {
+		return "", fmt.Errorf("could not read the key to decrypt the password for %v: %v", inst, err)
+	}
+	key, err := ssh.ParseRawPrivateKey(keyPEM)
+	if err != nil {
+		return "", fmt.Errorf("could not parse %v: %v", keyfile, err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("%v is not an RSA key, so it can't decrypt the password for %v", keyfile, inst)
+	}
+	password, err := rsa.DecryptPKCS1v15(rand.Reader, rsaKey, encrypted)
+	if err != nil {
+		return "", fmt.Errorf("could not decrypt the password for %v: %v", inst, err)
+	}
+	return string(password), nil
+}
+
+// SSHIdentity returns the identity that's used to connect to the instance.
+func (inst *ec2Instance) SSHIdentity(ctx context.Context) (transport.Identity, error) {
+	meta, err := inst.Metadata(ctx)
+	if err != nil {
+		return transport.Identity{}, err
+	}
+	hostname, err := inst.hostname(ctx, meta)
+	if err != nil {
+		return transport.Identity{}, err
+	}
+	identityfile := inst.keyFile(ctx, meta)
 
 	var fallbackuser string
 	// Scan console output for user name instance was provisioned with. Set to ec2-user if not found
@@ -319,7 +414,7 @@ func (inst *ec2Instance) Exec(ctx context.Context, cmd string, args []string, op
 	//
 	// fallbackuser and identiyfile can be overridden in ~/.ssh/config.
 	//
-	return transport.ExecSSH(ctx, transport.Identity{Host: hostname, FallbackUser: fallbackuser, IdentityFile: identityfile}, append([]string{cmd}, args...), opts)
+	return transport.Identity{Host: hostname, FallbackUser: fallbackuser, IdentityFile: identityfile}, nil
 }
 
 func (inst *ec2Instance) Signal(ctx context.Context, signal string) error {
@@ -353,14 +448,21 @@ func (inst *ec2Instance) Signal(ctx context.Context, signal string) error {
 }
 
 const ec2InstanceDescription = `
-This is an EC2 instance. Its Exec action uses SSH. It will look up port, user,
-and other configuration by exact hostname match from default SSH config files.
-If present, a local SSH agent will be used for authentication. Lots of SSH
-configuration is currently omitted, such as global known hosts files, finding
-known hosts from the config, identity file from config… pretty much everything
-but port and user from config as enumerated in
-https://github.com/kevinburke/ssh_config/blob/0.5/validators.go. The known hosts
-file will be ignored if StrictHostKeyChecking=no, such as in
+This is an EC2 instance. Its Exec action uses WinRM for Windows instances and SSH
+for everything else. WinRM connects over HTTPS as Administrator; if a password
+isn't configured, the instance's password is retrieved from EC2 and decrypted
+with its key pair at ~/.ssh/<KeyName>.pem. WinRM settings, including instances
+that should always use WinRM, are configured under aws.winrm in Wash's config.
+
+SSH looks up the instance's configuration by hostname from your user and global
+SSH config files (~/.ssh/config and /etc/ssh/ssh_config). Port, User, IdentityFile,
+IdentitiesOnly, CertificateFile, UserKnownHostsFile, GlobalKnownHostsFile,
+HostKeyAlias, ConnectTimeout and ServerAliveInterval are supported, and instances
+behind a bastion can be reached via ProxyJump or ProxyCommand. If present, a local
+SSH agent will be used for authentication, and encrypted private keys prompt for
+their passphrase. Match blocks aren't supported. New hosts are rejected if
+StrictHostKeyChecking=yes, and the known hosts files are ignored if
+StrictHostKeyChecking=no, such as in
 
 Host *.compute.amazonaws.com
   StrictHostKeyChecking no
@@ -8,6 +8,7 @@ import (
 	ec2Client "github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
 )
 
 // ec2InstancesDir represents the ec2/instances
@@ -19,14 +20,16 @@ type ec2InstancesDir struct {
 	plugin.EntryBase
 	session *session.Session
 	client  *ec2Client.EC2
+	winrm   *transport.WinRMConfig
 }
 
-func newEC2InstancesDir(ctx context.Context, session *session.Session, client *ec2Client.EC2) *ec2InstancesDir {
+func newEC2InstancesDir(ctx context.Context, session *session.Session, client *ec2Client.EC2, winrm *transport.WinRMConfig) *ec2InstancesDir {
 	ec2InstancesDir := &ec2InstancesDir{
 		EntryBase: plugin.NewEntry("instances"),
 	}
 	ec2InstancesDir.session = session
 	ec2InstancesDir.client = client
+	ec2InstancesDir.winrm = winrm
 	if _, err := plugin.List(ctx, ec2InstancesDir); err != nil {
 		ec2InstancesDir.MarkInaccessible(ctx, err)
 	}
@@ -67,6 +70,7 @@ func (is *ec2InstancesDir) List(ctx context.Context) ([]plugin.Entry, error) {
 				instance,
 				is.session,
 				is.client,
+				is.winrm,
 			)
 		}
 
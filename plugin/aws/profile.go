@@ -10,6 +10,7 @@ import (
 	stsClient "github.com/aws/aws-sdk-go/service/sts"
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
 )
 
 // profile represents an AWS profile
@@ -19,7 +20,7 @@ type profile struct {
 	resourcesDir []plugin.Entry
 }
 
-func newProfile(ctx context.Context, name string) (*profile, error) {
+func newProfile(ctx context.Context, name string, winrm *transport.WinRMConfig) (*profile, error) {
 	profile := &profile{
 		EntryBase: plugin.NewEntry(name),
 	}
@@ -58,7 +59,7 @@ func newProfile(ctx context.Context, name string) (*profile, error) {
 	}
 
 	profile.session = sess
-	profile.resourcesDir = []plugin.Entry{newResourcesDir(sess)}
+	profile.resourcesDir = []plugin.Entry{newResourcesDir(sess, winrm)}
 
 	return profile, nil
 }
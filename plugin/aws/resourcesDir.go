@@ -5,20 +5,23 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
 )
 
 // resourcesDir represents the <profile>/resources directory
 type resourcesDir struct {
 	plugin.EntryBase
 	session *session.Session
+	winrm   *transport.WinRMConfig
 }
 
-func newResourcesDir(session *session.Session) *resourcesDir {
+func newResourcesDir(session *session.Session, winrm *transport.WinRMConfig) *resourcesDir {
 	resourcesDir := &resourcesDir{
 		EntryBase: plugin.NewEntry("resources"),
 	}
 	resourcesDir.DisableDefaultCaching()
 	resourcesDir.session = session
+	resourcesDir.winrm = winrm
 	return resourcesDir
 }
 
@@ -37,6 +40,6 @@ func (r *resourcesDir) ChildSchemas() []*plugin.EntrySchema {
 func (r *resourcesDir) List(ctx context.Context) ([]plugin.Entry, error) {
 	return []plugin.Entry{
 		newS3Dir(ctx, r.session),
-		newEC2Dir(r.session),
+		newEC2Dir(r.session, r.winrm),
 	}, nil
 }
@@ -14,6 +14,7 @@ import (
 
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
 	"gopkg.in/go-ini/ini.v1"
 )
 
@@ -21,6 +22,7 @@ import (
 type Root struct {
 	plugin.EntryBase
 	profs map[string]struct{}
+	winrm *transport.WinRMConfig
 }
 
 func awsCredentialsFile() (string, error) {
@@ -81,8 +83,14 @@ func (r *Root) Init(cfg map[string]interface{}) error {
 		}
 	}
 
+	winrm, err := transport.ParseWinRMConfig("aws", cfg)
+	if err != nil {
+		return err
+	}
+	r.winrm = winrm
+
 	// Force authorizing profiles on startup
-	_, err := r.List(context.Background())
+	_, err = r.List(context.Background())
 	return err
 }
 
@@ -171,7 +179,7 @@ func (r *Root) List(ctx context.Context) ([]plugin.Entry, error) {
 			continue
 		}
 
-		profile, err := newProfile(ctx, name)
+		profile, err := newProfile(ctx, name, r.winrm)
 		if err != nil {
 			activity.Warnf(ctx, err.Error())
 			continue
@@ -197,6 +205,17 @@ aws:
 
 to Wash’s config file.
 
+Windows EC2 instances are exec'd via WinRM over HTTPS as Administrator, using the password
+EC2 generated for the instance. WinRM can be configured with
+
+aws:
+  winrm:
+    user: Administrator
+    password: hunter2
+    instances: [i-0123456789abcdef0]
+
+See Wash's config docs for the other WinRM settings.
+
 The AWS plugin currently supports EC2 and S3. IAM roles are supported when configured
 as described here. Note that currently region will also need to be specified with the
 profile.
@@ -5,23 +5,32 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/tracing"
 )
 
 // KeyType is used to create a unique key type for looking up context values.
 type keyType int
 
-// id is used to identify the parent's ID in a context.
-const parentID keyType = iota
+const (
+	// id is used to identify the parent's ID in a context.
+	parentID keyType = iota
+	// limitedPluginKey identifies the plugin whose limits already apply to a call.
+	limitedPluginKey
+	// gracePeriodKey is the grace period that the plugin's limits configure for a call.
+	gracePeriodKey
+)
 
 var cache datastore.Cache
 
 // InitCache initializes the cache
 func InitCache() {
 	if notRunningTests() {
-		cache = datastore.NewMemCache()
+		cache = datastore.NewMemCache().WithMetrics("plugin")
 	} else {
 		panic("InitCache can only be called in production. Tests should call SetTestCache instead.")
 	}
@@ -94,6 +103,12 @@ func allOpKeysIncludingChildrenRegex(path string) *regexp.Regexp {
 // TODO: If path == "/", we could optimize this by calling cache.Flush(). Not important
 // right now, but may be worth considering in the future.
 func ClearCacheFor(path string, clearParentList bool) []string {
+	deleted := clearCacheFor(path, clearParentList)
+	notifyCacheCleared(path, clearParentList)
+	return deleted
+}
+
+func clearCacheFor(path string, clearParentList bool) []string {
 	rx := allOpKeysIncludingChildrenRegex(path)
 	deleted := cache.Delete(rx)
 
@@ -106,6 +121,28 @@ func ClearCacheFor(path string, clearParentList bool) []string {
 	return deleted
 }
 
+var cacheClearedHooksMux sync.Mutex
+var cacheClearedHooks []func(path string, parentChanged bool)
+
+// OnCacheCleared registers a hook that's called whenever the cache is cleared for path and
+// its children, such as by ClearCacheFor or after a successful Signal or Delete. parentChanged
+// is true if the parent's list of children may also have changed. Hooks are called
+// synchronously, so they should return quickly.
+func OnCacheCleared(hook func(path string, parentChanged bool)) {
+	cacheClearedHooksMux.Lock()
+	defer cacheClearedHooksMux.Unlock()
+	cacheClearedHooks = append(cacheClearedHooks, hook)
+}
+
+func notifyCacheCleared(path string, parentChanged bool) {
+	cacheClearedHooksMux.Lock()
+	hooks := cacheClearedHooks
+	cacheClearedHooksMux.Unlock()
+	for _, hook := range hooks {
+		hook(path, parentChanged)
+	}
+}
+
 // returns (parentID, cname)
 func splitID(entryID string) (string, string) {
 	segments := strings.Split(entryID, "/")
@@ -140,7 +177,10 @@ func CachedOp(ctx context.Context, opName string, entry Entry, ttl time.Duration
 		panic("plugin.CachedOp: received a negative TTL")
 	}
 
-	return cachedOp(ctx, opName, entry, ttl, op)
+	ctx, span := tracing.Start(ctx, "cache."+opName)
+	result, err := cachedOp(ctx, opName, entry, ttl, op)
+	tracing.End(ctx, span, err)
+	return result, err
 }
 
 // DuplicateCNameErr represents a duplicate cname error, which
@@ -177,7 +217,7 @@ func (c DuplicateCNameErr) Error() string {
 // CachedList returns a map of <entry_cname> => <entry_object> to optimize
 // querying a specific entry.
 func cachedList(ctx context.Context, p Parent) (*EntryMap, error) {
-	cachedEntries, err := cachedDefaultOp(ctx, ListOp, p, func() (interface{}, error) {
+	cachedEntries, err := cachedDefaultOp(ctx, ListOp, p, func(ctx context.Context) (interface{}, error) {
 		// Including the entry's ID allows plugin authors to use any Cached* methods defined on the
 		// children after their creation. This is necessary when the child's Cached* methods are used
 		// to calculate its attributes. Note that the child's ID is set in cachedOp.
@@ -208,6 +248,12 @@ func cachedList(ctx context.Context, p Parent) (*EntryMap, error) {
 
 			searchedEntries.mp[cname] = entry
 
+			if p.eb().preserveChildIDs && entry.eb().id != "" {
+				// The entry belongs to another parent, so leave its ID
+				// and wrapped types alone.
+				continue
+			}
+
 			// Ensure ID is set on all entries so that we can use it for caching later in places
 			// where the context doesn't include the parent's ID.
 			setChildID(p.eb().id, entry)
@@ -227,7 +273,7 @@ func cachedList(ctx context.Context, p Parent) (*EntryMap, error) {
 
 // cachedRead caches an entry's Read method
 func cachedRead(ctx context.Context, e Entry) (entryContent, error) {
-	cachedContent, err := cachedDefaultOp(ctx, ReadOp, e, func() (interface{}, error) {
+	cachedContent, err := cachedDefaultOp(ctx, ReadOp, e, func(ctx context.Context) (interface{}, error) {
 		switch signature := ReadAction().signature(e); signature {
 		case DefaultSignature:
 			// Both external and core plugin entries that have the default Read signature
@@ -242,12 +288,20 @@ func cachedRead(ctx context.Context, e Entry) (entryContent, error) {
 			var readFunc blockReadFunc
 			switch t := e.(type) {
 			case externalPlugin:
-				readFunc = func(ctx context.Context, size int64, offset int64) ([]byte, error) {
-					return t.BlockRead(ctx, size, offset)
+				readFunc = func(ctx context.Context, size int64, offset int64) (data []byte, err error) {
+					err = limit(ctx, e, "read", func(ctx context.Context) (err error) {
+						data, err = t.BlockRead(ctx, size, offset)
+						return
+					})
+					return
 				}
 			case BlockReadable:
-				readFunc = func(ctx context.Context, size int64, offset int64) ([]byte, error) {
-					return t.Read(ctx, size, offset)
+				readFunc = func(ctx context.Context, size int64, offset int64) (data []byte, err error) {
+					err = limit(ctx, e, "read", func(ctx context.Context) (err error) {
+						data, err = t.Read(ctx, size, offset)
+						return
+					})
+					return
 				}
 			default:
 				// We should never hit this code-path
@@ -274,7 +328,7 @@ func cachedRead(ctx context.Context, e Entry) (entryContent, error) {
 
 // cachedMetadata caches an entry's Metadata method
 func cachedMetadata(ctx context.Context, e Entry) (JSONObject, error) {
-	cachedMetadata, err := cachedDefaultOp(ctx, MetadataOp, e, func() (interface{}, error) {
+	cachedMetadata, err := cachedDefaultOp(ctx, MetadataOp, e, func(ctx context.Context) (interface{}, error) {
 		return e.Metadata(ctx)
 	})
 
@@ -285,12 +339,22 @@ func cachedMetadata(ctx context.Context, e Entry) (JSONObject, error) {
 	return cachedMetadata.(JSONObject), nil
 }
 
-// Common helper for CachedList, CachedOpen and CachedMetadata
-func cachedDefaultOp(ctx context.Context, opCode defaultOpCode, entry Entry, op opFunc) (interface{}, error) {
+// Common helper for CachedList, CachedOpen and CachedMetadata. Unlike CachedOp, op is
+// subject to the plugin's limits.
+func cachedDefaultOp(ctx context.Context, opCode defaultOpCode, entry Entry, op func(context.Context) (interface{}, error)) (interface{}, error) {
 	opName := defaultOpCodeToNameMap[opCode]
 	ttl := entry.eb().ttl[opCode]
 
-	return cachedOp(ctx, opName, entry, ttl, op)
+	ctx, span := tracing.Start(ctx, "cache."+opName)
+	result, err := cachedOp(ctx, opName, entry, ttl, func() (result interface{}, err error) {
+		err = limit(ctx, entry, strings.ToLower(opName), func(ctx context.Context) (err error) {
+			result, err = op(ctx)
+			return
+		})
+		return
+	})
+	tracing.End(ctx, span, err)
+	return result, err
 }
 
 // Common helper for CachedOp and cachedDefaultOp.
@@ -303,8 +367,11 @@ func cachedOp(ctx context.Context, opName string, entry Entry, ttl time.Duration
 		}
 	}
 
+	start := time.Now()
 	if ttl < 0 {
-		return op()
+		result, err := op()
+		recordEvent(ctx, opName, entry, start, err, "")
+		return result, err
 	}
 
 	if entry.eb().id == "" {
@@ -316,7 +383,14 @@ func cachedOp(ctx context.Context, opName string, entry Entry, ttl time.Duration
 		}
 	}
 
-	return cache.GetOrUpdate(opName, entry.eb().id, ttl, false, op)
+	status := activity.CacheHit
+	result, err := cache.GetOrUpdate(opName, entry.eb().id, ttl, false, func() (interface{}, error) {
+		status = activity.CacheMiss
+		return op()
+	})
+	tracing.Annotate(ctx, tracing.String("wash.entry", entry.eb().id), tracing.String("wash.cache", string(status)))
+	recordEvent(ctx, opName, entry, start, err, status)
+	return result, err
 }
 
 func setChildID(parentID string, child Entry) {
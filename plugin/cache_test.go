@@ -3,11 +3,15 @@ package plugin
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"regexp"
 	"testing"
 	"time"
 
 	"github.com/emirpasic/gods/maps/linkedhashmap"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/datastore"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
@@ -157,6 +161,21 @@ func (suite *CacheTestSuite) TestClearCache_WithParent() {
 	suite.Equal([]string{"List:/a/b", "Read:/a/b", "List:/a"}, deleted)
 }
 
+func (suite *CacheTestSuite) TestClearCache_NotifiesHooks() {
+	savedHooks := cacheClearedHooks
+	defer func() { cacheClearedHooks = savedHooks }()
+
+	var cleared []string
+	OnCacheCleared(func(path string, parentChanged bool) {
+		cleared = append(cleared, fmt.Sprintf("%v %v", path, parentChanged))
+	})
+
+	suite.cache.On("Delete", mock.Anything).Return([]string{})
+	ClearCacheFor("/a", false)
+	ClearCacheFor("/a/b", true)
+	suite.Equal([]string{"/a false", "/a/b true"}, cleared)
+}
+
 type cacheTestsMockEntry struct {
 	EntryBase
 	mock.Mock
@@ -234,6 +253,41 @@ func (suite *CacheTestSuite) TestCachedOp() {
 	suite.cache.AssertCalled(suite.T(), "GetOrUpdate", opName, entry.eb().id, opTTL, false, mock.MatchedBy(generateValueMatcher))
 }
 
+func (suite *CacheTestSuite) TestCachedOp_RecordsEvents() {
+	dir, err := ioutil.TempDir("", "wash_events")
+	suite.Require().NoError(err)
+	savedDir := activity.Dir()
+	activity.SetDir(dir)
+	defer func() {
+		activity.CloseAll()
+		activity.SetDir(savedDir)
+		suite.NoError(os.RemoveAll(dir))
+	}()
+	UnsetTestCache()
+	SetTestCache(datastore.NewMemCache())
+
+	journal := activity.Journal{ID: "events"}
+	ctx := context.WithValue(context.Background(), activity.JournalKey, journal)
+	entry := newCacheTestsMockEntry("mock")
+	entry.SetTestID("/mock/id")
+	op := func() (interface{}, error) { return "result", nil }
+	for i := 0; i < 2; i++ {
+		_, err := CachedOp(ctx, "Op", entry, 5*time.Second, op)
+		suite.NoError(err)
+	}
+	_, err = cachedOp(ctx, "Failed", entry, -1, func() (interface{}, error) { return nil, fmt.Errorf("failed") })
+	suite.Error(err)
+
+	events, err := journal.Events()
+	// The cache hit isn't recorded.
+	if suite.NoError(err) && suite.Len(events, 2) {
+		suite.Equal(activity.Event{Method: "Op", EntryID: "/mock/id", Plugin: "mock", Duration: events[0].Duration, Cache: activity.CacheMiss}, events[0].Event)
+		suite.Equal("Failed", events[1].Method)
+		suite.Equal("failed", events[1].Error)
+		suite.Empty(events[1].Cache)
+	}
+}
+
 func (suite *CacheTestSuite) TestDuplicateCNameErr() {
 	err := DuplicateCNameErr{
 		ParentID:                 "/my_plugin/foo",
@@ -380,6 +434,29 @@ func (suite *CacheTestSuite) TestCachedListSetEntryID() {
 	}
 }
 
+func (suite *CacheTestSuite) TestCachedListPreserveChildIDs() {
+	// Test that CachedList keeps the existing IDs of the children
+	// when the parent preserves them, but still sets missing IDs
+	ctx := context.Background()
+	child1 := newCacheTestsMockEntry("child1")
+	child1.SetTestID("/other/child1")
+	child2 := newCacheTestsMockEntry("child2")
+	mockChildren := []Entry{child1, child2}
+
+	entry := newCacheTestsMockEntry("parent")
+	entry.SetTestID("/parent")
+	entry.DisableDefaultCaching()
+	entry.PreserveChildIDs()
+	entry.On("List", mock.Anything).Return(mockChildren, nil).Once()
+	children, err := cachedList(ctx, entry)
+	if suite.NoError(err) {
+		if suite.Equal(toMap(mockChildren), children.mp) {
+			suite.Equal("/other/child1", children.mp["child1"].eb().id)
+			suite.Equal("/parent/child2", children.mp["child2"].eb().id)
+		}
+	}
+}
+
 func (suite *CacheTestSuite) TestCachedRead_DefaultOp() {
 	// This also tests a successful read of a ReadableCorePluginEntry
 	mockRawContent := []byte("some raw content")
@@ -0,0 +1,167 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// CheckEntry checks that the entry conforms to its schema. This includes its
+// supported actions and its partial metadata; signal and delete are checked by
+// CheckSignals and CheckDelete. It returns a description of each violation.
+// Metadata is fetched separately, so check it with ValidateJSON. schema should
+// come from the plugin's schema graph (see SchemaGraph) so that its metadata
+// schemas are filled in.
+func CheckEntry(e Entry, schema *EntrySchema) []string {
+	var violations []string
+
+	// The schema's actions can include methods like metadata and schema, which
+	// aren't actions.
+	isChecked := func(action string) bool {
+		_, ok := actions[action]
+		return ok && action != SignalAction().Name && action != DeleteAction().Name
+	}
+	declared := []string{}
+	for _, action := range schema.Actions {
+		if isChecked(action) {
+			declared = append(declared, action)
+		}
+	}
+	sort.Strings(declared)
+	supported := []string{}
+	for _, action := range SupportedActionsOf(e) {
+		if isChecked(action) {
+			supported = append(supported, action)
+		}
+	}
+	sort.Strings(supported)
+	if strings.Join(declared, ",") != strings.Join(supported, ",") {
+		violations = append(violations, fmt.Sprintf("the entry supports %v, but its schema declares %v", supported, declared))
+	}
+
+	// Entries without partial metadata use their attributes instead, which aren't
+	// described by the schema.
+	if partialMetadata := e.eb().specifiedPartialMetadata; partialMetadata != nil {
+		if err := ValidateJSON(schema.PartialMetadataSchema, partialMetadata); err != nil {
+			violations = append(violations, fmt.Sprintf("the partial metadata does not match the schema: %v", err))
+		}
+	}
+	return violations
+}
+
+// CheckSignals checks that the schema declares signals, each with a unique name,
+// if and only if the entry is signalable.
+func CheckSignals(e Entry, schema *EntrySchema) []string {
+	var violations []string
+	declaresSignal := schemaDeclares(schema, SignalAction())
+	if SignalAction().IsSupportedOn(e) {
+		if !declaresSignal {
+			violations = append(violations, "the entry is signalable, but its schema doesn't declare the signal action")
+		}
+		if len(schema.Signals) == 0 {
+			violations = append(violations, "the entry is signalable, but its schema doesn't declare any signals")
+		}
+		names := make(map[string]bool)
+		for _, signal := range schema.Signals {
+			if signal.Name() == "" {
+				violations = append(violations, "a signal in the schema is missing its name")
+			} else if names[signal.Name()] {
+				violations = append(violations, fmt.Sprintf("the schema declares the %v signal more than once", signal.Name()))
+			}
+			names[signal.Name()] = true
+		}
+	} else {
+		if declaresSignal {
+			violations = append(violations, "the schema declares the signal action, but the entry is not signalable")
+		}
+		if len(schema.Signals) > 0 {
+			violations = append(violations, "the schema declares signals, but the entry is not signalable")
+		}
+	}
+	return violations
+}
+
+// CheckDelete checks that the schema declares the delete action if and only if
+// the entry is deletable.
+func CheckDelete(e Entry, schema *EntrySchema) []string {
+	declaresDelete := schemaDeclares(schema, DeleteAction())
+	if DeleteAction().IsSupportedOn(e) && !declaresDelete {
+		return []string{"the entry is deletable, but its schema doesn't declare the delete action"}
+	} else if !DeleteAction().IsSupportedOn(e) && declaresDelete {
+		return []string{"the schema declares the delete action, but the entry is not deletable"}
+	}
+	return nil
+}
+
+func schemaDeclares(schema *EntrySchema, action Action) bool {
+	for _, declared := range schema.Actions {
+		if declared == action.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckChildren checks that the parent's children conform to the parent's schema.
+// Each child's type must be one of the schema's children, and a singleton type
+// can only be listed once. schemaOf returns the schema for a type ID, or nil if
+// it's unknown.
+func CheckChildren(parentSchema *EntrySchema, children []Entry, schemaOf func(typeID string) *EntrySchema) []string {
+	var violations []string
+	declared := make(map[string]bool)
+	for _, child := range parentSchema.Children {
+		declared[child] = true
+	}
+
+	singletons := make(map[string]string)
+	for _, child := range children {
+		typeID := TypeID(child)
+		if !declared[typeID] {
+			violations = append(violations, fmt.Sprintf("%v's type %v is not one of its parent's children %v", CName(child), typeID, parentSchema.Children))
+			continue
+		}
+		schema := schemaOf(typeID)
+		if schema == nil || !schema.Singleton {
+			continue
+		}
+		if other, ok := singletons[typeID]; ok {
+			violations = append(violations, fmt.Sprintf("%v and %v have the singleton type %v", other, CName(child), typeID))
+		} else {
+			singletons[typeID] = CName(child)
+		}
+	}
+	return violations
+}
+
+// ValidateJSON checks that value conforms to the JSON schema. A nil schema
+// accepts any value.
+func ValidateJSON(schema *JSONSchema, value interface{}) error {
+	if schema == nil {
+		return nil
+	}
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	// Round-trip the value through JSON so that it's validated the same way
+	// that API clients will see it.
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schemaJSON), gojsonschema.NewBytesLoader(valueJSON))
+	if err != nil {
+		return err
+	}
+	if !result.Valid() {
+		var problems []string
+		for _, err := range result.Errors() {
+			problems = append(problems, err.String())
+		}
+		return fmt.Errorf("%v", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ConformanceTestSuite struct {
+	suite.Suite
+}
+
+type conformanceTestsEntry struct {
+	EntryBase
+}
+
+func (e *conformanceTestsEntry) Schema() *EntrySchema {
+	return nil
+}
+
+type conformanceTestsSignalable struct {
+	conformanceTestsEntry
+}
+
+func (e *conformanceTestsSignalable) Signal(context.Context, string) error {
+	return nil
+}
+
+type conformanceTestsMetadata struct {
+	Name string `json:"name"`
+}
+
+func (suite *ConformanceTestSuite) TestCheckEntry() {
+	e := &conformanceTestsSignalable{conformanceTestsEntry{EntryBase: NewEntry("foo")}}
+	schema := NewEntrySchema(e, "foo")
+	schema.Actions = append(schema.Actions, "metadata", "schema")
+	schema.Signals = []SignalSchema{*(&SignalSchema{}).SetName("start")}
+	var err error
+	schema.PartialMetadataSchema, err = schema.schemaOf(conformanceTestsMetadata{})
+	suite.Require().NoError(err)
+	suite.Empty(CheckEntry(e, schema))
+
+	// Partial metadata is only checked if it's specified
+	e.SetAttributes(EntryAttributes{})
+	e.Attributes().SetSize(10)
+	suite.Empty(CheckEntry(e, schema))
+	e.SetPartialMetadata(map[string]interface{}{"name": "foo"})
+	suite.Empty(CheckEntry(e, schema))
+	e.SetPartialMetadata(map[string]interface{}{"name": 1, "extra": true})
+	violations := CheckEntry(e, schema)
+	if suite.Len(violations, 1) {
+		suite.Contains(violations[0], "the partial metadata does not match the schema: ")
+		suite.Contains(violations[0], "name: Invalid type")
+	}
+	e.SetPartialMetadata(nil)
+
+	// Signal and delete are checked separately.
+	other := &conformanceTestsEntry{EntryBase: NewEntry("bar")}
+	suite.Empty(CheckEntry(other, schema))
+	schema.Actions = append(schema.Actions, "read")
+	suite.Equal([]string{"the entry supports [], but its schema declares [read]"}, CheckEntry(other, schema))
+}
+
+func (suite *ConformanceTestSuite) TestCheckSignals() {
+	e := &conformanceTestsSignalable{conformanceTestsEntry{EntryBase: NewEntry("foo")}}
+	schema := NewEntrySchema(e, "foo")
+	schema.Signals = []SignalSchema{*(&SignalSchema{}).SetName("start")}
+	suite.Empty(CheckSignals(e, schema))
+
+	schema.Signals = append(schema.Signals, *(&SignalSchema{}).SetName("start"))
+	suite.Equal([]string{"the schema declares the start signal more than once"}, CheckSignals(e, schema))
+	schema.Signals = nil
+	suite.Equal([]string{"the entry is signalable, but its schema doesn't declare any signals"}, CheckSignals(e, schema))
+	schema.Actions = nil
+	suite.Equal([]string{
+		"the entry is signalable, but its schema doesn't declare the signal action",
+		"the entry is signalable, but its schema doesn't declare any signals",
+	}, CheckSignals(e, schema))
+
+	other := &conformanceTestsEntry{EntryBase: NewEntry("bar")}
+	suite.Empty(CheckSignals(other, schema))
+	schema.Actions = []string{"signal"}
+	schema.Signals = []SignalSchema{*(&SignalSchema{}).SetName("start")}
+	suite.Equal([]string{
+		"the schema declares the signal action, but the entry is not signalable",
+		"the schema declares signals, but the entry is not signalable",
+	}, CheckSignals(other, schema))
+}
+
+type conformanceTestsDeletable struct {
+	conformanceTestsEntry
+}
+
+func (e *conformanceTestsDeletable) Delete(context.Context) (bool, error) {
+	return true, nil
+}
+
+func (suite *ConformanceTestSuite) TestCheckDelete() {
+	e := &conformanceTestsDeletable{conformanceTestsEntry{EntryBase: NewEntry("foo")}}
+	schema := NewEntrySchema(e, "foo")
+	suite.Empty(CheckDelete(e, schema))
+	schema.Actions = nil
+	suite.Equal([]string{"the entry is deletable, but its schema doesn't declare the delete action"}, CheckDelete(e, schema))
+
+	other := &conformanceTestsEntry{EntryBase: NewEntry("bar")}
+	suite.Empty(CheckDelete(other, schema))
+	schema.Actions = []string{"delete"}
+	suite.Equal([]string{"the schema declares the delete action, but the entry is not deletable"}, CheckDelete(other, schema))
+}
+
+func (suite *ConformanceTestSuite) TestCheckChildren() {
+	newDir := func(name string) Entry {
+		e := &conformanceTestsSignalable{conformanceTestsEntry{EntryBase: NewEntry(name)}}
+		e.eb().id = "/plugin/" + name
+		return e
+	}
+	newFile := func(name string) Entry {
+		e := &conformanceTestsEntry{EntryBase: NewEntry(name)}
+		e.eb().id = "/plugin/" + name
+		return e
+	}
+	dirTypeID, fileTypeID := TypeID(newDir("a")), TypeID(newFile("a"))
+	schemas := map[string]*EntrySchema{
+		dirTypeID:  {entrySchema: entrySchema{Singleton: true}},
+		fileTypeID: {},
+	}
+	schemaOf := func(typeID string) *EntrySchema { return schemas[typeID] }
+
+	parentSchema := &EntrySchema{entrySchema: entrySchema{Children: []string{dirTypeID, fileTypeID}}}
+	suite.Empty(CheckChildren(parentSchema, []Entry{newDir("a"), newFile("b"), newFile("c")}, schemaOf))
+	suite.Equal([]string{
+		"a and b have the singleton type " + dirTypeID,
+	}, CheckChildren(parentSchema, []Entry{newDir("a"), newDir("b"), newFile("c")}, schemaOf))
+
+	parentSchema.Children = []string{dirTypeID}
+	suite.Equal([]string{
+		"c's type " + fileTypeID + " is not one of its parent's children [" + dirTypeID + "]",
+	}, CheckChildren(parentSchema, []Entry{newDir("a"), newFile("c")}, schemaOf))
+}
+
+func (suite *ConformanceTestSuite) TestValidateJSON() {
+	suite.NoError(ValidateJSON(nil, "anything"))
+	suite.NoError(ValidateJSON(StringSchema(), "foo"))
+	suite.EqualError(ValidateJSON(IntegerSchema(), "foo"), "(root): Invalid type. Expected: integer, given: string")
+}
+
+func TestConformance(t *testing.T) {
+	suite.Run(t, new(ConformanceTestSuite))
+}
@@ -105,7 +105,17 @@ func (c *container) Exec(ctx context.Context, cmd string, args []string, opts pl
 	command := append([]string{cmd}, args...)
 	activity.Record(ctx, "Exec %v on %v", command, c.Name())
 
-	cfg := types.ExecConfig{Cmd: command, AttachStdout: true, AttachStderr: true, Tty: opts.Tty}
+	cfg := types.ExecConfig{
+		Cmd:          command,
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          opts.Tty,
+		WorkingDir:   opts.WorkingDir,
+		User:         opts.User,
+	}
+	for k, v := range opts.Env {
+		cfg.Env = append(cfg.Env, k+"="+v)
+	}
 	if opts.Stdin != nil || opts.Tty {
 		cfg.AttachStdin = true
 	}
@@ -133,6 +143,19 @@ func (c *container) Exec(ctx context.Context, cmd string, args []string, opts pl
 		}()
 	}
 
+	// Resize the TTY for interactive sessions. The exec has started at this point, so
+	// the first resize applies the user's initial terminal size.
+	if opts.Tty && opts.Resize != nil {
+		go func() {
+			for size := range opts.Resize {
+				resizeOpts := types.ResizeOptions{Height: uint(size.Rows), Width: uint(size.Cols)}
+				if err := c.client.ContainerExecResize(ctx, created.ID, resizeOpts); err != nil {
+					activity.Record(ctx, "Unable to resize the TTY for %v: %v", c.Name(), err)
+				}
+			}
+		}()
+	}
+
 	execCmd := plugin.NewExecCommand(ctx)
 	execCmd.SetStopFunc(func() {
 		// Close the response on cancellation. Copying will block until there's more to read from the
@@ -147,7 +170,13 @@ func (c *container) Exec(ctx context.Context, cmd string, args []string, opts pl
 	// Asynchronously copy container exec output, then fetch the exit code once
 	// the copy's finished.
 	go func() {
-		_, err := stdcopy.StdCopy(execCmd.Stdout(), execCmd.Stderr(), resp.Reader)
+		var err error
+		if opts.Tty {
+			// A TTY combines stdout and stderr into a single raw stream.
+			_, err = io.Copy(execCmd.Stdout(), resp.Reader)
+		} else {
+			_, err = stdcopy.StdCopy(execCmd.Stdout(), execCmd.Stderr(), resp.Reader)
+		}
 		activity.Record(ctx, "Exec on %v complete: %v", c.Name(), err)
 		execCmd.CloseStreamsWithError(err)
 		resp.Close()
@@ -262,6 +262,14 @@ func (v *volume) VolumeDelete(ctx context.Context, path string) (bool, error) {
 	return true, nil
 }
 
+func (v *volume) VolumeReadlink(ctx context.Context, path string) (string, error) {
+	output, err := v.runInTemporaryContainer(ctx, []string{"readlink", mountpoint + path})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 const volumeDescription = `
 This is a Docker volume. We create a temporary Docker container whenever
 Wash invokes a currently uncached List/Read/Stream action on it or one of
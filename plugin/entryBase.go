@@ -46,6 +46,7 @@ type EntryBase struct {
 	wrappedTypes             SchemaMap
 	isPrefetched             bool
 	isInaccessible           bool
+	preserveChildIDs         bool
 }
 
 // NewEntry creates a new entry
@@ -161,6 +162,16 @@ func (e *EntryBase) Prefetched() *EntryBase {
 	return e
 }
 
+// PreserveChildIDs tells plugin.List to keep the IDs of any children that
+// already have one instead of re-rooting them under e. Use it when e lists
+// entries that live elsewhere in the Wash hierarchy (e.g. the results of a
+// saved query) so that those entries keep their type IDs and share their
+// cached data with the originals.
+func (e *EntryBase) PreserveChildIDs() *EntryBase {
+	e.preserveChildIDs = true
+	return e
+}
+
 /*
 SetSlashReplacer overrides the default '/' replacer '#' to char.
 The '/' replacer is used when determining the entry's cname. See
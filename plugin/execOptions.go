@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+)
+
+// UnsupportedExecOptionErr indicates that an executor can't honour one of
+// the passed-in ExecOptions (e.g. a Kubernetes container can't run a command
+// as a different user).
+type UnsupportedExecOptionErr struct {
+	// Option is the option's JSON key, e.g. "user".
+	Option string
+	Reason string
+}
+
+func (e UnsupportedExecOptionErr) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("the %v exec option is not supported", e.Option)
+	}
+	return fmt.Sprintf("the %v exec option is not supported: %v", e.Option, e.Reason)
+}
+
+// IsUnsupportedExecOptionErr returns true if err is an UnsupportedExecOptionErr
+// error object
+func IsUnsupportedExecOptionErr(err error) bool {
+	_, ok := err.(UnsupportedExecOptionErr)
+	return ok
+}
+
+/*
+WrapCommand returns a POSIX command that runs cmd with opts' Env and
+WorkingDir options. It's meant for executors that run commands on a
+POSIX system but don't natively support those options. For example,
+
+	WrapCommand([]string{"ls", "-l"}, ExecOptions{WorkingDir: "/tmp", Env: map[string]string{"A": "b"}})
+
+returns
+
+	[]string{"sh", "-c", `cd "$0" && exec "$@"`, "/tmp", "env", "A=b", "ls", "-l"}
+
+The working directory and command are passed as positional arguments to
+sh so that they don't need to be quoted. cmd is returned as-is if neither
+option is set.
+*/
+func WrapCommand(cmd []string, opts ExecOptions) []string {
+	if len(opts.Env) > 0 {
+		vars := make([]string, 0, len(opts.Env))
+		for k, v := range opts.Env {
+			vars = append(vars, k+"="+v)
+		}
+		// Sort the variables so that the command is deterministic
+		sort.Strings(vars)
+		cmd = append(append([]string{"env"}, vars...), cmd...)
+	}
+	if opts.WorkingDir != "" {
+		cmd = append([]string{"sh", "-c", `cd "$0" && exec "$@"`, opts.WorkingDir}, cmd...)
+	}
+	return cmd
+}
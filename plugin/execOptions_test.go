@@ -0,0 +1,38 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ExecOptionsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ExecOptionsTestSuite) TestUnsupportedExecOptionErr() {
+	err := UnsupportedExecOptionErr{Option: "user"}
+	suite.EqualError(err, "the user exec option is not supported")
+	suite.True(IsUnsupportedExecOptionErr(err))
+
+	err.Reason = "it's not possible"
+	suite.EqualError(err, "the user exec option is not supported: it's not possible")
+}
+
+func (suite *ExecOptionsTestSuite) TestWrapCommand() {
+	cmd := []string{"ls", "-l"}
+	suite.Equal(cmd, WrapCommand(cmd, ExecOptions{}))
+
+	opts := ExecOptions{Env: map[string]string{"B": "b c", "A": "a"}}
+	suite.Equal([]string{"env", "A=a", "B=b c", "ls", "-l"}, WrapCommand(cmd, opts))
+
+	opts.WorkingDir = "/my dir"
+	suite.Equal(
+		[]string{"sh", "-c", `cd "$0" && exec "$@"`, "/my dir", "env", "A=a", "B=b c", "ls", "-l"},
+		WrapCommand(cmd, opts),
+	)
+}
+
+func TestExecOptions(t *testing.T) {
+	suite.Run(t, new(ExecOptionsTestSuite))
+}
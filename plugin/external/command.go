@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"sync"
 	"syscall"
@@ -11,6 +12,10 @@ import (
 
 	"github.com/kballard/go-shellquote"
 	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/metrics"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/tracing"
+	"go.opentelemetry.io/otel/api/trace"
 )
 
 // Command is a wrapper to exec.Cmd. It handles context-cancellation cleanup
@@ -28,10 +33,15 @@ type Command interface {
 	ExitCode() int
 }
 
+// defaultGracePeriod is how long a command has to exit after it's sent SIGTERM.
+const defaultGracePeriod = 5 * time.Second
+
 type command struct {
 	*exec.Cmd
 	ctx         context.Context
 	pgid        int
+	gracePeriod time.Duration
+	span        trace.Span
 	terminateCh chan struct{}
 	waitResult  error
 	waitDoneCh  chan struct{}
@@ -41,9 +51,10 @@ type command struct {
 // NewCommand creates a new command object that's tied to the passed-in
 // context. When cmd.Start() is invoked, the command will run in its
 // own process group. When the context is cancelled, a SIGTERM signal will
-// be sent to the command's process group. If after five seconds the command's
-// process has not been terminated, then a SIGKILL signal is sent to the
-// command's process group.
+// be sent to the command's process group. If after the grace period the
+// command's process has not been terminated, then a SIGKILL signal is sent
+// to the command's process group. The grace period is five seconds unless
+// the plugin's limits configure a different one.
 func NewCommand(ctx context.Context, cmd string, args ...string) Command {
 	if ctx == nil {
 		panic("plugin.newCommand called with a nil context")
@@ -52,21 +63,38 @@ func NewCommand(ctx context.Context, cmd string, args ...string) Command {
 		Cmd:         exec.Command(cmd, args...),
 		ctx:         ctx,
 		pgid:        -1,
+		gracePeriod: defaultGracePeriod,
 		terminateCh: make(chan struct{}),
 		waitDoneCh:  make(chan struct{}),
 	}
+	if gracePeriod := plugin.GracePeriod(ctx); gracePeriod > 0 {
+		cmdObj.gracePeriod = gracePeriod
+	}
 	cmdObj.SysProcAttr = &syscall.SysProcAttr{
 		Setpgid: true,
 	}
 	return cmdObj
 }
 
-// Start is a wrapper to exec.Cmd#Start
+// Start is a wrapper to exec.Cmd#Start. The command's span is propagated to it
+// via the TRACEPARENT and TRACESTATE environment variables.
 func (cmd *command) Start() error {
+	spanName := "external"
+	if len(cmd.Args) > 1 {
+		spanName += "." + cmd.Args[1]
+	}
+	spanCtx, span := tracing.Start(cmd.ctx, spanName, tracing.String("wash.command", cmd.Path))
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, tracing.Env(spanCtx)...)
 	err := cmd.Cmd.Start()
 	if err != nil {
+		tracing.End(spanCtx, span, err)
 		return err
 	}
+	cmd.span = span
+	metrics.ExternalProcessStarted()
 	// Get the command's PGID for logging. If this fails, we'll try
 	// again in cmd.signal() when it is needed.
 	pgid, err := syscall.Getpgid(cmd.Process.Pid)
@@ -90,16 +118,16 @@ func (cmd *command) Start() error {
 		if err := cmd.signal(syscall.SIGTERM); err != nil {
 			activity.Record(cmd.ctx, "%v: Failed to send SIGTERM signal: %v", cmd, err)
 		} else {
-			// SIGTERM was sent. Send SIGKILL after five seconds if the command failed
-			// to terminate.
-			time.AfterFunc(5*time.Second, func() {
+			// SIGTERM was sent. Send SIGKILL after the grace period if the command
+			// failed to terminate.
+			time.AfterFunc(cmd.gracePeriod, func() {
 				select {
 				case <-cmd.waitDoneCh:
 					return
 				default:
 					// Pass-thru
 				}
-				activity.Record(cmd.ctx, "%v: Did not terminate after five seconds. Sending SIGKILL signal", cmd)
+				activity.Record(cmd.ctx, "%v: Did not terminate after %v. Sending SIGKILL signal", cmd, cmd.gracePeriod)
 				if err := cmd.signal(syscall.SIGKILL); err != nil {
 					activity.Record(cmd.ctx, "%v: Failed to send SIGKILL signal: %v", cmd, err)
 				}
@@ -154,6 +182,12 @@ func (cmd *command) Wait() error {
 	// our own version.
 	cmd.waitOnce.Do(func() {
 		cmd.waitResult = cmd.Cmd.Wait()
+		if cmd.Process != nil {
+			metrics.ExternalProcessExited()
+		}
+		if cmd.span != nil {
+			tracing.End(cmd.ctx, cmd.span, cmd.waitResult)
+		}
 		close(cmd.waitDoneCh)
 	})
 	return cmd.waitResult
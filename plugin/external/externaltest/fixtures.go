@@ -0,0 +1,109 @@
+package externaltest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// Fixtures describe how a Harness drives an external plugin. They're usually
+// recorded in a YAML file and loaded with LoadFixtures. An example is
+//
+//     config:
+//       profile: dev
+//     entries:
+//       /myplugin/containers/web:
+//         read:
+//           size: 512
+//         stream:
+//           lines: 2
+//         exec:
+//           - cmd: echo
+//             args: [hello]
+//         signal: [stop]
+//         delete: true
+//
+// List, read, metadata, readlink and schema are invoked on every visited entry.
+// Exec, signal and delete can have side-effects, so they're only invoked on the
+// entries listed in the fixtures. Streams are only read from when the fixtures
+// request it, since they can block indefinitely.
+type Fixtures struct {
+	// Config is passed to the plugin's init method.
+	Config map[string]interface{} `json:"config"`
+	// All visits every entry rather than one example of each kind of entry. An entry
+	// listed in Entries is always visited.
+	All bool `json:"all"`
+	// MaxDepth limits how far the plugin's hierarchy is explored. The plugin root has
+	// a depth of 0. Zero means unlimited.
+	MaxDepth int `json:"max_depth"`
+	// Timeout limits each method invocation. It defaults to DefaultTimeout.
+	Timeout time.Duration `json:"-"`
+	// Entries maps an entry's ID (like /myplugin/containers/web) to the invocations
+	// to make on it.
+	Entries map[string]EntryFixture `json:"entries"`
+}
+
+// DefaultTimeout is the default timeout for each method invocation.
+const DefaultTimeout = 30 * time.Second
+
+// EntryFixture describes the invocations to make on a specific entry.
+type EntryFixture struct {
+	Read   *ReadFixture   `json:"read"`
+	Stream *StreamFixture `json:"stream"`
+	Exec   []ExecFixture  `json:"exec"`
+	Signal []string       `json:"signal"`
+	Delete bool           `json:"delete"`
+}
+
+// ReadFixture describes how to read an entry's content. By default, up to
+// DefaultReadSize bytes are read from the start of the content.
+type ReadFixture struct {
+	Size   int64 `json:"size"`
+	Offset int64 `json:"offset"`
+}
+
+// DefaultReadSize is the default number of bytes read from an entry's content.
+const DefaultReadSize = 4096
+
+// StreamFixture describes how much of an entry's stream to read.
+type StreamFixture struct {
+	// Lines is the number of lines to read before closing the stream.
+	Lines int `json:"lines"`
+}
+
+// ExecFixture describes a command to execute on an entry.
+type ExecFixture struct {
+	Cmd     string   `json:"cmd"`
+	Args    []string `json:"args"`
+	Stdin   string   `json:"stdin"`
+	Tty     bool     `json:"tty"`
+	Elevate bool     `json:"elevate"`
+}
+
+// LoadFixtures reads fixtures from a YAML (or JSON) file. The timeout is a
+// duration like "10s".
+func LoadFixtures(path string) (Fixtures, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Fixtures{}, err
+	}
+
+	// The outer timeout field shadows the embedded one so that it can be
+	// parsed as a duration.
+	var decoded struct {
+		Fixtures
+		Timeout string `json:"timeout"`
+	}
+	if err := yaml.Unmarshal(content, &decoded); err != nil {
+		return Fixtures{}, fmt.Errorf("could not parse fixtures %v: %v", path, err)
+	}
+	fixtures := decoded.Fixtures
+	if decoded.Timeout != "" {
+		if fixtures.Timeout, err = time.ParseDuration(decoded.Timeout); err != nil {
+			return Fixtures{}, fmt.Errorf("could not parse fixtures %v: invalid timeout %v", path, decoded.Timeout)
+		}
+	}
+	return fixtures, nil
+}
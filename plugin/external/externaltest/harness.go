@@ -0,0 +1,399 @@
+// Package externaltest provides a harness for testing external plugins from Go
+// test suites. The harness loads a plugin's script the same way the Wash daemon
+// does, so the plugin's output is decoded and validated by the same code. It
+// then drives the plugin's methods with recorded fixtures, checks the results
+// against the plugin's schema, and produces a report that can be compared
+// against a golden file.
+//
+// A typical test looks like
+//
+//     func TestMyPlugin(t *testing.T) {
+//         fixtures, err := externaltest.LoadFixtures("testdata/fixtures.yaml")
+//         if err != nil {
+//             t.Fatal(err)
+//         }
+//         h := externaltest.New(t, "myplugin.rb", fixtures)
+//         defer h.Close()
+//         externaltest.AssertGolden(t, h.Run(), "testdata/myplugin.golden")
+//     }
+package externaltest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/plugin/external"
+)
+
+// Harness drives an external plugin's methods. Wash's cache is global, so only
+// one Harness can be open at a time.
+type Harness struct {
+	fixtures Fixtures
+	root     plugin.Entry
+}
+
+// New loads the plugin's script and invokes its init method with the fixtures'
+// config. It fails the test if the plugin can't be loaded or initialized. Call
+// Close once the test is done.
+func New(t testing.TB, script string, fixtures Fixtures) *Harness {
+	t.Helper()
+	root, err := external.PluginSpec{Script: script}.Load()
+	if err != nil {
+		t.Fatalf("could not load %v: %v", script, err)
+	}
+
+	plugin.SetTestCache(datastore.NewMemCache())
+	registry := plugin.NewRegistry()
+	if err := registry.RegisterPlugin(root, fixtures.Config); err != nil {
+		plugin.UnsetTestCache()
+		t.Fatalf("init: %v", err)
+	}
+
+	// Use List on the registry to ensure cache IDs are generated.
+	entries, err := plugin.List(context.Background(), registry)
+	if err != nil {
+		plugin.UnsetTestCache()
+		t.Fatalf("could not list the plugin registry: %v", err)
+	}
+	h := &Harness{fixtures: fixtures}
+	h.root, _ = entries.Load(plugin.CName(root))
+	return h
+}
+
+// Root returns the plugin's root so that tests can invoke its methods directly.
+func (h *Harness) Root() plugin.Entry {
+	return h.root
+}
+
+// Close releases the harness's resources.
+func (h *Harness) Close() {
+	plugin.UnsetTestCache()
+}
+
+// Run walks the plugin's hierarchy breadth-first, starting from its root. If the
+// fixtures don't set All, then only one example of each kind of entry is visited
+// at each level, along with the entries listed in the fixtures.
+func (h *Harness) Run() *Report {
+	report := &Report{Plugin: plugin.Name(h.root)}
+
+	type item struct {
+		entry plugin.Entry
+		depth int
+	}
+	queue := []item{{entry: h.root}}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+
+		entryReport, children := h.visit(next.entry)
+		report.Entries = append(report.Entries, entryReport)
+		if h.fixtures.MaxDepth > 0 && next.depth >= h.fixtures.MaxDepth {
+			continue
+		}
+		for _, child := range h.selectChildren(next.entry, children) {
+			queue = append(queue, item{entry: child, depth: next.depth + 1})
+		}
+	}
+	return report
+}
+
+// visit invokes the entry's methods. It returns the entry's report and its
+// children sorted by cname.
+func (h *Harness) visit(e plugin.Entry) (EntryReport, []plugin.Entry) {
+	r := EntryReport{
+		ID:      plugin.ID(e),
+		TypeID:  plugin.TypeID(e),
+		Actions: plugin.SupportedActionsOf(e),
+	}
+	sort.Strings(r.Actions)
+	fixture := h.fixtures.Entries[r.ID]
+
+	schema, err := plugin.Schema(e)
+	if err != nil {
+		r.errorf("schema", "%v", err)
+	}
+	if schema != nil {
+		violations := plugin.CheckEntry(e, schema)
+		violations = append(violations, plugin.CheckSignals(e, schema)...)
+		violations = append(violations, plugin.CheckDelete(e, schema)...)
+		for _, violation := range violations {
+			r.errorf("schema", "%v", violation)
+		}
+	}
+
+	var children []plugin.Entry
+	if plugin.ListAction().IsSupportedOn(e) {
+		err := h.invoke(func(ctx context.Context) error {
+			entries, err := plugin.List(ctx, e.(plugin.Parent))
+			if err != nil {
+				return err
+			}
+			r.Children = []string{}
+			entries.Range(func(cname string, child plugin.Entry) bool {
+				r.Children = append(r.Children, cname)
+				return true
+			})
+			sort.Strings(r.Children)
+			for _, cname := range r.Children {
+				child, _ := entries.Load(cname)
+				children = append(children, child)
+			}
+			return nil
+		})
+		if err != nil {
+			r.errorf("list", "%v", err)
+		} else if schema != nil {
+			for _, violation := range plugin.CheckChildren(schema, children, h.schemaOf) {
+				r.errorf("schema", "%v", violation)
+			}
+		}
+	}
+
+	if plugin.ReadAction().IsSupportedOn(e) && isReadable(e) {
+		read := ReadFixture{Size: DefaultReadSize}
+		if fixture.Read != nil {
+			read = *fixture.Read
+		}
+		err := h.invoke(func(ctx context.Context) error {
+			data, err := plugin.Read(ctx, e, read.Size, read.Offset)
+			if err != nil && err != io.EOF {
+				return err
+			}
+			content := string(data)
+			r.Content = &content
+			return nil
+		})
+		if err != nil {
+			r.errorf("read", "%v", err)
+		}
+	}
+
+	err = h.invoke(func(ctx context.Context) (err error) {
+		r.Metadata, err = plugin.Metadata(ctx, e)
+		return
+	})
+	if err != nil {
+		r.errorf("metadata", "%v", err)
+	} else if schema != nil && schema.MetadataSchema != nil {
+		if err := plugin.ValidateJSON(schema.MetadataSchema, r.Metadata); err != nil {
+			r.errorf("metadata", "does not match the schema: %v", err)
+		}
+	}
+
+	if plugin.ReadlinkAction().IsSupportedOn(e) {
+		err := h.invoke(func(ctx context.Context) (err error) {
+			r.Target, err = plugin.Readlink(ctx, e.(plugin.Linkable))
+			return
+		})
+		if err != nil {
+			r.errorf("readlink", "%v", err)
+		}
+	}
+
+	if fixture.Stream != nil {
+		h.stream(&r, e, *fixture.Stream)
+	}
+	for _, exec := range fixture.Exec {
+		h.exec(&r, e, exec)
+	}
+	for _, signal := range fixture.Signal {
+		if !plugin.SignalAction().IsSupportedOn(e) {
+			r.errorf("signal", "the entry does not support signal")
+			break
+		}
+		err := h.invoke(func(ctx context.Context) error {
+			return plugin.Signal(ctx, e.(plugin.Signalable), signal)
+		})
+		if err != nil {
+			r.errorf("signal", "%v: %v", signal, err)
+		} else {
+			r.Signals = append(r.Signals, signal)
+		}
+	}
+	if fixture.Delete {
+		if !plugin.DeleteAction().IsSupportedOn(e) {
+			r.errorf("delete", "the entry does not support delete")
+		} else {
+			err := h.invoke(func(ctx context.Context) error {
+				deleted, err := plugin.Delete(ctx, e.(plugin.Deletable))
+				r.Deleted = &deleted
+				return err
+			})
+			if err != nil {
+				r.errorf("delete", "%v", err)
+			}
+		}
+	}
+
+	return r, children
+}
+
+func (h *Harness) stream(r *EntryReport, e plugin.Entry, fixture StreamFixture) {
+	if !plugin.StreamAction().IsSupportedOn(e) {
+		r.errorf("stream", "the entry does not support stream")
+		return
+	}
+	err := h.invoke(func(ctx context.Context) error {
+		rdr, err := plugin.Stream(ctx, e.(plugin.Streamable))
+		if err != nil {
+			return err
+		}
+		// Closing the stream unblocks the scanner if the timeout expires.
+		go func() {
+			<-ctx.Done()
+			rdr.Close()
+		}()
+
+		r.Stream = []string{}
+		scanner := bufio.NewScanner(rdr)
+		for len(r.Stream) < fixture.Lines && scanner.Scan() {
+			r.Stream = append(r.Stream, scanner.Text())
+		}
+		if len(r.Stream) < fixture.Lines {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return fmt.Errorf("the stream ended after %v of %v lines", len(r.Stream), fixture.Lines)
+		}
+		return nil
+	})
+	if err != nil {
+		r.errorf("stream", "%v", err)
+	}
+}
+
+func (h *Harness) exec(r *EntryReport, e plugin.Entry, fixture ExecFixture) {
+	if !plugin.ExecAction().IsSupportedOn(e) {
+		r.errorf("exec", "the entry does not support exec")
+		return
+	}
+	execReport := ExecReport{Cmd: fixture.Cmd, Args: fixture.Args}
+	err := h.invoke(func(ctx context.Context) error {
+		opts := plugin.ExecOptions{Tty: fixture.Tty, Elevate: fixture.Elevate}
+		if fixture.Stdin != "" {
+			opts.Stdin = strings.NewReader(fixture.Stdin)
+		}
+		cmd, err := plugin.Exec(ctx, e.(plugin.Execable), fixture.Cmd, fixture.Args, opts)
+		if err != nil {
+			return err
+		}
+
+		var stdout, stderr strings.Builder
+		for chunk := range cmd.OutputCh() {
+			if chunk.Err != nil {
+				return chunk.Err
+			}
+			if chunk.StreamID == plugin.Stdout {
+				stdout.WriteString(chunk.Data)
+			} else {
+				stderr.WriteString(chunk.Data)
+			}
+		}
+		execReport.Stdout = stdout.String()
+		execReport.Stderr = stderr.String()
+		execReport.ExitCode, err = cmd.ExitCode()
+		return err
+	})
+	if err != nil {
+		r.errorf("exec", "%v %v: %v", fixture.Cmd, strings.Join(fixture.Args, " "), err)
+	}
+	r.Exec = append(r.Exec, execReport)
+}
+
+// invoke calls fn with a context that times out after the fixtures' timeout.
+func (h *Harness) invoke(fn func(context.Context) error) error {
+	timeout := h.fixtures.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := fn(ctx)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %v: %v", timeout, err)
+	}
+	return err
+}
+
+// selectChildren returns the children that should be visited.
+func (h *Harness) selectChildren(parent plugin.Entry, children []plugin.Entry) []plugin.Entry {
+	if h.fixtures.All {
+		return children
+	}
+
+	kindOf := func(child plugin.Entry) string {
+		actions := plugin.SupportedActionsOf(child)
+		sort.Strings(actions)
+		return plugin.TypeID(child) + " " + strings.Join(actions, ",")
+	}
+
+	// Entries with fixtures are always visited, and they're the examples of their kind.
+	seen := make(map[string]bool)
+	for _, child := range children {
+		if h.hasFixture(plugin.ID(child)) {
+			seen[kindOf(child)] = true
+		}
+	}
+
+	var selected []plugin.Entry
+	parentTypeID := plugin.TypeID(parent)
+	for _, child := range children {
+		if h.hasFixture(plugin.ID(child)) {
+			selected = append(selected, child)
+			continue
+		}
+		// If we have a type ID, only explore children if they are different from the
+		// parent. This prevents simple recursion like volume directories containing
+		// more directories.
+		typeID := plugin.TypeID(child)
+		if typeID != "" && typeID == parentTypeID {
+			continue
+		}
+		if kind := kindOf(child); !seen[kind] {
+			seen[kind] = true
+			selected = append(selected, child)
+		}
+	}
+	return selected
+}
+
+// hasFixture returns true if id or one of its descendants has a fixture.
+func (h *Harness) hasFixture(id string) bool {
+	for fixtureID := range h.fixtures.Entries {
+		if fixtureID == id || strings.HasPrefix(fixtureID, id+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isReadable returns false for devices and pipes, which shouldn't be read.
+func isReadable(e plugin.Entry) bool {
+	attr := plugin.Attributes(e)
+	if !attr.HasMode() {
+		return true
+	}
+	mode := attr.Mode()
+	return mode&(os.ModeCharDevice|os.ModeNamedPipe|os.ModeDevice) == 0
+}
+
+// schemaOf returns the schema for the type ID from the plugin's schema graph.
+func (h *Harness) schemaOf(typeID string) *plugin.EntrySchema {
+	graph, err := plugin.SchemaGraph(h.root)
+	if err != nil || graph == nil {
+		return nil
+	}
+	if schema, ok := graph.Get(typeID); ok {
+		s := schema.(plugin.EntrySchema)
+		return &s
+	}
+	return nil
+}
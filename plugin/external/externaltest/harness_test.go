@@ -0,0 +1,97 @@
+package externaltest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type HarnessTestSuite struct {
+	suite.Suite
+}
+
+func (suite *HarnessTestSuite) TestLoadFixtures() {
+	fixtures, err := LoadFixtures("testdata/greeter.yaml")
+	if suite.NoError(err) {
+		suite.Equal(10*time.Second, fixtures.Timeout)
+		suite.Equal(&ReadFixture{Size: 5}, fixtures.Entries["/greeter/hello"].Read)
+		suite.Equal([]string{"wave"}, fixtures.Entries["/greeter/hello"].Signal)
+		suite.Len(fixtures.Entries["/greeter/hello"].Exec, 2)
+	}
+
+	dir, err := ioutil.TempDir("", "wash_externaltest")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fixtures.yaml")
+	suite.Require().NoError(ioutil.WriteFile(path, []byte("timeout: forever\n"), 0640))
+	_, err = LoadFixtures(path)
+	suite.EqualError(err, "could not parse fixtures "+path+": invalid timeout forever")
+}
+
+func (suite *HarnessTestSuite) TestRun() {
+	fixtures, err := LoadFixtures("testdata/greeter.yaml")
+	suite.Require().NoError(err)
+	h := New(suite.T(), "testdata/greeter.sh", fixtures)
+	defer h.Close()
+
+	report := h.Run()
+	suite.Empty(report.Errors())
+	AssertGolden(suite.T(), report, "testdata/greeter.golden")
+
+	hello := report.Entry("/greeter/hello")
+	if suite.NotNil(hello) {
+		suite.Equal("hello", *hello.Content)
+		suite.Equal([]string{"hello", "hello again"}, hello.Stream)
+		if suite.Len(hello.Exec, 2) {
+			suite.Equal(ExecReport{Cmd: "echo", Args: []string{"hi", "there"}, Stdout: "hi there\n"}, hello.Exec[0])
+			suite.Equal("oops\n", hello.Exec[1].Stderr)
+			suite.Equal(3, hello.Exec[1].ExitCode)
+		}
+		suite.Equal([]string{"wave"}, hello.Signals)
+		suite.True(*hello.Deleted)
+	}
+	// Only one greeting is visited since they're the same kind of entry.
+	suite.Nil(report.Entry("/greeter/hola"))
+	if latest := report.Entry("/greeter/latest"); suite.NotNil(latest) {
+		suite.Equal("hello", latest.Target)
+	}
+}
+
+func (suite *HarnessTestSuite) TestRun_All() {
+	h := New(suite.T(), "testdata/greeter.sh", Fixtures{
+		All: true,
+		Entries: map[string]EntryFixture{
+			"/greeter/hola":   {Signal: []string{"wink"}},
+			"/greeter/latest": {Delete: true},
+		},
+	})
+	defer h.Close()
+
+	report := h.Run()
+	suite.Len(report.Entries, 4)
+	errs := report.Errors()
+	if suite.Len(errs, 3) {
+		suite.Contains(errs[0], "/greeter/hola: metadata: does not match the schema: language: Invalid type")
+		suite.Contains(errs[1], "/greeter/hola: signal: wink: ")
+		suite.Equal("/greeter/latest: delete: the entry does not support delete", errs[2])
+	}
+}
+
+func (suite *HarnessTestSuite) TestRun_MaxDepth() {
+	h := New(suite.T(), "testdata/greeter.sh", Fixtures{MaxDepth: 1})
+	defer h.Close()
+
+	report := h.Run()
+	suite.Len(report.Entries, 3)
+	suite.Equal([]string{"hello", "hola", "latest"}, report.Entries[0].Children)
+	// Streams are only read when requested.
+	suite.Nil(report.Entry("/greeter/hello").Stream)
+}
+
+func TestHarness(t *testing.T) {
+	suite.Run(t, new(HarnessTestSuite))
+}
@@ -0,0 +1,124 @@
+package externaltest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// Report records the results of driving an external plugin's methods. It's
+// deterministic for a deterministic plugin, so it can be compared against a
+// golden file with AssertGolden.
+type Report struct {
+	Plugin  string        `json:"plugin"`
+	Entries []EntryReport `json:"entries"`
+}
+
+// EntryReport records the results of the methods invoked on an entry. Each
+// method's error is recorded in Errors, as are any violations of the protocol
+// or of the plugin's schema.
+type EntryReport struct {
+	ID       string            `json:"id"`
+	TypeID   string            `json:"type_id,omitempty"`
+	Actions  []string          `json:"actions"`
+	Children []string          `json:"children,omitempty"`
+	Content  *string           `json:"content,omitempty"`
+	Metadata plugin.JSONObject `json:"metadata,omitempty"`
+	Target   string            `json:"target,omitempty"`
+	Stream   []string          `json:"stream,omitempty"`
+	Exec     []ExecReport      `json:"exec,omitempty"`
+	Signals  []string          `json:"signals,omitempty"`
+	Deleted  *bool             `json:"deleted,omitempty"`
+	Errors   []string          `json:"errors,omitempty"`
+}
+
+// ExecReport records the result of executing a command on an entry.
+type ExecReport struct {
+	Cmd      string   `json:"cmd"`
+	Args     []string `json:"args,omitempty"`
+	Stdout   string   `json:"stdout,omitempty"`
+	Stderr   string   `json:"stderr,omitempty"`
+	ExitCode int      `json:"exit_code"`
+}
+
+func (r *EntryReport) errorf(method string, format string, a ...interface{}) {
+	r.Errors = append(r.Errors, method+": "+fmt.Sprintf(format, a...))
+}
+
+// Errors returns every error in the report, prefixed by the entry's ID.
+func (r *Report) Errors() []string {
+	var errs []string
+	for _, entry := range r.Entries {
+		for _, err := range entry.Errors {
+			errs = append(errs, entry.ID+": "+err)
+		}
+	}
+	return errs
+}
+
+// Entry returns the report for the entry with the given ID. It returns nil if
+// the entry wasn't visited.
+func (r *Report) Entry(id string) *EntryReport {
+	for i := range r.Entries {
+		if r.Entries[i].ID == id {
+			return &r.Entries[i]
+		}
+	}
+	return nil
+}
+
+// Marshal returns the report as YAML.
+func (r *Report) Marshal() ([]byte, error) {
+	return yaml.Marshal(r)
+}
+
+// UpdateGoldenEnv is the environment variable that makes AssertGolden update
+// golden files rather than comparing against them.
+const UpdateGoldenEnv = "WASH_UPDATE_GOLDEN"
+
+// AssertGolden compares the report against the golden file at path. If the
+// WASH_UPDATE_GOLDEN environment variable is set, then the golden file is
+// (re-)written instead.
+func AssertGolden(t testing.TB, r *Report, path string) bool {
+	t.Helper()
+	actual, err := r.Marshal()
+	if err != nil {
+		t.Errorf("could not marshal the report: %v", err)
+		return false
+	}
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Errorf("could not update %v: %v", path, err)
+			return false
+		}
+		if err := ioutil.WriteFile(path, actual, 0640); err != nil {
+			t.Errorf("could not update %v: %v", path, err)
+			return false
+		}
+		return true
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Errorf("could not read %v: %v. Set %v=1 to create it", path, err, UpdateGoldenEnv)
+		return false
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf(
+			"the report doesn't match %v. Set %v=1 to update it.\nexpected:\n%s\nactual:\n%s",
+			path,
+			UpdateGoldenEnv,
+			expected,
+			actual,
+		)
+		return false
+	}
+	return true
+}
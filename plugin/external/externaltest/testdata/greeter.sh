@@ -0,0 +1,59 @@
+#!/bin/sh
+# A plugin that implements every method, used to test the harness.
+
+method="$1"
+id="$2"
+state="$3"
+
+case "$method" in
+init)
+  cat <<'JSON'
+{"type_id":"root","methods":["list",["schema",{
+  "root":{"label":"greeter","singleton":true,"methods":["list","schema"],"children":["greeting","link"]},
+  "greeting":{"label":"greeting","methods":["read","metadata","stream","exec","signal","delete","schema"],
+    "signals":[{"name":"wave","description":"Waves"}],
+    "metadata_schema":{"type":"object","properties":{"language":{"type":"string"}},"required":["language"]}},
+  "link":{"label":"link","singleton":true,"methods":["readlink","schema"]}
+}]]}
+JSON
+  ;;
+list)
+  cat <<'JSON'
+[
+  {"name":"hello","type_id":"greeting","state":"english","methods":["read","metadata","stream","exec","signal","delete","schema"]},
+  {"name":"hola","type_id":"greeting","state":"spanish","methods":["read","metadata","stream","exec","signal","delete","schema"]},
+  {"name":"latest","type_id":"link","methods":[["readlink","hello"],"schema"]}
+]
+JSON
+  ;;
+read)
+  echo "${id##*/} in $state"
+  ;;
+metadata)
+  if [ "$state" = spanish ]; then
+    echo '{"language":1}'
+  else
+    echo "{\"language\":\"$state\"}"
+  fi
+  ;;
+stream)
+  echo 200
+  echo "${id##*/}"
+  echo "${id##*/} again"
+  sleep 60
+  ;;
+exec)
+  shift 4
+  "$@"
+  ;;
+signal)
+  [ "$4" = wave ] || { echo "unsupported signal $4" >&2; exit 1; }
+  ;;
+delete)
+  echo true
+  ;;
+*)
+  echo "unknown method $method" >&2
+  exit 1
+  ;;
+esac
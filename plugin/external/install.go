@@ -0,0 +1,215 @@
+package external
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Install installs the external plugin at src into pluginsDir. src is either a directory
+// or a tarball (optionally gzipped) containing the plugin's manifest and script. The
+// manifest can be at the root of src, or inside a single top-level directory. The plugin
+// is installed into pluginsDir/<name>, replacing any previously installed version.
+func Install(src string, pluginsDir string) (*Manifest, error) {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(pluginsDir, 0750); err != nil {
+		return nil, err
+	}
+
+	// Stage the plugin in pluginsDir so that it can be renamed into place.
+	staging, err := ioutil.TempDir(pluginsDir, ".install")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	if fi.IsDir() {
+		err = copyDir(src, staging)
+	} else {
+		err = extractTarball(src, staging)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not unpack %v: %v", src, err)
+	}
+
+	root, err := findManifestDir(staging)
+	if err != nil {
+		return nil, fmt.Errorf("could not install %v: %v", src, err)
+	}
+	manifest, err := LoadManifest(filepath.Join(root, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Name == "" {
+		return nil, fmt.Errorf("could not install %v: its manifest doesn't specify a name", src)
+	}
+	if manifest.Script == "" {
+		return nil, fmt.Errorf("could not install %v: its manifest doesn't specify a script", src)
+	}
+	if _, err := (PluginSpec{Script: manifest.ScriptPath()}).Load(); err != nil {
+		return nil, fmt.Errorf("could not install %v: %v", src, err)
+	}
+
+	dest := filepath.Join(pluginsDir, manifest.Name)
+	if err := os.RemoveAll(dest); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(root, dest); err != nil {
+		return nil, err
+	}
+	manifest.path = filepath.Join(dest, ManifestFile)
+	return manifest, nil
+}
+
+// InstalledPlugins returns the specs of the plugins installed in pluginsDir. Each
+// installed plugin is a directory containing a manifest that specifies the plugin's
+// script. A missing pluginsDir has no plugins. Plugins with invalid manifests are
+// skipped and reported in the returned error.
+func InstalledPlugins(pluginsDir string) ([]PluginSpec, error) {
+	dirs, err := ioutil.ReadDir(pluginsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var specs []PluginSpec
+	var problems []string
+	for _, dir := range dirs {
+		if !dir.IsDir() || strings.HasPrefix(dir.Name(), ".") {
+			continue
+		}
+		manifest, err := LoadManifest(filepath.Join(pluginsDir, dir.Name(), ManifestFile))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				problems = append(problems, err.Error())
+			}
+			continue
+		}
+		if manifest.Script == "" {
+			problems = append(problems, manifest.errorf("the manifest doesn't specify a script").Error())
+			continue
+		}
+		specs = append(specs, PluginSpec{Script: manifest.ScriptPath()})
+	}
+	if len(problems) > 0 {
+		return specs, fmt.Errorf("%v", strings.Join(problems, "; "))
+	}
+	return specs, nil
+}
+
+// findManifestDir returns the directory in dir that contains the plugin's manifest.
+func findManifestDir(dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, ManifestFile)); err == nil {
+		return dir, nil
+	}
+	children, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(children) == 1 && children[0].IsDir() {
+		subdir := filepath.Join(dir, children[0].Name())
+		if _, err := os.Stat(filepath.Join(subdir, ManifestFile)); err == nil {
+			return subdir, nil
+		}
+	}
+	return "", fmt.Errorf("%v not found", ManifestFile)
+}
+
+func copyDir(src string, dest string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode().IsRegular():
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return writeFile(target, f, info.Mode().Perm())
+		default:
+			return fmt.Errorf("%v is not a regular file or directory", path)
+		}
+	})
+}
+
+func extractTarball(src string, dest string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Support both gzipped and uncompressed tarballs.
+	var rdr io.Reader = bufio.NewReader(f)
+	if magic, err := rdr.(*bufio.Reader).Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(rdr)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		rdr = gz
+	}
+
+	tr := tar.NewReader(rdr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%v is outside of the tarball", hdr.Name)
+		}
+		target := filepath.Join(dest, name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.FileMode(hdr.Mode).Perm()|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+				return err
+			}
+			if err := writeFile(target, tr, os.FileMode(hdr.Mode).Perm()); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%v is not a regular file or directory", hdr.Name)
+		}
+	}
+}
+
+func writeFile(path string, rdr io.Reader, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, rdr); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,219 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/puppetlabs/wash/cmd/version"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ManifestFile is the name of an external plugin's manifest. A plugin's manifest is
+// optional. It's loaded from the same directory as the plugin's script.
+const ManifestFile = "plugin.yaml"
+
+// Manifest describes a packaged external plugin. It's validated before the plugin's
+// init method is invoked.
+type Manifest struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Script is the path to the plugin's script, relative to the manifest. It's required
+	// to install the plugin.
+	Script string `json:"script"`
+	// MinWashVersion is the earliest version of Wash that the plugin supports.
+	MinWashVersion string `json:"min_wash_version"`
+	// Methods lists the methods that the plugin's root can implement. If it's empty, then
+	// the root can implement any method.
+	Methods  []string `json:"methods"`
+	Requires struct {
+		// Binaries must be found in PATH.
+		Binaries []string `json:"binaries"`
+		// Env lists the environment variables that must be set.
+		Env []string `json:"env"`
+	} `json:"requires"`
+	// ConfigSchema is a JSON schema that the plugin's config must conform to.
+	ConfigSchema json.RawMessage `json:"config_schema"`
+
+	path         string
+	configSchema *gojsonschema.Schema
+}
+
+// The methods that an external plugin's entries can implement.
+var manifestMethods = map[string]bool{
+	"list":     true,
+	"read":     true,
+	"write":    true,
+	"metadata": true,
+	"stream":   true,
+	"exec":     true,
+	"schema":   true,
+	"delete":   true,
+	"signal":   true,
+	"readlink": true,
+}
+
+var manifestNameRegex = regexp.MustCompile("^[0-9a-zA-Z_-]+$")
+
+// LoadManifest reads and validates the manifest at path.
+func LoadManifest(path string) (*Manifest, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := yaml.Unmarshal(content, &m); err != nil {
+		return nil, fmt.Errorf("could not parse manifest %v: %v", path, err)
+	}
+	m.path = path
+
+	if m.Name != "" && !manifestNameRegex.MatchString(m.Name) {
+		return nil, m.errorf("invalid name %v. The name must consist of alphanumeric characters, or a hyphen", m.Name)
+	}
+	if m.MinWashVersion != "" {
+		if _, ok := parseVersion(m.MinWashVersion); !ok {
+			return nil, m.errorf("invalid min_wash_version %v. It must look like 1.2.3", m.MinWashVersion)
+		}
+	}
+	for _, method := range m.Methods {
+		if !manifestMethods[method] {
+			return nil, m.errorf("unknown method %v", method)
+		}
+	}
+	if len(m.ConfigSchema) > 0 {
+		m.configSchema, err = gojsonschema.NewSchema(gojsonschema.NewBytesLoader(m.ConfigSchema))
+		if err != nil {
+			return nil, m.errorf("invalid config_schema: %v", err)
+		}
+	}
+	return &m, nil
+}
+
+// loadManifestFor loads the manifest for script. It returns nil if there's no
+// manifest, or if the manifest describes another script.
+func loadManifestFor(script string) (*Manifest, error) {
+	path := filepath.Join(filepath.Dir(script), ManifestFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	m, err := LoadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	if m.Script != "" && filepath.Clean(m.ScriptPath()) != filepath.Clean(script) {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// ScriptPath returns the path to the plugin's script.
+func (m *Manifest) ScriptPath() string {
+	return filepath.Join(filepath.Dir(m.path), m.Script)
+}
+
+func (m *Manifest) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("manifest %v: %v", m.path, fmt.Sprintf(format, a...))
+}
+
+// checkRequirements checks that the plugin's requirements are met.
+func (m *Manifest) checkRequirements() error {
+	var problems []string
+	if m.MinWashVersion != "" {
+		// Development builds don't have a release version, so they're assumed to be recent.
+		if current, ok := parseVersion(version.BuildVersion); ok {
+			if min, _ := parseVersion(m.MinWashVersion); versionLess(current, min) {
+				problems = append(problems, fmt.Sprintf("Wash %v or later is required, but this is Wash %v", m.MinWashVersion, version.BuildVersion))
+			}
+		}
+	}
+	for _, binary := range m.Requires.Binaries {
+		if _, err := exec.LookPath(binary); err != nil {
+			problems = append(problems, fmt.Sprintf("the %v executable was not found in PATH", binary))
+		}
+	}
+	for _, env := range m.Requires.Env {
+		if _, ok := os.LookupEnv(env); !ok {
+			problems = append(problems, fmt.Sprintf("the %v environment variable is not set", env))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("missing requirements: %v", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateConfig checks the plugin's config against the manifest's config schema.
+func (m *Manifest) validateConfig(cfg map[string]interface{}) error {
+	if m.configSchema == nil {
+		return nil
+	}
+	result, err := m.configSchema.Validate(gojsonschema.NewGoLoader(cfg))
+	if err != nil {
+		return fmt.Errorf("could not validate the config: %v", err)
+	}
+	if !result.Valid() {
+		var problems []string
+		for _, err := range result.Errors() {
+			problems = append(problems, err.String())
+		}
+		return fmt.Errorf("invalid config: %v", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkMethods checks that the plugin's root only implements the manifest's methods.
+func (m *Manifest) checkMethods(root *pluginEntry) error {
+	if len(m.Methods) == 0 {
+		return nil
+	}
+	declared := make(map[string]bool)
+	for _, method := range m.Methods {
+		declared[method] = true
+	}
+	for method := range root.methods {
+		if !declared[method] {
+			return fmt.Errorf("the plugin root implements %v, which isn't declared in %v", method, m.path)
+		}
+	}
+	return nil
+}
+
+// parseVersion parses versions like 1.2, 1.2.3 and v1.2.3-4-gabcdef into their major,
+// minor and patch numbers. Anything after the patch number is ignored.
+func parseVersion(v string) ([3]int, bool) {
+	var parsed [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	segments := strings.Split(v, ".")
+	if len(segments) < 2 || len(segments) > 3 {
+		return parsed, false
+	}
+	for i, segment := range segments {
+		n, err := strconv.Atoi(segment)
+		if err != nil || n < 0 {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
+
+func versionLess(a, b [3]int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
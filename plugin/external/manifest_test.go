@@ -0,0 +1,290 @@
+package external
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/puppetlabs/wash/cmd/version"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+type ManifestTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (suite *ManifestTestSuite) SetupTest() {
+	var err error
+	suite.dir, err = ioutil.TempDir("", "wash_manifest")
+	suite.Require().NoError(err)
+}
+
+func (suite *ManifestTestSuite) TearDownTest() {
+	suite.NoError(os.RemoveAll(suite.dir))
+}
+
+// writePlugin writes a plugin with the given manifest and an executable foo.sh script to dir.
+func (suite *ManifestTestSuite) writePlugin(dir string, manifest string) {
+	suite.Require().NoError(os.MkdirAll(dir, 0750))
+	suite.Require().NoError(ioutil.WriteFile(filepath.Join(dir, ManifestFile), []byte(manifest), 0640))
+	suite.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "foo.sh"), []byte("#!/bin/sh\n"), 0750))
+}
+
+func (suite *ManifestTestSuite) TestLoadManifest() {
+	suite.writePlugin(suite.dir, `
+name: foo
+version: 1.0.0
+script: foo.sh
+min_wash_version: 0.10.0
+methods: [list, exec]
+requires:
+  binaries: [sh]
+  env: [HOME]
+config_schema:
+  type: object
+`)
+	m, err := LoadManifest(filepath.Join(suite.dir, ManifestFile))
+	if suite.NoError(err) {
+		suite.Equal("foo", m.Name)
+		suite.Equal("1.0.0", m.Version)
+		suite.Equal(filepath.Join(suite.dir, "foo.sh"), m.ScriptPath())
+		suite.Equal([]string{"list", "exec"}, m.Methods)
+		suite.Equal([]string{"sh"}, m.Requires.Binaries)
+		suite.Equal([]string{"HOME"}, m.Requires.Env)
+		suite.NotNil(m.configSchema)
+	}
+}
+
+func (suite *ManifestTestSuite) TestLoadManifest_Errors() {
+	path := filepath.Join(suite.dir, ManifestFile)
+	for manifest, expectedErr := range map[string]string{
+		"name: [foo":                    "could not parse manifest",
+		"name: foo/bar":                 "invalid name foo/bar",
+		"min_wash_version: latest":      "invalid min_wash_version latest",
+		"methods: [list, fly]":          "unknown method fly",
+		"config_schema: {type: banana}": "invalid config_schema",
+	} {
+		suite.writePlugin(suite.dir, manifest)
+		_, err := LoadManifest(path)
+		suite.Error(err)
+		suite.Contains(err.Error(), expectedErr)
+	}
+}
+
+func (suite *ManifestTestSuite) TestCheckRequirements() {
+	savedVersion := version.BuildVersion
+	defer func() { version.BuildVersion = savedVersion }()
+
+	m := &Manifest{}
+	suite.NoError(m.checkRequirements())
+
+	m.MinWashVersion = "0.12"
+	version.BuildVersion = "unknown"
+	suite.NoError(m.checkRequirements())
+	version.BuildVersion = "0.12.1"
+	suite.NoError(m.checkRequirements())
+	version.BuildVersion = "0.11.4-3-gabcdef"
+	suite.EqualError(m.checkRequirements(), "missing requirements: Wash 0.12 or later is required, but this is Wash 0.11.4-3-gabcdef")
+
+	m.MinWashVersion = ""
+	m.Requires.Binaries = []string{"sh", "wash-nonexistent-binary"}
+	m.Requires.Env = []string{"WASH_NONEXISTENT_VAR"}
+	suite.EqualError(m.checkRequirements(), "missing requirements: the wash-nonexistent-binary executable was not found in PATH; the WASH_NONEXISTENT_VAR environment variable is not set")
+}
+
+func (suite *ManifestTestSuite) TestValidateConfig() {
+	suite.writePlugin(suite.dir, `
+config_schema:
+  type: object
+  properties:
+    profile:
+      type: string
+  required: [profile]
+`)
+	m, err := LoadManifest(filepath.Join(suite.dir, ManifestFile))
+	suite.Require().NoError(err)
+
+	suite.NoError(m.validateConfig(map[string]interface{}{"profile": "dev"}))
+	err = m.validateConfig(map[string]interface{}{})
+	suite.Error(err)
+	suite.Contains(err.Error(), "invalid config: (root): profile is required")
+	err = m.validateConfig(map[string]interface{}{"profile": 1})
+	suite.Error(err)
+	suite.Contains(err.Error(), "invalid config: profile: Invalid type")
+
+	suite.NoError((&Manifest{}).validateConfig(nil))
+}
+
+func (suite *ManifestTestSuite) TestCheckMethods() {
+	root := &pluginEntry{methods: map[string]methodInfo{"list": {}, "exec": {}}}
+	suite.NoError((&Manifest{}).checkMethods(root))
+	suite.NoError((&Manifest{Methods: []string{"list", "exec", "schema"}}).checkMethods(root))
+	suite.EqualError(
+		(&Manifest{Methods: []string{"list"}, path: "plugin.yaml"}).checkMethods(root),
+		"the plugin root implements exec, which isn't declared in plugin.yaml",
+	)
+}
+
+func (suite *ManifestTestSuite) TestParseVersion() {
+	v, ok := parseVersion("v1.2.3-4-gabcdef")
+	suite.True(ok)
+	suite.Equal([3]int{1, 2, 3}, v)
+	v, ok = parseVersion("1.2")
+	suite.True(ok)
+	suite.Equal([3]int{1, 2, 0}, v)
+	_, ok = parseVersion("1")
+	suite.False(ok)
+	_, ok = parseVersion("1.x")
+	suite.False(ok)
+
+	suite.True(versionLess([3]int{0, 9, 9}, [3]int{0, 10, 0}))
+	suite.False(versionLess([3]int{1, 0, 0}, [3]int{1, 0, 0}))
+}
+
+func (suite *ManifestTestSuite) TestLoadSpecWithManifest() {
+	suite.writePlugin(suite.dir, "name: foo\nversion: 1.0.0\nscript: foo.sh\n")
+	root, err := PluginSpec{Script: filepath.Join(suite.dir, "foo.sh")}.Load()
+	if suite.NoError(err) {
+		suite.Equal("foo", plugin.Name(root))
+		suite.Equal("1.0.0", ManifestOf(root).Version)
+	}
+
+	// Manifests for other scripts are ignored.
+	suite.writePlugin(suite.dir, "name: bar\nscript: bar.sh\n")
+	root, err = PluginSpec{Script: filepath.Join(suite.dir, "foo.sh")}.Load()
+	if suite.NoError(err) {
+		suite.Nil(ManifestOf(root))
+	}
+
+	suite.writePlugin(suite.dir, "name: bar\n")
+	_, err = PluginSpec{Script: filepath.Join(suite.dir, "foo.sh")}.Load()
+	suite.Error(err)
+	suite.Contains(err.Error(), "the plugin's name is bar")
+}
+
+func (suite *ManifestTestSuite) TestInstallDir() {
+	src := filepath.Join(suite.dir, "src")
+	pluginsDir := filepath.Join(suite.dir, "plugins")
+	suite.writePlugin(src, "name: foo\nversion: 1.0.0\nscript: foo.sh\n")
+
+	m, err := Install(src, pluginsDir)
+	if suite.NoError(err) {
+		suite.Equal("foo", m.Name)
+		suite.Equal(filepath.Join(pluginsDir, "foo", "foo.sh"), m.ScriptPath())
+	}
+
+	// Reinstalling replaces the previous version.
+	suite.writePlugin(src, "name: foo\nversion: 1.1.0\nscript: foo.sh\n")
+	_, err = Install(src, pluginsDir)
+	suite.NoError(err)
+
+	specs, err := InstalledPlugins(pluginsDir)
+	if suite.NoError(err) {
+		suite.Equal([]PluginSpec{{Script: filepath.Join(pluginsDir, "foo", "foo.sh")}}, specs)
+	}
+	root, err := specs[0].Load()
+	if suite.NoError(err) {
+		suite.Equal("1.1.0", ManifestOf(root).Version)
+	}
+
+	// Nothing is left behind in pluginsDir.
+	files, err := ioutil.ReadDir(pluginsDir)
+	if suite.NoError(err) && suite.Len(files, 1) {
+		suite.Equal("foo", files[0].Name())
+	}
+}
+
+func (suite *ManifestTestSuite) TestInstallDir_Errors() {
+	src := filepath.Join(suite.dir, "src")
+	pluginsDir := filepath.Join(suite.dir, "plugins")
+
+	suite.writePlugin(src, "script: foo.sh\n")
+	_, err := Install(src, pluginsDir)
+	suite.Error(err)
+	suite.Contains(err.Error(), "its manifest doesn't specify a name")
+
+	suite.writePlugin(src, "name: foo\n")
+	_, err = Install(src, pluginsDir)
+	suite.Error(err)
+	suite.Contains(err.Error(), "its manifest doesn't specify a script")
+
+	suite.writePlugin(src, "name: foo\nscript: missing.sh\n")
+	_, err = Install(src, pluginsDir)
+	suite.Error(err)
+	suite.Contains(err.Error(), "no such file or directory")
+
+	suite.NoError(os.Remove(filepath.Join(src, ManifestFile)))
+	_, err = Install(src, pluginsDir)
+	suite.Error(err)
+	suite.Contains(err.Error(), ManifestFile+" not found")
+}
+
+func (suite *ManifestTestSuite) TestInstallTarball() {
+	tarball := filepath.Join(suite.dir, "foo.tar.gz")
+	suite.writeTarball(tarball, map[string]string{
+		"foo-1.0.0/":            "",
+		"foo-1.0.0/plugin.yaml": "name: foo\nscript: foo.sh\n",
+		"foo-1.0.0/foo.sh":      "#!/bin/sh\n",
+	})
+
+	pluginsDir := filepath.Join(suite.dir, "plugins")
+	m, err := Install(tarball, pluginsDir)
+	if suite.NoError(err) {
+		suite.Equal(filepath.Join(pluginsDir, "foo", "foo.sh"), m.ScriptPath())
+	}
+
+	suite.writeTarball(tarball, map[string]string{"../plugin.yaml": "name: foo\n"})
+	_, err = Install(tarball, pluginsDir)
+	suite.Error(err)
+	suite.Contains(err.Error(), "../plugin.yaml is outside of the tarball")
+}
+
+func (suite *ManifestTestSuite) writeTarball(path string, files map[string]string) {
+	f, err := os.Create(path)
+	suite.Require().NoError(err)
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	// Write directories first so that they exist before their files.
+	for _, dirs := range []bool{true, false} {
+		for name, content := range files {
+			isDir := name[len(name)-1] == '/'
+			if isDir != dirs {
+				continue
+			}
+			hdr := &tar.Header{Name: name, Mode: 0750, Size: int64(len(content)), Typeflag: tar.TypeReg}
+			if isDir {
+				hdr.Typeflag = tar.TypeDir
+			}
+			suite.Require().NoError(tw.WriteHeader(hdr))
+			_, err := tw.Write([]byte(content))
+			suite.Require().NoError(err)
+		}
+	}
+	suite.Require().NoError(tw.Close())
+	suite.Require().NoError(gz.Close())
+}
+
+func (suite *ManifestTestSuite) TestInstalledPlugins() {
+	specs, err := InstalledPlugins(filepath.Join(suite.dir, "missing"))
+	suite.NoError(err)
+	suite.Empty(specs)
+
+	suite.writePlugin(filepath.Join(suite.dir, "foo"), "name: foo\nscript: foo.sh\n")
+	suite.writePlugin(filepath.Join(suite.dir, "bar"), "name: bar\n")
+	suite.Require().NoError(os.MkdirAll(filepath.Join(suite.dir, "empty"), 0750))
+
+	specs, err = InstalledPlugins(suite.dir)
+	suite.Equal([]PluginSpec{{Script: filepath.Join(suite.dir, "foo", "foo.sh")}}, specs)
+	suite.Error(err)
+	suite.Contains(err.Error(), "the manifest doesn't specify a script")
+}
+
+func TestManifest(t *testing.T) {
+	suite.Run(t, new(ManifestTestSuite))
+}
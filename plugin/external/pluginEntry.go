@@ -130,6 +130,12 @@ func (e decodedExternalPluginEntry) getMungedMethods() (map[string]methodInfo, e
 				return nil, fmt.Errorf("unsupported transport %v requested, only ssh is supported", impl.Transport)
 			}
 			info.tupleValue = impl
+		case "readlink":
+			var target string
+			if err := json.Unmarshal(tuple.Value, &target); err != nil {
+				return nil, fmt.Errorf("Readlink method must provide a string, not %v", string(tuple.Value))
+			}
+			info.tupleValue = target
 		}
 
 		methods[tuple.Method] = info
@@ -478,6 +484,18 @@ func (e *pluginEntry) Metadata(ctx context.Context) (plugin.JSONObject, error) {
 	return metadata, nil
 }
 
+func (e *pluginEntry) Readlink(ctx context.Context) (string, error) {
+	if target := e.methods["readlink"].tupleValue; target != nil {
+		return target.(string), nil
+	}
+
+	inv, err := e.script.InvokeAndWait(ctx, "readlink", e)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(inv.Stdout().String()), nil
+}
+
 func (e *pluginEntry) Signal(ctx context.Context, signal string) error {
 	_, err := e.script.InvokeAndWait(ctx, "signal", e, signal)
 	return err
@@ -604,12 +622,16 @@ func (e *pluginEntry) Exec(ctx context.Context, cmd string, args []string, opts
 	// Serialize opts to JSON
 	type serializedOptions struct {
 		plugin.ExecOptions
-		Stdin bool `json:"stdin"`
+		Stdin   bool   `json:"stdin"`
+		Timeout string `json:"timeout,omitempty"`
 	}
 	serializedOpts := serializedOptions{
 		ExecOptions: opts,
 		Stdin:       opts.Stdin != nil,
 	}
+	if opts.Timeout > 0 {
+		serializedOpts.Timeout = opts.Timeout.String()
+	}
 	optsJSON, err := json.Marshal(serializedOpts)
 	if err != nil {
 		return nil, fmt.Errorf("could not marshal opts %v into JSON: %v", opts, err)
@@ -170,6 +170,22 @@ func (suite *ExternalPluginEntryTestSuite) TestDecodeExternalPluginEntryMethodTu
 	}
 }
 
+func (suite *ExternalPluginEntryTestSuite) TestDecodeExternalPluginEntryMethodTuple_Readlink() {
+	decodedEntry := decodedExternalPluginEntry{
+		Name:    "decodedEntry",
+		Methods: rawMethods(`["readlink", "../target"]`),
+	}
+	entry, err := decodedEntry.toExternalPluginEntry(context.Background(), false, false)
+	if suite.NoError(err) {
+		suite.Equal(plugin.DefaultSignature, entry.methods["readlink"].signature)
+		suite.Equal("../target", entry.methods["readlink"].tupleValue)
+	}
+
+	decodedEntry.Methods = rawMethods(`["readlink", 1]`)
+	_, err = decodedEntry.toExternalPluginEntry(context.Background(), false, false)
+	suite.Regexp("Readlink method must provide a string", err)
+}
+
 func newMockDecodedEntry(name string) decodedExternalPluginEntry {
 	return decodedExternalPluginEntry{
 		Name:    name,
@@ -592,6 +608,33 @@ func (suite *ExternalPluginEntryTestSuite) TestRead() {
 	}
 }
 
+func (suite *ExternalPluginEntryTestSuite) TestReadlink() {
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	entry := &pluginEntry{
+		EntryBase: plugin.NewEntry("foo"),
+		script:    mockScript,
+	}
+	entry.SetTestID("/foo")
+
+	ctx := context.Background()
+	mockInvokeAndWait := func(stdout []byte, err error) {
+		mockScript.OnInvokeAndWait(ctx, "readlink", entry).Return(mockInvocation(stdout), err).Once()
+	}
+
+	// Test that if InvokeAndWait errors, then Readlink returns its error
+	mockErr := fmt.Errorf("execution error")
+	mockInvokeAndWait([]byte{}, mockErr)
+	_, err := entry.Readlink(ctx)
+	suite.EqualError(err, mockErr.Error())
+
+	// Test that Readlink returns the invocation's trimmed stdout
+	mockInvokeAndWait([]byte("/foo/bar\n"), nil)
+	target, err := entry.Readlink(ctx)
+	if suite.NoError(err) {
+		suite.Equal("/foo/bar", target)
+	}
+}
+
 func (suite *ExternalPluginEntryTestSuite) TestBlockRead() {
 	mockScript := &mockPluginScript{path: "plugin_script"}
 	entry := &pluginEntry{
@@ -1000,6 +1043,33 @@ func (suite *ExternalPluginEntryTestSuite) TestExec() {
 	}
 }
 
+func (suite *ExternalPluginEntryTestSuite) TestExec_SerializesOptions() {
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	entry := &pluginEntry{
+		EntryBase: plugin.NewEntry("foo"),
+		methods:   map[string]methodInfo{"exec": methodInfo{}},
+		script:    mockScript,
+	}
+	entry.SetTestID("/foo")
+
+	ctx := context.Background()
+	mockInv := &mockedInvocation{Command: NewCommand(ctx, "")}
+	optsJSON := `{"tty":false,"elevate":false,"env":{"FOO":"bar"},"working_dir":"/tmp","user":"root","stdin":false,"timeout":"1m0s"}`
+	mockScript.On("NewInvocation", ctx, "exec", entry, []string{optsJSON, "echo", "hello"}).Return(mockInv).Once()
+	mockInv.MockExec(nil, nil, 0)
+
+	opts := plugin.ExecOptions{
+		Env:        map[string]string{"FOO": "bar"},
+		WorkingDir: "/tmp",
+		User:       "root",
+		Timeout:    time.Minute,
+	}
+	_, err := entry.Exec(ctx, "echo", []string{"hello"}, opts)
+	if suite.NoError(err) {
+		mockScript.AssertExpectations(suite.T())
+	}
+}
+
 func (suite *ExternalPluginEntryTestSuite) TestExec_Transport() {
 	// Mock transport.ExecSSH
 	savedFn := execSSHFn
@@ -1424,9 +1494,9 @@ func (suite *ExternalPluginEntryTestSuite) TestUnmarshalSchemaGraph_CoreEntries(
 			assertFunc(schema.(plugin.EntrySchema))
 		}
 
-		// Ensure that only four nodes exist in schema graph -- "foo", volume::fs,
-		// volume::dir, and volume::file
-		suite.Equal(int(4), graph.Size())
+		// Ensure that only six nodes exist in schema graph -- "foo", volume::fs,
+		// volume::dir, volume::file, volume::writableFile and volume::symlink
+		suite.Equal(int(6), graph.Size())
 
 		// Now ensure that the right nodes are set in the graph
 		volumeFSTemplate := (&volumeFS{}).template()
@@ -13,30 +13,50 @@ import (
 // pluginRoot represents an external plugin's root.
 type pluginRoot struct {
 	pluginEntry
+	manifest *Manifest
 }
 
 // Init initializes the external plugin root
 func (r *pluginRoot) Init(cfg map[string]interface{}) error {
+	return r.InitContext(context.Background(), cfg)
+}
+
+// InitContext implements plugin.ContextIniter#InitContext. The init script's
+// killed once ctx's deadline expires, which defaults to five seconds.
+func (r *pluginRoot) InitContext(ctx context.Context, cfg map[string]interface{}) error {
 	if cfg == nil {
 		cfg = make(map[string]interface{})
 	}
+	if r.manifest != nil {
+		if err := r.manifest.checkRequirements(); err != nil {
+			return err
+		}
+		if err := r.manifest.validateConfig(cfg); err != nil {
+			return err
+		}
+	}
+
 	cfgJSON, err := json.Marshal(cfg)
 	if err != nil {
 		return fmt.Errorf("could not marshal plugin config %v into JSON: %v", cfg, err)
 	}
 
 	// Give external plugins about five-seconds to finish their
-	// initialization
-	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	// initialization unless the plugin's limits configure an init
+	// timeout
+	start := time.Now()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = start.Add(5 * time.Second)
+	}
+	ctx, cancelFunc := context.WithDeadline(ctx, deadline)
 	defer cancelFunc()
 	inv, err := r.script.InvokeAndWait(ctx, "init", nil, string(cfgJSON))
 	if err != nil {
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("timed out while waiting for init to finish")
-		default:
-			return err
+		if ctx.Err() == context.DeadlineExceeded {
+			return plugin.NewTimeoutErr("init", deadline.Sub(start).Round(time.Millisecond), err)
 		}
+		return err
 	}
 	var decodedRoot decodedExternalPluginEntry
 	if err := json.Unmarshal(inv.Stdout().Bytes(), &decodedRoot); err != nil {
@@ -66,6 +86,11 @@ it's safe to omit name from the response to 'init'`, r.script.Path()))
 	if !plugin.ListAction().IsSupportedOn(entry) {
 		panic(fmt.Sprintf("plugin root for %s must implement 'list'", r.script.Path()))
 	}
+	if r.manifest != nil {
+		if err := r.manifest.checkMethods(entry); err != nil {
+			return err
+		}
+	}
 	script := r.script
 	r.pluginEntry = *entry
 	r.pluginEntry.script = script
@@ -19,7 +19,7 @@ type ExternalPluginRootTestSuite struct {
 
 func (suite *ExternalPluginRootTestSuite) TestInit() {
 	mockScript := &mockPluginScript{path: "plugin_script"}
-	root := &pluginRoot{pluginEntry{
+	root := &pluginRoot{pluginEntry: pluginEntry{
 		EntryBase: plugin.NewEntry("foo"),
 		script:    mockScript,
 	}}
@@ -67,7 +67,7 @@ func (suite *ExternalPluginRootTestSuite) TestInit() {
 
 func (suite *ExternalPluginRootTestSuite) TestInitWithConfig() {
 	mockScript := &mockPluginScript{path: "plugin_script"}
-	root := &pluginRoot{pluginEntry{
+	root := &pluginRoot{pluginEntry: pluginEntry{
 		EntryBase: plugin.NewEntry("foo"),
 		script:    mockScript,
 	}}
@@ -82,9 +82,37 @@ func (suite *ExternalPluginRootTestSuite) TestInitWithConfig() {
 	suite.NoError(root.Init(map[string]interface{}{"key": []string{"value"}}))
 }
 
+func (suite *ExternalPluginRootTestSuite) TestInitWithManifest() {
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	root := &pluginRoot{
+		pluginEntry: pluginEntry{
+			EntryBase: plugin.NewEntry("foo"),
+			script:    mockScript,
+		},
+		manifest: &Manifest{Methods: []string{"list"}, path: "plugin.yaml"},
+	}
+
+	// Test that Init doesn't invoke the script if the plugin's requirements aren't met
+	root.manifest.Requires.Env = []string{"WASH_NONEXISTENT_VAR"}
+	err := root.Init(nil)
+	suite.EqualError(err, "missing requirements: the WASH_NONEXISTENT_VAR environment variable is not set")
+	mockScript.AssertNotCalled(suite.T(), "InvokeAndWait", mock.Anything, "init", nil, "{}")
+	root.manifest.Requires.Env = nil
+
+	// Test that Init returns an error if the root implements undeclared methods
+	mockScript.OnInvokeAndWait(
+		mock.Anything,
+		"init",
+		nil,
+		"{}",
+	).Return(mockInvocation([]byte(`{"methods":["list","exec"]}`)), nil).Once()
+	err = root.Init(nil)
+	suite.EqualError(err, "the plugin root implements exec, which isn't declared in plugin.yaml")
+}
+
 func (suite *ExternalPluginRootTestSuite) TestInitWithSchema_SetsSchemaKnownVariable() {
 	mockScript := &mockPluginScript{path: "plugin_script"}
-	root := &pluginRoot{pluginEntry{
+	root := &pluginRoot{pluginEntry: pluginEntry{
 		EntryBase: plugin.NewEntry("foo"),
 		script:    mockScript,
 	}}
@@ -102,7 +130,7 @@ func (suite *ExternalPluginRootTestSuite) TestInitWithSchema_SetsSchemaKnownVari
 
 func (suite *ExternalPluginRootTestSuite) TestInitWithSchema_PrefetchedSchema_ReturnsErrorIfUnmarshallingSchemaFails() {
 	mockScript := &mockPluginScript{path: "plugin_script"}
-	root := &pluginRoot{pluginEntry{
+	root := &pluginRoot{pluginEntry: pluginEntry{
 		EntryBase: plugin.NewEntry("foo"),
 		script:    mockScript,
 	}}
@@ -123,7 +151,7 @@ func (suite *ExternalPluginRootTestSuite) TestInitWithSchema_PrefetchedSchema_Re
 
 func (suite *ExternalPluginRootTestSuite) TestInitWithSchema_PrefetchedSchema_PartitionsSchemaGraph() {
 	mockScript := &mockPluginScript{path: "plugin_script"}
-	root := &pluginRoot{pluginEntry{
+	root := &pluginRoot{pluginEntry: pluginEntry{
 		EntryBase: plugin.NewEntry("fooPlugin"),
 		script:    mockScript,
 	}}
@@ -28,6 +28,10 @@ type invocation interface {
 	Stderr() *bytes.Buffer
 }
 
+// RetryableExitCode is the exit code (EX_TEMPFAIL) that a plugin script uses to mark its
+// failure as temporary, like API throttling. See plugin.Retryable.
+const RetryableExitCode = 75
+
 type invocationImpl struct {
 	Command
 	stdout, stderr bytes.Buffer
@@ -49,7 +53,11 @@ func (inv *invocationImpl) RunAndWait(ctx context.Context) error {
 		activity.Record(ctx, "stderr: %v", inv.stderr.String())
 	}
 	if exitCode != 0 {
-		return newInvokeError(fmt.Sprintf("script returned a non-zero exit code of %v", exitCode), inv)
+		err := newInvokeError(fmt.Sprintf("script returned a non-zero exit code of %v", exitCode), inv)
+		if exitCode == RetryableExitCode {
+			return plugin.Retryable(err)
+		}
+		return err
 	}
 	return nil
 }
@@ -21,6 +21,7 @@ func (s PluginSpec) Name() string {
 }
 
 // Load ensures the external plugin represents an executable artifact and create a plugin Root.
+// If the script has a manifest, it's loaded as well.
 func (s PluginSpec) Load() (plugin.Root, error) {
 	fi, err := os.Stat(s.Script)
 	if err != nil {
@@ -31,9 +32,38 @@ func (s PluginSpec) Load() (plugin.Root, error) {
 		return nil, fmt.Errorf("script %v is not executable", s.Script)
 	}
 
-	root := &pluginRoot{pluginEntry{
-		EntryBase: plugin.NewEntry(s.Name()),
-		script:    externalPluginScriptImpl{path: s.Script},
-	}}
+	manifest, err := loadManifestFor(s.Script)
+	if err != nil {
+		return nil, err
+	}
+	if manifest != nil && manifest.Name != "" && manifest.Name != s.Name() {
+		return nil, manifest.errorf("the plugin's name is %v, but its script %v is named %v", manifest.Name, s.Script, s.Name())
+	}
+
+	root := &pluginRoot{
+		pluginEntry: pluginEntry{
+			EntryBase: plugin.NewEntry(s.Name()),
+			script:    externalPluginScriptImpl{path: s.Script},
+		},
+		manifest: manifest,
+	}
 	return root, nil
 }
+
+// ScriptPath returns the path to the script that implements root. It returns false
+// if root isn't an external plugin's root.
+func ScriptPath(root plugin.Root) (string, bool) {
+	if r, ok := root.(*pluginRoot); ok {
+		return r.script.Path(), true
+	}
+	return "", false
+}
+
+// ManifestOf returns the manifest of root. It returns nil if root isn't an external
+// plugin's root or if the plugin doesn't have a manifest.
+func ManifestOf(root plugin.Root) *Manifest {
+	if r, ok := root.(*pluginRoot); ok {
+		return r.manifest
+	}
+	return nil
+}
@@ -12,6 +12,10 @@ func TestLoadExternalPlugin(t *testing.T) {
 	root, err := spec.Load()
 	assert.NoError(t, err)
 	assert.Equal(t, "external", plugin.Name(root))
+
+	script, ok := ScriptPath(root)
+	assert.True(t, ok)
+	assert.Equal(t, "testdata/external.sh", script)
 }
 
 func TestLoadExternalPluginNoExec(t *testing.T) {
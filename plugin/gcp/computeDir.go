@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/option"
 )
@@ -12,6 +13,7 @@ import (
 type computeProjectService struct {
 	*compute.Service
 	projectID string
+	winrm     *transport.WinRMConfig
 }
 
 type computeDir struct {
@@ -21,14 +23,14 @@ type computeDir struct {
 
 const computeScope = compute.CloudPlatformScope
 
-func newComputeDir(ctx context.Context, client *http.Client, projID string) (*computeDir, error) {
+func newComputeDir(ctx context.Context, client *http.Client, projID string, winrm *transport.WinRMConfig) (*computeDir, error) {
 	svc, err := compute.NewService(context.Background(), option.WithHTTPClient(client))
 	if err != nil {
 		return nil, err
 	}
 	c := &computeDir{
 		EntryBase: plugin.NewEntry("compute"),
-		service:   computeProjectService{Service: svc, projectID: projID},
+		service:   computeProjectService{Service: svc, projectID: projID, winrm: winrm},
 	}
 	if _, err := plugin.List(ctx, c); err != nil {
 		c.MarkInaccessible(ctx, err)
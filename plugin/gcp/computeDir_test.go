@@ -12,7 +12,7 @@ func TestComputeDir(t *testing.T) {
 	ctx := plugin.SetTestCache(datastore.NewMemCache())
 	defer plugin.UnsetTestCache()
 
-	dir, err := newComputeDir(ctx, nil, "dummy")
+	dir, err := newComputeDir(ctx, nil, "dummy", nil)
 	if assert.NoError(t, err) {
 		assert.Implements(t, (*plugin.Parent)(nil), dir)
 	}
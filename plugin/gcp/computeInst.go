@@ -17,7 +17,6 @@ import (
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 	"github.com/puppetlabs/wash/transport"
-	"github.com/puppetlabs/wash/volume"
 	"golang.org/x/crypto/ssh"
 	compute "google.golang.org/api/compute/v1"
 )
@@ -38,17 +37,32 @@ func newComputeInstance(inst *compute.Instance, c computeProjectService) *comput
 	if err != nil {
 		panic(fmt.Sprintf("Timestamp for %v was not expected format RFC3339: %v", comp, inst.CreationTimestamp))
 	}
-	// No way to determine OS type from the Instance. Should we scrape some ports to see if
-	// SSH or WinRM are exposed?
+	shell := plugin.POSIXShell
+	if isWindows(inst) {
+		shell = plugin.PowerShell
+	}
 	comp.
 		DisableCachingFor(plugin.MetadataOp).
 		SetPartialMetadata(inst).
 		Attributes().
 		SetCrtime(crtime).
-		SetOS(plugin.OS{LoginShell: plugin.POSIXShell})
+		SetOS(plugin.OS{LoginShell: shell})
 	return comp
 }
 
+// isWindows returns true if the instance boots from a Windows image. Windows images are
+// published in the windows-cloud project, so their disks carry its licenses.
+func isWindows(inst *compute.Instance) bool {
+	for _, disk := range inst.Disks {
+		for _, license := range disk.Licenses {
+			if strings.Contains(license, "/projects/windows-cloud/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (c *computeInstance) List(ctx context.Context) ([]plugin.Entry, error) {
 	metadataJSONFile, err := plugin.NewMetadataJSONFile(ctx, c)
 	if err != nil {
@@ -58,9 +72,9 @@ func (c *computeInstance) List(ctx context.Context) ([]plugin.Entry, error) {
 	return []plugin.Entry{
 		newComputeInstanceConsoleOutput(c.instance, c.service),
 		metadataJSONFile,
-		// Include a view of the remote filesystem using volume.FS. Use a small maxdepth because
-		// VMs can have lots of files and SSH is fast.
-		volume.NewFS(ctx, "fs", c, 3),
+		// Include a view of the remote filesystem using SFTP, which falls back to exec if needed.
+		// Use a small maxdepth because VMs can have lots of files and SSH is fast.
+		transport.NewFS(ctx, "fs", c, 3),
 	}, nil
 }
 
@@ -83,7 +97,7 @@ func (c *computeInstance) ChildSchemas() []*plugin.EntrySchema {
 	return []*plugin.EntrySchema{
 		(&computeInstanceConsoleOutput{}).Schema(),
 		(&plugin.MetadataJSONFile{}).Schema(),
-		(&volume.FS{}).Schema(),
+		(&transport.SFTP{}).Schema(),
 	}
 }
 
@@ -104,20 +118,52 @@ func (c *computeInstance) Signal(ctx context.Context, signal string) error {
 
 func (c *computeInstance) Exec(ctx context.Context, cmd string, args []string,
 	opts plugin.ExecOptions) (plugin.ExecCommand, error) {
-	conf, err := gceSSHFiles()
+	if c.service.winrm.UsesWinRM(c.Name(), isWindows(c.instance)) {
+		identity, err := c.WinRMIdentity()
+		if err != nil {
+			return nil, err
+		}
+		return transport.ExecWinRM(ctx, identity, append([]string{cmd}, args...), opts)
+	}
+
+	identity, err := c.SSHIdentity(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return transport.ExecSSH(ctx, identity, append([]string{cmd}, args...), opts)
+}
+
+// WinRMIdentity returns the identity that's used to connect to a Windows instance. GCE
+// doesn't expose Windows passwords, so the user and password must be configured.
+func (c *computeInstance) WinRMIdentity() (transport.WinRMIdentity, error) {
+	hostname := getExternalIP(c.instance)
+	if hostname == "" {
+		return transport.WinRMIdentity{}, fmt.Errorf("%v does not have an external IP address", c.Name())
+	}
+	identity := c.service.winrm.Identity(hostname)
+	if identity.User == "" || identity.Password == "" {
+		return transport.WinRMIdentity{}, fmt.Errorf("gcp.winrm user and password must be configured to exec on %v", c.Name())
+	}
+	return identity, nil
+}
+
+// SSHIdentity returns the identity that's used to connect to the instance. It adds the
+// user's public key to the instance if needed.
+func (c *computeInstance) SSHIdentity(ctx context.Context) (transport.Identity, error) {
+	conf, err := gceSSHFiles()
+	if err != nil {
+		return transport.Identity{}, err
+	}
 
 	// Extract username and key from public key file name.
 	user, key, err := parseUserAndKey(conf.publicKey)
 	if err != nil {
-		return nil, err
+		return transport.Identity{}, err
 	}
 
 	keyAdded, err := c.addPublicKey(ctx, user, key)
 	if err != nil {
-		return nil, err
+		return transport.Identity{}, err
 	}
 
 	// TODO: Get host keys for the instance.
@@ -129,7 +175,7 @@ func (c *computeInstance) Exec(ctx context.Context, cmd string, args []string,
 
 	hostname := getExternalIP(c.instance)
 	if hostname == "" {
-		return nil, fmt.Errorf("%v does not have an external IP address", c.Name())
+		return transport.Identity{}, fmt.Errorf("%v does not have an external IP address", c.Name())
 	}
 
 	identity := transport.Identity{
@@ -143,7 +189,7 @@ func (c *computeInstance) Exec(ctx context.Context, cmd string, args []string,
 		// It may take some time for the new key to be added to the instance. Retry for up to 15s.
 		identity.Retries = 30
 	}
-	return transport.ExecSSH(ctx, identity, append([]string{cmd}, args...), opts)
+	return identity, nil
 }
 
 // Based on https://github.com/google-cloud-sdk/google-cloud-sdk/blob/v255.0.0/lib/googlecloudsdk/command_lib/compute/ssh_utils.py#L106
@@ -367,8 +413,18 @@ func ensureKey(metadata *compute.Metadata, keyField, user, key string, upload fu
 }
 
 const computeInstDescription = `
-This is a GCP Compute instance. Its Exec method mirrors running gcloud compute ssh.
+This is a GCP Compute instance. Windows instances, and instances listed in the
+gcp.winrm.instances config, are exec'd via WinRM using the user and password
+configured under gcp.winrm. Otherwise its Exec method mirrors running gcloud compute ssh.
 If not already present, it will generate a Google Compute-specific SSH key pair and
 known hosts file in your ~/.ssh directory and ensure they’re present on the machine
 you’re trying to connect to. Your current $USER name will be used as the login user.
+
+The rest of the SSH configuration is looked up by the instance's external IP address
+from your user and global SSH config files, so Port, ConnectTimeout,
+ServerAliveInterval and StrictHostKeyChecking are supported, and instances behind a
+bastion can be reached via ProxyJump or ProxyCommand. Any IdentityFile in SSH config
+is tried after the Google Compute key, and the Google Compute known hosts file is
+used instead of the ones in SSH config. If present, a local SSH agent will be used
+for authentication.
 `
@@ -77,3 +77,15 @@ func TestFindKey(t *testing.T) {
 	assert.Equal(t, &testString, findKey(&meta, "my-key"))
 	assert.Nil(t, findKey(&meta, "not-my-key"))
 }
+
+func TestIsWindows(t *testing.T) {
+	inst := compute.Instance{Name: "win", CreationTimestamp: time.Now().Format(time.RFC3339)}
+	assert.False(t, isWindows(&inst))
+
+	inst.Disks = []*compute.AttachedDisk{{
+		Licenses: []string{"https://www.googleapis.com/compute/v1/projects/windows-cloud/global/licenses/windows-server-2019-dc"},
+	}}
+	assert.True(t, isWindows(&inst))
+	compInst := newComputeInstance(&inst, computeProjectService{})
+	assert.Equal(t, plugin.PowerShell, compInst.Attributes().OS().LoginShell)
+}
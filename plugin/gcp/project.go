@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
 	crm "google.golang.org/api/cloudresourcemanager/v1"
 	"google.golang.org/api/option"
 )
@@ -16,15 +17,16 @@ type project struct {
 	plugin.EntryBase
 	client *http.Client
 	id     string
+	winrm  *transport.WinRMConfig
 }
 
 // NewProject creates a new project with a collection of service clients.
-func newProject(p *crm.Project, client *http.Client) *project {
+func newProject(p *crm.Project, client *http.Client, winrm *transport.WinRMConfig) *project {
 	name := p.Name
 	if name == "" {
 		name = p.ProjectId
 	}
-	proj := &project{EntryBase: plugin.NewEntry(name), client: client, id: p.ProjectId}
+	proj := &project{EntryBase: plugin.NewEntry(name), client: client, id: p.ProjectId, winrm: winrm}
 	proj.SetPartialMetadata(p)
 	return proj
 }
@@ -47,7 +49,7 @@ func (p *project) List(ctx context.Context) ([]plugin.Entry, error) {
 		}
 	}
 
-	go func() { save(newComputeDir(ctx, p.client, p.id)) }()
+	go func() { save(newComputeDir(ctx, p.client, p.id, p.winrm)) }()
 	go func() { save(newStorageDir(ctx, p.client, p.id)) }()
 	go func() { save(newFirestoreDir(ctx, p.id)) }()
 	go func() { save(newPubsubDir(ctx, p.id)) }()
@@ -9,6 +9,7 @@ import (
 
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
 	"golang.org/x/oauth2/google"
 	crm "google.golang.org/api/cloudresourcemanager/v1"
 	"google.golang.org/api/option"
@@ -19,6 +20,7 @@ type Root struct {
 	plugin.EntryBase
 	oauthClient *http.Client
 	projects    map[string]struct{}
+	winrm       *transport.WinRMConfig
 }
 
 // serviceScopes lists all scopes used by this module.
@@ -49,6 +51,10 @@ func (r *Root) Init(cfg map[string]interface{}) error {
 		}
 	}
 
+	if r.winrm, err = transport.ParseWinRMConfig("gcp", cfg); err != nil {
+		return err
+	}
+
 	return err
 }
 
@@ -89,7 +95,7 @@ func (r *Root) List(ctx context.Context) ([]plugin.Entry, error) {
 				continue
 			}
 		}
-		projects = append(projects, newProject(proj, r.oauthClient))
+		projects = append(projects, newProject(proj, r.oauthClient, r.winrm))
 	}
 	return projects, nil
 }
@@ -113,4 +119,15 @@ gcp:
   projects: [project-1, project-2]
 
 to Wash’s config file. Project can be referenced either by name or project ID.
+
+Windows instances are exec'd via WinRM over HTTPS. GCE doesn't expose their passwords,
+so configure credentials with
+
+gcp:
+  winrm:
+    user: wash
+    password: hunter2
+    instances: [linux-with-winrm]
+
+See Wash's config docs for the other WinRM settings.
 `
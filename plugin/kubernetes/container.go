@@ -89,23 +89,34 @@ func (c *container) Stream(ctx context.Context) (io.ReadCloser, error) {
 }
 
 func (c *container) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	if opts.User != "" {
+		return nil, plugin.UnsupportedExecOptionErr{
+			Option: "user",
+			Reason: "Kubernetes always runs exec'ed commands as the container's user",
+		}
+	}
+
 	execRequest := c.client.CoreV1().RESTClient().Post().
 		Resource("pods").
 		Name(c.pod.Name).
 		Namespace(c.ns).
 		SubResource("exec").
-		Param("command", cmd).
 		Param("container", c.Name()).
 		Param("stderr", "true").
 		Param("stdout", "true")
 
-	for _, arg := range args {
+	// The exec API doesn't support setting the environment or working directory,
+	// so those options require a POSIX shell in the container.
+	for _, arg := range plugin.WrapCommand(append([]string{cmd}, args...), opts) {
 		execRequest = execRequest.Param("command", arg)
 	}
 
-	if opts.Stdin != nil {
+	if opts.Stdin != nil || opts.Tty {
 		execRequest = execRequest.Param("stdin", "true")
 	}
+	if opts.Tty {
+		execRequest = execRequest.Param("tty", "true")
+	}
 
 	executor, err := remotecommand.NewSPDYExecutor(c.config, "POST", execRequest.URL())
 	if err != nil {
@@ -142,6 +153,9 @@ func (c *container) Exec(ctx context.Context, cmd string, args []string, opts pl
 			Stdin:  stdin,
 			Tty:    opts.Tty,
 		}
+		if opts.Tty && opts.Resize != nil {
+			streamOpts.TerminalSizeQueue = terminalSizeQueue(opts.Resize)
+		}
 		err = executor.Stream(streamOpts)
 		activity.Record(ctx, "Exec on %v complete: %v", c.Name(), err)
 		if err == nil {
@@ -159,3 +173,15 @@ func (c *container) Exec(ctx context.Context, cmd string, args []string, opts pl
 
 	return execCmd, nil
 }
+
+// terminalSizeQueue adapts an interactive session's resize channel to
+// remotecommand's TerminalSizeQueue.
+type terminalSizeQueue <-chan plugin.TerminalSize
+
+func (q terminalSizeQueue) Next() *remotecommand.TerminalSize {
+	size, ok := <-q
+	if !ok {
+		return nil
+	}
+	return &remotecommand.TerminalSize{Height: size.Rows, Width: size.Cols}
+}
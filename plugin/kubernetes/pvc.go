@@ -251,6 +251,14 @@ func (v *pvc) VolumeDelete(ctx context.Context, path string) (bool, error) {
 	return true, nil
 }
 
+func (v *pvc) VolumeReadlink(ctx context.Context, path string) (string, error) {
+	output, err := v.runInTemporaryPod(ctx, []string{"readlink", mountpoint + path})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 const pvcDescription = `
 This is a Kubernetes persistent volume claim. We create a temporary Kubernetes
 pod whenever Wash invokes a currently uncached List/Read/Stream action on it or
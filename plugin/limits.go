@@ -0,0 +1,638 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/puppetlabs/wash/tracing"
+	"golang.org/x/time/rate"
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+// LimitsKey is the plugin config key that configures the plugin's limits. It's
+// reserved by Wash, so it isn't passed to the plugin's Init.
+const LimitsKey = "limits"
+
+// Limits configures how a plugin's methods are invoked, such as
+//   limits:
+//     concurrency: 10
+//     rate: 20
+//     retries: 3
+//     timeout: 30s
+//     timeouts:
+//       list: 1m
+//     breaker:
+//       threshold: 5
+//     types:
+//       ec2Instance:
+//         concurrency: 2
+// They protect the APIs that a plugin calls from aggressive commands like 'wash find',
+// which can otherwise trip API throttling. Limits only apply to calls that aren't
+// answered by the cache.
+type Limits struct {
+	// Concurrency is the maximum number of calls that can be in progress at once. Zero
+	// means unlimited.
+	Concurrency int `json:"concurrency"`
+	// Rate is the number of calls per second that a token-bucket rate limiter allows.
+	// Zero means unlimited.
+	Rate float64 `json:"rate"`
+	// Burst is the number of calls that can be made at once before Rate applies. It
+	// defaults to 1.
+	Burst int `json:"burst"`
+	// Retries is the number of times a call that failed with a RetryableErr is retried.
+	Retries int `json:"retries"`
+	// Backoff is how long to wait before the first retry. It doubles with each retry,
+	// and defaults to 100ms.
+	Backoff string `json:"backoff"`
+	// Breaker configures the plugin's circuit breaker. It's disabled if unset.
+	Breaker *BreakerConfig `json:"breaker"`
+	// Timeout is how long a call can take before its context's deadline expires and it
+	// fails with a TimeoutErr. Zero means no timeout. It doesn't apply to exec or init.
+	Timeout string `json:"timeout"`
+	// Timeouts overrides Timeout for the given methods. The exec timeout is the default
+	// for commands that don't set their own, and the init timeout only applies to roots
+	// that implement ContextIniter. Stream can't be limited since streams are long-lived.
+	Timeouts map[string]string `json:"timeouts"`
+	// GracePeriod is how long an external plugin's script has to exit after its call's
+	// context is cancelled and it's sent SIGTERM. It's sent SIGKILL afterwards. It
+	// defaults to 5s.
+	GracePeriod string `json:"grace_period"`
+	// Types configures additional limits for entries of the given types. Types are
+	// keyed by their type ID without the plugin's namespace. Core plugin types use
+	// their Go type name (e.g. ec2Instance). A call must satisfy both its type's
+	// concurrency and rate limits and the plugin's. A type's retries, backoff and
+	// timeouts override the plugin's.
+	Types map[string]Limits `json:"types"`
+
+	backoff     time.Duration
+	timeouts    map[string]time.Duration
+	gracePeriod time.Duration
+}
+
+// timeoutMethods are the methods whose timeouts can be configured.
+var timeoutMethods = map[string]bool{
+	"init":     true,
+	"list":     true,
+	"read":     true,
+	"metadata": true,
+	"readlink": true,
+	"write":    true,
+	"exec":     true,
+	"signal":   true,
+	"delete":   true,
+}
+
+// BreakerConfig configures a plugin's circuit breaker. Once Threshold consecutive calls
+// have failed, the breaker opens and calls fail fast with a CircuitOpenErr. After
+// Cooldown, a single trial call is let through. The breaker closes if the trial
+// succeeds, and re-opens otherwise.
+type BreakerConfig struct {
+	Threshold int `json:"threshold"`
+	// Cooldown defaults to 30s.
+	Cooldown string `json:"cooldown"`
+
+	cooldown time.Duration
+}
+
+// ParseLimits parses the limits key of a plugin's config. It returns nil if the key isn't
+// set.
+func ParseLimits(pluginName string, cfg map[string]interface{}) (*Limits, error) {
+	limitsCfg, ok := cfg[LimitsKey]
+	if !ok {
+		return nil, nil
+	}
+	// Nested YAML maps aren't necessarily keyed by strings, so re-marshal the config as
+	// YAML and then decode it as JSON.
+	y, err := yamlv2.Marshal(limitsCfg)
+	if err != nil {
+		return nil, fmt.Errorf("%v.limits config is invalid: %v", pluginName, err)
+	}
+	j, err := yaml.YAMLToJSON(y)
+	if err != nil {
+		return nil, fmt.Errorf("%v.limits config is invalid: %v", pluginName, err)
+	}
+	var limits Limits
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&limits); err != nil {
+		return nil, fmt.Errorf("%v.limits config is invalid: %v", pluginName, err)
+	}
+	if err := limits.parse(false); err != nil {
+		return nil, fmt.Errorf("%v.limits config is invalid: %v", pluginName, err)
+	}
+	return &limits, nil
+}
+
+func (l *Limits) parse(isType bool) error {
+	if l.Concurrency < 0 {
+		return fmt.Errorf("concurrency must be positive, got %v", l.Concurrency)
+	}
+	if l.Rate < 0 {
+		return fmt.Errorf("rate must be positive, got %v", l.Rate)
+	}
+	if l.Burst < 0 {
+		return fmt.Errorf("burst must be positive, got %v", l.Burst)
+	}
+	if l.Retries < 0 {
+		return fmt.Errorf("retries must be positive, got %v", l.Retries)
+	}
+	if l.Backoff != "" {
+		var err error
+		if l.backoff, err = time.ParseDuration(l.Backoff); err != nil {
+			return fmt.Errorf("invalid backoff %v", l.Backoff)
+		}
+	}
+	l.timeouts = make(map[string]time.Duration)
+	if l.Timeout != "" {
+		timeout, err := time.ParseDuration(l.Timeout)
+		if err != nil || timeout < 0 {
+			return fmt.Errorf("invalid timeout %v", l.Timeout)
+		}
+		l.timeouts[""] = timeout
+	}
+	for method, str := range l.Timeouts {
+		if !timeoutMethods[method] || (isType && method == "init") {
+			return fmt.Errorf("the %v method's timeout can't be configured", method)
+		}
+		timeout, err := time.ParseDuration(str)
+		if err != nil || timeout < 0 {
+			return fmt.Errorf("invalid timeouts.%v %v", method, str)
+		}
+		l.timeouts[method] = timeout
+	}
+
+	if isType {
+		if l.Breaker != nil {
+			return fmt.Errorf("breaker can only be configured for the whole plugin")
+		}
+		if l.GracePeriod != "" {
+			return fmt.Errorf("grace_period can only be configured for the whole plugin")
+		}
+		if l.Types != nil {
+			return fmt.Errorf("types cannot be nested")
+		}
+		return nil
+	}
+
+	if l.GracePeriod != "" {
+		var err error
+		if l.gracePeriod, err = time.ParseDuration(l.GracePeriod); err != nil || l.gracePeriod < 0 {
+			return fmt.Errorf("invalid grace_period %v", l.GracePeriod)
+		}
+	}
+	if b := l.Breaker; b != nil {
+		if b.Threshold <= 0 {
+			return fmt.Errorf("breaker.threshold must be greater than zero, got %v", b.Threshold)
+		}
+		b.cooldown = 30 * time.Second
+		if b.Cooldown != "" {
+			var err error
+			if b.cooldown, err = time.ParseDuration(b.Cooldown); err != nil {
+				return fmt.Errorf("invalid breaker.cooldown %v", b.Cooldown)
+			}
+		}
+	}
+	for typeName, typeLimits := range l.Types {
+		if err := typeLimits.parse(true); err != nil {
+			return fmt.Errorf("types.%v: %v", typeName, err)
+		}
+		l.Types[typeName] = typeLimits
+	}
+	return nil
+}
+
+// RetryableErr indicates that a method failed for a transient reason, like API
+// throttling, so invoking it again could succeed. Plugins return it via Retryable.
+type RetryableErr struct {
+	err error
+}
+
+func (e RetryableErr) Error() string {
+	return e.err.Error()
+}
+
+func (e RetryableErr) Unwrap() error {
+	return e.err
+}
+
+// Retryable marks err as retryable. Calls that fail with a retryable error are retried
+// if the plugin's limits configure retries.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return RetryableErr{err}
+}
+
+// IsRetryableErr returns true if err is, or wraps, a RetryableErr error object
+func IsRetryableErr(err error) bool {
+	var retryableErr RetryableErr
+	return errors.As(err, &retryableErr)
+}
+
+// TimeoutErr indicates that a call didn't finish before its timeout expired.
+type TimeoutErr struct {
+	Method  string
+	Timeout time.Duration
+	err     error
+}
+
+// NewTimeoutErr returns a TimeoutErr for the method's call, which failed with err
+// once its timeout expired.
+func NewTimeoutErr(method string, timeout time.Duration, err error) error {
+	return TimeoutErr{Method: method, Timeout: timeout, err: err}
+}
+
+func (e TimeoutErr) Error() string {
+	return fmt.Sprintf("%v timed out after %v", e.Method, e.Timeout)
+}
+
+func (e TimeoutErr) Unwrap() error {
+	return e.err
+}
+
+// IsTimeoutErr returns true if err is, or wraps, a TimeoutErr error object
+func IsTimeoutErr(err error) bool {
+	var timeoutErr TimeoutErr
+	return errors.As(err, &timeoutErr)
+}
+
+// CircuitOpenErr is returned instead of invoking a plugin's method while the plugin's
+// circuit breaker is open.
+type CircuitOpenErr struct {
+	plugin  string
+	retryAt time.Time
+}
+
+func (e CircuitOpenErr) Error() string {
+	return fmt.Sprintf(
+		"the %v plugin's circuit breaker is open after too many consecutive failures; calls will resume after %v",
+		e.plugin,
+		e.retryAt.Format(time.RFC3339),
+	)
+}
+
+// IsCircuitOpenErr returns true if err is, or wraps, a CircuitOpenErr error object
+func IsCircuitOpenErr(err error) bool {
+	var circuitOpenErr CircuitOpenErr
+	return errors.As(err, &circuitOpenErr)
+}
+
+// The states of a circuit breaker.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// BreakerStatus describes the state of a plugin's circuit breaker.
+type BreakerStatus struct {
+	// State is one of "closed", "open" or "half-open".
+	State string
+	// Failures is the number of consecutive failed calls.
+	Failures int
+	// OpenedAt is when the breaker last opened.
+	OpenedAt time.Time
+}
+
+type breaker struct {
+	plugin string
+	config BreakerConfig
+
+	mux      sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	// trialing is true while the trial call of a half-open breaker is in progress.
+	trialing bool
+}
+
+// allow returns a CircuitOpenErr if calls shouldn't be invoked.
+func (b *breaker) allow() error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	retryAt := b.openedAt.Add(b.config.cooldown)
+	switch b.state {
+	case BreakerOpen:
+		if time.Now().Before(retryAt) {
+			return CircuitOpenErr{plugin: b.plugin, retryAt: retryAt}
+		}
+		b.state = BreakerHalfOpen
+		b.trialing = true
+	case BreakerHalfOpen:
+		if b.trialing {
+			return CircuitOpenErr{plugin: b.plugin, retryAt: retryAt}
+		}
+		b.trialing = true
+	}
+	return nil
+}
+
+// record records the result of a call.
+func (b *breaker) record(err error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.trialing = false
+	switch {
+	case err == nil:
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	case IsInvalidInputErr(err) || errors.Is(err, context.Canceled):
+		// These don't indicate whether the plugin's healthy.
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.config.Threshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+func (b *breaker) status() BreakerStatus {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return BreakerStatus{State: b.state, Failures: b.failures, OpenedAt: b.openedAt}
+}
+
+// limiter enforces a plugin's or a type's concurrency and rate limits.
+type limiter struct {
+	sem      chan struct{}
+	rate     *rate.Limiter
+	retries  int
+	backoff  time.Duration
+	timeouts map[string]time.Duration
+}
+
+func newLimiter(l Limits) *limiter {
+	lim := &limiter{retries: l.Retries, backoff: l.backoff, timeouts: l.timeouts}
+	if l.Concurrency > 0 {
+		lim.sem = make(chan struct{}, l.Concurrency)
+	}
+	if l.Rate > 0 {
+		burst := l.Burst
+		if burst == 0 {
+			burst = 1
+		}
+		lim.rate = rate.NewLimiter(rate.Limit(l.Rate), burst)
+	}
+	return lim
+}
+
+// timeout returns the method's timeout. It returns false if it isn't configured.
+func (l *limiter) timeout(method string) (time.Duration, bool) {
+	if timeout, ok := l.timeouts[method]; ok {
+		return timeout, true
+	}
+	if method == "exec" || method == "init" {
+		return 0, false
+	}
+	timeout, ok := l.timeouts[""]
+	return timeout, ok
+}
+
+// acquire waits until the call can proceed. The returned function releases the
+// call's concurrency slot.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	release := func() {}
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+			release = func() { <-l.sem }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if l.rate != nil {
+		if err := l.rate.Wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// pluginLimiter enforces a plugin's Limits.
+type pluginLimiter struct {
+	name        string
+	plugin      *limiter
+	types       map[string]*limiter
+	breaker     *breaker
+	gracePeriod time.Duration
+}
+
+func newPluginLimiter(name string, limits Limits) *pluginLimiter {
+	l := &pluginLimiter{
+		name:        name,
+		plugin:      newLimiter(limits),
+		types:       make(map[string]*limiter),
+		gracePeriod: limits.gracePeriod,
+	}
+	for typeName, typeLimits := range limits.Types {
+		l.types[typeName] = newLimiter(typeLimits)
+	}
+	if limits.Breaker != nil {
+		l.breaker = &breaker{plugin: name, config: *limits.Breaker, state: BreakerClosed}
+	}
+	return l
+}
+
+// timeout returns the method's timeout for entries of the given type. Zero means there
+// isn't a timeout.
+func (l *pluginLimiter) timeout(typeName string, method string) time.Duration {
+	if typeLimiter, ok := l.types[typeName]; ok {
+		if timeout, ok := typeLimiter.timeout(method); ok {
+			return timeout
+		}
+	}
+	timeout, _ := l.plugin.timeout(method)
+	return timeout
+}
+
+// invoke invokes the method's op, subject to the limits for entries of the given type.
+// op is only retried if retry is true.
+func (l *pluginLimiter) invoke(ctx context.Context, typeName string, method string, retry bool, op func(context.Context) error) error {
+	limiters := []*limiter{l.plugin}
+	retries, backoff := l.plugin.retries, l.plugin.backoff
+	if typeLimiter, ok := l.types[typeName]; ok {
+		limiters = append(limiters, typeLimiter)
+		if typeLimiter.retries > 0 {
+			retries, backoff = typeLimiter.retries, typeLimiter.backoff
+		}
+	}
+	if !retry {
+		retries = 0
+	}
+	if backoff == 0 {
+		backoff = 100 * time.Millisecond
+	}
+	// Exec'd commands and streams outlive their call, so their context can't have a
+	// deadline. Exec's timeout is applied to the command instead.
+	var timeout time.Duration
+	if method != "exec" && method != "stream" {
+		timeout = l.timeout(typeName, method)
+	}
+
+	// Nested calls on the plugin's entries, like a List that lists another entry, are
+	// covered by this call's limits. Otherwise they could deadlock waiting for a
+	// concurrency slot that this call holds.
+	ctx = context.WithValue(ctx, limitedPluginKey, l.name)
+	if l.gracePeriod > 0 {
+		ctx = context.WithValue(ctx, gracePeriodKey, l.gracePeriod)
+	}
+	for attempt := 0; ; attempt++ {
+		err := l.attempt(ctx, limiters, method, timeout, op)
+		if err == nil || attempt >= retries || !IsRetryableErr(err) {
+			return err
+		}
+		select {
+		case <-time.After(backoff << uint(attempt)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (l *pluginLimiter) attempt(ctx context.Context, limiters []*limiter, method string, timeout time.Duration, op func(context.Context) error) error {
+	if l.breaker != nil {
+		if err := l.breaker.allow(); err != nil {
+			return err
+		}
+	}
+	var releases []func()
+	defer func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}()
+	for _, lim := range limiters {
+		release, err := lim.acquire(ctx)
+		if err != nil {
+			if l.breaker != nil {
+				// Giving up while waiting says nothing about the plugin's health.
+				l.breaker.record(context.Canceled)
+			}
+			return err
+		}
+		releases = append(releases, release)
+	}
+
+	// The timeout doesn't include the time spent waiting on the limiters.
+	callCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	err := op(callCtx)
+	if err != nil && timeout > 0 && callCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		err = NewTimeoutErr(method, timeout, err)
+	}
+	if l.breaker != nil {
+		l.breaker.record(err)
+	}
+	return err
+}
+
+var limitersMux sync.Mutex
+var limiters = make(map[string]*pluginLimiter)
+
+// setLimits sets the named plugin's limits. Nil limits remove them.
+func setLimits(pluginName string, limits *Limits) {
+	limitersMux.Lock()
+	defer limitersMux.Unlock()
+	if limits == nil {
+		delete(limiters, pluginName)
+		return
+	}
+	limiters[pluginName] = newPluginLimiter(pluginName, *limits)
+}
+
+// BreakerStatusOf returns the state of the named plugin's circuit breaker. It returns
+// false if the plugin doesn't have a circuit breaker.
+func BreakerStatusOf(pluginName string) (BreakerStatus, bool) {
+	l := limiterOf(pluginName)
+	if l == nil || l.breaker == nil {
+		return BreakerStatus{}, false
+	}
+	return l.breaker.status(), true
+}
+
+// GracePeriod returns the grace period that the plugin's limits configure for the call
+// with the given context. It returns zero if one isn't configured.
+func GracePeriod(ctx context.Context) time.Duration {
+	gracePeriod, _ := ctx.Value(gracePeriodKey).(time.Duration)
+	return gracePeriod
+}
+
+// limit invokes the method's op on e, subject to the limits of e's plugin.
+func limit(ctx context.Context, e Entry, method string, op func(context.Context) error) error {
+	return limitCall(ctx, e, method, true, op)
+}
+
+// limitOnce is like limit, but op is never retried. It's for calls that consume their
+// input, like Exec with stdin, or that aren't idempotent, like Signal and Delete.
+func limitOnce(ctx context.Context, e Entry, method string, op func(context.Context) error) error {
+	return limitCall(ctx, e, method, false, op)
+}
+
+func limitCall(ctx context.Context, e Entry, method string, retry bool, op func(context.Context) error) (err error) {
+	name := pluginName(e)
+	// Every plugin call goes through here, so this is where it's traced.
+	ctx, span := tracing.Start(ctx, "plugin."+strings.Title(method), tracing.String("wash.plugin", name), tracing.String("wash.entry", e.eb().id))
+	defer func() { tracing.End(ctx, span, err) }()
+
+	if ctx.Value(limitedPluginKey) == name {
+		return op(ctx)
+	}
+	l := limiterOf(name)
+	if l == nil {
+		return op(ctx)
+	}
+	return l.invoke(ctx, limitsTypeName(e), method, retry, op)
+}
+
+// execTimeout returns the default timeout for commands exec'd on e. Zero means there
+// isn't a timeout.
+func execTimeout(e Entry) time.Duration {
+	l := limiterOf(pluginName(e))
+	if l == nil {
+		return 0
+	}
+	return l.timeout(limitsTypeName(e), "exec")
+}
+
+// initContext returns the context that a plugin with the given limits is initialized
+// with.
+func initContext(limits *Limits) (context.Context, context.CancelFunc) {
+	ctx := context.Background()
+	if limits == nil {
+		return context.WithCancel(ctx)
+	}
+	if limits.gracePeriod > 0 {
+		ctx = context.WithValue(ctx, gracePeriodKey, limits.gracePeriod)
+	}
+	if timeout, ok := limits.timeouts["init"]; ok && timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+func limiterOf(pluginName string) *pluginLimiter {
+	limitersMux.Lock()
+	defer limitersMux.Unlock()
+	return limiters[pluginName]
+}
+
+// limitsTypeName returns the name that e's type is configured by in Limits.Types.
+func limitsTypeName(e Entry) string {
+	typeID := rawTypeID(e)
+	return typeID[strings.LastIndex(typeID, "/")+1:]
+}
@@ -0,0 +1,287 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/stretchr/testify/suite"
+)
+
+type LimitsTestSuite struct {
+	suite.Suite
+}
+
+type limitsTestsEntry struct {
+	EntryBase
+}
+
+func (e *limitsTestsEntry) Schema() *EntrySchema {
+	return nil
+}
+
+func (suite *LimitsTestSuite) TestParseLimits() {
+	limits, err := ParseLimits("mine", map[string]interface{}{})
+	suite.NoError(err)
+	suite.Nil(limits)
+
+	// YAML config is decoded into maps with interface{} keys.
+	limits, err = ParseLimits("mine", map[string]interface{}{
+		"limits": map[interface{}]interface{}{
+			"concurrency":  10,
+			"rate":         2.5,
+			"retries":      3,
+			"backoff":      "1s",
+			"breaker":      map[interface{}]interface{}{"threshold": 5},
+			"timeout":      "10s",
+			"timeouts":     map[interface{}]interface{}{"list": "1m"},
+			"grace_period": "1s",
+			"types": map[interface{}]interface{}{
+				"ec2Instance": map[interface{}]interface{}{"concurrency": 2},
+			},
+		},
+	})
+	if suite.NoError(err) {
+		suite.Equal(10, limits.Concurrency)
+		suite.Equal(2.5, limits.Rate)
+		suite.Equal(time.Second, limits.backoff)
+		suite.Equal(5, limits.Breaker.Threshold)
+		suite.Equal(30*time.Second, limits.Breaker.cooldown)
+		suite.Equal(2, limits.Types["ec2Instance"].Concurrency)
+		suite.Equal(time.Minute, limits.timeouts["list"])
+		suite.Equal(10*time.Second, limits.timeouts[""])
+		suite.Equal(time.Second, limits.gracePeriod)
+	}
+
+	parseErr := func(limits map[interface{}]interface{}) error {
+		_, err := ParseLimits("mine", map[string]interface{}{"limits": limits})
+		return err
+	}
+	suite.EqualError(
+		parseErr(map[interface{}]interface{}{"concurrency": -1}),
+		"mine.limits config is invalid: concurrency must be positive, got -1",
+	)
+	suite.EqualError(
+		parseErr(map[interface{}]interface{}{"backoff": "soon"}),
+		"mine.limits config is invalid: invalid backoff soon",
+	)
+	suite.EqualError(
+		parseErr(map[interface{}]interface{}{"timeouts": map[interface{}]interface{}{"stream": "1s"}}),
+		"mine.limits config is invalid: the stream method's timeout can't be configured",
+	)
+	suite.EqualError(
+		parseErr(map[interface{}]interface{}{
+			"types": map[interface{}]interface{}{
+				"foo": map[interface{}]interface{}{"timeouts": map[interface{}]interface{}{"init": "1s"}},
+			},
+		}),
+		"mine.limits config is invalid: types.foo: the init method's timeout can't be configured",
+	)
+	suite.EqualError(
+		parseErr(map[interface{}]interface{}{"breaker": map[interface{}]interface{}{"cooldown": "1m"}}),
+		"mine.limits config is invalid: breaker.threshold must be greater than zero, got 0",
+	)
+	suite.EqualError(
+		parseErr(map[interface{}]interface{}{
+			"types": map[interface{}]interface{}{
+				"foo": map[interface{}]interface{}{"breaker": map[interface{}]interface{}{"threshold": 1}},
+			},
+		}),
+		"mine.limits config is invalid: types.foo: breaker can only be configured for the whole plugin",
+	)
+	suite.Regexp("mine.limits config is invalid: .*unknown field", parseErr(map[interface{}]interface{}{"parallel": 1}))
+}
+
+func (suite *LimitsTestSuite) TestRetries() {
+	l := newPluginLimiter("mine", Limits{Retries: 2, backoff: time.Millisecond})
+	calls := 0
+	err := l.invoke(context.Background(), "", "list", true, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return Retryable(errors.New("throttled"))
+		}
+		return nil
+	})
+	suite.NoError(err)
+	suite.Equal(3, calls)
+
+	// Retries are exhausted
+	calls = 0
+	err = l.invoke(context.Background(), "", "list", true, func(context.Context) error {
+		calls++
+		return Retryable(errors.New("throttled"))
+	})
+	suite.EqualError(err, "throttled")
+	suite.True(IsRetryableErr(err))
+	suite.Equal(3, calls)
+
+	// Other errors, and calls that can't be retried, aren't retried
+	calls = 0
+	err = l.invoke(context.Background(), "", "list", true, func(context.Context) error {
+		calls++
+		return errors.New("failed")
+	})
+	suite.EqualError(err, "failed")
+	suite.Equal(1, calls)
+	calls = 0
+	_ = l.invoke(context.Background(), "", "list", false, func(context.Context) error {
+		calls++
+		return Retryable(errors.New("throttled"))
+	})
+	suite.Equal(1, calls)
+}
+
+func (suite *LimitsTestSuite) TestBreaker() {
+	l := newPluginLimiter("mine", Limits{Breaker: &BreakerConfig{Threshold: 2, cooldown: 20 * time.Millisecond}})
+	fail := func(context.Context) error { return errors.New("failed") }
+	succeed := func(context.Context) error { return nil }
+
+	suite.EqualError(l.invoke(context.Background(), "", "list", true, fail), "failed")
+	suite.Equal(BreakerClosed, l.breaker.status().State)
+	// Invalid input doesn't count as a failure
+	_ = l.invoke(context.Background(), "", "list", true, func(context.Context) error { return InvalidInputErr{"bad"} })
+	suite.EqualError(l.invoke(context.Background(), "", "list", true, fail), "failed")
+	status := l.breaker.status()
+	suite.Equal(BreakerOpen, status.State)
+	suite.Equal(2, status.Failures)
+
+	// Calls fail fast while the breaker's open
+	err := l.invoke(context.Background(), "", "list", true, func(context.Context) error {
+		suite.Fail("the call should not have been invoked")
+		return nil
+	})
+	suite.True(IsCircuitOpenErr(err))
+	suite.Regexp("the mine plugin's circuit breaker is open", err.Error())
+
+	// A failed trial re-opens the breaker, and a successful one closes it
+	time.Sleep(25 * time.Millisecond)
+	suite.EqualError(l.invoke(context.Background(), "", "list", true, fail), "failed")
+	suite.True(IsCircuitOpenErr(l.invoke(context.Background(), "", "list", true, succeed)))
+	time.Sleep(25 * time.Millisecond)
+	suite.NoError(l.invoke(context.Background(), "", "list", true, succeed))
+	status = l.breaker.status()
+	suite.Equal(BreakerClosed, status.State)
+	suite.Equal(0, status.Failures)
+}
+
+func (suite *LimitsTestSuite) TestTimeouts() {
+	l := newPluginLimiter("mine", Limits{
+		timeouts:    map[string]time.Duration{"": 10 * time.Millisecond, "exec": time.Minute},
+		gracePeriod: time.Second,
+		Types: map[string]Limits{
+			"slow": {timeouts: map[string]time.Duration{"list": time.Hour}},
+		},
+		Breaker: &BreakerConfig{Threshold: 1, cooldown: time.Minute},
+	})
+	suite.Equal(10*time.Millisecond, l.timeout("", "read"))
+	suite.Equal(time.Hour, l.timeout("slow", "list"))
+	suite.Equal(10*time.Millisecond, l.timeout("slow", "read"))
+	suite.Equal(time.Minute, l.timeout("", "exec"))
+	suite.Equal(time.Duration(0), l.timeout("", "init"))
+
+	err := l.invoke(context.Background(), "", "list", true, func(ctx context.Context) error {
+		suite.Equal(time.Second, GracePeriod(ctx))
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	suite.EqualError(err, "list timed out after 10ms")
+	suite.True(IsTimeoutErr(err))
+	suite.True(errors.Is(err, context.DeadlineExceeded))
+	// Timeouts count as failures
+	suite.Equal(BreakerOpen, l.breaker.status().State)
+
+	// Streams don't time out
+	l = newPluginLimiter("mine", Limits{timeouts: map[string]time.Duration{"": time.Millisecond}})
+	suite.NoError(l.invoke(context.Background(), "", "stream", true, func(ctx context.Context) error {
+		_, ok := ctx.Deadline()
+		suite.False(ok)
+		return nil
+	}))
+
+	// Cancelling the parent context isn't a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = l.invoke(ctx, "", "list", true, func(ctx context.Context) error {
+		return ctx.Err()
+	})
+	suite.Equal(context.Canceled, err)
+}
+
+func (suite *LimitsTestSuite) TestConcurrency() {
+	setLimits("mine", &Limits{Concurrency: 1, Types: map[string]Limits{"limitsTestsEntry": {Concurrency: 1}}})
+	defer setLimits("mine", nil)
+	e := &limitsTestsEntry{NewEntry("foo")}
+	e.id = "/mine/foo"
+	suite.Equal("limitsTestsEntry", limitsTypeName(e))
+
+	var mux sync.Mutex
+	var wg sync.WaitGroup
+	inProgress, maxInProgress := 0, 0
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := limit(context.Background(), e, "list", func(ctx context.Context) error {
+				mux.Lock()
+				inProgress++
+				if inProgress > maxInProgress {
+					maxInProgress = inProgress
+				}
+				mux.Unlock()
+
+				// Nested calls on the plugin's entries don't wait for another slot.
+				err := limit(ctx, e, "list", func(context.Context) error { return nil })
+
+				time.Sleep(time.Millisecond)
+				mux.Lock()
+				inProgress--
+				mux.Unlock()
+				return err
+			})
+			suite.NoError(err)
+		}()
+	}
+	wg.Wait()
+	suite.Equal(1, maxInProgress)
+
+	// Waiting for a slot stops when the context's cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l := newPluginLimiter("mine", Limits{Concurrency: 1})
+	l.plugin.sem <- struct{}{}
+	suite.Equal(context.Canceled, l.invoke(ctx, "", "list", true, func(context.Context) error { return nil }))
+}
+
+func (suite *LimitsTestSuite) TestRegisterPluginWithLimits() {
+	SetTestCache(datastore.NewMemCache())
+	defer UnsetTestCache()
+
+	reg := NewRegistry()
+	m := &mockRoot{EntryBase: NewEntry("limited")}
+	m.On("Init", map[string]interface{}{"key": "value"}).Return(nil)
+	cfg := map[string]interface{}{
+		"key":    "value",
+		"limits": map[string]interface{}{"breaker": map[string]interface{}{"threshold": 1}},
+	}
+
+	suite.NoError(reg.RegisterPlugin(m, cfg))
+	m.AssertExpectations(suite.T())
+	status, _ := reg.PluginStatus("limited")
+	if suite.NotNil(status.Limits) {
+		suite.Equal(1, status.Limits.Breaker.Threshold)
+	}
+	breaker, ok := BreakerStatusOf("limited")
+	suite.True(ok)
+	suite.Equal(BreakerClosed, breaker.State)
+
+	suite.True(reg.UnregisterPlugin("limited"))
+	_, ok = BreakerStatusOf("limited")
+	suite.False(ok)
+}
+
+func TestLimits(t *testing.T) {
+	suite.Run(t, new(LimitsTestSuite))
+}
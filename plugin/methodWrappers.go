@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/metrics"
 )
 
 // InvalidInputErr indicates that the method invocation received invalid
@@ -191,28 +194,137 @@ func PartialMetadata(e Entry) JSONObject {
 	return e.eb().partialMetadata()
 }
 
+// TTLOf returns the TTL set for the specified op on the entry. A negative TTL means that the
+// op isn't cached.
+func TTLOf(e Entry, op defaultOpCode) time.Duration {
+	return e.eb().TTLOf(op)
+}
+
 // Metadata returns the entry's metadata. Note that Metadata's results could be cached.
 func Metadata(ctx context.Context, e Entry) (JSONObject, error) {
 	return cachedMetadata(ctx, e)
 }
 
-// Exec execs the command on the given entry.
-func Exec(ctx context.Context, e Execable, cmd string, args []string, opts ExecOptions) (ExecCommand, error) {
-	return e.Exec(ctx, cmd, args, opts)
+// Exec execs the command on the given entry. If opts.Timeout is set, then the
+// command is cancelled once the timeout expires. Otherwise, it defaults to the
+// exec timeout configured in the plugin's limits.
+func Exec(ctx context.Context, e Execable, cmd string, args []string, opts ExecOptions) (execCmd ExecCommand, err error) {
+	defer func(start time.Time) { recordEvent(ctx, "Exec", e, start, err, "") }(time.Now())
+	if opts.Timeout < 0 {
+		return nil, InvalidInputErr{fmt.Sprintf("the timeout must be positive, got %v", opts.Timeout)}
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = execTimeout(e)
+	}
+	if opts.Timeout == 0 {
+		err = limitOnce(ctx, e, "exec", func(ctx context.Context) (err error) {
+			execCmd, err = e.Exec(ctx, cmd, args, opts)
+			return
+		})
+		return
+	}
+
+	// The command's lifetime is tied to the timeout context, so it can't be
+	// cancelled when Exec returns. Instead, its resources are released once
+	// its exit code is fetched, the timeout expires, or the parent context is
+	// done.
+	timeoutCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	err = limitOnce(timeoutCtx, e, "exec", func(ctx context.Context) (err error) {
+		execCmd, err = e.Exec(ctx, cmd, args, opts)
+		return
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &timeoutExecCommand{ExecCommand: execCmd, ctx: timeoutCtx, cancel: cancel, timeout: opts.Timeout}, nil
+}
+
+// timeoutExecCommand reports a clearer error when the command's exit code
+// could not be fetched because it timed out.
+type timeoutExecCommand struct {
+	ExecCommand
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+}
+
+func (cmd *timeoutExecCommand) ExitCode() (int, error) {
+	exitCode, err := cmd.ExecCommand.ExitCode()
+	timedOut := cmd.ctx.Err() == context.DeadlineExceeded
+	// Callers drain the command's output before fetching its exit code, so
+	// there's nothing left for the timeout to cancel.
+	cmd.cancel()
+	if err != nil && timedOut {
+		return 0, NewTimeoutErr("exec", cmd.timeout, err)
+	}
+	return exitCode, err
 }
 
 // Stream streams the entry's content for updates.
 func Stream(ctx context.Context, s Streamable) (io.ReadCloser, error) {
-	return s.Stream(ctx)
+	start := time.Now()
+	var rdr io.ReadCloser
+	err := limit(ctx, s, "stream", func(ctx context.Context) (err error) {
+		rdr, err = s.Stream(ctx)
+		return
+	})
+	recordEvent(ctx, "Stream", s, start, err, "")
+	if err != nil {
+		return nil, err
+	}
+	metrics.StreamOpened()
+	return &meteredStream{ReadCloser: rdr}, nil
+}
+
+// meteredStream reports when the stream's closed so that open streams can be
+// counted.
+type meteredStream struct {
+	io.ReadCloser
+	closeOnce sync.Once
+}
+
+func (s *meteredStream) Close() error {
+	s.closeOnce.Do(metrics.StreamClosed)
+	return s.ReadCloser.Close()
 }
 
 // Write sends the supplied buffer to the entry.
 func Write(ctx context.Context, a Writable, b []byte) error {
-	return a.Write(ctx, b)
+	start := time.Now()
+	err := limit(ctx, a, "write", func(ctx context.Context) error {
+		return a.Write(ctx, b)
+	})
+	recordEvent(ctx, "Write", a, start, err, "")
+	return err
+}
+
+// WriteFrom writes size bytes read from r to the entry. BlockWritable entries are passed r so
+// that they can stream the data; Writable entries are passed all of the data at once.
+func WriteFrom(ctx context.Context, e Entry, size int64, r io.Reader) (err error) {
+	defer func(start time.Time) { recordEvent(ctx, "Write", e, start, err, "") }(time.Now())
+	switch WriteAction().signature(e) {
+	case BlockWritableSignature:
+		// The data's streamed from r, so the write can't be retried.
+		return limitOnce(ctx, e, "write", func(ctx context.Context) error {
+			return e.(BlockWritable).Write(ctx, size, io.LimitReader(r, size))
+		})
+	case DefaultSignature:
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return fmt.Errorf("could not read the data to write: %w", err)
+		}
+		return limit(ctx, e, "write", func(ctx context.Context) error {
+			return e.(Writable).Write(ctx, data)
+		})
+	default:
+		panic("plugin.WriteFrom called on a non-writable entry")
+	}
 }
 
 // Signal signals the entry with the specified signal
-func Signal(ctx context.Context, s Signalable, signal string) error {
+func Signal(ctx context.Context, s Signalable, signal string) (err error) {
+	defer func(start time.Time) { recordEvent(ctx, "Signal", s, start, err, "") }(time.Now())
 	// Signals are case-insensitive
 	signal = strings.ToLower(signal)
 
@@ -250,7 +362,9 @@ func Signal(ctx context.Context, s Signalable, signal string) error {
 	}
 
 	// Go ahead and send the signal
-	err = s.Signal(ctx, signal)
+	err = limitOnce(ctx, s, "signal", func(ctx context.Context) error {
+		return s.Signal(ctx, signal)
+	})
 	if err != nil {
 		return err
 	}
@@ -261,9 +375,37 @@ func Signal(ctx context.Context, s Signalable, signal string) error {
 	return nil
 }
 
+// Readlink returns the link's target. See Linkable for how to interpret it.
+func Readlink(ctx context.Context, l Linkable) (target string, err error) {
+	defer func(start time.Time) { recordEvent(ctx, "Readlink", l, start, err, "") }(time.Now())
+	err = limit(ctx, l, "readlink", func(ctx context.Context) (err error) {
+		target, err = l.Readlink(ctx)
+		return
+	})
+	if err != nil {
+		return "", err
+	}
+	if target == "" {
+		return "", fmt.Errorf("%v has an empty link target", ID(l))
+	}
+	return target, nil
+}
+
+// ResolveLink returns the absolute Wash path of the target of the link with the given ID.
+func ResolveLink(linkID string, target string) string {
+	if path.IsAbs(target) {
+		return path.Clean(target)
+	}
+	return path.Join(path.Dir(linkID), target)
+}
+
 // Delete deletes the given entry.
 func Delete(ctx context.Context, d Deletable) (deleted bool, err error) {
-	deleted, err = d.Delete(ctx)
+	defer func(start time.Time) { recordEvent(ctx, "Delete", d, start, err, "") }(time.Now())
+	err = limitOnce(ctx, d, "delete", func(ctx context.Context) (err error) {
+		deleted, err = d.Delete(ctx)
+		return
+	})
 	if err != nil {
 		return
 	}
@@ -276,7 +418,7 @@ func Delete(ctx context.Context, d Deletable) (deleted bool, err error) {
 	// If !deleted, the entry will eventually be deleted. However it's likely that
 	// Delete did update the entry (e.g. on VMs, Delete causes a state transition).
 	// Thus we also clear the parent's cached list result to ensure fresh data.
-	ClearCacheFor(d.eb().id, !deleted)
+	clearCacheFor(d.eb().id, !deleted)
 	if deleted {
 		// The entry was deleted, so delete the entry from the parent's cached list
 		// result.
@@ -287,6 +429,30 @@ func Delete(ctx context.Context, d Deletable) (deleted bool, err error) {
 			entries.(*EntryMap).Delete(cname)
 		}
 	}
+	// Either way, the parent's children have changed.
+	notifyCacheCleared(d.eb().id, true)
 
 	return
 }
+
+// recordEvent records a structured activity event for the invocation of method on e that
+// started at start. Cache hits are only counted in metrics; journaling them would add a
+// history entry for every cached Lookup or Attr the filesystem serves.
+func recordEvent(ctx context.Context, method string, e Entry, start time.Time, err error, cache activity.CacheStatus) {
+	event := activity.Event{
+		Method:   method,
+		EntryID:  e.eb().id,
+		Plugin:   pluginName(e),
+		Duration: time.Since(start),
+		Cache:    cache,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	if cache == activity.CacheHit {
+		metrics.ObservePluginCacheHit(event.Plugin, method, err)
+		return
+	}
+	activity.RecordEvent(ctx, event)
+	metrics.ObservePluginCall(event.Plugin, method, event.Duration, err)
+}
@@ -296,6 +296,94 @@ func (suite *MethodWrappersTestSuite) TestWrite() {
 	writable.AssertExpectations(suite.T())
 }
 
+// methodWrappersTestsMockExecable runs commands that never exit on their own
+type methodWrappersTestsMockExecable struct {
+	EntryBase
+}
+
+func (e *methodWrappersTestsMockExecable) Schema() *EntrySchema {
+	return nil
+}
+
+func (e *methodWrappersTestsMockExecable) Exec(ctx context.Context, cmd string, args []string, opts ExecOptions) (ExecCommand, error) {
+	return NewExecCommand(ctx), nil
+}
+
+func (suite *MethodWrappersTestSuite) TestExec_InvalidTimeout() {
+	e := &methodWrappersTestsMockExecable{NewEntry("foo")}
+	_, err := Exec(context.Background(), e, "echo", nil, ExecOptions{Timeout: -1})
+	suite.True(IsInvalidInputErr(err))
+}
+
+func (suite *MethodWrappersTestSuite) TestExec_Timeout() {
+	e := &methodWrappersTestsMockExecable{NewEntry("foo")}
+	cmd, err := Exec(context.Background(), e, "sleep", []string{"10"}, ExecOptions{Timeout: 10 * time.Millisecond})
+	if suite.NoError(err) {
+		for range cmd.OutputCh() {
+		}
+		_, err := cmd.ExitCode()
+		suite.EqualError(err, "exec timed out after 10ms")
+		suite.True(IsTimeoutErr(err))
+	}
+}
+
+// methodWrappersTestsExitingExecable runs commands that exit immediately
+type methodWrappersTestsExitingExecable struct {
+	EntryBase
+	ctx context.Context
+}
+
+func (e *methodWrappersTestsExitingExecable) Schema() *EntrySchema {
+	return nil
+}
+
+func (e *methodWrappersTestsExitingExecable) Exec(ctx context.Context, cmd string, args []string, opts ExecOptions) (ExecCommand, error) {
+	e.ctx = ctx
+	execCmd := NewExecCommand(ctx)
+	execCmd.CloseStreamsWithError(nil)
+	execCmd.SetExitCode(0)
+	return execCmd, nil
+}
+
+func (suite *MethodWrappersTestSuite) TestExec_TimeoutIsReleasedByExitCode() {
+	e := &methodWrappersTestsExitingExecable{EntryBase: NewEntry("foo")}
+	cmd, err := Exec(context.Background(), e, "true", nil, ExecOptions{Timeout: time.Minute})
+	if suite.NoError(err) {
+		for range cmd.OutputCh() {
+		}
+		exitCode, err := cmd.ExitCode()
+		suite.NoError(err)
+		suite.Equal(0, exitCode)
+		suite.Equal(context.Canceled, e.ctx.Err())
+	}
+}
+
+// methodWrappersTestsThrottledExecable counts its Exec calls, which are always throttled
+type methodWrappersTestsThrottledExecable struct {
+	EntryBase
+	calls int
+}
+
+func (e *methodWrappersTestsThrottledExecable) Schema() *EntrySchema {
+	return nil
+}
+
+func (e *methodWrappersTestsThrottledExecable) Exec(context.Context, string, []string, ExecOptions) (ExecCommand, error) {
+	e.calls++
+	return nil, Retryable(fmt.Errorf("throttled"))
+}
+
+func (suite *MethodWrappersTestSuite) TestExec_IsNotRetried() {
+	setLimits("foo", &Limits{Retries: 2, backoff: time.Millisecond})
+	defer setLimits("foo", nil)
+
+	e := &methodWrappersTestsThrottledExecable{EntryBase: NewEntry("bar")}
+	e.SetTestID("/foo/bar")
+	_, err := Exec(context.Background(), e, "cat", nil, ExecOptions{Timeout: time.Second})
+	suite.EqualError(err, "throttled")
+	suite.Equal(1, e.calls)
+}
+
 func (suite *MethodWrappersTestSuite) TestSignal_ReturnsSignalError() {
 	ctx := context.Background()
 	e := newMethodWrappersTestsMockEntry("foo")
@@ -309,6 +397,19 @@ func (suite *MethodWrappersTestSuite) TestSignal_ReturnsSignalError() {
 	suite.Equal(expectedErr, err)
 }
 
+func (suite *MethodWrappersTestSuite) TestSignal_IsNotRetried() {
+	setLimits("foo", &Limits{Retries: 2, backoff: time.Millisecond})
+	defer setLimits("foo", nil)
+
+	e := newMethodWrappersTestsMockEntry("bar")
+	e.SetTestID("/foo/bar")
+	e.On("Schema").Return((*EntrySchema)(nil))
+	e.On("Signal", mock.Anything, "start").Return(Retryable(fmt.Errorf("throttled")))
+
+	suite.EqualError(Signal(context.Background(), e, "start"), "throttled")
+	e.AssertNumberOfCalls(suite.T(), "Signal", 1)
+}
+
 func (suite *MethodWrappersTestSuite) TestSignal_SendsSignalAndUpdatesCache() {
 	ctx := context.Background()
 	e := newMethodWrappersTestsMockEntry("bar")
@@ -365,6 +466,38 @@ func (suite *MethodWrappersTestSuite) TestSignal_SchemaKnown_ReturnsInvalidInput
 	suite.Regexp("invalid.*signal.*invalid_signal.*start.*stop.*linux", err)
 }
 
+type methodWrappersTestsMockLinkable struct {
+	EntryBase
+	target string
+}
+
+func (m *methodWrappersTestsMockLinkable) Schema() *EntrySchema {
+	return nil
+}
+
+func (m *methodWrappersTestsMockLinkable) Readlink(ctx context.Context) (string, error) {
+	return m.target, nil
+}
+
+func (suite *MethodWrappersTestSuite) TestReadlink() {
+	e := &methodWrappersTestsMockLinkable{EntryBase: NewEntry("link"), target: "../data"}
+	e.SetTestID("/foo/link")
+	target, err := Readlink(context.Background(), e)
+	if suite.NoError(err) {
+		suite.Equal("../data", target)
+	}
+
+	e.target = ""
+	_, err = Readlink(context.Background(), e)
+	suite.EqualError(err, "/foo/link has an empty link target")
+}
+
+func (suite *MethodWrappersTestSuite) TestResolveLink() {
+	suite.Equal("/docker/volumes/data", ResolveLink("/docker/volumes/link", "/docker/volumes/data/"))
+	suite.Equal("/docker/volumes/data", ResolveLink("/docker/volumes/link", "data"))
+	suite.Equal("/docker/data", ResolveLink("/docker/volumes/link", "../data"))
+}
+
 func (suite *MethodWrappersTestSuite) TestDelete_ReturnsDeleteError() {
 	ctx := context.Background()
 	e := newMethodWrappersTestsMockEntry("foo")
@@ -376,6 +509,19 @@ func (suite *MethodWrappersTestSuite) TestDelete_ReturnsDeleteError() {
 	suite.Equal(expectedErr, err)
 }
 
+func (suite *MethodWrappersTestSuite) TestDelete_IsNotRetried() {
+	setLimits("foo", &Limits{Retries: 2, backoff: time.Millisecond})
+	defer setLimits("foo", nil)
+
+	e := newMethodWrappersTestsMockEntry("bar")
+	e.SetTestID("/foo/bar")
+	e.On("Delete", mock.Anything).Return(false, Retryable(fmt.Errorf("throttled")))
+
+	_, err := Delete(context.Background(), e)
+	suite.EqualError(err, "throttled")
+	e.AssertNumberOfCalls(suite.T(), "Delete", 1)
+}
+
 func (suite *MethodWrappersTestSuite) TestDelete_EntryDeletionInProgress_UpdatesCache() {
 	e := newMethodWrappersTestsMockEntry("bar")
 	e.SetTestID("/foo/bar")
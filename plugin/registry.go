@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"regexp"
 	"sync"
+	"time"
 )
 
 // Registry represents the plugin registry. It is also Wash's root.
@@ -13,6 +14,7 @@ type Registry struct {
 	mux         sync.Mutex
 	plugins     map[string]Root
 	pluginRoots []Entry
+	statuses    map[string]PluginStatus
 }
 
 // NewRegistry creates a new plugin registry object
@@ -20,6 +22,7 @@ func NewRegistry() *Registry {
 	r := &Registry{
 		EntryBase: NewEntry("/"),
 		plugins:   make(map[string]Root),
+		statuses:  make(map[string]PluginStatus),
 	}
 	r.eb().id = "/"
 	r.DisableDefaultCaching()
@@ -27,10 +30,51 @@ func NewRegistry() *Registry {
 	return r
 }
 
-// Plugins returns a map of the currently registered plugins. It should not be called
-// while registering plugins.
+// Plugins returns a map of the currently registered plugins.
 func (r *Registry) Plugins() map[string]Root {
-	return r.plugins
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	plugins := make(map[string]Root, len(r.plugins))
+	for name, root := range r.plugins {
+		plugins[name] = root
+	}
+	return plugins
+}
+
+// PluginStatus describes the initialization of a registered plugin.
+type PluginStatus struct {
+	// Root is the root that was initialized. If its Init failed, then the registry
+	// lists a stub root in its place.
+	Root         Root
+	Config       map[string]interface{}
+	InitErr      error
+	InitStart    time.Time
+	InitDuration time.Duration
+	// Limits are the plugin's limits, as configured by its limits key. They're nil if
+	// the key isn't set.
+	Limits *Limits
+}
+
+// IsExternal returns true if the plugin is an external plugin.
+func (s PluginStatus) IsExternal() bool {
+	_, ok := s.Root.(externalPlugin)
+	return ok
+}
+
+// PluginStatus returns the named plugin's status. It returns false if the plugin
+// isn't registered.
+func (r *Registry) PluginStatus(name string) (PluginStatus, bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	status, ok := r.statuses[name]
+	return status, ok
+}
+
+// InitErr returns the error returned by the named plugin's last Init, or nil if
+// it succeeded or the plugin isn't registered.
+func (r *Registry) InitErr(name string) error {
+	status, _ := r.PluginStatus(name)
+	return status.InitErr
 }
 
 var pluginNameRegex = regexp.MustCompile("^[0-9a-zA-Z_-]+$")
@@ -38,31 +82,77 @@ var pluginNameRegex = regexp.MustCompile("^[0-9a-zA-Z_-]+$")
 // RegisterPlugin initializes the given plugin and adds it to the registry if
 // initialization was successful.
 func (r *Registry) RegisterPlugin(root Root, config map[string]interface{}) error {
-	registerPlugin := func(initSucceeded bool) {
-		r.mux.Lock()
-		if initSucceeded {
-			if !pluginNameRegex.MatchString(root.eb().name) {
-				msg := fmt.Sprintf("r.RegisterPlugin: invalid plugin name %v. The plugin name must consist of alphanumeric characters, or a hyphen", root.eb().name)
-				panic(msg)
-			}
+	root, status := initPlugin(root, config)
 
-			if _, ok := r.plugins[root.eb().name]; ok {
-				msg := fmt.Sprintf("r.RegisterPlugin: the %v plugin's already been registered", root.eb().name)
-				panic(msg)
-			}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if _, ok := r.plugins[root.eb().name]; ok {
+		msg := fmt.Sprintf("r.RegisterPlugin: the %v plugin's already been registered", root.eb().name)
+		panic(msg)
+	}
+	r.setPlugin(root, status)
+	return status.InitErr
+}
+
+// ReplacePlugin initializes the given plugin, then atomically swaps it for the
+// registered plugin of the same name. The plugin's added if it isn't registered.
+// Like RegisterPlugin, a plugin that fails to initialize is replaced by a stub.
+// The plugin's cached entries are cleared after the swap.
+func (r *Registry) ReplacePlugin(root Root, config map[string]interface{}) error {
+	root, status := initPlugin(root, config)
+
+	r.mux.Lock()
+	r.setPlugin(root, status)
+	r.mux.Unlock()
 
-			if DeleteAction().IsSupportedOn(root) {
-				msg := fmt.Sprintf("r.RegisterPlugin: the %v plugin's root implements delete", root.eb().name)
-				panic(msg)
+	ClearCacheFor("/"+root.eb().name, true)
+	return status.InitErr
+}
+
+// UnregisterPlugin removes the named plugin from the registry and clears its
+// cached entries. It returns false if the plugin wasn't registered.
+func (r *Registry) UnregisterPlugin(name string) bool {
+	r.mux.Lock()
+	_, ok := r.plugins[name]
+	if ok {
+		delete(r.plugins, name)
+		delete(r.statuses, name)
+		setLimits(name, nil)
+		for i, root := range r.pluginRoots {
+			if root.eb().name == name {
+				r.pluginRoots = append(r.pluginRoots[:i:i], r.pluginRoots[i+1:]...)
+				break
 			}
 		}
+	}
+	r.mux.Unlock()
 
-		r.plugins[root.eb().name] = root
-		r.pluginRoots = append(r.pluginRoots, root)
-		r.mux.Unlock()
+	if ok {
+		ClearCacheFor("/"+name, true)
 	}
+	return ok
+}
 
-	if err := root.Init(config); err != nil {
+// initPlugin initializes root, returning the root that should be registered and its status.
+func initPlugin(root Root, config map[string]interface{}) (Root, PluginStatus) {
+	status := PluginStatus{Root: root, Config: config, InitStart: time.Now()}
+	if initer, ok := root.(ContextIniter); ok {
+		// The init timeout's part of the plugin's limits, so they're parsed first.
+		status.Limits, status.InitErr = ParseLimits(root.eb().name, config)
+		if status.InitErr == nil {
+			ctx, cancel := initContext(status.Limits)
+			status.InitErr = initer.InitContext(ctx, withoutLimits(config))
+			cancel()
+		}
+		status.InitDuration = time.Since(status.InitStart)
+	} else {
+		status.InitErr = root.Init(withoutLimits(config))
+		status.InitDuration = time.Since(status.InitStart)
+		if status.InitErr == nil {
+			status.Limits, status.InitErr = ParseLimits(root.eb().name, config)
+		}
+	}
+	if status.InitErr != nil {
 		// Create a stubPluginRoot so that Wash users can see the plugin's
 		// documentation via 'describe <plugin>'. This is important b/c the
 		// plugin docs also include details on how to set it up. Note that
@@ -71,13 +161,57 @@ func (r *Registry) RegisterPlugin(root Root, config map[string]interface{}) erro
 		// the root's description is contained in the root's schema. Retrieving
 		// an external plugin root's schema requires a successful Init invocation,
 		// which is not the case here.
-		root = newStubRoot(root)
-		registerPlugin(false)
-		return err
+		return newStubRoot(root), status
 	}
 
-	registerPlugin(true)
-	return nil
+	if !pluginNameRegex.MatchString(root.eb().name) {
+		msg := fmt.Sprintf("r.RegisterPlugin: invalid plugin name %v. The plugin name must consist of alphanumeric characters, or a hyphen", root.eb().name)
+		panic(msg)
+	}
+
+	if DeleteAction().IsSupportedOn(root) {
+		msg := fmt.Sprintf("r.RegisterPlugin: the %v plugin's root implements delete", root.eb().name)
+		panic(msg)
+	}
+	return root, status
+}
+
+// withoutLimits returns config without the limits key, which is reserved by Wash.
+func withoutLimits(config map[string]interface{}) map[string]interface{} {
+	if _, ok := config[LimitsKey]; !ok {
+		return config
+	}
+	pluginConfig := make(map[string]interface{}, len(config)-1)
+	for key, value := range config {
+		if key != LimitsKey {
+			pluginConfig[key] = value
+		}
+	}
+	return pluginConfig
+}
+
+// setPlugin adds or replaces the named plugin. Replaced plugins keep their position
+// in the registry's list. It should be called with r.mux held.
+func (r *Registry) setPlugin(root Root, status PluginStatus) {
+	name := root.eb().name
+	r.statuses[name] = status
+	setLimits(name, status.Limits)
+
+	if _, ok := r.plugins[name]; ok {
+		for i, existing := range r.pluginRoots {
+			if existing.eb().name == name {
+				// Copy the slice so that earlier results of List aren't modified.
+				pluginRoots := make([]Entry, len(r.pluginRoots))
+				copy(pluginRoots, r.pluginRoots)
+				pluginRoots[i] = root
+				r.pluginRoots = pluginRoots
+				break
+			}
+		}
+	} else {
+		r.pluginRoots = append(r.pluginRoots, root)
+	}
+	r.plugins[name] = root
 }
 
 // ChildSchemas only makes sense for core plugin roots
@@ -92,6 +226,8 @@ func (r *Registry) Schema() *EntrySchema {
 
 // List all of Wash's loaded plugins
 func (r *Registry) List(ctx context.Context) ([]Entry, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
 	return r.pluginRoots, nil
 }
 
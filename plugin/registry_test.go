@@ -5,6 +5,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/puppetlabs/wash/datastore"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
@@ -91,6 +92,14 @@ func (suite *RegistryTestSuite) TestRegisterPluginInitError() {
 	suite.Contains(reg.Plugins(), "mine")
 	_, ok := reg.Plugins()["mine"].(*stubRoot)
 	suite.True(ok, "expected a stub plugin root to be registered")
+
+	status, ok := reg.PluginStatus("mine")
+	if suite.True(ok) {
+		suite.Equal(m, status.Root)
+		suite.EqualError(status.InitErr, "failed")
+		suite.False(status.IsExternal())
+		suite.False(status.InitStart.IsZero())
+	}
 }
 
 func (suite *RegistryTestSuite) TestRegisterPluginInvalidPluginName() {
@@ -117,6 +126,58 @@ func (suite *RegistryTestSuite) TestRegisterPluginRegisteredPlugin() {
 	suite.Panics(panicFunc, "r.RegisterPlugin: the mine plugin's already been registered")
 }
 
+func (suite *RegistryTestSuite) TestReplacePlugin() {
+	SetTestCache(datastore.NewMemCache())
+	defer UnsetTestCache()
+
+	reg := NewRegistry()
+	other := &mockRoot{EntryBase: NewEntry("other")}
+	other.On("Init", map[string]interface{}(nil)).Return(nil)
+	suite.NoError(reg.RegisterPlugin(other, nil))
+
+	m1 := &mockRoot{EntryBase: NewEntry("mine")}
+	m1.On("Init", map[string]interface{}(nil)).Return(errors.New("failed"))
+	suite.EqualError(reg.RegisterPlugin(m1, nil), "failed")
+	suite.EqualError(reg.InitErr("mine"), "failed")
+
+	m2 := &mockRoot{EntryBase: NewEntry("mine")}
+	m2.On("Init", map[string]interface{}(nil)).Return(nil)
+	suite.NoError(reg.ReplacePlugin(m2, nil))
+	m2.AssertExpectations(suite.T())
+	suite.Equal(m2, reg.Plugins()["mine"])
+	suite.NoError(reg.InitErr("mine"))
+
+	// The replaced plugin keeps its position.
+	entries, err := reg.List(context.Background())
+	suite.NoError(err)
+	suite.Equal([]Entry{other, m2}, entries)
+
+	// Replacing an unregistered plugin adds it.
+	m3 := &mockRoot{EntryBase: NewEntry("new")}
+	m3.On("Init", map[string]interface{}(nil)).Return(nil)
+	suite.NoError(reg.ReplacePlugin(m3, nil))
+	suite.Contains(reg.Plugins(), "new")
+}
+
+func (suite *RegistryTestSuite) TestUnregisterPlugin() {
+	SetTestCache(datastore.NewMemCache())
+	defer UnsetTestCache()
+
+	reg := NewRegistry()
+	m := &mockRoot{EntryBase: NewEntry("mine")}
+	m.On("Init", map[string]interface{}(nil)).Return(errors.New("failed"))
+	_ = reg.RegisterPlugin(m, nil)
+
+	suite.True(reg.UnregisterPlugin("mine"))
+	suite.Empty(reg.Plugins())
+	suite.NoError(reg.InitErr("mine"))
+	entries, err := reg.List(context.Background())
+	suite.NoError(err)
+	suite.Empty(entries)
+
+	suite.False(reg.UnregisterPlugin("mine"))
+}
+
 type mockRootWithDelete struct {
 	*mockRoot
 }
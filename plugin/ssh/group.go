@@ -0,0 +1,69 @@
+package ssh
+
+import (
+	"context"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// group is an inventory group. It contains its child groups and its hosts.
+type group struct {
+	plugin.EntryBase
+	entries []plugin.Entry
+}
+
+func newGroup(name string, entries []plugin.Entry) *group {
+	g := &group{EntryBase: plugin.NewEntry(name), entries: entries}
+	g.DisableDefaultCaching()
+	return g
+}
+
+// newInventoryGroup creates the entries for an inventory group and its descendants.
+// ancestors guards against inventories whose groups contain each other.
+func newInventoryGroup(ctx context.Context, inv *inventory, g *inventoryGroup, ancestors ...string) *group {
+	ancestors = append(ancestors, g.name)
+	var entries []plugin.Entry
+	for _, child := range g.children {
+		if contains(ancestors, child) {
+			activity.Warnf(ctx, "Omitting group %v from %v because it contains %v", child, g.name, g.name)
+			continue
+		}
+		if childGroup, ok := inv.groups[child]; ok {
+			entries = append(entries, newInventoryGroup(ctx, inv, childGroup, ancestors...))
+		}
+	}
+	for _, name := range g.hosts {
+		h, err := newHost(name, inv.hostGroups(name), inv.varsFor(name))
+		if err != nil {
+			activity.Warnf(ctx, "Omitting %v: %v", name, err)
+			continue
+		}
+		entries = append(entries, h)
+	}
+	return newGroup(g.name, entries)
+}
+
+func (g *group) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(g, "group").
+		SetDescription(groupDescription)
+}
+
+func (g *group) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&group{}).Schema(),
+		(&host{}).Schema(),
+	}
+}
+
+// List lists the group's child groups and hosts.
+func (g *group) List(ctx context.Context) ([]plugin.Entry, error) {
+	return g.entries, nil
+}
+
+const groupDescription = `
+This is a group of hosts. Groups from an Ansible inventory contain their child
+groups and their hosts. Hosts that are only in the inventory's all group are in
+the ungrouped group, and hosts from SSH config are in the ssh_config group.
+`
@@ -0,0 +1,264 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
+)
+
+type host struct {
+	plugin.EntryBase
+	identity transport.Identity
+	meta     hostMetadata
+}
+
+// hostMetadata is a host's partial metadata. Its full metadata also includes the Uname
+// and OSRelease fields, which are gathered from the host.
+type hostMetadata struct {
+	Host string `json:"host"`
+	Port uint   `json:"port,omitempty"`
+	User string `json:"user,omitempty"`
+	// Groups are the inventory groups that contain the host.
+	Groups []string `json:"groups,omitempty"`
+	// Vars are the host's inventory variables. Variables that look like passwords are omitted.
+	Vars      map[string]interface{} `json:"vars,omitempty"`
+	Uname     *unameInfo             `json:"uname,omitempty"`
+	OSRelease map[string]string      `json:"os_release,omitempty"`
+}
+
+type unameInfo struct {
+	KernelName    string `json:"kernel_name"`
+	Nodename      string `json:"nodename"`
+	KernelRelease string `json:"kernel_release"`
+	Machine       string `json:"machine"`
+}
+
+// newHost creates a host from its inventory variables. Hosts from SSH config don't have
+// any variables, so everything but their name is configured from SSH config.
+func newHost(name string, groups []string, vars map[string]interface{}) (*host, error) {
+	h := &host{EntryBase: plugin.NewEntry(name)}
+	h.identity = transport.Identity{Host: name}
+
+	if addr := firstVar(vars, "ansible_host", "ansible_ssh_host"); addr != nil {
+		h.identity.Host = fmt.Sprint(addr)
+	}
+	if user := firstVar(vars, "ansible_user", "ansible_ssh_user"); user != nil {
+		h.identity.User = fmt.Sprint(user)
+	}
+	if password := firstVar(vars, "ansible_password", "ansible_ssh_pass"); password != nil {
+		h.identity.Password = fmt.Sprint(password)
+	}
+	if key := firstVar(vars, "ansible_ssh_private_key_file"); key != nil {
+		h.identity.IdentityFile = fmt.Sprint(key)
+	}
+	if port := firstVar(vars, "ansible_port", "ansible_ssh_port"); port != nil {
+		n, err := strconv.ParseUint(fmt.Sprint(port), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("%v has an invalid port %v", name, port)
+		}
+		h.identity.Port = uint(n)
+	}
+
+	h.meta = hostMetadata{
+		Host:   h.identity.Host,
+		Port:   h.identity.Port,
+		User:   h.identity.User,
+		Groups: groups,
+		Vars:   make(map[string]interface{}),
+	}
+	for k, v := range vars {
+		if !strings.Contains(strings.ToLower(k), "pass") {
+			h.meta.Vars[k] = jsonValue(v)
+		}
+	}
+	h.
+		SetPartialMetadata(h.meta).
+		Attributes().
+		SetOS(plugin.OS{LoginShell: plugin.POSIXShell})
+	return h, nil
+}
+
+func firstVar(vars map[string]interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		if v, ok := vars[key]; ok && v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// jsonValue converts the maps that YAML decodes, which can have non-string keys, to
+// maps that can be marshalled as JSON.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = jsonValue(val)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[k] = jsonValue(val)
+		}
+		return m
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i, val := range v {
+			arr[i] = jsonValue(val)
+		}
+		return arr
+	default:
+		return v
+	}
+}
+
+func (h *host) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(h, "host").
+		SetDescription(hostDescription).
+		SetPartialMetadataSchema(hostMetadata{}).
+		SetMetadataSchema(hostMetadata{}).
+		AddSignal("reboot", "Reboots the host").
+		AddSignal("shutdown", "Shuts down the host")
+}
+
+func (h *host) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&plugin.MetadataJSONFile{}).Schema(),
+		(&transport.SFTP{}).Schema(),
+	}
+}
+
+func (h *host) List(ctx context.Context) ([]plugin.Entry, error) {
+	metadataJSON, err := plugin.NewMetadataJSONFile(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	// Include a view of the remote filesystem using SFTP, which falls back to exec if needed.
+	// Use a small maxdepth because hosts can have lots of files and SSH is fast.
+	return []plugin.Entry{metadataJSON, transport.NewFS(ctx, "fs", h, 3)}, nil
+}
+
+func (h *host) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	return transport.ExecSSH(ctx, h.identity, append([]string{cmd}, args...), opts)
+}
+
+// SSHIdentity returns the identity that's used to connect to the host.
+func (h *host) SSHIdentity(context.Context) (transport.Identity, error) {
+	return h.identity, nil
+}
+
+// hostInfoScript prints the output of uname, then the contents of /etc/os-release
+// if it exists.
+const hostInfoScript = "uname -snrm; cat /etc/os-release 2>/dev/null || true"
+
+// Metadata adds the host's uname and /etc/os-release information to its partial metadata.
+func (h *host) Metadata(ctx context.Context) (plugin.JSONObject, error) {
+	stdout, stderr, exitCode, err := h.run(ctx, []string{"sh", "-c", hostInfoScript}, false)
+	if err != nil {
+		return nil, fmt.Errorf("could not gather information about %v: %v", h.Name(), err)
+	} else if exitCode != 0 {
+		return nil, fmt.Errorf("could not gather information about %v: uname exited %v: %v", h.Name(), exitCode, stderr)
+	}
+
+	meta := h.meta
+	uname, osRelease := parseHostInfo(stdout)
+	meta.Uname = &uname
+	if len(osRelease) > 0 {
+		meta.OSRelease = osRelease
+	}
+	return plugin.ToJSONObject(meta), nil
+}
+
+// parseHostInfo parses the output of hostInfoScript.
+func parseHostInfo(output string) (unameInfo, map[string]string) {
+	var uname unameInfo
+	osRelease := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	if scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		for i, field := range []*string{&uname.KernelName, &uname.Nodename, &uname.KernelRelease, &uname.Machine} {
+			if i < len(fields) {
+				*field = fields[i]
+			}
+		}
+	}
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		segments := strings.SplitN(line, "=", 2)
+		if len(segments) != 2 {
+			continue
+		}
+		value := segments[1]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+		osRelease[segments[0]] = value
+	}
+	return uname, osRelease
+}
+
+// Signal reboots or shuts down the host. The command runs in the background so that it
+// completes before the host drops the connection.
+func (h *host) Signal(ctx context.Context, signal string) error {
+	var flag string
+	switch signal {
+	case "reboot":
+		flag = "-r"
+	case "shutdown":
+		flag = "-h"
+	default:
+		return fmt.Errorf("unsupported signal %v", signal)
+	}
+	script := fmt.Sprintf("(sleep 1; shutdown %v now) >/dev/null 2>&1 &", flag)
+	_, stderr, exitCode, err := h.run(ctx, []string{"sh", "-c", script}, true)
+	if err != nil {
+		return err
+	} else if exitCode != 0 {
+		return fmt.Errorf("could not %v %v: exited %v: %v", signal, h.Name(), exitCode, stderr)
+	}
+	return nil
+}
+
+func (h *host) run(ctx context.Context, cmd []string, elevate bool) (string, string, int, error) {
+	execCmd, err := plugin.Exec(ctx, h, cmd[0], cmd[1:], plugin.ExecOptions{Elevate: elevate})
+	if err != nil {
+		return "", "", 0, err
+	}
+	var stdout, stderr bytes.Buffer
+	for chunk := range execCmd.OutputCh() {
+		if chunk.Err != nil {
+			return "", "", 0, chunk.Err
+		}
+		if chunk.StreamID == plugin.Stdout {
+			stdout.WriteString(chunk.Data)
+		} else {
+			stderr.WriteString(chunk.Data)
+		}
+	}
+	exitCode, err := execCmd.ExitCode()
+	return stdout.String(), strings.TrimSpace(stderr.String()), exitCode, err
+}
+
+const hostDescription = `
+This is a host from SSH config or an Ansible inventory. Its Exec action uses SSH.
+Inventory hosts are reached via their ansible_host, ansible_port, ansible_user and
+ansible_ssh_private_key_file variables; everything else, including settings for
+hosts from SSH config, is looked up from SSH config.
+
+Its metadata includes the output of uname and the contents of /etc/os-release.
+The reboot and shutdown signals run shutdown via sudo.
+`
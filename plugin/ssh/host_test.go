@@ -0,0 +1,80 @@
+package ssh
+
+import (
+	"context"
+	"testing"
+
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHost(t *testing.T) {
+	h, err := newHost("web1", []string{"webservers"}, map[string]interface{}{
+		"ansible_host":                 "10.0.0.1",
+		"ansible_port":                 2222,
+		"ansible_user":                 "deploy",
+		"ansible_password":             "hunter2",
+		"ansible_ssh_private_key_file": "/keys/deploy",
+		"tags":                         map[interface{}]interface{}{"role": "web"},
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, transport.Identity{
+			Host:         "10.0.0.1",
+			Port:         2222,
+			User:         "deploy",
+			Password:     "hunter2",
+			IdentityFile: "/keys/deploy",
+		}, h.identity)
+		assert.Implements(t, (*plugin.Execable)(nil), h)
+		assert.Implements(t, (*plugin.Signalable)(nil), h)
+		assert.Implements(t, (*transport.SSHTarget)(nil), h)
+
+		meta, err := h.EntryBase.Metadata(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "10.0.0.1", meta["host"])
+		assert.Equal(t, []interface{}{"webservers"}, meta["groups"])
+		vars := meta["vars"].(map[string]interface{})
+		assert.NotContains(t, vars, "ansible_password")
+		assert.Equal(t, map[string]interface{}{"role": "web"}, vars["tags"])
+	}
+
+	// Hosts from SSH config are configured from SSH config
+	h, err = newHost("bastion", nil, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, transport.Identity{Host: "bastion"}, h.identity)
+	}
+
+	_, err = newHost("web1", nil, map[string]interface{}{"ansible_port": "ssh"})
+	assert.EqualError(t, err, "web1 has an invalid port ssh")
+}
+
+func TestParseHostInfo(t *testing.T) {
+	uname, osRelease := parseHostInfo(`Linux web1 5.4.0-42-generic x86_64
+NAME="Ubuntu"
+VERSION_ID='20.04'
+# comment
+
+ID=ubuntu
+PRETTY_NAME="Ubuntu 20.04.1 LTS"
+`)
+	assert.Equal(t, unameInfo{KernelName: "Linux", Nodename: "web1", KernelRelease: "5.4.0-42-generic", Machine: "x86_64"}, uname)
+	assert.Equal(t, map[string]string{
+		"NAME":        "Ubuntu",
+		"VERSION_ID":  "20.04",
+		"ID":          "ubuntu",
+		"PRETTY_NAME": "Ubuntu 20.04.1 LTS",
+	}, osRelease)
+
+	// Hosts without /etc/os-release only have uname
+	uname, osRelease = parseHostInfo("Darwin mac 19.6.0 x86_64\n")
+	assert.Equal(t, "Darwin", uname.KernelName)
+	assert.Empty(t, osRelease)
+}
+
+func TestSignalUnsupported(t *testing.T) {
+	h, err := newHost("web1", nil, nil)
+	if assert.NoError(t, err) {
+		assert.EqualError(t, h.Signal(context.Background(), "hibernate"), "unsupported signal hibernate")
+	}
+}
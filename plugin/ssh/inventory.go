@@ -0,0 +1,254 @@
+package ssh
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Groups with special meaning in Ansible inventories. Every host is in the all group;
+// hosts that aren't in any other group are in the ungrouped group.
+const (
+	allGroup       = "all"
+	ungroupedGroup = "ungrouped"
+)
+
+// yamlGroup is a group in an Ansible YAML inventory, such as
+//   all:
+//     hosts:
+//       web1.example.com:
+//         ansible_port: 2222
+//     children:
+//       webservers:
+//         hosts:
+//           web1.example.com:
+//         vars:
+//           ansible_user: deploy
+type yamlGroup struct {
+	Hosts    map[string]map[string]interface{} `yaml:"hosts"`
+	Children map[string]*yamlGroup             `yaml:"children"`
+	Vars     map[string]interface{}            `yaml:"vars"`
+}
+
+type inventoryGroup struct {
+	name     string
+	hosts    []string
+	children []string
+	vars     map[string]interface{}
+}
+
+// inventory is the combination of one or more Ansible YAML inventories. Groups and hosts
+// with the same name in different files are merged.
+type inventory struct {
+	groups   map[string]*inventoryGroup
+	hostVars map[string]map[string]interface{}
+}
+
+func newInventory() *inventory {
+	return &inventory{
+		groups:   make(map[string]*inventoryGroup),
+		hostVars: make(map[string]map[string]interface{}),
+	}
+}
+
+func (inv *inventory) group(name string) *inventoryGroup {
+	group, ok := inv.groups[name]
+	if !ok {
+		group = &inventoryGroup{name: name, vars: make(map[string]interface{})}
+		inv.groups[name] = group
+	}
+	return group
+}
+
+// load adds the hosts and groups from an inventory file.
+func (inv *inventory) load(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read the %v inventory: %v", path, err)
+	}
+	var groups map[string]*yamlGroup
+	if err := yaml.UnmarshalStrict(content, &groups); err != nil {
+		return fmt.Errorf("could not parse the %v inventory: %v", path, err)
+	}
+	all := inv.group(allGroup)
+	for _, name := range sortedKeys(groups) {
+		inv.add(name, groups[name])
+		if name != allGroup {
+			all.addChild(name)
+		}
+	}
+	return nil
+}
+
+func (inv *inventory) add(name string, g *yamlGroup) {
+	group := inv.group(name)
+	if g == nil {
+		return
+	}
+	for _, host := range sortedKeys(g.Hosts) {
+		group.addHost(host)
+		vars, ok := inv.hostVars[host]
+		if !ok {
+			vars = make(map[string]interface{})
+			inv.hostVars[host] = vars
+		}
+		for k, v := range g.Hosts[host] {
+			vars[k] = v
+		}
+	}
+	for k, v := range g.Vars {
+		group.vars[k] = v
+	}
+	for _, child := range sortedKeys(g.Children) {
+		group.addChild(child)
+		inv.add(child, g.Children[child])
+	}
+}
+
+func (g *inventoryGroup) addHost(host string) {
+	if !contains(g.hosts, host) {
+		g.hosts = append(g.hosts, host)
+	}
+}
+
+func (g *inventoryGroup) addChild(child string) {
+	if child != g.name && !contains(g.children, child) {
+		g.children = append(g.children, child)
+	}
+}
+
+// topLevelGroups returns the groups that are shown at the plugin's root. These are the
+// children of the all group, plus the ungrouped group if it has any hosts.
+func (inv *inventory) topLevelGroups() []*inventoryGroup {
+	all, ok := inv.groups[allGroup]
+	if !ok {
+		return nil
+	}
+
+	ungrouped := inv.group(ungroupedGroup)
+	for _, host := range all.hosts {
+		if len(inv.hostGroups(host)) == 0 {
+			ungrouped.addHost(host)
+		}
+	}
+
+	var groups []*inventoryGroup
+	for _, name := range all.children {
+		if name != ungroupedGroup {
+			groups = append(groups, inv.groups[name])
+		}
+	}
+	if len(ungrouped.hosts) > 0 || len(ungrouped.children) > 0 {
+		groups = append(groups, ungrouped)
+	}
+	return groups
+}
+
+// hostGroups returns the names of the groups, other than all and ungrouped, that directly
+// contain host.
+func (inv *inventory) hostGroups(host string) []string {
+	var names []string
+	for name, group := range inv.groups {
+		if name != allGroup && name != ungroupedGroup && contains(group.hosts, host) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// varsFor returns host's variables. Like Ansible, variables from every group that contains
+// the host are merged, with child groups overriding their parents and groups at the same
+// depth applied in alphabetical order. Host variables override group variables.
+func (inv *inventory) varsFor(host string) map[string]interface{} {
+	parents := make(map[string][]string)
+	for name, group := range inv.groups {
+		for _, child := range group.children {
+			parents[child] = append(parents[child], name)
+		}
+	}
+
+	// Find the depth of each group from the all group.
+	depths := map[string]int{allGroup: 0}
+	queue := []string{allGroup}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, child := range inv.groups[name].children {
+			if _, ok := depths[child]; !ok {
+				depths[child] = depths[name] + 1
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	// Collect the groups that contain the host, directly or via a child group.
+	containing := make(map[string]struct{})
+	var visit func(string)
+	visit = func(name string) {
+		if _, ok := containing[name]; ok {
+			return
+		}
+		containing[name] = struct{}{}
+		for _, parent := range parents[name] {
+			visit(parent)
+		}
+	}
+	for name, group := range inv.groups {
+		if contains(group.hosts, host) {
+			visit(name)
+		}
+	}
+	visit(allGroup)
+
+	names := make([]string, 0, len(containing))
+	for name := range containing {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if depths[names[i]] != depths[names[j]] {
+			return depths[names[i]] < depths[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	vars := make(map[string]interface{})
+	for _, name := range names {
+		if group, ok := inv.groups[name]; ok {
+			for k, v := range group.vars {
+				vars[k] = v
+			}
+		}
+	}
+	for k, v := range inv.hostVars[host] {
+		vars[k] = v
+	}
+	return vars
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]*yamlGroup:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]map[string]interface{}:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package ssh
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const testInventory = `
+all:
+  hosts:
+    web1.example.com:
+      ansible_host: 10.0.0.1
+    lonely.example.com:
+  vars:
+    ansible_user: admin
+    ntp_server: ntp.example.com
+  children:
+    webservers:
+      hosts:
+        web1.example.com:
+        web2.example.com:
+          ansible_port: 2222
+      vars:
+        ansible_user: deploy
+      children:
+        canary:
+          hosts:
+            web2.example.com:
+          vars:
+            ansible_user: canary
+    dbservers:
+      hosts:
+        db1.example.com:
+      vars:
+        ansible_user: postgres
+`
+
+type InventoryTestSuite struct {
+	suite.Suite
+	files []string
+}
+
+func (suite *InventoryTestSuite) TearDownTest() {
+	for _, file := range suite.files {
+		suite.NoError(os.Remove(file))
+	}
+	suite.files = nil
+}
+
+func (suite *InventoryTestSuite) writeInventory(content string) string {
+	f, err := ioutil.TempFile("", "wash_inventory")
+	suite.Require().NoError(err)
+	_, err = f.WriteString(content)
+	suite.Require().NoError(err)
+	suite.Require().NoError(f.Close())
+	suite.files = append(suite.files, f.Name())
+	return f.Name()
+}
+
+func (suite *InventoryTestSuite) loadInventory(contents ...string) *inventory {
+	inv := newInventory()
+	for _, content := range contents {
+		suite.Require().NoError(inv.load(suite.writeInventory(content)))
+	}
+	return inv
+}
+
+func (suite *InventoryTestSuite) TestTopLevelGroups() {
+	inv := suite.loadInventory(testInventory)
+
+	var names []string
+	for _, g := range inv.topLevelGroups() {
+		names = append(names, g.name)
+	}
+	suite.Equal([]string{"dbservers", "webservers", "ungrouped"}, names)
+
+	suite.Equal([]string{"lonely.example.com"}, inv.groups[ungroupedGroup].hosts)
+	suite.Equal([]string{"web1.example.com", "web2.example.com"}, inv.groups["webservers"].hosts)
+	suite.Equal([]string{"canary"}, inv.groups["webservers"].children)
+	suite.Equal([]string{"canary", "webservers"}, inv.hostGroups("web2.example.com"))
+}
+
+func (suite *InventoryTestSuite) TestVarsFor() {
+	inv := suite.loadInventory(testInventory)
+
+	// Host vars from all.hosts apply wherever the host's listed
+	vars := inv.varsFor("web1.example.com")
+	suite.Equal("10.0.0.1", vars["ansible_host"])
+	suite.Equal("deploy", vars["ansible_user"])
+	suite.Equal("ntp.example.com", vars["ntp_server"])
+
+	// Child groups override their parents
+	vars = inv.varsFor("web2.example.com")
+	suite.Equal("canary", vars["ansible_user"])
+	suite.Equal(2222, vars["ansible_port"])
+
+	suite.Equal("admin", inv.varsFor("lonely.example.com")["ansible_user"])
+	suite.Equal("postgres", inv.varsFor("db1.example.com")["ansible_user"])
+}
+
+func (suite *InventoryTestSuite) TestMergesInventories() {
+	inv := suite.loadInventory(testInventory, `
+webservers:
+  hosts:
+    web3.example.com:
+staging:
+  hosts:
+    lonely.example.com:
+`)
+	var names []string
+	for _, g := range inv.topLevelGroups() {
+		names = append(names, g.name)
+	}
+	suite.Equal([]string{"dbservers", "webservers", "staging"}, names)
+	suite.Equal([]string{"web1.example.com", "web2.example.com", "web3.example.com"}, inv.groups["webservers"].hosts)
+	suite.Equal("deploy", inv.varsFor("web3.example.com")["ansible_user"])
+}
+
+func (suite *InventoryTestSuite) TestLoadErrors() {
+	inv := newInventory()
+	suite.Regexp("could not read the /does/not/exist inventory", inv.load("/does/not/exist"))
+	suite.Regexp("could not parse the .* inventory", inv.load(suite.writeInventory("all:\n  hostz:\n    web1:\n")))
+}
+
+func TestInventory(t *testing.T) {
+	suite.Run(t, new(InventoryTestSuite))
+}
@@ -0,0 +1,142 @@
+// Package ssh presents a filesystem hierarchy for hosts that are reached via SSH.
+//
+// It reads hosts from the Host blocks in SSH config and from Ansible YAML inventories.
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
+)
+
+// sshConfigGroup is the group that contains the hosts from SSH config.
+const sshConfigGroup = "ssh_config"
+
+// sshConfigHosts returns the hosts in SSH config. It's a variable so that the tests can
+// mock it.
+var sshConfigHosts = transport.SSHConfigHosts
+
+// Root of the SSH plugin
+type Root struct {
+	plugin.EntryBase
+	sshConfig   bool
+	inventories []string
+}
+
+// Init for root
+func (r *Root) Init(cfg map[string]interface{}) error {
+	r.EntryBase = plugin.NewEntry("ssh")
+	r.SetTTLOf(plugin.ListOp, 30*time.Second)
+
+	r.sshConfig = true
+	if sshConfigI, ok := cfg["ssh_config"]; ok {
+		sshConfig, ok := sshConfigI.(bool)
+		if !ok {
+			return fmt.Errorf("ssh.ssh_config config must be a boolean, not %v", sshConfigI)
+		}
+		r.sshConfig = sshConfig
+	}
+
+	r.inventories = nil
+	if invsI, ok := cfg["inventories"]; ok {
+		invs, ok := invsI.([]interface{})
+		if !ok {
+			return fmt.Errorf("ssh.inventories config must be an array of strings, not %v", invsI)
+		}
+		for _, elem := range invs {
+			inv, ok := elem.(string)
+			if !ok {
+				return fmt.Errorf("ssh.inventories config must be an array of strings, not %v", invs)
+			}
+			if inv == "~" || strings.HasPrefix(inv, "~/") {
+				homedir, err := os.UserHomeDir()
+				if err != nil {
+					return fmt.Errorf("could not expand %v: %v", inv, err)
+				}
+				inv = filepath.Join(homedir, inv[1:])
+			}
+			r.inventories = append(r.inventories, inv)
+		}
+	}
+
+	// Force loading the inventories on startup to expose errors early.
+	_, err := r.loadInventory()
+	return err
+}
+
+func (r *Root) loadInventory() (*inventory, error) {
+	inv := newInventory()
+	for _, path := range r.inventories {
+		if err := inv.load(path); err != nil {
+			return nil, err
+		}
+	}
+	return inv, nil
+}
+
+// ChildSchemas returns the root's child schema
+func (r *Root) ChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&group{}).Schema(),
+	}
+}
+
+// Schema returns the root's schema
+func (r *Root) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(r, "ssh").
+		SetDescription(rootDescription).
+		IsSingleton()
+}
+
+// List lists the inventory groups, followed by the ssh_config group.
+func (r *Root) List(ctx context.Context) ([]plugin.Entry, error) {
+	inv, err := r.loadInventory()
+	if err != nil {
+		return nil, err
+	}
+	activity.Record(ctx, "Loaded %v hosts from %v inventories", len(inv.hostVars), len(r.inventories))
+
+	var entries []plugin.Entry
+	for _, g := range inv.topLevelGroups() {
+		entries = append(entries, newInventoryGroup(ctx, inv, g))
+	}
+
+	if r.sshConfig {
+		aliases, err := sshConfigHosts()
+		if err != nil {
+			return nil, err
+		}
+		activity.Record(ctx, "Loaded %v hosts from SSH config", len(aliases))
+		if len(aliases) > 0 {
+			hosts := make([]plugin.Entry, len(aliases))
+			for i, alias := range aliases {
+				// Hosts from SSH config have no variables, so newHost can't fail.
+				hosts[i], _ = newHost(alias, nil, nil)
+			}
+			entries = append(entries, newGroup(sshConfigGroup, hosts))
+		}
+	}
+	return entries, nil
+}
+
+const rootDescription = `
+This is the SSH plugin root. It contains hosts that aren't managed by a cloud
+API. Hosts are read from the Host blocks in ~/.ssh/config and /etc/ssh/ssh_config
+(skipping wildcard patterns), and from Ansible YAML inventories. Configure them with
+
+ssh:
+  ssh_config: true
+  inventories: [~/inventory.yaml]
+
+in Wash’s config file. ssh_config defaults to true. Each inventory group is a
+directory containing its child groups and its hosts; hosts from SSH config are
+in the ssh_config group.
+`
@@ -0,0 +1,79 @@
+package ssh
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+type RootTestSuite struct {
+	suite.Suite
+	inventory      string
+	savedHostsFunc func() ([]string, error)
+}
+
+func (suite *RootTestSuite) SetupTest() {
+	f, err := ioutil.TempFile("", "wash_inventory")
+	suite.Require().NoError(err)
+	_, err = f.WriteString(testInventory)
+	suite.Require().NoError(err)
+	suite.Require().NoError(f.Close())
+	suite.inventory = f.Name()
+
+	suite.savedHostsFunc = sshConfigHosts
+	sshConfigHosts = func() ([]string, error) { return []string{"bastion", "nas"}, nil }
+}
+
+func (suite *RootTestSuite) TearDownTest() {
+	sshConfigHosts = suite.savedHostsFunc
+	suite.NoError(os.Remove(suite.inventory))
+}
+
+func names(entries []plugin.Entry) []string {
+	var names []string
+	for _, entry := range entries {
+		names = append(names, plugin.Name(entry))
+	}
+	return names
+}
+
+func (suite *RootTestSuite) TestList() {
+	var root Root
+	suite.Require().NoError(root.Init(map[string]interface{}{"inventories": []interface{}{suite.inventory}}))
+	ctx := context.Background()
+	entries, err := root.List(ctx)
+	suite.Require().NoError(err)
+	suite.Equal([]string{"dbservers", "webservers", "ungrouped", "ssh_config"}, names(entries))
+
+	webservers, err := entries[1].(*group).List(ctx)
+	suite.NoError(err)
+	suite.Equal([]string{"canary", "web1.example.com", "web2.example.com"}, names(webservers))
+	suite.Equal("deploy", webservers[1].(*host).identity.User)
+
+	sshConfig, err := entries[3].(*group).List(ctx)
+	suite.NoError(err)
+	suite.Equal([]string{"bastion", "nas"}, names(sshConfig))
+}
+
+func (suite *RootTestSuite) TestListWithoutSSHConfig() {
+	var root Root
+	suite.Require().NoError(root.Init(map[string]interface{}{"ssh_config": false}))
+	entries, err := root.List(context.Background())
+	suite.NoError(err)
+	suite.Empty(entries)
+}
+
+func (suite *RootTestSuite) TestInitErrors() {
+	var root Root
+	suite.EqualError(root.Init(map[string]interface{}{"ssh_config": "yes"}), "ssh.ssh_config config must be a boolean, not yes")
+	suite.EqualError(root.Init(map[string]interface{}{"inventories": "hosts.yaml"}), "ssh.inventories config must be an array of strings, not hosts.yaml")
+	suite.Regexp("could not read the /does/not/exist inventory", root.Init(map[string]interface{}{"inventories": []interface{}{"/does/not/exist"}}))
+}
+
+func TestRoot(t *testing.T) {
+	suite.Run(t, new(RootTestSuite))
+}
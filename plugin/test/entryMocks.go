@@ -2,6 +2,8 @@ package plugintest
 
 import (
 	"context"
+	"io"
+	"io/ioutil"
 
 	"github.com/puppetlabs/wash/plugin"
 	"github.com/stretchr/testify/mock"
@@ -114,3 +116,46 @@ func (m *MockBlockReadWrite) Write(ctx context.Context, p []byte) error {
 
 var _ = plugin.BlockReadable(&MockBlockReadWrite{})
 var _ = plugin.Writable(&MockBlockReadWrite{})
+
+// MockBlockWrite mocks block write operations. Write reads all of the data so that
+// expectations can be set on it.
+type MockBlockWrite struct {
+	MockBase
+}
+
+// NewMockBlockWrite creates a new "mock" entry for block writes.
+func NewMockBlockWrite() *MockBlockWrite {
+	m := &MockBlockWrite{MockBase{EntryBase: plugin.NewEntry("mockbw")}}
+	m.SetTestID("/mockbw")
+	return m
+}
+
+func (m *MockBlockWrite) Write(ctx context.Context, size int64, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	args := m.Called(ctx, size, data)
+	return args.Error(0)
+}
+
+var _ = plugin.BlockWritable(&MockBlockWrite{})
+
+// MockLink only mocks Readlink operations.
+type MockLink struct {
+	MockBase
+}
+
+// NewMockLink creates a new "mock" entry for links.
+func NewMockLink() *MockLink {
+	m := &MockLink{MockBase{EntryBase: plugin.NewEntry("mockl")}}
+	m.SetTestID("/mockl")
+	return m
+}
+
+func (m *MockLink) Readlink(ctx context.Context) (string, error) {
+	args := m.Called(ctx)
+	return args.String(0), args.Error(1)
+}
+
+var _ = plugin.Linkable(&MockLink{})
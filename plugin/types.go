@@ -16,7 +16,9 @@ Implementing the Parent interface displays that resource as a directory on the f
 Anything that does not implement Parent will be displayed as a file.
 
 The Readable interface allows reading data from an entry via the filesystem. The Writable
-interface allows sending data to the entry.
+interface allows sending data to the entry. Entries that support ranged or multipart uploads can
+implement BlockWritable instead, so that large writes are streamed to them rather than held in
+memory.
 
 Wash distinguishes between two different patterns for things you can read and write. It considers
 a "file-like" entry to be one with a defined size (so the `size` attribute is set when listing the
@@ -27,6 +29,8 @@ non-file-like entries error if you try to open them with a ReadWrite handle. If
 implements non-file-like write-semantics, remember to document how they work in the plugin schema's
 description.
 
+The Linkable interface represents an entry as a symbolic link to another entry.
+
 All of the above, as well as other types - Execable, Stream - provide additional functionality
 via the HTTP API.
 */
@@ -100,6 +104,13 @@ type Root interface {
 	Init(map[string]interface{}) error
 }
 
+// ContextIniter is implemented by roots whose initialization can be cancelled. If a
+// root implements it, then InitContext is called instead of Init with a context whose
+// deadline is the init timeout configured in the plugin's limits.
+type ContextIniter interface {
+	InitContext(context.Context, map[string]interface{}) error
+}
+
 // HasWrappedTypes is an interface that's used by the EntrySchema#SetMeta*Schema methods to return
 // the right metadata schema for wrapped types. Plugin roots should implement this interface if the
 // plugin's SDK wraps primitive types like date, integer, number, string, boolean, etc. See
@@ -118,7 +129,9 @@ type HasWrappedTypes interface {
 }
 
 // ExecOptions is a struct we can add new features to that must be serializable to JSON.
-// Examples of potential features: user, privileged, map of environment variables, timeout.
+//
+// NOTE TO PLUGIN AUTHORS: If your executor can't honour one of the Env, WorkingDir or User
+// options, then Exec should return an UnsupportedExecOptionErr instead of silently ignoring it.
 type ExecOptions struct {
 	// Stdin can be used to pass a stream of input to write to stdin when executing the command.
 	// It is not included in ExecOption's JSON serialization.
@@ -141,6 +154,53 @@ type ExecOptions struct {
 
 	// Elevate execution to run as a privileged user if not already running as a privileged user.
 	Elevate bool `json:"elevate"`
+
+	// Env contains additional environment variables to set for the command.
+	Env map[string]string `json:"env,omitempty"`
+
+	// WorkingDir is the directory that the command runs in. If it's empty, then the command runs
+	// in the executor's default working directory.
+	WorkingDir string `json:"working_dir,omitempty"`
+
+	// User is the user that the command runs as. If it's empty, then the command runs as the
+	// executor's default user.
+	User string `json:"user,omitempty"`
+
+	// Timeout is the maximum amount of time that the command can run for. A zero Timeout means
+	// that there is no timeout. plugin.Exec enforces the timeout by cancelling the Exec context
+	// once it expires, so executors don't need to handle it. It is not included in ExecOption's
+	// JSON serialization.
+	Timeout time.Duration `json:"-"`
+
+	// Resize is set for interactive exec sessions (e.g. `wash exec -it`). In an interactive
+	// session, Tty is set and Stdin streams the user's input. Resize delivers the size of the
+	// user's terminal; its first value is sent before Exec is called, and subsequent values are
+	// sent whenever the terminal's resized. The channel is closed when the session ends. It is
+	// not included in ExecOption's JSON serialization.
+	//
+	// NOTE TO PLUGIN AUTHORS: Executors that support interactive sessions should allocate a TTY
+	// with the initial size, resize it as new sizes arrive, and write the TTY's output to the
+	// command's Stdout.
+	Resize <-chan TerminalSize `json:"-"`
+}
+
+// TerminalSize represents the size of a terminal.
+type TerminalSize struct {
+	Rows uint16 `json:"rows"`
+	Cols uint16 `json:"cols"`
+}
+
+// InitialTerminalSize returns the first size that's available on resize. If resize
+// is nil or empty, then it returns the default size (40 rows, 80 columns).
+func InitialTerminalSize(resize <-chan TerminalSize) TerminalSize {
+	select {
+	case size, ok := <-resize:
+		if ok {
+			return size
+		}
+	default:
+	}
+	return TerminalSize{Rows: 40, Cols: 80}
 }
 
 // ExecPacketType identifies the packet type.
@@ -214,6 +274,18 @@ type Writable interface {
 	Write(context.Context, []byte) error
 }
 
+// BlockWritable is an entry whose data can be written in blocks, such as via ranged or
+// multipart uploads. Like Writable, a write replaces the entry's data. Write is passed the size
+// of the new data and a reader for it; it should read the data in whatever block size suits
+// the entry and return once all of it's been written. Unlike Writable, the data isn't read into
+// memory first, so BlockWritable is better suited to large files.
+//
+// Go doesn't allow overloaded methods, so an entry can't be both Writable and BlockWritable.
+type BlockWritable interface {
+	Entry
+	Write(ctx context.Context, size int64, r io.Reader) error
+}
+
 // Deletable is an entry that can be deleted. Entries that implement Delete
 // should ensure that it and all its children are removed. If the entry has
 // any dependencies that need to be deleted, then Delete should return an
@@ -238,6 +310,18 @@ type Signalable interface {
 	Signal(context.Context, string) error
 }
 
+// Linkable is an entry that's a symbolic link to another entry, such as a Docker volume that
+// mounts another path or a symlink on a remote filesystem. It's represented as a symlink on the
+// filesystem. Readlink returns the link's target, which is either an absolute Wash path (e.g.
+// "/docker/volumes/data") or a path relative to the link's parent (e.g. "../data").
+//
+// Links are not followed when walking the Wash hierarchy, so a Linkable entry shouldn't also
+// be a Parent.
+type Linkable interface {
+	Entry
+	Readlink(context.Context) (string, error)
+}
+
 // This interface exists to break the circular dependency between plugin and external.
 // The external plugin implementation is in its own module so it can use other modules
 // that implement new features and have dependencies on this module.
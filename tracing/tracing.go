@@ -0,0 +1,166 @@
+// Package tracing records OpenTelemetry spans for API requests, plugin method
+// invocations, cache lookups and the processes that Wash shells out to. Spans are
+// only exported once Init's called with an endpoint or a file; otherwise they're
+// no-ops.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/api/core"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/key"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/trace/stdout"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/codes"
+)
+
+// Config configures where spans are exported to. It's read from the tracing
+// key in wash.yaml.
+type Config struct {
+	// Endpoint is the address of an OpenTelemetry collector that spans are
+	// exported to via OTLP, e.g. localhost:55680.
+	Endpoint string `mapstructure:"endpoint"`
+	// File is the path of a file that spans are appended to as newline-delimited
+	// JSON objects.
+	File string `mapstructure:"file"`
+}
+
+// Enabled returns true if spans are exported somewhere.
+func (c Config) Enabled() bool {
+	return c.Endpoint != "" || c.File != ""
+}
+
+const tracerName = "github.com/puppetlabs/wash"
+
+// Init starts exporting spans as configured. The returned function flushes any
+// outstanding spans and releases the exporters, so it should be called before
+// the process exits.
+func Init(config Config) (func(), error) {
+	if !config.Enabled() {
+		return func() {}, nil
+	}
+
+	provider, err := sdktrace.NewProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+		sdktrace.WithResourceAttributes(key.String("service.name", "wash")),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var processors []sdktrace.SpanProcessor
+	var closers []func()
+	shutdown := func() {
+		// Unregistering the processors shuts them down, which flushes any batched spans.
+		for _, processor := range processors {
+			provider.UnregisterSpanProcessor(processor)
+		}
+		for _, close := range closers {
+			close()
+		}
+	}
+	if config.Endpoint != "" {
+		exporter, err := otlp.NewExporter(otlp.WithInsecure(), otlp.WithAddress(config.Endpoint))
+		if err != nil {
+			return nil, fmt.Errorf("could not export spans to %v: %v", config.Endpoint, err)
+		}
+		closers = append(closers, func() { _ = exporter.Stop() })
+		processor, err := sdktrace.NewBatchSpanProcessor(exporter)
+		if err != nil {
+			shutdown()
+			return nil, err
+		}
+		processors = append(processors, processor)
+	}
+	if config.File != "" {
+		f, err := os.OpenFile(config.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+		if err != nil {
+			shutdown()
+			return nil, fmt.Errorf("could not open the traces file: %v", err)
+		}
+		closers = append(closers, func() { f.Close() })
+		exporter, err := stdout.NewExporter(stdout.Options{Writer: f})
+		if err != nil {
+			shutdown()
+			return nil, err
+		}
+		processors = append(processors, sdktrace.NewSimpleSpanProcessor(exporter))
+	}
+
+	for _, processor := range processors {
+		provider.RegisterSpanProcessor(processor)
+	}
+	global.SetTraceProvider(provider)
+	atomic.StoreInt32(&enabled, 1)
+	return func() {
+		atomic.StoreInt32(&enabled, 0)
+		global.SetTraceProvider(trace.NoopProvider{})
+		shutdown()
+	}, nil
+}
+
+// enabled is 1 while spans are exported.
+var enabled int32
+
+// Start starts a span that's a child of ctx's span. The returned context should be
+// passed to any calls that the span covers. If spans aren't exported, then ctx is
+// returned as-is with a no-op span.
+func Start(ctx context.Context, name string, attrs ...core.KeyValue) (context.Context, trace.Span) {
+	if atomic.LoadInt32(&enabled) == 0 {
+		return ctx, trace.NoopSpan{}
+	}
+	return global.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// String returns a span attribute with the given value.
+func String(name string, value string) core.KeyValue {
+	return key.String(name, value)
+}
+
+// Annotate adds the attributes to ctx's span.
+func Annotate(ctx context.Context, attrs ...core.KeyValue) {
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+}
+
+// End ends the span, recording err if it's not nil.
+func End(ctx context.Context, span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Unknown))
+	}
+	span.End()
+}
+
+// Env returns the environment variables that propagate ctx's span to a child
+// process. They follow the W3C Trace Context format, e.g.
+//     TRACEPARENT=00-<trace-id>-<span-id>-01
+func Env(ctx context.Context) []string {
+	carrier := envCarrier{}
+	trace.TraceContext{}.Inject(ctx, carrier)
+	env := make([]string, 0, len(carrier))
+	for k, v := range carrier {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+// envCarrier maps Trace Context headers to environment variables.
+type envCarrier map[string]string
+
+func (c envCarrier) Get(key string) string {
+	return c[envName(key)]
+}
+
+func (c envCarrier) Set(key string, value string) {
+	c[envName(key)] = value
+}
+
+func envName(header string) string {
+	return strings.ToUpper(strings.Replace(header, "-", "_", -1))
+}
@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitDisabled(t *testing.T) {
+	shutdown, err := Init(Config{})
+	if assert.NoError(t, err) {
+		shutdown()
+	}
+
+	ctx, span := Start(context.Background(), "disabled")
+	assert.Equal(t, context.Background(), ctx)
+	assert.False(t, span.SpanContext().IsValid())
+	assert.Empty(t, Env(ctx))
+	End(ctx, span, nil)
+}
+
+func TestInitFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tracing")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "traces.json")
+	shutdown, err := Init(Config{File: file})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx, parent := Start(context.Background(), "parent", String("wash.plugin", "mine"))
+	childCtx, child := Start(ctx, "child")
+	Annotate(childCtx, String("wash.cache", "miss"))
+
+	env := Env(childCtx)
+	if assert.Len(t, env, 1) {
+		traceID := child.SpanContext().TraceID.String()
+		spanID := child.SpanContext().SpanID.String()
+		assert.Equal(t, "TRACEPARENT=00-"+traceID+"-"+spanID+"-01", env[0])
+		assert.Equal(t, parent.SpanContext().TraceID, child.SpanContext().TraceID)
+	}
+
+	End(childCtx, child, errors.New("failed"))
+	End(ctx, parent, nil)
+	shutdown()
+
+	data, err := ioutil.ReadFile(file)
+	if !assert.NoError(t, err) {
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Contains(t, lines[0], `"Name":"child"`)
+		assert.Contains(t, lines[0], "wash.cache")
+		assert.Contains(t, lines[0], "failed")
+		assert.Contains(t, lines[1], `"Name":"parent"`)
+		assert.Contains(t, lines[1], "wash.plugin")
+	}
+
+	// Spans aren't exported after shutdown.
+	_, span := Start(context.Background(), "after")
+	assert.False(t, span.SpanContext().IsValid())
+}
+
+func TestInitInvalidFile(t *testing.T) {
+	_, err := Init(Config{File: "/nonexistent/traces.json"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package transport
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/puppetlabs/wash/plugin"
+	"golang.org/x/crypto/ssh"
+)
+
+// promptFn prompts for an encrypted private key's passphrase. It's a variable
+// so that the tests can mock it.
+var promptFn = plugin.Prompt
+
+// decryptedKeys caches the signers of encrypted private keys so that users are
+// only prompted for a key's passphrase once.
+var decryptedKeys = struct {
+	sync.Mutex
+	signers map[string]ssh.Signer
+}{signers: make(map[string]ssh.Signer)}
+
+// loadSigner loads the private key at path. If the key is encrypted, then its
+// passphrase is prompted for. Both PEM and OpenSSH-format keys can be encrypted.
+func loadSigner(path string) (ssh.Signer, error) {
+	key, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err == nil {
+		return signer, nil
+	}
+	if _, ok := err.(*ssh.PassphraseMissingError); !ok {
+		return nil, err
+	}
+
+	// Hold the lock while prompting so that concurrent connections don't prompt
+	// for the same key.
+	decryptedKeys.Lock()
+	defer decryptedKeys.Unlock()
+	if signer, ok := decryptedKeys.signers[path]; ok {
+		return signer, nil
+	}
+	passphrase, err := promptFn(fmt.Sprintf("Enter passphrase for key %v", path))
+	if err != nil {
+		return nil, fmt.Errorf("%v is encrypted, and its passphrase could not be read: %v", path, err)
+	}
+	rawKey, err := ssh.ParseRawPrivateKeyWithPassphrase(key, []byte(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt %v: %v", path, err)
+	}
+	signer, err = ssh.NewSignerFromKey(rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not load %v: %v", path, err)
+	}
+	decryptedKeys.signers[path] = signer
+	return signer, nil
+}
+
+// loadCertificate loads the OpenSSH certificate at path.
+func loadCertificate(path string) (*ssh.Certificate, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(b)
+	if err != nil {
+		return nil, err
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a certificate", path)
+	}
+	return cert, nil
+}
+
+// withCertificates returns signers with a certificate signer inserted before
+// each signer that has a matching certificate. Servers that trust the
+// certificate's CA will then accept the certificate, while servers that
+// don't can still accept the plain key.
+func withCertificates(signers []ssh.Signer, certs []*ssh.Certificate) []ssh.Signer {
+	if len(certs) == 0 {
+		return signers
+	}
+	var result []ssh.Signer
+	for _, signer := range signers {
+		pub := signer.PublicKey().Marshal()
+		for _, cert := range certs {
+			if !bytes.Equal(cert.Key.Marshal(), pub) {
+				continue
+			}
+			if certSigner, err := ssh.NewCertSigner(cert, signer); err == nil {
+				result = append(result, certSigner)
+			}
+		}
+		result = append(result, signer)
+	}
+	return result
+}
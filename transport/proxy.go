@@ -0,0 +1,152 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+)
+
+// parseProxyJump parses a ProxyJump setting, which is a comma-separated list
+// of [user@]host[:port] hops.
+func parseProxyJump(proxyJump string) ([]Identity, error) {
+	if proxyJump == "" || proxyJump == "none" {
+		return nil, nil
+	}
+
+	var hops []Identity
+	for _, hop := range strings.Split(proxyJump, ",") {
+		hop = strings.TrimPrefix(strings.TrimSpace(hop), "ssh://")
+		var id Identity
+		if ix := strings.LastIndex(hop, "@"); ix >= 0 {
+			id.User, hop = hop[:ix], hop[ix+1:]
+		}
+		id.Host = hop
+		// Hosts with a port are either host:port or [ipv6]:port
+		if strings.Count(hop, ":") == 1 || strings.HasPrefix(hop, "[") {
+			host, port, err := net.SplitHostPort(hop)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ProxyJump host %v: %v", hop, err)
+			}
+			portNum, err := strconv.ParseUint(port, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ProxyJump port %v: %v", port, err)
+			}
+			id.Host, id.Port = host, uint(portNum)
+		}
+		if id.Host == "" {
+			return nil, fmt.Errorf("invalid ProxyJump %v: hosts cannot be empty", proxyJump)
+		}
+		hops = append(hops, id)
+	}
+	return hops, nil
+}
+
+// expandProxyCommand expands the ProxyCommand tokens that OpenSSH supports:
+// %h (host), %p (port), %r (user), %n (the original host name) and %%.
+func expandProxyCommand(proxyCommand, originalHost string, conf sshConfig) string {
+	return strings.NewReplacer(
+		"%%", "%",
+		"%h", conf.host,
+		"%p", conf.port,
+		"%r", conf.user,
+		"%n", originalHost,
+	).Replace(proxyCommand)
+}
+
+// proxyCommandConn is a connection over a ProxyCommand's stdin and stdout.
+type proxyCommandConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	addr   proxyAddr
+}
+
+// newProxyCommandConn starts the ProxyCommand. Like OpenSSH, the command is run
+// by the shell. The command outlives ctx because cached connections can be
+// re-used by other requests; it's killed when the connection's closed.
+func newProxyCommandConn(ctx context.Context, proxyCommand string, addr string) (*proxyCommandConn, error) {
+	cmd := exec.Command("sh", "-c", "exec "+proxyCommand)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = proxyStderr{ctx: ctx}
+	activity.Record(ctx, "Starting ProxyCommand %v for %v", proxyCommand, addr)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("Failed to start ProxyCommand %v: %v", proxyCommand, err)
+	}
+	return &proxyCommandConn{cmd: cmd, stdin: stdin, stdout: stdout, addr: proxyAddr(addr)}, nil
+}
+
+func (c *proxyCommandConn) Read(p []byte) (int, error) {
+	return c.stdout.Read(p)
+}
+
+func (c *proxyCommandConn) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+func (c *proxyCommandConn) Close() error {
+	c.stdin.Close()
+	if c.cmd.ProcessState == nil {
+		_ = c.cmd.Process.Kill()
+	}
+	// Wait releases the process' resources. It'll return an error because the
+	// process was killed, so ignore it.
+	_ = c.cmd.Wait()
+	return nil
+}
+
+func (c *proxyCommandConn) LocalAddr() net.Addr {
+	return proxyAddr("")
+}
+
+func (c *proxyCommandConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+// Deadlines aren't supported because they can't be set on the command's pipes.
+
+func (c *proxyCommandConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *proxyCommandConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *proxyCommandConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// proxyAddr is the address of a host that's reached via a ProxyCommand.
+type proxyAddr string
+
+func (a proxyAddr) Network() string {
+	return "proxy"
+}
+
+func (a proxyAddr) String() string {
+	return string(a)
+}
+
+// proxyStderr records the ProxyCommand's stderr in the activity journal.
+type proxyStderr struct {
+	ctx context.Context
+}
+
+func (w proxyStderr) Write(p []byte) (int, error) {
+	activity.Record(w.ctx, "ProxyCommand: %s", strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
@@ -0,0 +1,66 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ProxyTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ProxyTestSuite) TestParseProxyJump() {
+	hops, err := parseProxyJump("")
+	suite.NoError(err)
+	suite.Empty(hops)
+
+	hops, err = parseProxyJump("none")
+	suite.NoError(err)
+	suite.Empty(hops)
+
+	hops, err = parseProxyJump("bastion, admin@jump:2222,ssh://[::1]:22")
+	if suite.NoError(err) {
+		suite.Equal([]Identity{
+			{Host: "bastion"},
+			{Host: "jump", User: "admin", Port: 2222},
+			{Host: "::1", Port: 22},
+		}, hops)
+	}
+
+	_, err = parseProxyJump("bastion,")
+	suite.Regexp("hosts cannot be empty", err)
+
+	_, err = parseProxyJump("bastion:abc")
+	suite.Regexp("invalid ProxyJump port", err)
+}
+
+func (suite *ProxyTestSuite) TestExpandProxyCommand() {
+	conf := sshConfig{host: "10.0.0.1", port: "22", user: "admin"}
+	suite.Equal(
+		"ssh -W 10.0.0.1:22 -l admin bastion # myhost 100%",
+		expandProxyCommand("ssh -W %h:%p -l %r bastion # %n 100%%", "myhost", conf),
+	)
+}
+
+func (suite *ProxyTestSuite) TestProxyCommandConn() {
+	conn, err := newProxyCommandConn(context.Background(), "cat", "myhost:22")
+	if suite.NoError(err) {
+		suite.Equal("myhost:22", conn.RemoteAddr().String())
+
+		_, err = conn.Write([]byte("hello"))
+		suite.NoError(err)
+		buf := make([]byte, 5)
+		_, err = io.ReadFull(conn, buf)
+		suite.NoError(err)
+		suite.Equal("hello", string(buf))
+
+		suite.NoError(conn.Close())
+	}
+}
+
+func TestProxy(t *testing.T) {
+	suite.Run(t, new(ProxyTestSuite))
+}
@@ -0,0 +1,513 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/volume"
+)
+
+// SSHTarget is an Execable entry that's reached via SSH.
+type SSHTarget interface {
+	plugin.Execable
+	// SSHIdentity returns the identity that's used to connect to the target.
+	SSHIdentity(ctx context.Context) (Identity, error)
+}
+
+// NewFS creates an SFTP entry with the given name that views the target's filesystem.
+func NewFS(ctx context.Context, name string, target SSHTarget, maxdepth int) *SFTP {
+	fs := &SFTP{
+		EntryBase: plugin.NewEntry(name),
+		target:    target,
+		maxdepth:  maxdepth,
+	}
+	fs.SetTTLOf(plugin.ListOp, volume.ListTTL)
+
+	// SFTP paths on Windows look like /C:/Users, which volume.FS doesn't use.
+	if attr := plugin.Attributes(target); attr.HasOS() && attr.OS().LoginShell == plugin.PowerShell {
+		fs.useExec = true
+	}
+	if _, err := plugin.List(ctx, fs); err != nil {
+		fs.MarkInaccessible(ctx, err)
+	}
+	return fs
+}
+
+// SFTP presents a view of the filesystem of an SSHTarget via SFTP. Unlike volume.FS, it
+// doesn't exec a command for each volume operation, and its files can be written to. Files
+// are accessed as the SSH user, i.e. without sudo.
+//
+// If the target's SSH server doesn't support the SFTP subsystem, then SFTP falls back to
+// exec'ing commands on the target like volume.FS does. Operations that the SSH user doesn't
+// have permission for are also retried with volume.FS, which uses sudo.
+type SFTP struct {
+	plugin.EntryBase
+	target   SSHTarget
+	maxdepth int
+	mux      sync.Mutex
+	execFS   *volume.FS
+	// useExec is set when SFTP's unavailable, so that all volume operations use execFS.
+	useExec bool
+}
+
+// ChildSchemas returns the SFTP entry's child schema
+func (s *SFTP) ChildSchemas() []*plugin.EntrySchema {
+	return volume.ChildSchemas()
+}
+
+// Schema returns the SFTP entry's schema
+func (s *SFTP) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(s, "fs").
+		SetDescription(sftpDescription).
+		IsSingleton()
+}
+
+// List creates a hierarchy of the target's filesystem.
+func (s *SFTP) List(ctx context.Context) ([]plugin.Entry, error) {
+	return volume.List(ctx, s)
+}
+
+// VolumeList satisfies the volume.Interface required by List to enumerate files. Like
+// volume.FS, symlinks are listed as links rather than followed.
+func (s *SFTP) VolumeList(ctx context.Context, dir string) (volume.DirMap, error) {
+	if fs := s.fallback(); fs != nil {
+		return fs.VolumeList(ctx, dir)
+	}
+	activity.Record(ctx, "Listing %v on %v via SFTP", remotePath(dir), plugin.ID(s.target))
+	dirmap := make(volume.DirMap)
+	err := s.withClient(ctx, func(client *sftp.Client) error {
+		return listDir(ctx, client, dirmap, dir, s.maxdepth)
+	})
+	if fs := s.execOnError(ctx, err); fs != nil {
+		return fs.VolumeList(ctx, dir)
+	} else if err != nil {
+		return nil, err
+	}
+	activity.Record(ctx, "VolumeList complete")
+	return dirmap, nil
+}
+
+// listDir adds dir's children to dirmap, exploring subdirectories up to maxdepth. Errors
+// reading a subdirectory are recorded rather than returned, similar to how volume.FS
+// ignores 'find' errors.
+func listDir(ctx context.Context, client *sftp.Client, dirmap volume.DirMap, dir string, maxdepth int) error {
+	infos, err := client.ReadDir(remotePath(dir))
+	if err != nil {
+		return err
+	}
+
+	children := make(volume.Children)
+	dirmap[dir] = children
+	for _, info := range infos {
+		subpath := dir + "/" + info.Name()
+		children[info.Name()] = sftpAttributes(info)
+
+		if !info.IsDir() {
+			continue
+		}
+		if maxdepth <= 1 {
+			// Mark directories at maxdepth as unexplored.
+			dirmap[subpath] = nil
+		} else if err := listDir(ctx, client, dirmap, subpath, maxdepth-1); isPermissionErr(err) {
+			// Mark it as unexplored so that listing it retries with exec.
+			activity.Record(ctx, "Unable to list %v: %v", subpath, err)
+			dirmap[subpath] = nil
+		} else if err != nil {
+			activity.Record(ctx, "Unable to list %v: %v", subpath, err)
+			dirmap[subpath] = make(volume.Children)
+		}
+	}
+	return nil
+}
+
+func sftpAttributes(info os.FileInfo) plugin.EntryAttributes {
+	var attr plugin.EntryAttributes
+	attr.
+		SetSize(uint64(info.Size())).
+		SetMode(info.Mode()).
+		SetMtime(info.ModTime())
+	if stat, ok := info.Sys().(*sftp.FileStat); ok {
+		attr.SetAtime(time.Unix(int64(stat.Atime), 0))
+	}
+	return attr
+}
+
+// VolumeRead satisfies the volume.Interface required by List to read file contents.
+func (s *SFTP) VolumeRead(ctx context.Context, path string) ([]byte, error) {
+	if fs := s.fallback(); fs != nil {
+		return fs.VolumeRead(ctx, path)
+	}
+	activity.Record(ctx, "Reading %v on %v via SFTP", path, plugin.ID(s.target))
+	var content []byte
+	err := s.withClient(ctx, func(client *sftp.Client) error {
+		f, err := client.Open(remotePath(path))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		content, err = ioutil.ReadAll(f)
+		return err
+	})
+	if fs := s.execOnError(ctx, err); fs != nil {
+		return fs.VolumeRead(ctx, path)
+	}
+	return content, err
+}
+
+// streamPollInterval is how often VolumeStream checks for new content. It's a variable so
+// that the tests can shorten it.
+var streamPollInterval = time.Second
+
+// streamTailLines is the number of existing lines that VolumeStream starts with, which
+// matches 'tail -f'.
+const streamTailLines = 10
+
+// VolumeStream satisfies the volume.Interface required by List to stream file contents. It
+// emulates 'tail -f' by polling the file for new content.
+func (s *SFTP) VolumeStream(ctx context.Context, path string) (io.ReadCloser, error) {
+	if fs := s.fallback(); fs != nil {
+		return fs.VolumeStream(ctx, path)
+	}
+	activity.Record(ctx, "Streaming %v on %v via SFTP", path, plugin.ID(s.target))
+	id, err := s.target.SSHIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The client's closed when streaming stops, so it can't be shared with other operations.
+	client, err := newSFTPClient(ctx, id)
+	if fs := s.execOnError(ctx, err); fs != nil {
+		return fs.VolumeStream(ctx, path)
+	} else if err != nil {
+		return nil, err
+	}
+	f, err := client.Open(remotePath(path))
+	if err != nil {
+		client.Close()
+		if fs := s.execOnError(ctx, err); fs != nil {
+			return fs.VolumeStream(ctx, path)
+		}
+		return nil, err
+	}
+	offset, err := tailOffset(f)
+	if err == nil {
+		_, err = f.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		client.Close()
+		return nil, err
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		defer client.Close()
+		defer f.Close()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := f.Read(buf)
+			if n > 0 {
+				if _, err := w.Write(buf[:n]); err != nil {
+					// The reader was closed.
+					return
+				}
+				offset += int64(n)
+			}
+			if err == nil {
+				continue
+			} else if err != io.EOF {
+				activity.Record(ctx, "Error streaming %v: %v", path, err)
+				w.CloseWithError(err)
+				return
+			}
+
+			// Start over if the file was truncated, like 'tail -f'.
+			if info, err := f.Stat(); err == nil && info.Size() < offset {
+				activity.Record(ctx, "%v was truncated, streaming from the beginning", path)
+				if offset, err = f.Seek(0, io.SeekStart); err != nil {
+					w.CloseWithError(err)
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				activity.Record(ctx, "Closing write pipe: %v", w.Close())
+				return
+			case <-time.After(streamPollInterval):
+			}
+		}
+	}()
+	return r, nil
+}
+
+// tailOffset returns the offset of the last streamTailLines lines of f. Lines are only
+// searched for in the last 64 KiB of f.
+func tailOffset(f *sftp.File) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	start := info.Size() - 64*1024
+	if start < 0 {
+		start = 0
+	}
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return 0, err
+	}
+	buf, err := ioutil.ReadAll(io.LimitReader(f, info.Size()-start))
+	if err != nil {
+		return 0, err
+	}
+
+	// Skip the trailing newline so that it isn't counted as the end of a line.
+	buf = bytes.TrimSuffix(buf, []byte("\n"))
+	for i := 0; i < streamTailLines; i++ {
+		ix := bytes.LastIndexByte(buf, '\n')
+		if ix < 0 {
+			return start, nil
+		}
+		buf = buf[:ix]
+	}
+	return start + int64(len(buf)) + 1, nil
+}
+
+// VolumeWrite satisfies the volume.WritableInterface required to write file contents. Files
+// can't be written to when SFTP is unavailable.
+func (s *SFTP) VolumeWrite(ctx context.Context, path string, data []byte) error {
+	if s.fallback() != nil {
+		return fmt.Errorf("cannot write %v: SFTP is unavailable on %v", path, plugin.ID(s.target))
+	}
+	id, err := s.target.SSHIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	err = UploadSFTP(ctx, id, remotePath(path), bytes.NewReader(data))
+	if isSFTPUnavailable(err) {
+		s.fallBackToExec(ctx, err)
+	}
+	return err
+}
+
+// VolumeDelete satisfies the volume.Interface required by Delete to delete volume nodes.
+func (s *SFTP) VolumeDelete(ctx context.Context, path string) (bool, error) {
+	if fs := s.fallback(); fs != nil {
+		return fs.VolumeDelete(ctx, path)
+	}
+	activity.Record(ctx, "Deleting %v on %v via SFTP", path, plugin.ID(s.target))
+	err := s.withClient(ctx, func(client *sftp.Client) error {
+		return removeAll(client, remotePath(path))
+	})
+	if fs := s.execOnError(ctx, err); fs != nil {
+		return fs.VolumeDelete(ctx, path)
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// VolumeReadlink satisfies the volume.LinkableInterface required by Readlink to read symlink targets.
+func (s *SFTP) VolumeReadlink(ctx context.Context, path string) (string, error) {
+	if fs := s.fallback(); fs != nil {
+		return fs.VolumeReadlink(ctx, path)
+	}
+	activity.Record(ctx, "Reading link %v on %v via SFTP", path, plugin.ID(s.target))
+	var target string
+	err := s.withClient(ctx, func(client *sftp.Client) (err error) {
+		target, err = client.ReadLink(remotePath(path))
+		return
+	})
+	if fs := s.execOnError(ctx, err); fs != nil {
+		return fs.VolumeReadlink(ctx, path)
+	}
+	return target, err
+}
+
+// fallback returns the volume.FS that volume operations are delegated to, or nil if they
+// use SFTP.
+func (s *SFTP) fallback() *volume.FS {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if !s.useExec {
+		return nil
+	}
+	return s.execVolume()
+}
+
+// fallBackToExec delegates future volume operations to a volume.FS, which execs commands
+// on the target. It's called the first time that an operation finds SFTP's unavailable.
+func (s *SFTP) fallBackToExec(ctx context.Context, err error) *volume.FS {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if !s.useExec {
+		activity.Record(ctx, "SFTP is unavailable on %v, falling back to exec: %v", plugin.ID(s.target), err)
+		s.useExec = true
+	}
+	return s.execVolume()
+}
+
+// execOnError returns the volume.FS that an operation should be retried with if err shows
+// that SFTP can't perform it, or nil otherwise. Only the failed operation is retried when
+// the SSH user doesn't have permission, because most files are accessible via SFTP.
+func (s *SFTP) execOnError(ctx context.Context, err error) *volume.FS {
+	if isSFTPUnavailable(err) {
+		return s.fallBackToExec(ctx, err)
+	} else if !isPermissionErr(err) {
+		return nil
+	}
+	activity.Record(ctx, "Permission denied via SFTP on %v, retrying with exec: %v", plugin.ID(s.target), err)
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.execVolume()
+}
+
+// execVolume returns the volume.FS, creating it if needed. s.mux must be locked.
+func (s *SFTP) execVolume() *volume.FS {
+	if s.execFS == nil {
+		s.execFS = volume.NewExecFS(s.Name(), s.target, s.maxdepth)
+	}
+	return s.execFS
+}
+
+func removeAll(client *sftp.Client, p string) error {
+	info, err := client.Lstat(p)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return client.Remove(p)
+	}
+
+	infos, err := client.ReadDir(p)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if err := removeAll(client, path.Join(p, info.Name())); err != nil {
+			return err
+		}
+	}
+	return client.RemoveDirectory(p)
+}
+
+// withClient calls fn with a new SFTP client, which is closed when fn returns or when ctx
+// is cancelled.
+func (s *SFTP) withClient(ctx context.Context, fn func(*sftp.Client) error) error {
+	id, err := s.target.SSHIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	return withSFTPClient(ctx, id, fn)
+}
+
+// UploadSFTP writes content to the file at path on the target via SFTP. The file's
+// created if it doesn't exist, and overwritten otherwise.
+func UploadSFTP(ctx context.Context, id Identity, path string, content io.Reader) error {
+	activity.Record(ctx, "Uploading %v to %v via SFTP", path, id.Host)
+	return withSFTPClient(ctx, id, func(client *sftp.Client) error {
+		f, err := client.Create(path)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, content); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+func withSFTPClient(ctx context.Context, id Identity, fn func(*sftp.Client) error) error {
+	client, err := newSFTPClient(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		client.Close()
+	}()
+
+	if err := fn(client); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// newSFTPClient starts an SFTP session over the target's cached SSH connection.
+func newSFTPClient(ctx context.Context, id Identity) (*sftp.Client, error) {
+	connection, err := connect(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	client, err := sftp.NewClient(connection)
+	if err != nil {
+		return nil, sftpUnavailableError{fmt.Errorf("Failed to start SFTP session: %s", err)}
+	}
+	return client, nil
+}
+
+// sftpUnavailableError is returned when an SFTP session can't be started over an SSH
+// connection, usually because the server doesn't support the SFTP subsystem.
+type sftpUnavailableError struct {
+	error
+}
+
+func isSFTPUnavailable(err error) bool {
+	_, ok := err.(sftpUnavailableError)
+	return ok
+}
+
+// isPermissionErr returns whether err is an SFTP permission denied error.
+func isPermissionErr(err error) bool {
+	var statusErr *sftp.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code == sftpPermissionDenied
+	}
+	return os.IsPermission(err)
+}
+
+// sftpPermissionDenied is SSH_FX_PERMISSION_DENIED, which the sftp package doesn't export.
+const sftpPermissionDenied = 3
+
+// remotePath translates a volume path to a path on the target. Volumes use "" to mean root.
+func remotePath(path string) string {
+	if path == volume.RootPath {
+		return "/"
+	}
+	return path
+}
+
+const sftpDescription = `
+This represents the root directory of a VM. It lets you navigate and interact
+with that VM's filesystem as if you were logged into it. Thus, you're able to
+do things like 'cat'/'tail' that VM's files (or even multiple files spread out
+across multiple VMs).
+
+Wash accesses the filesystem via SFTP, using the same SSH connection that it
+uses to exec commands on the VM. Files are accessed as the SSH user, and they
+can be written to. Streaming a file polls it for new content every second.
+
+If the VM's SSH server doesn't support SFTP, or the VM runs Windows, then Wash
+execs a command on the VM for each List/Read/Stream action instead, and files
+can't be written to. Actions that the SSH user doesn't have permission for, like
+reading /var/log/secure, are retried by exec'ing a command with sudo.
+`
@@ -0,0 +1,324 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	gssh "github.com/gliderlabs/ssh"
+	"github.com/pkg/sftp"
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/volume"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+type mockSSHTarget struct {
+	plugin.EntryBase
+	id Identity
+}
+
+func (m *mockSSHTarget) Schema() *plugin.EntrySchema {
+	return nil
+}
+
+func (m *mockSSHTarget) Exec(context.Context, string, []string, plugin.ExecOptions) (plugin.ExecCommand, error) {
+	return nil, errors.New("exec is not supported")
+}
+
+func (m *mockSSHTarget) SSHIdentity(context.Context) (Identity, error) {
+	return m.id, nil
+}
+
+type SFTPTestSuite struct {
+	suite.Suite
+	ctx          context.Context
+	servers      []*gssh.Server
+	sftpID       Identity
+	noSFTPID     Identity
+	readOnlyID   Identity
+	files        []string
+	dir          string
+	savedFiles   func() []string
+	savedPollInt time.Duration
+}
+
+// sftpHandler serves the SFTP subsystem, which the gliderlabs server doesn't support.
+func sftpHandler(options ...sftp.ServerOption) gssh.ChannelHandler {
+	return func(srv *gssh.Server, conn *ssh.ServerConn, newChan ssh.NewChannel, ctx gssh.Context) {
+		ch, reqs, err := newChan.Accept()
+		if err != nil {
+			return
+		}
+		defer ch.Close()
+		for req := range reqs {
+			if req.Type != "subsystem" || len(req.Payload) < 4 || string(req.Payload[4:]) != "sftp" {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			_ = req.Reply(true, nil)
+			go ssh.DiscardRequests(reqs)
+			server, err := sftp.NewServer(ch, options...)
+			if err == nil {
+				_ = server.Serve()
+			}
+			return
+		}
+	}
+}
+
+// startServer starts an SSH server. It serves SFTP with the given handler, if it's not nil.
+func (suite *SFTPTestSuite) startServer(hostKey ssh.Signer, handler gssh.ChannelHandler) uint {
+	l, err := net.Listen("tcp", host+":0")
+	suite.Require().NoError(err)
+	srv := &gssh.Server{
+		Handler:          func(gssh.Session) {},
+		PublicKeyHandler: func(gssh.Context, gssh.PublicKey) bool { return true },
+	}
+	if handler != nil {
+		srv.ChannelHandlers = map[string]gssh.ChannelHandler{"session": handler}
+	}
+	srv.AddHostKey(hostKey)
+	go func() { _ = srv.Serve(l) }()
+	suite.servers = append(suite.servers, srv)
+	return uint(l.Addr().(*net.TCPAddr).Port)
+}
+
+func (suite *SFTPTestSuite) writeTempFile(prefix string, content []byte) string {
+	f, err := ioutil.TempFile("", prefix)
+	suite.Require().NoError(err)
+	_, err = f.Write(content)
+	suite.Require().NoError(err)
+	suite.Require().NoError(f.Close())
+	suite.files = append(suite.files, f.Name())
+	return f.Name()
+}
+
+func (suite *SFTPTestSuite) SetupSuite() {
+	suite.savedFiles = sshConfigFiles
+	sshConfigFiles = func() []string { return nil }
+	suite.savedPollInt = streamPollInterval
+	streamPollInterval = 10 * time.Millisecond
+
+	hostKey, err := generateSigner()
+	suite.Require().NoError(err)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+	identityFile := suite.writeTempFile("wash_sftp_key", pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}))
+
+	sftpPort := suite.startServer(hostKey, sftpHandler())
+	noSFTPPort := suite.startServer(hostKey, nil)
+	// The read-only server denies permission to modify files.
+	readOnlyPort := suite.startServer(hostKey, sftpHandler(sftp.ReadOnly()))
+	var knownHosts []string
+	for _, port := range []uint{sftpPort, noSFTPPort, readOnlyPort} {
+		addr := net.JoinHostPort(host, strconv.Itoa(int(port)))
+		knownHosts = append(knownHosts, knownhosts.Line([]string{addr}, hostKey.PublicKey()))
+	}
+	knownHostsFile := suite.writeTempFile("wash_sftp_knownhosts", []byte(strings.Join(knownHosts, "\n")+"\n"))
+
+	suite.sftpID = Identity{Host: host, Port: sftpPort, IdentityFile: identityFile, KnownHosts: knownHostsFile}
+	suite.noSFTPID = Identity{Host: host, Port: noSFTPPort, IdentityFile: identityFile, KnownHosts: knownHostsFile}
+	suite.readOnlyID = Identity{Host: host, Port: readOnlyPort, IdentityFile: identityFile, KnownHosts: knownHostsFile}
+	suite.ctx = plugin.SetTestCache(datastore.NewMemCache())
+}
+
+func (suite *SFTPTestSuite) TearDownSuite() {
+	plugin.UnsetTestCache()
+	for _, srv := range suite.servers {
+		_ = srv.Close()
+	}
+	for _, file := range suite.files {
+		_ = os.Remove(file)
+	}
+	sshConfigFiles = suite.savedFiles
+	streamPollInterval = suite.savedPollInt
+}
+
+func (suite *SFTPTestSuite) SetupTest() {
+	var err error
+	suite.dir, err = ioutil.TempDir("", "wash_sftp")
+	suite.Require().NoError(err)
+	suite.Require().NoError(os.MkdirAll(filepath.Join(suite.dir, "a", "b", "c"), 0755))
+	suite.Require().NoError(ioutil.WriteFile(filepath.Join(suite.dir, "a", "file"), []byte("hello"), 0644))
+	suite.Require().NoError(os.Symlink(filepath.Join(suite.dir, "a"), filepath.Join(suite.dir, "link")))
+}
+
+func (suite *SFTPTestSuite) TearDownTest() {
+	connectionCache.Flush()
+	suite.NoError(os.RemoveAll(suite.dir))
+}
+
+func (suite *SFTPTestSuite) newSFTP(maxdepth int) *SFTP {
+	target := &mockSSHTarget{EntryBase: plugin.NewEntry("target"), id: suite.sftpID}
+	target.SetTestID("/target")
+	return &SFTP{
+		EntryBase: plugin.NewEntry("fs"),
+		target:    target,
+		maxdepth:  maxdepth,
+	}
+}
+
+func (suite *SFTPTestSuite) TestNewFS() {
+	target := &mockSSHTarget{EntryBase: plugin.NewEntry("target"), id: suite.sftpID}
+	target.SetTestID("/target")
+	fs := NewFS(suite.ctx, "fs", target, 1)
+	suite.Nil(fs.fallback())
+	suite.False(fs.IsInaccessible())
+}
+
+func (suite *SFTPTestSuite) TestNewFS_FallsBackToExec() {
+	target := &mockSSHTarget{EntryBase: plugin.NewEntry("target"), id: suite.noSFTPID}
+	target.SetTestID("/target-nosftp")
+	fs := NewFS(suite.ctx, "fs-nosftp", target, 1)
+	suite.NotNil(fs.fallback())
+	// The mock target doesn't support exec, so listing via volume.FS fails.
+	_, err := fs.VolumeList(suite.ctx, volume.RootPath)
+	suite.EqualError(err, "exec is not supported")
+	suite.Error(fs.VolumeWrite(suite.ctx, "/foo", []byte("foo")))
+
+	target = &mockSSHTarget{EntryBase: plugin.NewEntry("windows"), id: suite.sftpID}
+	target.SetTestID("/windows")
+	target.Attributes().SetOS(plugin.OS{LoginShell: plugin.PowerShell})
+	suite.NotNil(NewFS(suite.ctx, "fs-windows", target, 1).fallback())
+}
+
+func (suite *SFTPTestSuite) TestVolumeList() {
+	dirmap, err := suite.newSFTP(2).VolumeList(suite.ctx, suite.dir)
+	suite.Require().NoError(err)
+
+	a := suite.dir + "/a"
+	suite.Len(dirmap[suite.dir], 2)
+	attr := dirmap[suite.dir]["a"]
+	suite.True(attr.Mode().IsDir())
+	// Symlinks aren't followed
+	attr = dirmap[suite.dir]["link"]
+	suite.True(attr.Mode()&os.ModeSymlink != 0)
+	suite.NotContains(dirmap, suite.dir+"/link")
+	suite.Len(dirmap[a], 2)
+	attr = dirmap[a]["file"]
+	suite.Equal(uint64(5), attr.Size())
+	suite.Equal(os.FileMode(0644), attr.Mode())
+	// Directories at maxdepth are unexplored
+	if children, ok := dirmap[a+"/b"]; suite.True(ok) {
+		suite.Nil(children)
+	}
+	suite.NotContains(dirmap, a+"/b/c")
+}
+
+func (suite *SFTPTestSuite) TestVolumeReadlink() {
+	fs := suite.newSFTP(1)
+	target, err := fs.VolumeReadlink(suite.ctx, suite.dir+"/link")
+	suite.NoError(err)
+	suite.Equal(filepath.Join(suite.dir, "a"), target)
+
+	_, err = fs.VolumeReadlink(suite.ctx, suite.dir+"/a")
+	suite.Error(err)
+}
+
+func (suite *SFTPTestSuite) TestVolumeReadWrite() {
+	fs := suite.newSFTP(1)
+	path := suite.dir + "/a/file"
+	content, err := fs.VolumeRead(suite.ctx, path)
+	suite.NoError(err)
+	suite.Equal("hello", string(content))
+
+	suite.NoError(fs.VolumeWrite(suite.ctx, path, []byte("bye")))
+	content, err = ioutil.ReadFile(path)
+	suite.NoError(err)
+	suite.Equal("bye", string(content))
+
+	_, err = fs.VolumeRead(suite.ctx, suite.dir+"/missing")
+	suite.Error(err)
+}
+
+func (suite *SFTPTestSuite) TestUploadSFTP() {
+	path := suite.dir + "/uploaded"
+	suite.NoError(UploadSFTP(suite.ctx, suite.sftpID, path, strings.NewReader("uploaded content")))
+	content, err := ioutil.ReadFile(path)
+	suite.NoError(err)
+	suite.Equal("uploaded content", string(content))
+
+	err = UploadSFTP(suite.ctx, suite.noSFTPID, path, strings.NewReader(""))
+	suite.Error(err)
+}
+
+func (suite *SFTPTestSuite) TestVolumeDelete() {
+	deleted, err := suite.newSFTP(1).VolumeDelete(suite.ctx, suite.dir+"/a")
+	suite.NoError(err)
+	suite.True(deleted)
+	_, err = os.Stat(suite.dir + "/a")
+	suite.True(os.IsNotExist(err))
+}
+
+func (suite *SFTPTestSuite) TestVolumeDelete_PermissionDenied() {
+	target := &mockSSHTarget{EntryBase: plugin.NewEntry("target"), id: suite.readOnlyID}
+	target.SetTestID("/target-readonly")
+	fs := &SFTP{EntryBase: plugin.NewEntry("fs"), target: target, maxdepth: 1}
+
+	// The delete's retried with exec, which the mock target doesn't support.
+	path := suite.dir + "/a/file"
+	_, err := fs.VolumeDelete(suite.ctx, path)
+	suite.EqualError(err, "exec is not supported")
+	suite.FileExists(path)
+
+	// Other operations still use SFTP.
+	suite.Nil(fs.fallback())
+	content, err := fs.VolumeRead(suite.ctx, path)
+	suite.NoError(err)
+	suite.Equal("hello", string(content))
+}
+
+func (suite *SFTPTestSuite) TestVolumeStream() {
+	path := suite.dir + "/log"
+	var lines []string
+	for i := 1; i <= 12; i++ {
+		lines = append(lines, strconv.Itoa(i))
+	}
+	suite.Require().NoError(ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+
+	ctx, cancel := context.WithCancel(suite.ctx)
+	defer cancel()
+	rdr, err := suite.newSFTP(1).VolumeStream(ctx, path)
+	suite.Require().NoError(err)
+	defer rdr.Close()
+
+	scanner := bufio.NewScanner(rdr)
+	// Streaming starts with the last 10 lines, like 'tail -f'
+	for _, expected := range lines[2:] {
+		suite.Require().True(scanner.Scan())
+		suite.Equal(expected, scanner.Text())
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	suite.Require().NoError(err)
+	_, err = f.WriteString("13\n")
+	suite.NoError(err)
+	suite.NoError(f.Close())
+	suite.Require().True(scanner.Scan())
+	suite.Equal("13", scanner.Text())
+
+	cancel()
+	suite.False(scanner.Scan())
+}
+
+func TestSFTP(t *testing.T) {
+	suite.Run(t, new(SFTPTestSuite))
+}
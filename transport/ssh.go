@@ -3,19 +3,18 @@ package transport
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 	"net"
 	"os"
-	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/avast/retry-go"
 	"github.com/kballard/go-shellquote"
-	"github.com/kevinburke/ssh_config"
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/datastore"
 	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/tracing"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
 	"golang.org/x/crypto/ssh/knownhosts"
@@ -32,23 +31,52 @@ func closeConnection(id string, obj interface{}) {
 	}
 }
 
-func newAgent(path string) (ssh.AuthMethod, error) {
+func newAgent(path string) (agent.Agent, error) {
 	sshAgent, err := net.Dial("unix", path)
 
 	if err != nil {
 		return nil, err
 	}
-	return ssh.PublicKeysCallback(agent.NewClient(sshAgent).Signers), nil
+	return agent.NewClient(sshAgent), nil
 }
 
 type sshConfig struct {
 	host, port, user, password string
-	identityFiles              []string
-	hostKeyCallback            ssh.HostKeyCallback
+	// identityFiles are the private keys to authenticate with, and certificateFiles are
+	// their certificates. If identitiesOnly is set, then the SSH agent's keys aren't used.
+	identityFiles    []string
+	certificateFiles []string
+	identitiesOnly   bool
+	hostKeyCallback  ssh.HostKeyCallback
+	connectTimeout   time.Duration
+	// serverAliveInterval is how often to send keepalives. The connection is closed after
+	// serverAliveCountMax keepalives go unanswered.
+	serverAliveInterval time.Duration
+	serverAliveCountMax uint
+	// jump lists the hosts to connect through, in order. proxyCommand is the
+	// expanded ProxyCommand. At most one of them is set.
+	jump         []Identity
+	proxyCommand string
+	// route contains the addresses of the hosts that are connecting through
+	// this host. It's used to detect ProxyJump loops.
+	route []string
 }
 
+func (conf sshConfig) addr() string {
+	return net.JoinHostPort(conf.host, conf.port)
+}
+
+// defaultIdentityFiles are the private keys that are tried if SSH config doesn't
+// specify an IdentityFile. Unlike IdentityFile, they're skipped if they don't exist.
+var defaultIdentityFiles = []string{"~/.ssh/id_rsa", "~/.ssh/id_ecdsa", "~/.ssh/id_ed25519", "~/.ssh/id_dsa"}
+
 func getConnInfo(ctx context.Context, id Identity) (conf sshConfig, err error) {
-	if conf.host, err = ssh_config.GetStrict(id.Host, "HostName"); err != nil {
+	var settings sshConfigSettings
+	if settings, err = loadSSHConfig(); err != nil {
+		return
+	}
+
+	if conf.host, err = settings.get(id.Host, "HostName"); err != nil {
 		return
 	}
 	if conf.host == "" {
@@ -58,13 +86,17 @@ func getConnInfo(ctx context.Context, id Identity) (conf sshConfig, err error) {
 	// ssh_config provides a default of port 22.
 	if id.Port != 0 {
 		conf.port = strconv.Itoa(int(id.Port))
-	} else if conf.port, err = ssh_config.GetStrict(id.Host, "Port"); err != nil {
-		return
+	} else {
+		var port uint
+		if port, err = settings.getUint(id.Host, "Port"); err != nil {
+			return
+		}
+		conf.port = strconv.Itoa(int(port))
 	}
 
 	conf.user = id.User
 	if conf.user == "" {
-		if conf.user, err = ssh_config.GetStrict(id.Host, "User"); err != nil {
+		if conf.user, err = settings.get(id.Host, "User"); err != nil {
 			return
 		}
 	}
@@ -77,57 +109,141 @@ func getConnInfo(ctx context.Context, id Identity) (conf sshConfig, err error) {
 
 	conf.password = id.Password
 
-	// Try the requested identity file first. Include any in SSH config as well just-in-case.
-	conf.identityFiles = make([]string, 0)
-	if id.IdentityFile != "" {
-		conf.identityFiles = append(conf.identityFiles, id.IdentityFile)
+	if conf.connectTimeout, err = settings.getSeconds(id.Host, "ConnectTimeout"); err != nil {
+		return
 	}
-	if id.IdentityFile, err = ssh_config.GetStrict(id.Host, "IdentityFile"); err != nil {
+	if conf.serverAliveInterval, err = settings.getSeconds(id.Host, "ServerAliveInterval"); err != nil {
 		return
 	}
+	if conf.serverAliveCountMax, err = settings.getUint(id.Host, "ServerAliveCountMax"); err != nil {
+		return
+	}
+
+	// An explicit jump takes precedence over the ProxyJump and ProxyCommand settings
+	// in SSH config. ProxyJump takes precedence over ProxyCommand.
+	conf.jump = id.Jump
+	if len(conf.jump) == 0 {
+		var proxyJump string
+		if proxyJump, err = settings.get(id.Host, "ProxyJump"); err != nil {
+			return
+		}
+		if conf.jump, err = parseProxyJump(proxyJump); err != nil {
+			return
+		}
+		// Hops from SSH config are checked against the target's known hosts file.
+		for i := range conf.jump {
+			conf.jump[i].KnownHosts = id.KnownHosts
+			conf.jump[i].Retries = id.Retries
+		}
+	}
+	if len(conf.jump) == 0 {
+		var proxyCommand string
+		if proxyCommand, err = settings.get(id.Host, "ProxyCommand"); err != nil {
+			return
+		}
+		if proxyCommand != "" && proxyCommand != "none" {
+			conf.proxyCommand = expandProxyCommand(proxyCommand, id.Host, conf)
+		}
+	}
+
+	// Try the requested identity file first, followed by any in SSH config. If SSH config
+	// doesn't specify any, then try the default identity files that exist. Like OpenSSH,
+	// <identity file>-cert.pub is used as the identity file's certificate if it exists.
 	if id.IdentityFile != "" {
 		conf.identityFiles = append(conf.identityFiles, id.IdentityFile)
 	}
+	configuredIdentityFiles := settings.getAll(id.Host, "IdentityFile")
+	for _, identityFile := range configuredIdentityFiles {
+		conf.identityFiles = append(conf.identityFiles, expandPath(identityFile, conf))
+	}
+	if len(configuredIdentityFiles) == 0 {
+		for _, identityFile := range defaultIdentityFiles {
+			if identityFile = expandPath(identityFile, conf); fileExists(identityFile) {
+				conf.identityFiles = append(conf.identityFiles, identityFile)
+			}
+		}
+	}
+	for _, certificateFile := range settings.getAll(id.Host, "CertificateFile") {
+		conf.certificateFiles = append(conf.certificateFiles, expandPath(certificateFile, conf))
+	}
+	for _, identityFile := range conf.identityFiles {
+		if certificateFile := identityFile + "-cert.pub"; fileExists(certificateFile) {
+			conf.certificateFiles = append(conf.certificateFiles, certificateFile)
+		}
+	}
 	// We later provide a fallback to ssh-agent if none of the provided identity files work.
+	if conf.identitiesOnly, err = settings.getBool(id.Host, "IdentitiesOnly"); err != nil {
+		return
+	}
 
-	// Implement permissive and accept-new host key checking. Also account for HostKeyAlias.
-	// Defaults to accepting new hosts.
+	// Implement permissive, strict and accept-new host key checking. Also account for
+	// HostKeyAlias. Wash can't ask whether to accept a new host, so "ask" (the default)
+	// accepts new hosts.
 	var hostKeyChecking string
-	if hostKeyChecking, err = ssh_config.GetStrict(id.Host, "StrictHostKeyChecking"); err != nil {
+	if hostKeyChecking, err = settings.get(id.Host, "StrictHostKeyChecking"); err != nil {
 		return
 	}
 	conf.hostKeyCallback = ssh.InsecureIgnoreHostKey()
-	if hostKeyChecking == "no" {
+	if hostKeyChecking == "no" || hostKeyChecking == "off" {
 		// Return early. This must be the last field configured.
 		return
 	}
 
-	if id.KnownHosts == "" {
-		var homedir string
-		if homedir, err = os.UserHomeDir(); err != nil {
+	// An explicit known hosts file replaces the known hosts files in SSH config. Otherwise
+	// new hosts are added to the first UserKnownHostsFile, and the global known hosts files
+	// are also checked if they exist.
+	var userKnownHosts, knownHostsFiles []string
+	if id.KnownHosts != "" {
+		userKnownHosts = []string{id.KnownHosts}
+	} else {
+		var userKnownHostsFile, globalKnownHostsFile string
+		if userKnownHostsFile, err = settings.get(id.Host, "UserKnownHostsFile"); err != nil {
 			return
 		}
-
-		id.KnownHosts = filepath.Join(homedir, ".ssh", "known_hosts")
+		if globalKnownHostsFile, err = settings.get(id.Host, "GlobalKnownHostsFile"); err != nil {
+			return
+		}
+		for _, file := range strings.Fields(userKnownHostsFile) {
+			userKnownHosts = append(userKnownHosts, expandPath(file, conf))
+		}
+		for _, file := range strings.Fields(globalKnownHostsFile) {
+			if file = expandPath(file, conf); fileExists(file) {
+				knownHostsFiles = append(knownHostsFiles, file)
+			}
+		}
+	}
+	if len(userKnownHosts) == 0 || userKnownHosts[0] == "none" {
+		err = fmt.Errorf("UserKnownHostsFile cannot be none unless StrictHostKeyChecking is no")
+		return
 	}
-	// The known hosts file must exist before we try to use it.
+	// The first user known hosts file must exist before we try to use it. The rest are only
+	// read if they exist.
 	var f *os.File
-	if f, err = os.OpenFile(id.KnownHosts, os.O_RDONLY|os.O_CREATE, 0644); err != nil {
+	if f, err = os.OpenFile(userKnownHosts[0], os.O_RDONLY|os.O_CREATE, 0644); err != nil {
 		return
 	}
 	f.Close()
+	knownHostsFiles = append(knownHostsFiles, userKnownHosts[0])
+	for _, file := range userKnownHosts[1:] {
+		if fileExists(file) {
+			knownHostsFiles = append(knownHostsFiles, file)
+		}
+	}
 
-	conf.hostKeyCallback, err = knownhosts.New(id.KnownHosts)
+	// knownhosts also handles @cert-authority and @revoked entries.
+	conf.hostKeyCallback, err = knownhosts.New(knownHostsFiles...)
 	if err != nil {
 		err = fmt.Errorf("Loading SSH known hosts file: %v", err)
 		return
 	}
-	conf.hostKeyCallback = acceptNewCallback(ctx, conf.hostKeyCallback, id.KnownHosts)
+	if hostKeyChecking != "yes" {
+		conf.hostKeyCallback = acceptNewCallback(ctx, conf.hostKeyCallback, userKnownHosts[0])
+	}
 
 	// Lookup host key alias for use in key checking. This should be the last wrapped so that
 	// other callbacks use the alias.
 	if id.HostKeyAlias == "" {
-		if id.HostKeyAlias, err = ssh_config.GetStrict(id.Host, "HostKeyAlias"); err != nil {
+		if id.HostKeyAlias, err = settings.get(id.Host, "HostKeyAlias"); err != nil {
 			return
 		}
 	}
@@ -137,18 +253,75 @@ func getConnInfo(ctx context.Context, id Identity) (conf sshConfig, err error) {
 	return
 }
 
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
 func sshConnect(ctx context.Context, conf sshConfig, retries uint) (*ssh.Client, error) {
-	connID := conf.user + "@" + conf.host + ":" + conf.port
+	connID := conf.user + "@" + conf.addr()
+	var dial func(*ssh.ClientConfig) (*ssh.Client, error)
+	switch {
+	case len(conf.jump) > 0:
+		// Connect to the last hop, which connects through the preceding hops. Hops are
+		// cached like any other connection, so they're re-used by other targets.
+		hop := conf.jump[len(conf.jump)-1]
+		if len(hop.Jump) == 0 {
+			hop.Jump = conf.jump[:len(conf.jump)-1]
+		}
+		if hop.Retries == 0 {
+			hop.Retries = retries
+		}
+		hopConf, err := getConnInfo(ctx, hop)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get connection info for jump host %v: %v", hop.Host, err)
+		}
+		hopConf.route = append(append([]string{}, conf.route...), conf.addr())
+		for _, addr := range hopConf.route {
+			if addr == hopConf.addr() {
+				return nil, fmt.Errorf("ProxyJump loop detected: %v connects through itself", addr)
+			}
+		}
+		hopClient, err := sshConnect(ctx, hopConf, hop.Retries)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to connect to jump host %v: %v", hop.Host, err)
+		}
+		// Include the hop's client in the ID so that we don't re-use connections through
+		// a hop connection that's since been closed.
+		connID += fmt.Sprintf(" via %v@%v (%p)", hopConf.user, hopConf.addr(), hopClient)
+		dial = func(sshConfig *ssh.ClientConfig) (*ssh.Client, error) {
+			conn, err := hopClient.Dial("tcp", conf.addr())
+			if err != nil {
+				return nil, err
+			}
+			return newClient(conn, conf.addr(), sshConfig)
+		}
+	case conf.proxyCommand != "":
+		connID += " via " + conf.proxyCommand
+		dial = func(sshConfig *ssh.ClientConfig) (*ssh.Client, error) {
+			conn, err := newProxyCommandConn(ctx, conf.proxyCommand, conf.addr())
+			if err != nil {
+				return nil, err
+			}
+			return newClient(conn, conf.addr(), sshConfig)
+		}
+	default:
+		dial = func(sshConfig *ssh.ClientConfig) (*ssh.Client, error) {
+			return ssh.Dial("tcp", conf.addr(), sshConfig)
+		}
+	}
+
 	// This is a single-use cache, so pass in an empty category.
 	obj, err := connectionCache.GetOrUpdate("", connID, expires, true, func() (interface{}, error) {
-		agentPath := os.Getenv("SSH_AUTH_SOCK")
-		var agent ssh.AuthMethod
-		if agentPath == "" {
+		var sshAgent agent.Agent
+		if conf.identitiesOnly {
+			activity.Record(ctx, "IdentitiesOnly is set, skipping SSH agent configuration")
+		} else if agentPath := os.Getenv("SSH_AUTH_SOCK"); agentPath == "" {
 			// If an SSH agent isn't configured, we should still try to connect without it
 			activity.Record(ctx, "SSH_AUTH_SOCK unconfigured, skipping SSH agent configuration")
 		} else {
 			var err error
-			if agent, err = newAgent(agentPath); err != nil {
+			if sshAgent, err = newAgent(agentPath); err != nil {
 				activity.Warnf(ctx, "Failed to connect to SSH agent at %v: %v", agentPath, err)
 			}
 		}
@@ -157,40 +330,60 @@ func sshConnect(ctx context.Context, conf sshConfig, retries uint) (*ssh.Client,
 		if conf.password != "" {
 			authmethod = append(authmethod, ssh.Password(conf.password))
 		}
+		var signers []ssh.Signer
 		for _, identityFile := range conf.identityFiles {
-			if key, err := ioutil.ReadFile(identityFile); err != nil {
-				activity.Record(ctx, "Unable to read private key: %v", err)
+			if signer, err := loadSigner(identityFile); err != nil {
+				activity.Record(ctx, "Unable to load private key: %v", err)
 			} else {
-				if signer, err := ssh.ParsePrivateKey(key); err != nil {
-					activity.Record(ctx, "Unable to parse private key: %v", err)
-				} else {
-					authmethod = append(authmethod, ssh.PublicKeys(signer))
-				}
+				signers = append(signers, signer)
+			}
+		}
+		var certs []*ssh.Certificate
+		for _, certificateFile := range conf.certificateFiles {
+			if cert, err := loadCertificate(certificateFile); err != nil {
+				activity.Record(ctx, "Unable to load certificate: %v", err)
+			} else {
+				certs = append(certs, cert)
 			}
 		}
 
-		// Append agent now so that it comes last in case we find another method to try.
-		if agent != nil {
+		// Add the agent's keys last in case we find another key to try. Certificates also
+		// apply to the agent's keys.
+		if sshAgent != nil {
 			activity.Record(ctx, "Adding SSH agent at %v", os.Getenv("SSH_AUTH_SOCK"))
-			authmethod = append(authmethod, agent)
 		}
+		authmethod = append(authmethod, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			allSigners := signers
+			if sshAgent != nil {
+				agentSigners, err := sshAgent.Signers()
+				if err != nil {
+					activity.Warnf(ctx, "Failed to get keys from the SSH agent: %v", err)
+				}
+				allSigners = append(append([]ssh.Signer{}, signers...), agentSigners...)
+			}
+			return withCertificates(allSigners, certs), nil
+		}))
 
 		sshConfig := &ssh.ClientConfig{
 			User:            conf.user,
 			Auth:            authmethod,
 			HostKeyCallback: conf.hostKeyCallback,
+			Timeout:         conf.connectTimeout,
 		}
 
 		// Try until we've retried desired number of times or connection is established.
 		var cli *ssh.Client
 		err := retry.Do(
 			func() (err error) {
-				cli, err = ssh.Dial("tcp", conf.host+":"+conf.port, sshConfig)
+				cli, err = dial(sshConfig)
 				return
 			},
 			retry.Attempts(retries+1),
 			retry.Delay(500*time.Millisecond),
 		)
+		if err == nil && conf.serverAliveInterval > 0 {
+			go keepAlive(cli, conf.serverAliveInterval, conf.serverAliveCountMax)
+		}
 		return cli, err
 	})
 
@@ -200,6 +393,82 @@ func sshConnect(ctx context.Context, conf sshConfig, retries uint) (*ssh.Client,
 	return obj.(*ssh.Client), nil
 }
 
+// keepAlive sends a keepalive every interval, and closes the client after
+// countMax consecutive keepalives go unanswered. It returns once the client's
+// closed.
+func keepAlive(client *ssh.Client, interval time.Duration, countMax uint) {
+	closed := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(closed)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var missed uint
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+		}
+
+		replied := make(chan error, 1)
+		go func() {
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			replied <- err
+		}()
+		select {
+		case <-closed:
+			return
+		case err := <-replied:
+			if err == nil {
+				missed = 0
+				continue
+			}
+		case <-time.After(interval):
+		}
+		if missed++; missed >= countMax {
+			client.Close()
+			return
+		}
+	}
+}
+
+// newClient starts an SSH connection to addr over conn. The connection's closed
+// if the handshake doesn't finish within config.Timeout.
+func newClient(conn net.Conn, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	if config.Timeout > 0 {
+		timer := time.AfterFunc(config.Timeout, func() { conn.Close() })
+		defer timer.Stop()
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// connect returns a cached connection to the target, or establishes a new one.
+func connect(ctx context.Context, id Identity) (_ *ssh.Client, err error) {
+	ctx, span := tracing.Start(ctx, "ssh.connect", tracing.String("wash.host", id.Host))
+	defer func() { tracing.End(ctx, span, err) }()
+
+	// find port, username, etc from .ssh/config
+	conf, err := getConnInfo(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get connection info: %s", err)
+	}
+	activity.Record(ctx, "Found connection info %+v", conf)
+
+	connection, err := sshConnect(ctx, conf, id.Retries)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect: %s", err)
+	}
+	return connection, nil
+}
+
 // Identity identifies how to connect to a target.
 type Identity struct {
 	Host         string `json:"host"`
@@ -212,32 +481,47 @@ type Identity struct {
 	// Retries can be set to a non-zero value to retry every 500ms for that many times.
 	Retries uint `json:"retries"`
 	Port    uint `json:"port"`
+	// Jump lists the hosts to connect through to reach Host, in order. Each hop is configured
+	// from its own Identity and SSH config. If Jump is empty, then the ProxyJump or ProxyCommand
+	// settings in SSH config are used.
+	Jump []Identity `json:"jump"`
 }
 
 // ExecSSH executes against a target via SSH. It will look up port, user, and other configuration
 // by exact hostname match from default SSH config files. Identity can be used to override the
-// user configured in SSH config. If opts.Elevate is true, will attempt to `sudo` as root.
+// user configured in SSH config. If opts.Elevate is true, will attempt to `sudo` as root. If
+// opts.User is set, will attempt to `sudo` as that user instead. opts.Env and opts.WorkingDir
+// are applied by wrapping the command in `sh` and `env`, so the target must be a POSIX system.
+//
+// If present, a local SSH agent will be used for authentication unless IdentitiesOnly=yes.
 //
-// If present, a local SSH agent will be used for authentication.
+// The following SSH config directives are supported: HostName, Port, User, IdentityFile,
+// IdentitiesOnly, CertificateFile, UserKnownHostsFile, GlobalKnownHostsFile,
+// StrictHostKeyChecking, HostKeyAlias, ConnectTimeout, ServerAliveInterval, ServerAliveCountMax,
+// ProxyJump and ProxyCommand. Known hosts files can include @cert-authority and @revoked
+// entries. If a private key is encrypted, its passphrase is prompted for via plugin.Prompt. Match
+// blocks are not supported.
 //
-// Lots of SSH configuration is currently omitted, such as global known hosts files, finding known
-// hosts from the config, identity file from config... pretty much everything but port and user
-// from config as enumerated in https://github.com/kevinburke/ssh_config/blob/0.5/validators.go.
+// Hosts behind a bastion are reached via Identity.Jump, or the ProxyJump or ProxyCommand settings
+// in SSH config. Each hop is configured from its own SSH config, and hop connections are cached
+// so that they're re-used by other targets.
 //
-// The known hosts file will be ignored if StrictHostKeyChecking=no, such as in
+// The known hosts files will be ignored if StrictHostKeyChecking=no, such as in
 //   Host *.compute.amazonaws.com
 //     StrictHostKeyChecking no
-func ExecSSH(ctx context.Context, id Identity, cmd []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
-	// find port, username, etc from .ssh/config
-	conf, err := getConnInfo(ctx, id)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to get connection info: %s", err)
-	}
-	activity.Record(ctx, "Found connection info %+v", conf)
+// New hosts are added to the first known hosts file unless StrictHostKeyChecking=yes.
+func ExecSSH(ctx context.Context, id Identity, cmd []string, opts plugin.ExecOptions) (_ plugin.ExecCommand, err error) {
+	// The span ends when the command exits, or now if it couldn't be started.
+	ctx, span := tracing.Start(ctx, "ssh.exec", tracing.String("wash.host", id.Host))
+	defer func() {
+		if err != nil {
+			tracing.End(ctx, span, err)
+		}
+	}()
 
-	connection, err := sshConnect(ctx, conf, id.Retries)
+	connection, err := connect(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to connect: %s", err)
+		return nil, err
 	}
 
 	// Run command via session
@@ -248,8 +532,14 @@ func ExecSSH(ctx context.Context, id Identity, cmd []string, opts plugin.ExecOpt
 
 	if opts.Tty {
 		// sshd only processes signal codes if a TTY has been allocated. So set one up when requested.
-		modes := ssh.TerminalModes{ssh.ECHO: 0, ssh.TTY_OP_ISPEED: 14400, ssh.TTY_OP_OSPEED: 14400}
-		if err := session.RequestPty("xterm", 40, 80, modes); err != nil {
+		// Interactive sessions need the TTY to echo the user's input.
+		echo := uint32(0)
+		if opts.Resize != nil {
+			echo = 1
+		}
+		size := plugin.InitialTerminalSize(opts.Resize)
+		modes := ssh.TerminalModes{ssh.ECHO: echo, ssh.TTY_OP_ISPEED: 14400, ssh.TTY_OP_OSPEED: 14400}
+		if err := session.RequestPty("xterm", int(size.Rows), int(size.Cols), modes); err != nil {
 			return nil, fmt.Errorf("Unable to setup a TTY: %v", err)
 		}
 	}
@@ -257,7 +547,10 @@ func ExecSSH(ctx context.Context, id Identity, cmd []string, opts plugin.ExecOpt
 	execCmd := plugin.NewExecCommand(ctx)
 	session.Stdin, session.Stdout, session.Stderr = opts.Stdin, execCmd.Stdout(), execCmd.Stderr()
 
-	if opts.Elevate {
+	cmd = plugin.WrapCommand(cmd, opts)
+	if opts.User != "" {
+		cmd = append([]string{"sudo", "-u", opts.User, "--"}, cmd...)
+	} else if opts.Elevate {
 		cmd = append([]string{"sudo"}, cmd...)
 	}
 
@@ -265,6 +558,15 @@ func ExecSSH(ctx context.Context, id Identity, cmd []string, opts plugin.ExecOpt
 	if err := session.Start(cmdStr); err != nil {
 		return nil, err
 	}
+	if opts.Tty && opts.Resize != nil {
+		go func() {
+			for size := range opts.Resize {
+				if err := session.WindowChange(int(size.Rows), int(size.Cols)); err != nil {
+					activity.Record(ctx, "Unable to resize the TTY for %v: %v", id.Host, err)
+				}
+			}
+		}()
+	}
 	execCmd.SetStopFunc(func() {
 		// Close the session on context cancellation. Copying will block until there's more to read
 		// from the exec output. For an action with no more output it may never return.
@@ -282,10 +584,13 @@ func ExecSSH(ctx context.Context, id Identity, cmd []string, opts plugin.ExecOpt
 		execCmd.CloseStreamsWithError(nil)
 		if err == nil {
 			execCmd.SetExitCode(0)
+			tracing.End(ctx, span, nil)
 		} else if exitErr, ok := err.(*ssh.ExitError); ok {
 			execCmd.SetExitCode(exitErr.ExitStatus())
+			tracing.End(ctx, span, nil)
 		} else {
 			execCmd.SetExitCodeErr(err)
+			tracing.End(ctx, span, err)
 		}
 	}()
 	return execCmd, nil
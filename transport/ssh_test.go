@@ -8,7 +8,9 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 
@@ -23,10 +25,16 @@ import (
 type SSHTestSuite struct {
 	suite.Suite
 	s             gssh.Server
+	bastion       gssh.Server
+	forwarded     chan string
 	m             mock.Mock
 	knownHosts    string
 	badKnownHosts string
 	identityFile  string
+	identityKey   *rsa.PrivateKey
+	hostSigner    ssh.Signer
+	sshConfigFile string
+	savedFiles    func() []string
 }
 
 var _ = suite.SetupAllSuite(&SSHTestSuite{})
@@ -35,8 +43,9 @@ var _ = suite.TearDownAllSuite(&SSHTestSuite{})
 var _ = suite.TearDownTestSuite(&SSHTestSuite{})
 
 const (
-	host = "localhost"
-	port = 2222
+	host        = "localhost"
+	port        = 2222
+	bastionPort = 2223
 )
 
 // Must be mocked for successful connections.
@@ -105,6 +114,8 @@ func (suite *SSHTestSuite) SetupSuite() {
 	if err != nil {
 		suite.T().Fatal(err)
 	}
+	suite.identityKey = key
+	suite.hostSigner = signer
 	privBytes := x509.MarshalPKCS1PrivateKey(key)
 	if err := pem.Encode(tmpfile, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: privBytes}); err != nil {
 		suite.T().Fatal(err)
@@ -114,13 +125,46 @@ func (suite *SSHTestSuite) SetupSuite() {
 	}
 	suite.identityFile = tmpfile.Name()
 
+	// Use our own SSH config so that the tests don't depend on the user's
+	sshConfig, err := ioutil.TempFile("", "wash_ssh_config")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	if err := sshConfig.Close(); err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.sshConfigFile = sshConfig.Name()
+	suite.savedFiles = sshConfigFiles
+	sshConfigFiles = func() []string { return []string{suite.sshConfigFile} }
+
 	// Setup SSH server
 	suite.s.Addr = addr
 	suite.s.Handle(suite.Handler)
 	suite.s.AddHostKey(signer)
 	suite.s.PasswordHandler = suite.PasswordHandler
 	suite.s.PublicKeyHandler = suite.PublicKeyHandler
-	go func() { fmt.Println(suite.s.ListenAndServe()) }()
+	// Listen before serving so that the server's ready once SetupSuite returns.
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	go func() { fmt.Println(suite.s.Serve(l)) }()
+
+	// Setup a bastion server that only forwards connections
+	suite.forwarded = make(chan string, 10)
+	suite.bastion.Addr = host + ":" + strconv.Itoa(bastionPort)
+	suite.bastion.AddHostKey(signer)
+	suite.bastion.PublicKeyHandler = func(gssh.Context, gssh.PublicKey) bool { return true }
+	suite.bastion.LocalPortForwardingCallback = func(ctx gssh.Context, host string, port uint32) bool {
+		suite.forwarded <- host + ":" + strconv.Itoa(int(port))
+		return true
+	}
+	suite.bastion.ChannelHandlers = map[string]gssh.ChannelHandler{"direct-tcpip": gssh.DirectTCPIPHandler}
+	bl, err := net.Listen("tcp", suite.bastion.Addr)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	go func() { fmt.Println(suite.bastion.Serve(bl)) }()
 }
 
 func (suite *SSHTestSuite) SetupTest() {
@@ -130,6 +174,41 @@ func (suite *SSHTestSuite) SetupTest() {
 
 func (suite *SSHTestSuite) TearDownTest() {
 	connectionCache.Flush()
+	suite.writeSSHConfig("")
+}
+
+func (suite *SSHTestSuite) writeSSHConfig(config string) {
+	suite.Require().NoError(ioutil.WriteFile(suite.sshConfigFile, []byte(config), 0644))
+}
+
+func (suite *SSHTestSuite) writeTempFile(prefix string, content []byte) string {
+	f, err := ioutil.TempFile("", prefix)
+	suite.Require().NoError(err)
+	_, err = f.Write(content)
+	suite.Require().NoError(err)
+	suite.Require().NoError(f.Close())
+	return f.Name()
+}
+
+// startServer starts another SSH server with the given host key. It returns the server's port.
+func (suite *SSHTestSuite) startServer(hostKey ssh.Signer) (*gssh.Server, uint) {
+	l, err := net.Listen("tcp", host+":0")
+	suite.Require().NoError(err)
+	srv := &gssh.Server{Handler: suite.Handler, PublicKeyHandler: suite.PublicKeyHandler}
+	srv.AddHostKey(hostKey)
+	go func() { _ = srv.Serve(l) }()
+	return srv, uint(l.Addr().(*net.TCPAddr).Port)
+}
+
+func (suite *SSHTestSuite) runEcho(identity Identity) error {
+	cmd, err := ExecSSH(context.Background(), identity, []string{"echo", "hello"}, plugin.ExecOptions{})
+	if err != nil {
+		return err
+	}
+	for range cmd.OutputCh() {
+	}
+	_, err = cmd.ExitCode()
+	return err
 }
 
 func (suite *SSHTestSuite) TearDownSuite() {
@@ -137,6 +216,9 @@ func (suite *SSHTestSuite) TearDownSuite() {
 	if err := suite.s.Close(); err != nil {
 		suite.T().Log(err)
 	}
+	if err := suite.bastion.Close(); err != nil {
+		suite.T().Log(err)
+	}
 	if err := os.Remove(suite.knownHosts); err != nil {
 		suite.T().Log(err)
 	}
@@ -146,6 +228,10 @@ func (suite *SSHTestSuite) TearDownSuite() {
 	if err := os.Remove(suite.identityFile); err != nil {
 		suite.T().Log(err)
 	}
+	if err := os.Remove(suite.sshConfigFile); err != nil {
+		suite.T().Log(err)
+	}
+	sshConfigFiles = suite.savedFiles
 }
 
 func (suite *SSHTestSuite) Identity() Identity {
@@ -256,6 +342,192 @@ func (suite *SSHTestSuite) TestExec_ContextCancelled() {
 	}
 }
 
+func (suite *SSHTestSuite) TestExec_Jump() {
+	suite.m.On("Handler", mock.Anything).Run(func(args mock.Arguments) {
+		sess := args.Get(0).(gssh.Session)
+		_, err := sess.Write([]byte("hello\n"))
+		suite.NoError(err)
+		suite.NoError(sess.CloseWrite())
+	})
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.Anything).Return(true)
+
+	identity := suite.Identity()
+	identity.Jump = []Identity{{Host: host, Port: bastionPort, IdentityFile: suite.identityFile, KnownHosts: os.DevNull}}
+	cmd, err := ExecSSH(context.Background(), identity, []string{"echo", "hello"}, plugin.ExecOptions{})
+	if suite.NoError(err) {
+		var resp []plugin.ExecOutputChunk
+		for chunk := range cmd.OutputCh() {
+			resp = append(resp, chunk)
+		}
+		exit, err := cmd.ExitCode()
+		suite.NoError(err)
+		suite.Zero(exit)
+		if suite.Len(resp, 1) {
+			suite.Equal("hello\n", resp[0].Data)
+		}
+		suite.Equal(host+":"+strconv.Itoa(port), <-suite.forwarded)
+
+		// The hop's connection should be cached so that other targets can re-use it
+		_, err = connectionCache.Get("", "root@"+host+":"+strconv.Itoa(bastionPort))
+		suite.NoError(err)
+	}
+}
+
+func (suite *SSHTestSuite) TestExec_JumpLoop() {
+	identity := suite.Identity()
+	identity.Jump = []Identity{suite.Identity()}
+	_, err := ExecSSH(context.Background(), identity, []string{"echo", "hello"}, plugin.ExecOptions{})
+	suite.Regexp("ProxyJump loop detected", err)
+}
+
+func (suite *SSHTestSuite) TestExec_ConfigIdentityFiles() {
+	suite.m.On("Handler", mock.Anything).Run(func(args mock.Arguments) {})
+	identityKey, err := ssh.NewPublicKey(&suite.identityKey.PublicKey)
+	suite.Require().NoError(err)
+	isIdentityKey := func(key gssh.PublicKey) bool { return gssh.KeysEqual(key, identityKey) }
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.MatchedBy(isIdentityKey)).Return(true)
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.Anything).Return(false)
+
+	suite.writeSSHConfig(fmt.Sprintf("Host %v\n  IdentityFile /does/not/exist\n  IdentityFile %v\n  IdentitiesOnly yes\n", host, suite.identityFile))
+	identity := suite.Identity()
+	identity.IdentityFile = ""
+	suite.NoError(suite.runEcho(identity))
+}
+
+func (suite *SSHTestSuite) TestExec_UserCertificate() {
+	suite.m.On("Handler", mock.Anything).Run(func(args mock.Arguments) {})
+	ca, err := generateSigner()
+	suite.Require().NoError(err)
+	signedByCA := func(key gssh.PublicKey) bool {
+		cert, ok := key.(*ssh.Certificate)
+		return ok && gssh.KeysEqual(cert.SignatureKey, ca.PublicKey())
+	}
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.MatchedBy(signedByCA)).Return(true)
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.Anything).Return(false)
+
+	// Sign the identity's key with the CA
+	identityKey, err := ssh.NewPublicKey(&suite.identityKey.PublicKey)
+	suite.Require().NoError(err)
+	cert := &ssh.Certificate{
+		Key:             identityKey,
+		CertType:        ssh.UserCert,
+		KeyId:           "wash",
+		ValidPrincipals: []string{"root"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	suite.Require().NoError(cert.SignCert(rand.Reader, ca))
+	certFile := suite.writeTempFile("wash_ssh_cert", ssh.MarshalAuthorizedKey(cert))
+	defer os.Remove(certFile)
+
+	suite.writeSSHConfig(fmt.Sprintf("Host %v\n  CertificateFile %v\n  IdentitiesOnly yes\n", host, certFile))
+	suite.NoError(suite.runEcho(suite.Identity()))
+}
+
+func (suite *SSHTestSuite) TestExec_HostCertificate() {
+	suite.m.On("Handler", mock.Anything).Run(func(args mock.Arguments) {})
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.Anything).Return(true)
+
+	// Start a server whose host key is signed by the CA
+	ca, err := generateSigner()
+	suite.Require().NoError(err)
+	hostCert := &ssh.Certificate{
+		Key:             suite.hostSigner.PublicKey(),
+		CertType:        ssh.HostCert,
+		KeyId:           "localhost",
+		ValidPrincipals: []string{host},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	suite.Require().NoError(hostCert.SignCert(rand.Reader, ca))
+	hostCertSigner, err := ssh.NewCertSigner(hostCert, suite.hostSigner)
+	suite.Require().NoError(err)
+	srv, srvPort := suite.startServer(hostCertSigner)
+	defer srv.Close()
+
+	// StrictHostKeyChecking=yes ensures that the host is only accepted because of the CA
+	suite.writeSSHConfig("Host *\n  StrictHostKeyChecking yes\n")
+	// Patterns without a port only match port 22
+	pattern := fmt.Sprintf("@cert-authority [%v]:%v ", host, srvPort)
+	knownHosts := suite.writeTempFile("wash_ssh_knownhosts", []byte(pattern+string(ssh.MarshalAuthorizedKey(ca.PublicKey()))))
+	defer os.Remove(knownHosts)
+	identity := suite.Identity()
+	identity.Port, identity.KnownHosts = srvPort, knownHosts
+	suite.NoError(suite.runEcho(identity))
+
+	// An untrusted CA should be rejected
+	otherCA, err := generateSigner()
+	suite.Require().NoError(err)
+	suite.Require().NoError(ioutil.WriteFile(knownHosts, []byte(pattern+string(ssh.MarshalAuthorizedKey(otherCA.PublicKey()))), 0644))
+	connectionCache.Flush()
+	suite.Error(suite.runEcho(identity))
+}
+
+func (suite *SSHTestSuite) TestExec_StrictHostKeyChecking() {
+	suite.m.On("Handler", mock.Anything).Run(func(args mock.Arguments) {})
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.Anything).Return(true)
+
+	knownHosts := suite.writeTempFile("wash_ssh_knownhosts", nil)
+	defer os.Remove(knownHosts)
+	identity := suite.Identity()
+	identity.KnownHosts = knownHosts
+
+	suite.writeSSHConfig("Host *\n  StrictHostKeyChecking yes\n")
+	suite.Regexp("knownhosts: key is unknown", suite.runEcho(identity))
+
+	// By default, new hosts are accepted and added to the known hosts file. Connection
+	// errors are cached, so flush the cache first.
+	connectionCache.Flush()
+	suite.writeSSHConfig("")
+	suite.NoError(suite.runEcho(identity))
+	content, err := ioutil.ReadFile(knownHosts)
+	if suite.NoError(err) {
+		suite.Contains(string(content), "[localhost]:2222")
+	}
+}
+
+func (suite *SSHTestSuite) TestExec_EncryptedIdentityFile() {
+	//nolint:staticcheck
+	block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(suite.identityKey), []byte("passphrase"), x509.PEMCipherAES256)
+	suite.Require().NoError(err)
+	encryptedFile := suite.writeTempFile("wash_ssh_key", pem.EncodeToMemory(block))
+	defer os.Remove(encryptedFile)
+	suite.testEncryptedIdentityFile(encryptedFile)
+}
+
+func (suite *SSHTestSuite) TestExec_EncryptedOpenSSHIdentityFile() {
+	// testdata/encrypted_ed25519 was generated by ssh-keygen with the passphrase "passphrase".
+	encryptedFile, err := filepath.Abs(filepath.Join("testdata", "encrypted_ed25519"))
+	suite.Require().NoError(err)
+	suite.testEncryptedIdentityFile(encryptedFile)
+}
+
+func (suite *SSHTestSuite) testEncryptedIdentityFile(encryptedFile string) {
+	suite.m.On("Handler", mock.Anything).Run(func(args mock.Arguments) {})
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.Anything).Return(true)
+
+	savedPromptFn := promptFn
+	defer func() { promptFn = savedPromptFn }()
+	var prompts int
+	promptFn = func(msg string) (string, error) {
+		prompts++
+		return "passphrase", nil
+	}
+	defer func() {
+		decryptedKeys.Lock()
+		delete(decryptedKeys.signers, encryptedFile)
+		decryptedKeys.Unlock()
+	}()
+
+	identity := suite.Identity()
+	identity.IdentityFile = encryptedFile
+	suite.writeSSHConfig("Host *\n  IdentitiesOnly yes\n")
+	suite.NoError(suite.runEcho(identity))
+
+	// The decrypted key should be re-used for new connections
+	connectionCache.Flush()
+	suite.NoError(suite.runEcho(identity))
+	suite.Equal(1, prompts)
+}
+
 func TestClient(t *testing.T) {
 	suite.Run(t, new(SSHTestSuite))
 }
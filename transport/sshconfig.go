@@ -0,0 +1,167 @@
+package transport
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kevinburke/ssh_config"
+)
+
+// sshConfigFiles returns the SSH config files to search, in order of
+// precedence. It's a variable so that the tests can mock it.
+var sshConfigFiles = func() []string {
+	files := []string{filepath.Join("/", "etc", "ssh", "ssh_config")}
+	if homedir, err := os.UserHomeDir(); err == nil {
+		files = append([]string{filepath.Join(homedir, ".ssh", "config")}, files...)
+	}
+	return files
+}
+
+// sshConfigSettings are the parsed SSH config files. Unlike ssh_config's
+// DefaultUserSettings, they're re-read for each connection so that changes
+// to the SSH config are picked up without restarting Wash. They also support
+// directives that can be specified multiple times, like IdentityFile.
+type sshConfigSettings []*ssh_config.Config
+
+func loadSSHConfig() (sshConfigSettings, error) {
+	var settings sshConfigSettings
+	for _, file := range sshConfigFiles() {
+		f, err := os.Open(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		cfg, err := ssh_config.Decode(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %v: %v", file, err)
+		}
+		settings = append(settings, cfg)
+	}
+	return settings, nil
+}
+
+// get returns the first value of key for the host alias, or key's default
+// value if it isn't set.
+func (s sshConfigSettings) get(alias, key string) (string, error) {
+	for _, cfg := range s {
+		val, err := cfg.Get(alias, key)
+		if err != nil {
+			return "", err
+		}
+		if val != "" {
+			return val, nil
+		}
+	}
+	return ssh_config.Default(key), nil
+}
+
+// getAll returns all the values of key for the host alias, in order. Unlike
+// get, it doesn't search included files and it doesn't return key's default.
+func (s sshConfigSettings) getAll(alias, key string) []string {
+	var vals []string
+	for _, cfg := range s {
+		for _, host := range cfg.Hosts {
+			if !host.Matches(alias) {
+				continue
+			}
+			for _, node := range host.Nodes {
+				if kv, ok := node.(*ssh_config.KV); ok && strings.EqualFold(kv.Key, key) {
+					vals = append(vals, kv.Value)
+				}
+			}
+		}
+	}
+	return vals
+}
+
+func (s sshConfigSettings) getBool(alias, key string) (bool, error) {
+	val, err := s.get(alias, key)
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(val) {
+	case "yes", "true":
+		return true, nil
+	case "", "no", "false":
+		return false, nil
+	default:
+		return false, fmt.Errorf("%v must be yes or no, got %v", key, val)
+	}
+}
+
+func (s sshConfigSettings) getUint(alias, key string) (uint, error) {
+	val, err := s.get(alias, key)
+	if err != nil || val == "" {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(val, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%v must be an unsigned integer, got %v", key, val)
+	}
+	return uint(n), nil
+}
+
+// getSeconds returns a duration that's specified in seconds, like ConnectTimeout.
+func (s sshConfigSettings) getSeconds(alias, key string) (time.Duration, error) {
+	n, err := s.getUint(alias, key)
+	return time.Duration(n) * time.Second, err
+}
+
+// expandPath expands a leading ~ and the tokens that OpenSSH supports in file
+// paths: %d (home directory), %u (local user), %h (host), %r (remote user) and
+// %%.
+func expandPath(path string, conf sshConfig) string {
+	homedir, _ := os.UserHomeDir()
+	localUser := ""
+	if u, err := user.Current(); err == nil {
+		localUser = u.Username
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		path = homedir + path[1:]
+	}
+	return strings.NewReplacer(
+		"%%", "%",
+		"%d", homedir,
+		"%u", localUser,
+		"%h", conf.host,
+		"%r", conf.user,
+	).Replace(path)
+}
+
+// SSHConfigHosts returns the host aliases from the Host blocks in the SSH config files, in
+// the order that they're declared. Wildcard and negated patterns are skipped because they
+// don't name a specific host.
+func SSHConfigHosts() ([]string, error) {
+	settings, err := loadSSHConfig()
+	if err != nil {
+		return nil, err
+	}
+	var aliases []string
+	seen := make(map[string]struct{})
+	for _, cfg := range settings {
+		for _, host := range cfg.Hosts {
+			for _, pattern := range host.Patterns {
+				// Patterns don't expose whether they're negated, but a negated alias doesn't
+				// match its own Host block.
+				alias := pattern.String()
+				if strings.ContainsAny(alias, "*?") || !host.Matches(alias) {
+					continue
+				}
+				if _, ok := seen[alias]; ok {
+					continue
+				}
+				seen[alias] = struct{}{}
+				aliases = append(aliases, alias)
+			}
+		}
+	}
+	return aliases, nil
+}
@@ -0,0 +1,106 @@
+package transport
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SSHConfigTestSuite struct {
+	suite.Suite
+	file       string
+	savedFiles func() []string
+}
+
+func (suite *SSHConfigTestSuite) SetupTest() {
+	f, err := ioutil.TempFile("", "wash_ssh_config")
+	suite.Require().NoError(err)
+	suite.Require().NoError(f.Close())
+	suite.file = f.Name()
+	suite.savedFiles = sshConfigFiles
+	sshConfigFiles = func() []string { return []string{suite.file, "/does/not/exist"} }
+}
+
+func (suite *SSHConfigTestSuite) TearDownTest() {
+	sshConfigFiles = suite.savedFiles
+	suite.NoError(os.Remove(suite.file))
+}
+
+func (suite *SSHConfigTestSuite) load(config string) sshConfigSettings {
+	suite.Require().NoError(ioutil.WriteFile(suite.file, []byte(config), 0644))
+	settings, err := loadSSHConfig()
+	suite.Require().NoError(err)
+	return settings
+}
+
+func (suite *SSHConfigTestSuite) TestGet() {
+	settings := suite.load("Host foo\n  Port 2222\n  IdentityFile ~/a\n  IdentityFile ~/b\nHost *\n  IdentityFile ~/c\n  IdentitiesOnly maybe\n")
+
+	port, err := settings.getUint("foo", "Port")
+	suite.NoError(err)
+	suite.Equal(uint(2222), port)
+
+	// Unset keys use their defaults
+	port, err = settings.getUint("bar", "Port")
+	suite.NoError(err)
+	suite.Equal(uint(22), port)
+
+	suite.Equal([]string{"~/a", "~/b", "~/c"}, settings.getAll("foo", "IdentityFile"))
+	suite.Equal([]string{"~/c"}, settings.getAll("bar", "identityfile"))
+
+	_, err = settings.getBool("foo", "IdentitiesOnly")
+	suite.Regexp("must be yes or no", err)
+}
+
+func (suite *SSHConfigTestSuite) TestExpandPath() {
+	homedir, err := os.UserHomeDir()
+	suite.Require().NoError(err)
+	conf := sshConfig{host: "example.com", user: "admin"}
+	suite.Equal(homedir+"/.ssh/admin@example.com", expandPath("~/.ssh/%r@%h", conf))
+	suite.Equal(homedir+"/100%", expandPath("%d/100%%", conf))
+}
+
+func (suite *SSHConfigTestSuite) TestGetConnInfo() {
+	suite.load(`Host target
+  HostName 10.0.0.1
+  User admin
+  ConnectTimeout 5
+  ServerAliveInterval 30
+  ServerAliveCountMax 2
+  IdentitiesOnly yes
+  IdentityFile /keys/%r
+  CertificateFile /keys/%r-cert.pub
+  StrictHostKeyChecking no
+`)
+	conf, err := getConnInfo(context.Background(), Identity{Host: "target"})
+	if suite.NoError(err) {
+		suite.Equal("10.0.0.1", conf.host)
+		suite.Equal("22", conf.port)
+		suite.Equal("admin", conf.user)
+		suite.Equal(5*time.Second, conf.connectTimeout)
+		suite.Equal(30*time.Second, conf.serverAliveInterval)
+		suite.Equal(uint(2), conf.serverAliveCountMax)
+		suite.True(conf.identitiesOnly)
+		suite.Equal([]string{"/keys/admin"}, conf.identityFiles)
+		suite.Equal([]string{"/keys/admin-cert.pub"}, conf.certificateFiles)
+	}
+
+	suite.load("Host target\n  ConnectTimeout soon\n")
+	_, err = getConnInfo(context.Background(), Identity{Host: "target"})
+	suite.Regexp("ConnectTimeout must be an unsigned integer", err)
+}
+
+func (suite *SSHConfigTestSuite) TestSSHConfigHosts() {
+	suite.load("Host web1 web2\n  Port 2222\nHost *.example.com !bastion db?\n  User admin\nHost web1 db1\n  User root\n")
+	hosts, err := SSHConfigHosts()
+	suite.NoError(err)
+	suite.Equal([]string{"web1", "web2", "db1"}, hosts)
+}
+
+func TestSSHConfig(t *testing.T) {
+	suite.Run(t, new(SSHConfigTestSuite))
+}
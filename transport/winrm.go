@@ -0,0 +1,273 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	"github.com/ghodss/yaml"
+	"github.com/masterzen/winrm"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/tracing"
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+// WinRMIdentity identifies how to connect to a target via WinRM. Connections always use
+// HTTPS.
+type WinRMIdentity struct {
+	Host string `json:"host"`
+	// Port defaults to 5986, WinRM's HTTPS port.
+	Port     uint   `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	// Auth is the authentication scheme. It can be "ntlm" (the default) or "basic".
+	Auth string `json:"auth"`
+	// CACert is the path to a PEM-encoded CA certificate that's used to validate the
+	// target's certificate instead of the system's CAs.
+	CACert string `json:"ca_cert"`
+	// TLSServerName is the name that the target's certificate is validated against. It
+	// defaults to Host.
+	TLSServerName string `json:"tls_server_name"`
+	// Insecure skips validating the target's certificate.
+	Insecure bool `json:"insecure"`
+}
+
+const defaultWinRMPort = 5986
+
+func newWinRMClient(id WinRMIdentity) (*winrm.Client, error) {
+	port := id.Port
+	if port == 0 {
+		port = defaultWinRMPort
+	}
+	var caCert []byte
+	if id.CACert != "" {
+		var err error
+		if caCert, err = ioutil.ReadFile(id.CACert); err != nil {
+			return nil, fmt.Errorf("could not read the CA certificate: %v", err)
+		}
+	}
+	endpoint := winrm.NewEndpoint(id.Host, int(port), true, id.Insecure, caCert, nil, nil, 0)
+	endpoint.TLSServerName = id.TLSServerName
+
+	params := *winrm.DefaultParameters
+	switch strings.ToLower(id.Auth) {
+	case "", "ntlm":
+		params.TransportDecorator = func() winrm.Transporter { return &winrm.ClientNTLM{} }
+	case "basic":
+	default:
+		return nil, fmt.Errorf("unsupported WinRM auth %v, must be ntlm or basic", id.Auth)
+	}
+	return winrm.NewClientWithParameters(endpoint, id.User, id.Password, &params)
+}
+
+// ExecWinRM executes against a target via WinRM. The command's run by PowerShell, so a
+// single-element command can be a PowerShell expression. The remaining elements are passed
+// as arguments, and are quoted if necessary.
+//
+// WinRM can't run commands as another user, and Wash doesn't wrap commands to set their
+// environment or working directory, so the User, Env and WorkingDir options are unsupported.
+// opts.Tty is best-effort and ignored because WinRM can't allocate a TTY, and opts.Elevate
+// is ignored because commands already run with the authenticated user's privileges.
+func ExecWinRM(ctx context.Context, id WinRMIdentity, cmd []string, opts plugin.ExecOptions) (_ plugin.ExecCommand, err error) {
+	// The span ends when the command exits, or now if it couldn't be started.
+	ctx, span := tracing.Start(ctx, "winrm.exec", tracing.String("wash.host", id.Host))
+	defer func() {
+		if err != nil {
+			tracing.End(ctx, span, err)
+		}
+	}()
+
+	switch {
+	case opts.User != "":
+		return nil, plugin.UnsupportedExecOptionErr{Option: "user", Reason: "WinRM can't run commands as another user"}
+	case len(opts.Env) > 0:
+		return nil, plugin.UnsupportedExecOptionErr{Option: "env"}
+	case opts.WorkingDir != "":
+		return nil, plugin.UnsupportedExecOptionErr{Option: "working_dir"}
+	}
+
+	client, err := newWinRMClient(id)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to configure WinRM: %v", err)
+	}
+	activity.Record(ctx, "Connecting to %v via WinRM as %v", id.Host, id.User)
+	shell, err := client.CreateShell()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect: %v", err)
+	}
+	script := powershellCommand(cmd)
+	activity.Record(ctx, "Running %v on %v", script, id.Host)
+	winrmCmd, err := shell.Execute(encodePowershell(script))
+	if err != nil {
+		activity.Record(ctx, "Closing shell for %v: %v", id.Host, shell.Close())
+		return nil, err
+	}
+
+	execCmd := plugin.NewExecCommand(ctx)
+	if opts.Stdin != nil {
+		go func() {
+			_, err := io.Copy(winrmCmd.Stdin, opts.Stdin)
+			if err != nil {
+				activity.Record(ctx, "Error sending input to %v: %v", id.Host, err)
+			}
+			activity.Record(ctx, "Closing stdin for %v: %v", id.Host, winrmCmd.Stdin.Close())
+		}()
+	}
+	execCmd.SetStopFunc(func() {
+		activity.Record(ctx, "Terminating command on context termination for %v: %v", id.Host, winrmCmd.Close())
+	})
+
+	// Wait for the command to complete and stash the result.
+	go func() {
+		var wg sync.WaitGroup
+		var stdoutErr, stderrErr error
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, stdoutErr = io.Copy(execCmd.Stdout(), winrmCmd.Stdout)
+		}()
+		go func() {
+			defer wg.Done()
+			_, stderrErr = io.Copy(execCmd.Stderr(), winrmCmd.Stderr)
+		}()
+		winrmCmd.Wait()
+		if ctx.Err() != nil {
+			// The command was terminated by the stop func. Its output pipes are left open
+			// in that case, so don't wait for the copies to finish.
+			activity.Record(ctx, "Closing shell for %v: %v", id.Host, shell.Close())
+			execCmd.CloseStreamsWithError(ctx.Err())
+			tracing.End(ctx, span, ctx.Err())
+			return
+		}
+		wg.Wait()
+		activity.Record(ctx, "Closing shell for %v: %v", id.Host, shell.Close())
+
+		execCmd.CloseStreamsWithError(nil)
+		if stdoutErr != nil {
+			execCmd.SetExitCodeErr(stdoutErr)
+		} else if stderrErr != nil {
+			execCmd.SetExitCodeErr(stderrErr)
+		} else {
+			execCmd.SetExitCode(winrmCmd.ExitCode())
+		}
+		if stdoutErr != nil {
+			tracing.End(ctx, span, stdoutErr)
+		} else {
+			tracing.End(ctx, span, stderrErr)
+		}
+	}()
+	return execCmd, nil
+}
+
+var safePowershellArg = regexp.MustCompile(`^[\w@%+=:,./\\-]+$`)
+
+// powershellCommand joins cmd into a PowerShell command, quoting the arguments that need it.
+// A single-element command is returned as-is so that it can be a PowerShell expression.
+func powershellCommand(cmd []string) string {
+	if len(cmd) == 1 {
+		return cmd[0]
+	}
+	quoted := make([]string, len(cmd))
+	for i, arg := range cmd {
+		if safePowershellArg.MatchString(arg) {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.Replace(arg, "'", "''", -1) + "'"
+		}
+	}
+	// A quoted command is a string, so it must be invoked with the call operator.
+	if quoted[0] != cmd[0] {
+		return "& " + strings.Join(quoted, " ")
+	}
+	return strings.Join(quoted, " ")
+}
+
+// encodePowershell returns a powershell.exe invocation that runs script. The script's
+// encoded so that it doesn't need to be escaped for cmd.exe.
+func encodePowershell(script string) string {
+	var buf bytes.Buffer
+	for _, c := range utf16.Encode([]rune(script)) {
+		_ = binary.Write(&buf, binary.LittleEndian, c)
+	}
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+	return "powershell.exe -NoProfile -NonInteractive -EncodedCommand " + encoded
+}
+
+// WinRMConfig is a plugin's WinRM configuration, such as
+//   winrm:
+//     user: Administrator
+//     password: hunter2
+//     insecure: true
+//     instances: [win-builder]
+// The identity options apply to all of the plugin's WinRM targets. Host is ignored; it's set
+// from each target.
+type WinRMConfig struct {
+	WinRMIdentity
+	// Instances lists the names of targets that are reached via WinRM even if their
+	// platform isn't Windows.
+	Instances []string `json:"instances"`
+}
+
+// ParseWinRMConfig parses the winrm key of a plugin's config. It returns nil if the key
+// isn't set.
+func ParseWinRMConfig(pluginName string, cfg map[string]interface{}) (*WinRMConfig, error) {
+	winrmCfg, ok := cfg["winrm"]
+	if !ok {
+		return nil, nil
+	}
+	// Nested YAML maps aren't necessarily keyed by strings, so re-marshal the config as
+	// YAML and then decode it as JSON.
+	y, err := yamlv2.Marshal(winrmCfg)
+	if err != nil {
+		return nil, fmt.Errorf("%v.winrm config is invalid: %v", pluginName, err)
+	}
+	j, err := yaml.YAMLToJSON(y)
+	if err != nil {
+		return nil, fmt.Errorf("%v.winrm config is invalid: %v", pluginName, err)
+	}
+	var conf WinRMConfig
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&conf); err != nil {
+		return nil, fmt.Errorf("%v.winrm config is invalid: %v", pluginName, err)
+	}
+	return &conf, nil
+}
+
+// UsesWinRM returns true if the named target should be reached via WinRM. Windows targets
+// always use WinRM. It's safe to call on a nil config.
+func (c *WinRMConfig) UsesWinRM(name string, isWindows bool) bool {
+	if isWindows {
+		return true
+	}
+	if c == nil {
+		return false
+	}
+	for _, instance := range c.Instances {
+		if instance == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Identity returns the identity that's used to connect to host. It's safe to call on a nil
+// config.
+func (c *WinRMConfig) Identity(host string) WinRMIdentity {
+	var id WinRMIdentity
+	if c != nil {
+		id = c.WinRMIdentity
+	}
+	id.Host = host
+	return id
+}
@@ -0,0 +1,314 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/volume"
+	"github.com/stretchr/testify/suite"
+)
+
+const winrmEnvelope = `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:w="http://schemas.dmtf.org/wbem/wsman/1/wsman.xsd" xmlns:rsp="http://schemas.microsoft.com/wbem/wsman/1/windows/shell"><s:Body>%v</s:Body></s:Envelope>`
+
+const (
+	winrmShellResponse   = `<w:SelectorSet><w:Selector Name="ShellId">shell-1</w:Selector></w:SelectorSet>`
+	winrmCommandResponse = `<rsp:CommandResponse><rsp:CommandId>command-1</rsp:CommandId></rsp:CommandResponse>`
+	winrmOutputResponse  = `<rsp:ReceiveResponse><rsp:Stream Name="stdout" CommandId="command-1">%v</rsp:Stream><rsp:Stream Name="stderr" CommandId="command-1">%v</rsp:Stream><rsp:CommandState CommandId="command-1" State="http://schemas.microsoft.com/wbem/wsman/1/windows/shell/CommandState/Running"></rsp:CommandState></rsp:ReceiveResponse>`
+	winrmDoneResponse    = `<rsp:ReceiveResponse><rsp:CommandState CommandId="command-1" State="http://schemas.microsoft.com/wbem/wsman/1/windows/shell/CommandState/Done"><rsp:ExitCode>%v</rsp:ExitCode></rsp:CommandState></rsp:ReceiveResponse>`
+)
+
+// stubWinRMServer is a WinRM server that runs a single command, which writes
+// to stdout and stderr and then exits.
+type stubWinRMServer struct {
+	*httptest.Server
+	mux       sync.Mutex
+	command   string
+	auth      []string
+	receives  int
+	stdout    string
+	stderr    string
+	exitCode  int
+	shellOpen bool
+	// requireNTLM rejects all requests like a server that only accepts NTLM.
+	requireNTLM bool
+}
+
+func (s *stubWinRMServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.auth = append(s.auth, r.Header.Get("Authorization"))
+	if s.requireNTLM {
+		w.Header().Set("WWW-Authenticate", "NTLM")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "password" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var response string
+	switch req := string(body); {
+	case strings.Contains(req, "transfer/Create"):
+		s.shellOpen = true
+		response = winrmShellResponse
+	case strings.Contains(req, "shell/Command"):
+		s.command = regexp.MustCompile(`<rsp:Command><!\[CDATA\[(.*)\]\]></rsp:Command>`).FindStringSubmatch(req)[1]
+		response = winrmCommandResponse
+	case strings.Contains(req, "shell/Receive"):
+		s.receives++
+		if s.receives == 1 {
+			encode := base64.StdEncoding.EncodeToString
+			response = fmt.Sprintf(winrmOutputResponse, encode([]byte(s.stdout)), encode([]byte(s.stderr)))
+		} else {
+			response = fmt.Sprintf(winrmDoneResponse, s.exitCode)
+		}
+	case strings.Contains(req, "transfer/Delete"):
+		s.shellOpen = false
+	}
+	w.Header().Set("Content-Type", "application/soap+xml")
+	fmt.Fprintf(w, winrmEnvelope, response)
+}
+
+type WinRMTestSuite struct {
+	suite.Suite
+	server *stubWinRMServer
+	caCert string
+}
+
+func (suite *WinRMTestSuite) SetupTest() {
+	suite.server = &stubWinRMServer{stdout: "hello\n", stderr: "oops\n", exitCode: 3}
+	suite.server.Server = httptest.NewTLSServer(suite.server)
+
+	f, err := ioutil.TempFile("", "wash_winrm_ca")
+	suite.Require().NoError(err)
+	suite.Require().NoError(pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: suite.server.Certificate().Raw}))
+	suite.Require().NoError(f.Close())
+	suite.caCert = f.Name()
+}
+
+func (suite *WinRMTestSuite) TearDownTest() {
+	suite.server.Close()
+	suite.NoError(os.Remove(suite.caCert))
+}
+
+func (suite *WinRMTestSuite) identity() WinRMIdentity {
+	host, port, err := net.SplitHostPort(suite.server.Listener.Addr().String())
+	suite.Require().NoError(err)
+	portNum, err := strconv.Atoi(port)
+	suite.Require().NoError(err)
+	return WinRMIdentity{Host: host, Port: uint(portNum), User: "user", Password: "password", Auth: "basic", CACert: suite.caCert}
+}
+
+func (suite *WinRMTestSuite) run(id WinRMIdentity, cmd []string) (string, string, int, error) {
+	execCmd, err := ExecWinRM(context.Background(), id, cmd, plugin.ExecOptions{Elevate: true})
+	if err != nil {
+		return "", "", 0, err
+	}
+	var stdout, stderr bytes.Buffer
+	for chunk := range execCmd.OutputCh() {
+		suite.NoError(chunk.Err)
+		if chunk.StreamID == plugin.Stdout {
+			stdout.WriteString(chunk.Data)
+		} else {
+			stderr.WriteString(chunk.Data)
+		}
+	}
+	exitCode, err := execCmd.ExitCode()
+	return stdout.String(), stderr.String(), exitCode, err
+}
+
+func decodePowershell(command string) string {
+	encoded := command[strings.LastIndex(command, " ")+1:]
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return ""
+	}
+	u := make([]uint16, len(b)/2)
+	_ = binary.Read(bytes.NewReader(b), binary.LittleEndian, u)
+	return string(utf16.Decode(u))
+}
+
+func (suite *WinRMTestSuite) TestExecWinRM() {
+	stdout, stderr, exitCode, err := suite.run(suite.identity(), []string{"Write-Output", "hi there"})
+	suite.Require().NoError(err)
+	suite.Equal("hello\n", stdout)
+	suite.Equal("oops\n", stderr)
+	suite.Equal(3, exitCode)
+
+	suite.True(strings.HasPrefix(suite.server.command, "powershell.exe -NoProfile -NonInteractive -EncodedCommand "))
+	suite.Equal("Write-Output 'hi there'", decodePowershell(suite.server.command))
+	suite.False(suite.server.shellOpen)
+}
+
+func (suite *WinRMTestSuite) TestExecWinRM_ValidatesCertificate() {
+	id := suite.identity()
+	id.CACert = ""
+	_, err := ExecWinRM(context.Background(), id, []string{"hostname"}, plugin.ExecOptions{})
+	if suite.Error(err) {
+		suite.Regexp("certificate", err.Error())
+	}
+
+	id.Insecure = true
+	_, _, exitCode, err := suite.run(id, []string{"hostname"})
+	suite.NoError(err)
+	suite.Equal(3, exitCode)
+
+	// The test server's certificate is valid for example.com
+	id = suite.identity()
+	id.TLSServerName = "example.org"
+	_, err = ExecWinRM(context.Background(), id, []string{"hostname"}, plugin.ExecOptions{})
+	suite.Error(err)
+	id.TLSServerName = "example.com"
+	_, _, _, err = suite.run(id, []string{"hostname"})
+	suite.NoError(err)
+}
+
+func (suite *WinRMTestSuite) TestExecWinRM_NTLM() {
+	suite.server.requireNTLM = true
+	id := suite.identity()
+	id.Auth = ""
+	_, err := ExecWinRM(context.Background(), id, []string{"hostname"}, plugin.ExecOptions{})
+	suite.Error(err)
+
+	var negotiated bool
+	for _, auth := range suite.server.auth {
+		negotiated = negotiated || strings.HasPrefix(auth, "NTLM ")
+	}
+	suite.True(negotiated, "expected an NTLM negotiate message, got %v", suite.server.auth)
+
+	id.Auth = "kerberos"
+	_, err = ExecWinRM(context.Background(), id, []string{"hostname"}, plugin.ExecOptions{})
+	suite.EqualError(err, "Failed to configure WinRM: unsupported WinRM auth kerberos, must be ntlm or basic")
+}
+
+func (suite *WinRMTestSuite) TestExecWinRM_UnsupportedOptions() {
+	for _, opts := range []plugin.ExecOptions{
+		{User: "Administrator"},
+		{Env: map[string]string{"A": "b"}},
+		{WorkingDir: `C:\`},
+	} {
+		_, err := ExecWinRM(context.Background(), suite.identity(), []string{"hostname"}, opts)
+		suite.True(plugin.IsUnsupportedExecOptionErr(err), "expected an unsupported option error for %+v, got %v", opts, err)
+	}
+	suite.Empty(suite.server.auth)
+}
+
+type winrmTarget struct {
+	plugin.EntryBase
+	id WinRMIdentity
+}
+
+func (t *winrmTarget) Schema() *plugin.EntrySchema {
+	return nil
+}
+
+func (t *winrmTarget) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	return ExecWinRM(ctx, t.id, append([]string{cmd}, args...), opts)
+}
+
+func (suite *WinRMTestSuite) TestVolumeFS() {
+	suite.server.stdout = `"FullName","Length","CreationTimeUtc","LastAccessTimeUtc","LastWriteTimeUtc","Attributes"
+"C:\Users",,"2018-09-15T07:19:00Z","2020-01-07T21:11:01Z","2020-01-07T21:10:43Z","Directory"
+`
+	suite.server.stderr = ""
+	suite.server.exitCode = 0
+	target := &winrmTarget{EntryBase: plugin.NewEntry("windows"), id: suite.identity()}
+	target.SetTestID("/windows")
+	target.Attributes().SetOS(plugin.OS{LoginShell: plugin.PowerShell})
+	fs := volume.NewExecFS("fs", target, 1)
+
+	dirmap, err := fs.VolumeList(context.Background(), volume.RootPath)
+	if suite.NoError(err) && suite.Contains(dirmap[volume.RootPath], "Users") {
+		attr := dirmap[volume.RootPath]["Users"]
+		suite.True(attr.Mode().IsDir())
+	}
+	suite.Regexp("^Get-ChildItem '/'", decodePowershell(suite.server.command))
+
+	// Streaming requests a TTY, which WinRM ignores.
+	suite.server.receives = 0
+	rdr, err := fs.VolumeStream(context.Background(), "/log")
+	if suite.NoError(err) {
+		content, err := ioutil.ReadAll(rdr)
+		suite.NoError(err)
+		suite.Contains(string(content), "Users")
+		suite.NoError(rdr.Close())
+	}
+	suite.Equal("Get-Content -Wait -Tail 10 '/log'", decodePowershell(suite.server.command))
+}
+
+func TestWinRM(t *testing.T) {
+	suite.Run(t, new(WinRMTestSuite))
+}
+
+type WinRMConfigTestSuite struct {
+	suite.Suite
+}
+
+func (suite *WinRMConfigTestSuite) TestPowershellCommand() {
+	suite.Equal("Get-Content -Wait 'C:\\log'", powershellCommand([]string{"Get-Content -Wait 'C:\\log'"}))
+	suite.Equal(`Get-Item C:\Windows`, powershellCommand([]string{"Get-Item", `C:\Windows`}))
+	suite.Equal(`Write-Output 'it''s here'`, powershellCommand([]string{"Write-Output", "it's here"}))
+	suite.Equal(`& 'C:\Program Files\app.exe' -v`, powershellCommand([]string{`C:\Program Files\app.exe`, "-v"}))
+}
+
+func (suite *WinRMConfigTestSuite) TestParseWinRMConfig() {
+	conf, err := ParseWinRMConfig("aws", map[string]interface{}{})
+	suite.NoError(err)
+	suite.Nil(conf)
+
+	conf, err = ParseWinRMConfig("aws", map[string]interface{}{
+		"winrm": map[interface{}]interface{}{
+			"user":      "Administrator",
+			"password":  "hunter2",
+			"port":      5985,
+			"insecure":  true,
+			"instances": []interface{}{"win-builder"},
+		},
+	})
+	if suite.NoError(err) {
+		suite.Equal(WinRMIdentity{User: "Administrator", Password: "hunter2", Port: 5985, Insecure: true}, conf.WinRMIdentity)
+		suite.Equal([]string{"win-builder"}, conf.Instances)
+		suite.Equal("win-host", conf.Identity("win-host").Host)
+	}
+
+	_, err = ParseWinRMConfig("aws", map[string]interface{}{"winrm": map[string]interface{}{"usr": "Administrator"}})
+	suite.Regexp(`aws.winrm config is invalid: .*unknown field "usr"`, err)
+}
+
+func (suite *WinRMConfigTestSuite) TestUsesWinRM() {
+	var conf *WinRMConfig
+	suite.True(conf.UsesWinRM("win", true))
+	suite.False(conf.UsesWinRM("linux", false))
+	suite.Equal(WinRMIdentity{Host: "win"}, conf.Identity("win"))
+
+	conf = &WinRMConfig{Instances: []string{"win-builder"}}
+	suite.True(conf.UsesWinRM("win-builder", false))
+	suite.False(conf.UsesWinRM("linux", false))
+}
+
+func TestWinRMConfig(t *testing.T) {
+	suite.Run(t, new(WinRMConfigTestSuite))
+}
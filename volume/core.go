@@ -35,6 +35,24 @@ type Interface interface {
 	VolumeDelete(ctx context.Context, path string) (bool, error)
 }
 
+// WritableInterface is an Interface whose files can be written to. Files in a
+// WritableInterface's volume are Writable.
+type WritableInterface interface {
+	Interface
+
+	// Overwrites the content of the file at the specified path. Mirrors plugin.Writable#Write
+	VolumeWrite(ctx context.Context, path string, data []byte) error
+}
+
+// LinkableInterface is an Interface whose volume can contain symbolic links. Symlinks in a
+// LinkableInterface's volume are Linkable; symlinks in other volumes are presented as files.
+type LinkableInterface interface {
+	Interface
+
+	// Returns the target of the symlink at the specified path. Mirrors plugin.Linkable#Readlink
+	VolumeReadlink(ctx context.Context, path string) (string, error)
+}
+
 // Children represents a directory's children. It is a map of <child_basename> => <child_attributes>.
 type Children = map[string]plugin.EntryAttributes
 
@@ -55,6 +73,8 @@ func ChildSchemas() []*plugin.EntrySchema {
 	return []*plugin.EntrySchema{
 		(&dir{}).Schema(),
 		(&file{}).Schema(),
+		(&writableFile{}).Schema(),
+		(&symlink{}).Schema(),
 	}
 }
 
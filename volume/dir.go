@@ -2,6 +2,7 @@ package volume
 
 import (
 	"context"
+	"os"
 
 	"github.com/puppetlabs/wash/plugin"
 )
@@ -52,10 +53,18 @@ func (v *dir) generateChildren(dirmap *dirMap) []plugin.Entry {
 				newEntry.DisableCachingFor(plugin.ListOp)
 			}
 			entries = append(entries, newEntry)
+		} else if impl, ok := v.impl.(LinkableInterface); ok && attr.Mode()&os.ModeSymlink != 0 {
+			newEntry := newSymlink(name, attr, impl, subpath)
+			newEntry.dirmap = dirmap
+			entries = append(entries, newEntry)
 		} else {
 			newEntry := newFile(name, attr, v.impl, subpath)
 			newEntry.dirmap = dirmap
-			entries = append(entries, newEntry)
+			if impl, ok := v.impl.(WritableInterface); ok {
+				entries = append(entries, &writableFile{file: *newEntry, impl: impl})
+			} else {
+				entries = append(entries, newEntry)
+			}
 		}
 	}
 	return entries
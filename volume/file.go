@@ -46,6 +46,21 @@ func (v *file) Delete(ctx context.Context) (bool, error) {
 	return deleteNode(ctx, v.impl, v.path, v.dirmap)
 }
 
+// writableFile is a file in a WritableInterface's volume.
+type writableFile struct {
+	file
+	impl WritableInterface
+}
+
+func (v *writableFile) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(v, "file").SetDescription(fileDescription)
+}
+
+// Write overwrites the content of the file
+func (v *writableFile) Write(ctx context.Context, data []byte) error {
+	return v.impl.VolumeWrite(ctx, v.path, data)
+}
+
 const fileDescription = `
 This is a file on a remote volume or a container/VM.
 `
@@ -84,3 +84,45 @@ func TestVolumeFileErr(t *testing.T) {
 	assert.Nil(t, rdr)
 	assert.Equal(t, errors.New("fail"), err)
 }
+
+type mockWritableFileEntry struct {
+	mockFileEntry
+	written map[string]string
+}
+
+func (m *mockWritableFileEntry) VolumeWrite(_ context.Context, path string, data []byte) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.written[path] = string(data)
+	return nil
+}
+
+func TestVolumeWritableFile(t *testing.T) {
+	impl := &mockWritableFileEntry{
+		mockFileEntry: mockFileEntry{EntryBase: plugin.NewEntry("parent"), content: "hello"},
+		written:       make(map[string]string),
+	}
+	attr := plugin.EntryAttributes{}
+	attr.SetMode(0644)
+	dirmap := &dirMap{mp: DirMap{RootPath: Children{"mine": attr}}}
+	entries := newDir("dummy", plugin.EntryAttributes{}, impl, RootPath).generateChildren(dirmap)
+	if assert.Len(t, entries, 1) {
+		vf, ok := entries[0].(*writableFile)
+		if assert.True(t, ok) {
+			assert.True(t, plugin.WriteAction().IsSupportedOn(vf))
+			assert.NoError(t, vf.Write(context.Background(), []byte("world")))
+			assert.Equal(t, map[string]string{"/mine": "world"}, impl.written)
+
+			content, err := vf.Read(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("hello"), content)
+		}
+	}
+
+	// Files in volumes that can't be written to aren't writable.
+	entries = newDir("dummy", plugin.EntryAttributes{}, &impl.mockFileEntry, RootPath).generateChildren(dirmap)
+	if assert.Len(t, entries, 1) {
+		assert.False(t, plugin.WriteAction().IsSupportedOn(entries[0]))
+	}
+}
@@ -21,17 +21,23 @@ type FS struct {
 // NewFS creates a new FS entry with the given name, using the supplied executor to satisfy volume
 // operations.
 func NewFS(ctx context.Context, name string, executor plugin.Execable, maxdepth int) *FS {
+	fs := NewExecFS(name, executor, maxdepth)
+	if _, err := plugin.List(ctx, fs); err != nil {
+		fs.MarkInaccessible(ctx, err)
+	}
+
+	return fs
+}
+
+// NewExecFS is like NewFS, except it doesn't list the filesystem. It's for entries that
+// delegate their volume operations to an FS rather than including it in the hierarchy.
+func NewExecFS(name string, executor plugin.Execable, maxdepth int) *FS {
 	fs := &FS{
 		EntryBase: plugin.NewEntry(name),
 	}
 	fs.executor = executor
 	fs.maxdepth = maxdepth
 	fs.SetTTLOf(plugin.ListOp, ListTTL)
-
-	if _, err := plugin.List(ctx, fs); err != nil {
-		fs.MarkInaccessible(ctx, err)
-	}
-
 	return fs
 }
 
@@ -219,6 +225,22 @@ func (d *FS) VolumeDelete(ctx context.Context, path string) (bool, error) {
 	return true, nil
 }
 
+// VolumeReadlink satisfies the LinkableInterface required by Readlink to read symlink targets.
+func (d *FS) VolumeReadlink(ctx context.Context, path string) (string, error) {
+	activity.Record(ctx, "Reading link %v on %v", path, plugin.ID(d.executor))
+	command := d.selectShellCommand(
+		[]string{"readlink", path},
+		[]string{"(Get-Item '" + path + "').Target"},
+	)
+
+	buf, err := exec(ctx, d.executor, command, false)
+	if err != nil {
+		activity.Record(ctx, "Exec error running %+v in VolumeReadlink: %v", command, err)
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
 // Selects between a posix and powershell command based on the entry's login shell.
 // Note that powershell commands are often a single string because they represent a PowerShell
 // expression, and it's easier to pass that as a string than try to correctly escape it as
@@ -252,5 +274,6 @@ it. Thus, you're able to do things like 'cat'/'tail' that container/VM's files
 Note that Wash will exec a command on the container/VM whenever it invokes a
 List/Read/Stream action on a directory/file, and the action's result is not
 currently cached. For List, that command is 'find -exec stat'. For Read, that
-command is 'cat'. For Stream, that command is 'tail -f'.
+command is 'cat'. For Stream, that command is 'tail -f'. Symbolic links are
+shown as links; their target is read with 'readlink'.
 `
@@ -33,6 +33,11 @@ const (
 96 1550611510 1550611448 1550611448 41ed /var
 96 1550611510 1550611448 1550611448 41ed /var/log
 96 1550611510 1550611448 1550611448 41ed /var/log/path
+`
+	posixFixtureLinks = `
+96 1550611510 1550611448 1550611448 41ed /var/log/path
+7 1550611510 1550611448 1550611448 a1ff /var/log/current
+11 1550611510 1550611448 1550611448 a1ff /var/log/latest
 `
 	posixFixtureDeep = `
 96 1550611510 1550611448 1550611448 41ed /var/log/path/has
@@ -249,6 +254,42 @@ func (suite *fsTestSuite) TestFSRead() {
 	exec.AssertExpectations(suite.T())
 }
 
+func (suite *fsTestSuite) TestFSReadlink() {
+	if suite.loginShell != plugin.POSIXShell {
+		suite.T().Skip("Symlinks are only reported by StatCmdPOSIX")
+	}
+	depth := 3
+	exec := suite.createExec()
+	exec.onExec(suite.statCmd("/", depth), suite.createResult(posixFixtureLinks))
+
+	fs := NewFS(suite.ctx, "fs", exec, depth)
+
+	entries, err := plugin.List(suite.ctx, suite.find(fs, "var/log").(plugin.Parent))
+	if !suite.NoError(err) {
+		suite.FailNow("Listing entries failed")
+	}
+	suite.Equal(3, entries.Len())
+	suite.Implements((*plugin.Parent)(nil), entries.Map()["path"])
+
+	current, ok := entries.Map()["current"].(plugin.Linkable)
+	if suite.True(ok) {
+		exec.onExec([]string{"readlink", "/var/log/current"}, suite.createResult("path\n"))
+		target, err := current.Readlink(suite.ctx)
+		suite.NoError(err)
+		suite.Equal("path", target)
+	}
+
+	latest, ok := entries.Map()["latest"].(plugin.Linkable)
+	if suite.True(ok) {
+		// Absolute targets are made relative to the link's directory.
+		exec.onExec([]string{"readlink", "/var/log/latest"}, suite.createResult("/etc/hosts\n"))
+		target, err := latest.Readlink(suite.ctx)
+		suite.NoError(err)
+		suite.Equal("../../etc/hosts", target)
+	}
+	exec.AssertExpectations(suite.T())
+}
+
 func (suite *fsTestSuite) TestVolumeDelete() {
 	exec := suite.createExec()
 	exec.onExec(suite.statCmd("/", suite.outputDepth), suite.createResult(suite.outputFixture))
@@ -25,10 +25,9 @@ func StatCmdPOSIX(path string, maxdepth int) []string {
 	// %Z - Time of last status change as seconds since Epoch
 	// %f - Raw mode in hex
 	// %n - File name
-	// TODO: fix as part of https://github.com/puppetlabs/wash/issues/378. We don't currently handle
-	// showing symbolic links, instead representing them as the resolved target.
-	return []string{"find", "-L", path, "-mindepth", "1", "-maxdepth", strconv.Itoa(maxdepth),
-		"-exec", "stat", "-L", "-c", "%s %X %Y %Z %f %n", "{}", "+"}
+	// Symbolic links aren't followed, so they're reported as links rather than as their target.
+	return []string{"find", path, "-mindepth", "1", "-maxdepth", strconv.Itoa(maxdepth),
+		"-exec", "stat", "-c", "%s %X %Y %Z %f %n", "{}", "+"}
 }
 
 // Keep as its own specialized function as it will be faster than munge.ToTime.
@@ -0,0 +1,56 @@
+package volume
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// symlink represents a symbolic link in a LinkableInterface's volume.
+type symlink struct {
+	plugin.EntryBase
+	impl   LinkableInterface
+	path   string
+	dirmap *dirMap
+}
+
+// newSymlink creates a symlink.
+func newSymlink(name string, attr plugin.EntryAttributes, impl LinkableInterface, path string) *symlink {
+	vl := &symlink{
+		EntryBase: plugin.NewEntry(name),
+	}
+	vl.impl = impl
+	vl.path = path
+	vl.SetAttributes(attr)
+	return vl
+}
+
+func (v *symlink) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(v, "symlink").SetDescription(symlinkDescription)
+}
+
+// Readlink returns the link's target. Absolute targets are made relative to the link's
+// directory so that they resolve within the volume rather than from the Wash root.
+func (v *symlink) Readlink(ctx context.Context) (string, error) {
+	target, err := v.impl.VolumeReadlink(ctx, v.path)
+	if err != nil || !path.IsAbs(target) {
+		return target, err
+	}
+	// The volume's root is RootPath, so link paths are absolute within the volume.
+	rel, err := filepath.Rel(path.Dir("/"+v.path), target)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func (v *symlink) Delete(ctx context.Context) (bool, error) {
+	return deleteNode(ctx, v.impl, v.path, v.dirmap)
+}
+
+const symlinkDescription = `
+This is a symbolic link on a remote volume or a container/VM. Its target
+is resolved within the volume.
+`